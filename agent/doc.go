@@ -0,0 +1,3 @@
+// Package agent provides chatbot agents and agent executors that complement those provided by LangChainGo, such as an
+// agent that drives tools through native LLM function calling instead of text-based ReAct prompting.
+package agent
@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/agents"
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/chains"
+	"github.com/tmc/langchaingo/memory"
+	"github.com/tmc/langchaingo/schema"
+	lcgtools "github.com/tmc/langchaingo/tools"
+	"go.opentelemetry.io/otel/attribute"
+	"tmwong.org/arxiv-researcher-go/telemetry"
+)
+
+// The default maximum number of plan/act iterations an [Executor] runs before giving up on an agent.
+const defaultMaxIterations = 25
+
+// A chain that runs an agent to completion. Unlike [agents.Executor], which runs the actions of each agent iteration
+// one after the other, the executor runs all of the actions of an iteration in parallel, which speeds up agents that
+// issue several independent tool calls at once (such as a [FunctionsAgent]).
+//
+// Implements the [chains.Chain] interface.
+type Executor struct {
+	agent         agents.Agent
+	maxIterations int
+	// An optional introspection callback handler for agent actions and finishes.
+	callbacksHandler callbacks.Handler
+}
+
+var _ chains.Chain = (*Executor)(nil)
+
+// An option for configuring an [Executor].
+type ExecutorOption func(*Executor)
+
+// Set the maximum number of plan/act iterations the executor runs before giving up on the agent.
+func WithMaxIterations(maxIterations int) ExecutorOption {
+	return func(executor *Executor) {
+		executor.maxIterations = maxIterations
+	}
+}
+
+// Set the introspection callback handler for agent actions and finishes.
+func WithCallbacksHandler(handler callbacks.Handler) ExecutorOption {
+	return func(executor *Executor) {
+		executor.callbacksHandler = handler
+	}
+}
+
+// Create a new [Executor] that runs an agent.
+//
+// Returns the new executor.
+func NewExecutor(agent agents.Agent, options ...ExecutorOption) *Executor {
+	executor := &Executor{
+		agent:         agent,
+		maxIterations: defaultMaxIterations,
+	}
+	for _, option := range options {
+		option(executor)
+	}
+	return executor
+}
+
+// Run the agent until it returns a final answer, exhausts its iterations, or the context is cancelled. If the agent
+// returns output that we cannot parse, we feed the parse error back to the agent as the observation of a step without
+// an action, so that it can correct itself.
+//
+// Implements the [chains.Chain.Call] API call.
+func (executor *Executor) Call(
+	ctx context.Context,
+	inputValues map[string]any,
+	options ...chains.ChainCallOption,
+) (map[string]any, error) {
+	inputs := make(map[string]string, len(inputValues))
+	for key, value := range inputValues {
+		text, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", agents.ErrExecutorInputNotString, key)
+		}
+		inputs[key] = text
+	}
+	tools := make(map[string]lcgtools.Tool)
+	for _, tool := range executor.agent.GetTools() {
+		tools[strings.ToUpper(tool.Name())] = tool
+	}
+
+	var steps []schema.AgentStep
+	for iteration := range executor.maxIterations {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var finish *schema.AgentFinish
+		var err error
+		steps, finish, err = executor.iterate(ctx, iteration, tools, steps, inputs, options...)
+		if err != nil {
+			return nil, err
+		}
+		if finish != nil {
+			return finish.ReturnValues, nil
+		}
+	}
+	return nil, agents.ErrNotFinished
+}
+
+// Run one plan/act iteration of the agent, traced as an "agent.iteration" operation (see [telemetry.Start]): ask the
+// agent for its next actions given the steps so far, and run them.
+//
+// Returns the steps so far, including those of the iteration, along with the final answer of the agent if it gives
+// one, if the iteration succeeds, otherwise returns an error.
+func (executor *Executor) iterate(
+	ctx context.Context,
+	iteration int,
+	tools map[string]lcgtools.Tool,
+	steps []schema.AgentStep,
+	inputs map[string]string,
+	options ...chains.ChainCallOption,
+) (_ []schema.AgentStep, _ *schema.AgentFinish, err error) {
+	ctx, operation := telemetry.Start(ctx, "agent.iteration")
+	operation.SetAttributes(attribute.Int("agent.iteration", iteration))
+	defer func() { operation.End(err) }()
+	actions, finish, err := executor.agent.Plan(ctx, steps, inputs, options...)
+	if errors.Is(err, agents.ErrUnableToParseOutput) {
+		return append(steps, schema.AgentStep{Observation: err.Error()}), nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if finish != nil {
+		if executor.callbacksHandler != nil {
+			executor.callbacksHandler.HandleAgentFinish(ctx, *finish)
+		}
+		return steps, finish, nil
+	}
+	if len(actions) == 0 {
+		return nil, nil, agents.ErrAgentNoReturn
+	}
+	observations, err := executor.act(ctx, tools, actions)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, action := range actions {
+		steps = append(steps, schema.AgentStep{Action: action, Observation: observations[i]})
+	}
+	return steps, nil, nil
+}
+
+// Run a set of agent actions in parallel.
+//
+// Returns the observation for each action, in the same order as the actions, if all of the tools return
+// successfully, otherwise returns the errors returned by the failing tools.
+func (executor *Executor) act(
+	ctx context.Context,
+	tools map[string]lcgtools.Tool,
+	actions []schema.AgentAction,
+) ([]string, error) {
+	observations := make([]string, len(actions))
+	errs := make([]error, len(actions))
+	var wg sync.WaitGroup
+	for i, action := range actions {
+		if executor.callbacksHandler != nil {
+			executor.callbacksHandler.HandleAgentAction(ctx, action)
+		}
+		tool, ok := tools[strings.ToUpper(action.Tool)]
+		if !ok {
+			observations[i] = fmt.Sprintf("%s is not a valid tool, try another one", action.Tool)
+			continue
+		}
+		wg.Go(func() {
+			observations[i], errs[i] = tool.Call(ctx, strings.TrimSuffix(action.ToolInput, "\nObservation:"))
+		})
+	}
+	wg.Wait()
+	return observations, errors.Join(errs...)
+}
+
+// Get the memory of the executor. Agents keep their own record of intermediate steps, so the executor has no memory.
+//
+// Implements the [chains.Chain.GetMemory] API call.
+func (executor *Executor) GetMemory() schema.Memory {
+	return memory.NewSimple()
+}
+
+// Get the input keys of the agent run by the executor.
+//
+// Implements the [chains.Chain.GetInputKeys] API call.
+func (executor *Executor) GetInputKeys() []string {
+	return executor.agent.GetInputKeys()
+}
+
+// Get the output keys of the agent run by the executor.
+//
+// Implements the [chains.Chain.GetOutputKeys] API call.
+func (executor *Executor) GetOutputKeys() []string {
+	return executor.agent.GetOutputKeys()
+}
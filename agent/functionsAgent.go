@@ -0,0 +1,248 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/langchaingo/agents"
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/chains"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/prompts"
+	"github.com/tmc/langchaingo/schema"
+	lcgtools "github.com/tmc/langchaingo/tools"
+)
+
+// The key under which agents return their final answer to the executor.
+const outputKey = "output"
+
+// A tool that can describe its input arguments with a JSON schema, such as a [tools.Tool].
+type parameterizedTool interface {
+	lcgtools.Tool
+	Parameters() map[string]any
+}
+
+// An agent that drives its tools with native LLM tool (function) calling. Instead of asking the LLM to emit actions in
+// the ReAct text format and parsing them out again, the agent passes the LLM a JSON schema for each tool and receives
+// structured tool calls back, whose JSON arguments it hands to the tools verbatim. The LLM may request several tool
+// calls in a single response, which the [Executor] then runs in parallel.
+//
+// Implements the [agents.Agent] interface.
+type FunctionsAgent struct {
+	llm   llms.Model
+	tools []lcgtools.Tool
+	// The template for the system message that sets up the task for the LLM. The template receives the same inputs
+	// as the agent, e.g., `today`.
+	systemPrompt prompts.PromptTemplate
+	// An optional introspection callback handler for LLM events.
+	callbacksHandler callbacks.Handler
+}
+
+var _ agents.Agent = (*FunctionsAgent)(nil)
+
+// Returned by [FunctionsAgent.Plan] when the LLM rejects the first request of a run because its model does not support
+// tool calling, so that callers can fall back to a text-based ReAct agent.
+var ErrToolCallingUnsupported = errors.New("LLM does not support tool calling")
+
+// Create a new [FunctionsAgent] that uses an LLM to drive a set of tools. The system prompt is a Go template that may
+// refer to any agent input, e.g., `{{.today}}`.
+//
+// Returns the new agent.
+func NewFunctionsAgent(
+	llm llms.Model,
+	agentTools []lcgtools.Tool,
+	systemPrompt string,
+	callbacksHandler callbacks.Handler,
+) *FunctionsAgent {
+	return &FunctionsAgent{
+		llm:   llm,
+		tools: agentTools,
+		systemPrompt: prompts.PromptTemplate{
+			Template:       systemPrompt,
+			TemplateFormat: prompts.TemplateFormatGoTemplate,
+		},
+		callbacksHandler: callbacksHandler,
+	}
+}
+
+// Ask the LLM for the next set of tool calls, or for the final answer, given the record of tool calls so far.
+//
+// Implements the [agents.Agent.Plan] API call.
+func (agent *FunctionsAgent) Plan(
+	ctx context.Context,
+	intermediateSteps []schema.AgentStep,
+	inputs map[string]string,
+	options ...chains.ChainCallOption,
+) ([]schema.AgentAction, *schema.AgentFinish, error) {
+	values := make(map[string]any, len(inputs))
+	for key, value := range inputs {
+		values[key] = value
+	}
+	systemMessage, err := agent.systemPrompt.Format(values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed while formatting system prompt: %w", err)
+	}
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, systemMessage),
+		llms.TextParts(llms.ChatMessageTypeHuman, inputs["input"]),
+	}
+	messages = append(messages, scratchpad(intermediateSteps)...)
+
+	if agent.callbacksHandler != nil {
+		agent.callbacksHandler.HandleLLMGenerateContentStart(ctx, messages)
+	}
+	llmOptions := append([]llms.CallOption{llms.WithTools(agent.definitions())},
+		chains.GetLLMCallOptions(options...)...)
+	response, err := agent.llm.GenerateContent(ctx, messages, llmOptions...)
+	if err != nil {
+		if agent.callbacksHandler != nil {
+			agent.callbacksHandler.HandleLLMError(ctx, err)
+		}
+		if len(intermediateSteps) == 0 && rejectsTools(err) {
+			return nil, nil, fmt.Errorf("%w: %w", ErrToolCallingUnsupported, err)
+		}
+		return nil, nil, err
+	}
+	if agent.callbacksHandler != nil {
+		agent.callbacksHandler.HandleLLMGenerateContentEnd(ctx, response)
+	}
+	if len(response.Choices) == 0 {
+		return nil, nil, fmt.Errorf("%w: LLM returned no choices", agents.ErrUnableToParseOutput)
+	}
+
+	choice := response.Choices[0]
+	if len(choice.ToolCalls) == 0 {
+		return nil, &schema.AgentFinish{
+			ReturnValues: map[string]any{outputKey: choice.Content},
+			Log:          choice.Content,
+		}, nil
+	}
+	// Tag every action from this response with the same log entry, so that we can regroup the actions into a single
+	// assistant message when we rebuild the scratchpad on the next iteration.
+	log := fmt.Sprintf("Step %d: %s", len(intermediateSteps), choice.Content)
+	actions := make([]schema.AgentAction, 0, len(choice.ToolCalls))
+	for _, toolCall := range choice.ToolCalls {
+		if toolCall.FunctionCall == nil {
+			continue
+		}
+		actions = append(actions, schema.AgentAction{
+			Tool:      toolCall.FunctionCall.Name,
+			ToolInput: toolCall.FunctionCall.Arguments,
+			Log:       log,
+			ToolID:    toolCall.ID,
+		})
+	}
+	// The executor feeds parse errors back to the LLM, so that it can retry the tool calls it left without functions.
+	if len(actions) == 0 {
+		return nil, nil, fmt.Errorf("%w: LLM returned tool calls without functions", agents.ErrUnableToParseOutput)
+	}
+	return actions, nil, nil
+}
+
+// Check whether an LLM error tells that the model does not support tools, e.g., "tools is not supported in this
+// model" or "Unsupported parameter: 'tools'", as OpenAI reports for models without tool calling.
+//
+// Returns true if the error rejects tools, otherwise returns false.
+func rejectsTools(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "tools") && (strings.Contains(message, "not supported") ||
+		strings.Contains(message, "unsupported") || strings.Contains(message, "does not support"))
+}
+
+// Get the input keys of the agent.
+//
+// Implements the [agents.Agent.GetInputKeys] API call.
+func (agent *FunctionsAgent) GetInputKeys() []string {
+	return []string{"input"}
+}
+
+// Get the output keys of the agent.
+//
+// Implements the [agents.Agent.GetOutputKeys] API call.
+func (agent *FunctionsAgent) GetOutputKeys() []string {
+	return []string{outputKey}
+}
+
+// Get the tools available to the agent.
+//
+// Implements the [agents.Agent.GetTools] API call.
+func (agent *FunctionsAgent) GetTools() []lcgtools.Tool {
+	return agent.tools
+}
+
+// Build the LLM tool definitions for the tools available to the agent. We use the JSON schema of tools that provide
+// one, and otherwise fall back to a schema with a single free-form string argument.
+//
+// Returns a tool definition for each tool.
+func (agent *FunctionsAgent) definitions() []llms.Tool {
+	definitions := make([]llms.Tool, len(agent.tools))
+	for i, tool := range agent.tools {
+		var parameters map[string]any
+		if parameterized, ok := tool.(parameterizedTool); ok {
+			parameters = parameterized.Parameters()
+		} else {
+			parameters = map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"input": map[string]any{"type": "string"}},
+				"required":   []string{"input"},
+			}
+		}
+		definitions[i] = llms.Tool{
+			Type: "function",
+			Function: &llms.FunctionDefinition{
+				Name:        tool.Name(),
+				Description: tool.Description(),
+				Parameters:  parameters,
+			},
+		}
+	}
+	return definitions
+}
+
+// Rebuild the conversation with the LLM from the record of tool calls so far. Each group of consecutive steps sharing
+// a log entry came from a single LLM response, so we replay each group as one assistant message holding all of the
+// group's tool calls, followed by one tool message per call holding its result. A step without a tool call records
+// an LLM response that we could not parse (see [Executor]), which we replay as a user message holding the error, since
+// the LLM rejects tool messages that answer no tool call.
+//
+// Returns the messages to append to the prompt.
+func scratchpad(steps []schema.AgentStep) []llms.MessageContent {
+	var messages []llms.MessageContent
+	for start := 0; start < len(steps); {
+		if steps[start].Action.Tool == "" {
+			messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, steps[start].Observation))
+			start++
+			continue
+		}
+		end := start
+		for end < len(steps) && steps[end].Action.Tool != "" && steps[end].Action.Log == steps[start].Action.Log {
+			end++
+		}
+		calls := llms.MessageContent{Role: llms.ChatMessageTypeAI}
+		for _, step := range steps[start:end] {
+			calls.Parts = append(calls.Parts, llms.ToolCall{
+				ID:   step.Action.ToolID,
+				Type: "function",
+				FunctionCall: &llms.FunctionCall{
+					Name:      step.Action.Tool,
+					Arguments: step.Action.ToolInput,
+				},
+			})
+		}
+		messages = append(messages, calls)
+		for _, step := range steps[start:end] {
+			messages = append(messages, llms.MessageContent{
+				Role: llms.ChatMessageTypeTool,
+				Parts: []llms.ContentPart{llms.ToolCallResponse{
+					ToolCallID: step.Action.ToolID,
+					Name:       step.Action.Tool,
+					Content:    step.Observation,
+				}},
+			})
+		}
+		start = end
+	}
+	return messages
+}
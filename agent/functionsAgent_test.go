@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	lcgtools "github.com/tmc/langchaingo/tools"
+	"tmwong.org/arxiv-researcher-go/fakes"
+)
+
+// A tool that echoes its input.
+//
+// Implements the [lcgtools.Tool] interface.
+type echoTool struct{}
+
+// Implements the [lcgtools.Tool.Name] API call.
+func (echoTool) Name() string {
+	return "Echo"
+}
+
+// Implements the [lcgtools.Tool.Description] API call.
+func (echoTool) Description() string {
+	return "Echo the input."
+}
+
+// Implements the [lcgtools.Tool.Call] API call.
+func (echoTool) Call(ctx context.Context, input string) (string, error) {
+	return input, nil
+}
+
+// A response whose tool calls all lack functions is fed back to the LLM as a parse error, rather than ending the run
+// with neither actions nor an answer.
+func TestFunctionsAgentToolCallsWithoutFunctions(t *testing.T) {
+	llm := fakes.NewLLM(
+		llms.ContentChoice{StopReason: "tool_calls", ToolCalls: []llms.ToolCall{{ID: "call_1", Type: "function"}}},
+		fakes.Answer("ReAct interleaves reasoning and acting."),
+	)
+	agent := NewFunctionsAgent(llm, []lcgtools.Tool{echoTool{}}, "You are a research assistant.", nil)
+	outputs, err := NewExecutor(agent).Call(t.Context(), map[string]any{"input": "What is ReAct?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outputs[outputKey] != "ReAct interleaves reasoning and acting." {
+		t.Errorf("unexpected outputs %v", outputs)
+	}
+	calls := llm.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected the LLM to be asked again, got %d calls", len(calls))
+	}
+	last := calls[1][len(calls[1])-1]
+	if text, ok := last.Parts[0].(llms.TextContent); last.Role != llms.ChatMessageTypeHuman || !ok ||
+		!strings.Contains(text.Text, "tool calls without functions") {
+		t.Errorf("expected the parse error as the last message, got %+v", last)
+	}
+}
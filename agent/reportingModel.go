@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// An LLM wrapper that reports the calls of an LLM to an introspection callback handler, for agents that do not report
+// the LLM calls they make, such as the LangChainGo [agents.OneShotZeroAgent].
+//
+// Implements the [llms.Model] interface.
+type reportingModel struct {
+	llm              llms.Model
+	callbacksHandler callbacks.Handler
+}
+
+var _ llms.Model = (*reportingModel)(nil)
+
+// Wrap an LLM so that it reports the start, end, and errors of its calls to a callback handler.
+//
+// Returns the wrapped LLM.
+func NewReportingModel(llm llms.Model, callbacksHandler callbacks.Handler) llms.Model {
+	return &reportingModel{llm: llm, callbacksHandler: callbacksHandler}
+}
+
+// Call the LLM, and report the call.
+//
+// Implements the [llms.Model.GenerateContent] API call.
+func (model *reportingModel) GenerateContent(
+	ctx context.Context,
+	messages []llms.MessageContent,
+	options ...llms.CallOption,
+) (*llms.ContentResponse, error) {
+	model.callbacksHandler.HandleLLMGenerateContentStart(ctx, messages)
+	response, err := model.llm.GenerateContent(ctx, messages, options...)
+	if err != nil {
+		model.callbacksHandler.HandleLLMError(ctx, err)
+		return nil, err
+	}
+	model.callbacksHandler.HandleLLMGenerateContentEnd(ctx, response)
+	return response, nil
+}
+
+// Call the LLM with a single prompt, and report the call.
+//
+// Implements the [llms.Model.Call] API call.
+func (model *reportingModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, model, prompt, options...)
+}
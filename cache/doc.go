@@ -0,0 +1,4 @@
+// Package cache provides on-disk caches that save agents and tools from repeating expensive requests to external
+// services, such as computing embeddings of texts they have embedded before, or repeating arXiv queries they have
+// recently made.
+package cache
@@ -0,0 +1,278 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/tmc/langchaingo/embeddings"
+	bolt "go.etcd.io/bbolt"
+)
+
+// The maximum number of texts we send to the underlying embedder in a single request when filling cache misses.
+const embeddingBatchSize = 256
+
+// An on-disk cache of embeddings, backed by a bbolt database. The cache keeps one bucket of embeddings per embedding
+// model, keyed by the SHA-256 hash of the embedded text, so that changing models never serves stale vectors. Each entry
+// records when we cached it, so that users can prune old entries.
+type EmbeddingCache struct {
+	db     *bolt.DB
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// Statistics about an [EmbeddingCache].
+type EmbeddingCacheStats struct {
+	// The number of lookups the cache answered, and failed to answer, since we opened it.
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	// The number of cached embeddings, keyed by embedding model.
+	Entries map[string]int `json:"entries"`
+	// The size of the cache database file, in bytes.
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// Open (or create) an embedding cache database file. Only one process may open the file at a time; if another process
+// holds the file for longer than a second, we give up and return an error.
+//
+// Returns the cache if we open it successfully, otherwise returns an error.
+func OpenEmbeddingCache(path string) (*EmbeddingCache, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed while opening embedding cache '%s': %w", path, err)
+	}
+	return &EmbeddingCache{db: db}, nil
+}
+
+// Close the cache database file.
+func (cache *EmbeddingCache) Close() error {
+	return cache.db.Close()
+}
+
+// Wrap an embedder so that it answers from the cache where it can, and caches the embeddings it computes. The model
+// name must identify the model behind the embedder.
+//
+// Returns the wrapped embedder.
+func (cache *EmbeddingCache) Embedder(inner embeddings.Embedder, model string) embeddings.Embedder {
+	return &cachedEmbedder{cache: cache, inner: inner, bucket: []byte(model)}
+}
+
+// Get statistics about the cache.
+//
+// Returns the statistics if we read them successfully, otherwise returns an error.
+func (cache *EmbeddingCache) Stats() (EmbeddingCacheStats, error) {
+	stats := EmbeddingCacheStats{
+		Hits:    cache.hits.Load(),
+		Misses:  cache.misses.Load(),
+		Entries: map[string]int{},
+	}
+	err := cache.db.View(func(tx *bolt.Tx) error {
+		stats.SizeBytes = tx.Size()
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			stats.Entries[string(name)] = bucket.Stats().KeyN
+			return nil
+		})
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed while reading embedding cache statistics: %w", err)
+	}
+	return stats, nil
+}
+
+// Remove entries from the cache. We remove every entry cached before the cutoff time, and, if the model name is not
+// empty, every entry for that model regardless of age. Passing the zero time removes no entries by age.
+//
+// Returns the number of entries removed if we prune the cache successfully, otherwise returns an error.
+func (cache *EmbeddingCache) Prune(cutoff time.Time, model string) (int, error) {
+	removed := 0
+	err := cache.db.Update(func(tx *bolt.Tx) error {
+		if model != "" {
+			if bucket := tx.Bucket([]byte(model)); bucket != nil {
+				removed += bucket.Stats().KeyN
+				if err := tx.DeleteBucket([]byte(model)); err != nil {
+					return err
+				}
+			}
+		}
+		if cutoff.IsZero() {
+			return nil
+		}
+		return tx.ForEach(func(_ []byte, bucket *bolt.Bucket) error {
+			cursor := bucket.Cursor()
+			for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+				if cachedAt, _ := decodeEntry(value); cachedAt.Before(cutoff) {
+					if err := cursor.Delete(); err != nil {
+						return err
+					}
+					removed++
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed while pruning embedding cache: %w", err)
+	}
+	return removed, nil
+}
+
+// An embedder wrapper that answers from an [EmbeddingCache] where it can.
+//
+// Implements the [embeddings.Embedder] interface.
+type cachedEmbedder struct {
+	cache  *EmbeddingCache
+	inner  embeddings.Embedder
+	bucket []byte
+}
+
+// Embed a set of documents. We look up every text in the cache first, and then embed the distinct texts we miss in
+// batches.
+//
+// Implements the [embeddings.Embedder.EmbedDocuments] API call.
+func (embedder *cachedEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([][]byte, len(texts))
+	for i, text := range texts {
+		keys[i] = embeddingKey(text)
+	}
+	err := embedder.cache.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(embedder.bucket)
+		if bucket == nil {
+			return nil
+		}
+		for i, key := range keys {
+			if value := bucket.Get(key); value != nil {
+				_, vectors[i] = decodeEntry(value)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading embedding cache: %w", err)
+	}
+
+	// Collect the distinct texts we missed, remembering every position at which each one occurs.
+	var missed []string
+	positions := map[string][]int{}
+	for i, text := range texts {
+		if vectors[i] != nil {
+			embedder.cache.hits.Add(1)
+			continue
+		}
+		embedder.cache.misses.Add(1)
+		if _, seen := positions[text]; !seen {
+			missed = append(missed, text)
+		}
+		positions[text] = append(positions[text], i)
+	}
+	for start := 0; start < len(missed); start += embeddingBatchSize {
+		batch := missed[start:min(start+embeddingBatchSize, len(missed))]
+		// Pass a copy of the batch, since some embedders (e.g., those of LangChainGo, which strip newlines) modify the
+		// texts in place, and we need the original texts to store and place the vectors.
+		batchVectors, err := embedder.inner.EmbedDocuments(ctx, slices.Clone(batch))
+		if err != nil {
+			return nil, err
+		}
+		if len(batchVectors) != len(batch) {
+			return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(batchVectors), len(batch))
+		}
+		if err := embedder.store(batch, batchVectors); err != nil {
+			return nil, err
+		}
+		for i, text := range batch {
+			for _, position := range positions[text] {
+				vectors[position] = batchVectors[i]
+			}
+		}
+	}
+	return vectors, nil
+}
+
+// Embed a query.
+//
+// Implements the [embeddings.Embedder.EmbedQuery] API call.
+func (embedder *cachedEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	key := embeddingKey(text)
+	var vector []float32
+	err := embedder.cache.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(embedder.bucket); bucket != nil {
+			if value := bucket.Get(key); value != nil {
+				_, vector = decodeEntry(value)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading embedding cache: %w", err)
+	}
+	if vector != nil {
+		embedder.cache.hits.Add(1)
+		return vector, nil
+	}
+	embedder.cache.misses.Add(1)
+	vector, err = embedder.inner.EmbedQuery(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	if err := embedder.store([]string{text}, [][]float32{vector}); err != nil {
+		return nil, err
+	}
+	return vector, nil
+}
+
+// Save a set of embeddings to the cache.
+func (embedder *cachedEmbedder) store(texts []string, vectors [][]float32) error {
+	now := time.Now()
+	err := embedder.cache.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(embedder.bucket)
+		if err != nil {
+			return err
+		}
+		for i, text := range texts {
+			if err := bucket.Put(embeddingKey(text), encodeEntry(now, vectors[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed while writing embedding cache: %w", err)
+	}
+	return nil
+}
+
+// Compute the cache key of a text.
+func embeddingKey(text string) []byte {
+	sum := sha256.Sum256([]byte(text))
+	return sum[:]
+}
+
+// Encode a cache entry as the time we cached it (in Unix nanoseconds), followed by the components of the vector, all
+// little-endian.
+func encodeEntry(cachedAt time.Time, vector []float32) []byte {
+	entry := make([]byte, 8+4*len(vector))
+	binary.LittleEndian.PutUint64(entry, uint64(cachedAt.UnixNano()))
+	for i, component := range vector {
+		binary.LittleEndian.PutUint32(entry[8+4*i:], math.Float32bits(component))
+	}
+	return entry
+}
+
+// Decode a cache entry encoded by [encodeEntry]. The returned vector does not alias the entry, which bbolt only keeps
+// valid for the duration of a transaction.
+func decodeEntry(entry []byte) (time.Time, []float32) {
+	if len(entry) < 8 {
+		return time.Time{}, nil
+	}
+	cachedAt := time.Unix(0, int64(binary.LittleEndian.Uint64(entry)))
+	vector := make([]float32, (len(entry)-8)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(entry[8+4*i:]))
+	}
+	return cachedAt, vector
+}
@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"tmwong.org/arxiv-researcher-go/fakes"
+)
+
+// Open a fresh embedding cache in a temporary directory, and close it when the test ends.
+func openCache(t *testing.T, path string) *EmbeddingCache {
+	t.Helper()
+	embeddingCache, err := OpenEmbeddingCache(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { embeddingCache.Close() })
+	return embeddingCache
+}
+
+// The cache embeds each distinct text once per model, and serves repeats from disk.
+func TestEmbeddingCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embeddings.db")
+	embeddingCache := openCache(t, path)
+	inner := fakes.NewEmbedder()
+	embedder := embeddingCache.Embedder(inner, "model-a")
+
+	texts := []string{"reasoning and acting", "tool use", "reasoning and acting"}
+	first, err := embedder.EmbedDocuments(t.Context(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.Texts() != 2 {
+		t.Errorf("embedded %d texts, want 2 distinct texts", inner.Texts())
+	}
+	want, _ := inner.EmbedDocuments(t.Context(), texts)
+	if !reflect.DeepEqual(first, want) {
+		t.Errorf("cached embedder returned different vectors from the inner embedder")
+	}
+
+	inner = fakes.NewEmbedder()
+	embedder = embeddingCache.Embedder(inner, "model-a")
+	if _, err := embedder.EmbedQuery(t.Context(), "tool use"); err != nil {
+		t.Fatal(err)
+	}
+	if inner.Texts() != 0 {
+		t.Errorf("embedded %d texts, want all answered from cache", inner.Texts())
+	}
+	if _, err := embeddingCache.Embedder(inner, "model-b").EmbedQuery(t.Context(), "tool use"); err != nil {
+		t.Fatal(err)
+	}
+	if inner.Texts() != 1 {
+		t.Errorf("embedded %d texts, want a miss for a different model", inner.Texts())
+	}
+
+	stats, err := embeddingCache.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Hits != 1 || stats.Misses != 4 {
+		t.Errorf("got %d hits and %d misses, want 1 and 4", stats.Hits, stats.Misses)
+	}
+	if want := map[string]int{"model-a": 2, "model-b": 1}; !reflect.DeepEqual(stats.Entries, want) {
+		t.Errorf("got entries %v, want %v", stats.Entries, want)
+	}
+
+	removed, err := embeddingCache.Prune(time.Time{}, "model-b")
+	if err != nil || removed != 1 {
+		t.Errorf("pruned %d entries (%v), want 1", removed, err)
+	}
+	removed, err = embeddingCache.Prune(time.Now().Add(time.Hour), "")
+	if err != nil || removed != 2 {
+		t.Errorf("pruned %d entries (%v), want 2", removed, err)
+	}
+}
+
+// Inner embedders that modify the texts they embed, as LangChainGo embedders do when stripping newlines, must not keep
+// the cache from placing and storing their vectors.
+func TestEmbeddingCacheMutatingEmbedder(t *testing.T) {
+	embeddingCache := openCache(t, filepath.Join(t.TempDir(), "embeddings.db"))
+	inner, err := embeddings.NewEmbedder(fakes.NewEmbedder())
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := []string{"Title: {ReAct}\nSummary: {Reasoning and acting.}"}
+	vectors, err := embeddingCache.Embedder(inner, "model-a").EmbedDocuments(t.Context(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 1 || len(vectors[0]) != fakes.DefaultDimension {
+		t.Errorf("unexpected vectors %v", vectors)
+	}
+	if texts[0] != "Title: {ReAct}\nSummary: {Reasoning and acting.}" {
+		t.Errorf("cached embedder modified its input to %q", texts[0])
+	}
+}
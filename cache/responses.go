@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// The bucket in which a [ResponseCache] keeps its entries.
+var responsesBucket = []byte("responses")
+
+// An on-disk cache of HTTP responses, backed by a bbolt database. The cache keeps successful responses to GET requests,
+// keyed by the normalized request URL, and serves them without contacting the server until they are older than the
+// time-to-live. Once an entry expires, we revalidate it with a conditional request if the server gave us an `ETag` or
+// `Last-Modified` header, so that an unchanged response costs the server no more than a `304 Not Modified` answer.
+type ResponseCache struct {
+	// How long we serve a cached response without contacting the server.
+	TTL time.Duration
+	// If true, we never serve cached responses, but still cache the responses we receive. Users set this to refresh
+	// the cache, e.g., after arXiv publishes new papers.
+	Refresh bool
+
+	db            *bolt.DB
+	now           func() time.Time
+	hits          atomic.Int64
+	revalidations atomic.Int64
+	misses        atomic.Int64
+}
+
+// Statistics about a [ResponseCache].
+type ResponseCacheStats struct {
+	// The number of requests the cache answered without contacting the server, answered after the server confirmed
+	// that a cached response was still current, and passed on to the server, since we opened it.
+	Hits          int64 `json:"hits"`
+	Revalidations int64 `json:"revalidations"`
+	Misses        int64 `json:"misses"`
+	// The number of cached responses.
+	Entries int `json:"entries"`
+	// The size of the cache database file, in bytes.
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+// A cached HTTP response.
+type responseEntry struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	FetchedAt  time.Time   `json:"fetched_at"`
+}
+
+// Open (or create) a response cache database file. Only one process may open the file at a time; if another process
+// holds the file for longer than a second, we give up and return an error.
+//
+// Returns the cache if we open it successfully, otherwise returns an error.
+func OpenResponseCache(path string, ttl time.Duration) (*ResponseCache, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed while opening response cache '%s': %w", path, err)
+	}
+	return &ResponseCache{TTL: ttl, db: db, now: time.Now}, nil
+}
+
+// Close the cache database file.
+func (cache *ResponseCache) Close() error {
+	return cache.db.Close()
+}
+
+// Wrap an HTTP client so that it answers GET requests from the cache where it can, and caches the successful responses
+// it receives. The wrapped client shares the timeout, redirect policy, and cookie jar of the given client.
+//
+// Returns the wrapped client.
+func (cache *ResponseCache) Client(inner *http.Client) *http.Client {
+	client := *inner
+	client.Transport = &cachingTransport{cache: cache, inner: inner.Transport}
+	return &client
+}
+
+// Get statistics about the cache.
+//
+// Returns the statistics if we read them successfully, otherwise returns an error.
+func (cache *ResponseCache) Stats() (ResponseCacheStats, error) {
+	stats := ResponseCacheStats{
+		Hits:          cache.hits.Load(),
+		Revalidations: cache.revalidations.Load(),
+		Misses:        cache.misses.Load(),
+	}
+	err := cache.db.View(func(tx *bolt.Tx) error {
+		stats.SizeBytes = tx.Size()
+		if bucket := tx.Bucket(responsesBucket); bucket != nil {
+			stats.Entries = bucket.Stats().KeyN
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed while reading response cache statistics: %w", err)
+	}
+	return stats, nil
+}
+
+// Remove every entry fetched (or last revalidated) before the cutoff time from the cache.
+//
+// Returns the number of entries removed if we prune the cache successfully, otherwise returns an error.
+func (cache *ResponseCache) Prune(cutoff time.Time) (int, error) {
+	removed := 0
+	err := cache.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(responsesBucket)
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+			var entry responseEntry
+			if err := json.Unmarshal(value, &entry); err != nil || entry.FetchedAt.Before(cutoff) {
+				if err := cursor.Delete(); err != nil {
+					return err
+				}
+				removed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed while pruning response cache: %w", err)
+	}
+	return removed, nil
+}
+
+// Look up the cached response to a request URL.
+//
+// Returns the cached response if there is one, otherwise returns nil.
+func (cache *ResponseCache) load(key string) *responseEntry {
+	var entry *responseEntry
+	cache.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(responsesBucket); bucket != nil {
+			if value := bucket.Get([]byte(key)); value != nil {
+				entry = &responseEntry{}
+				if err := json.Unmarshal(value, entry); err != nil {
+					entry = nil
+				}
+			}
+		}
+		return nil
+	})
+	return entry
+}
+
+// Save the response to a request URL to the cache.
+//
+// Returns nil if we save the response successfully, otherwise returns an error.
+func (cache *ResponseCache) store(key string, entry *responseEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed while writing response cache: %w", err)
+	}
+	err = cache.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(responsesBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed while writing response cache: %w", err)
+	}
+	return nil
+}
+
+// An HTTP transport that answers from a [ResponseCache] where it can.
+//
+// Implements the [http.RoundTripper] interface.
+type cachingTransport struct {
+	cache *ResponseCache
+	inner http.RoundTripper
+}
+
+// Answer a request from the cache if we hold a fresh response, otherwise pass the request on to the server, asking it
+// to confirm that our stale response is still current if we can.
+//
+// Implements the [http.RoundTripper.RoundTrip] API call.
+func (transport *cachingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	inner := transport.inner
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	if request.Method != http.MethodGet {
+		return inner.RoundTrip(request)
+	}
+	cache := transport.cache
+	key := NormalizeUrl(request.URL)
+	var entry *responseEntry
+	if !cache.Refresh {
+		entry = cache.load(key)
+	}
+	if entry != nil && cache.now().Sub(entry.FetchedAt) < cache.TTL {
+		cache.hits.Add(1)
+		return entry.response(request), nil
+	}
+
+	outgoing := request
+	if entry != nil {
+		outgoing = request.Clone(request.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			outgoing.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			outgoing.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+	response, err := inner.RoundTrip(outgoing)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode == http.StatusNotModified && entry != nil {
+		response.Body.Close()
+		cache.revalidations.Add(1)
+		entry.FetchedAt = cache.now()
+		if err := cache.store(key, entry); err != nil {
+			return nil, err
+		}
+		return entry.response(request), nil
+	}
+	cache.misses.Add(1)
+	if response.StatusCode != http.StatusOK {
+		return response, nil
+	}
+	body, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	entry = &responseEntry{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       body,
+		FetchedAt:  cache.now(),
+	}
+	if err := cache.store(key, entry); err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	return response, nil
+}
+
+// Reconstruct the response to a request from a cache entry.
+//
+// Returns the response.
+func (entry *responseEntry) response(request *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.StatusCode, http.StatusText(entry.StatusCode)),
+		StatusCode:    entry.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        entry.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       request,
+	}
+}
+
+// Normalize a request URL for use as a cache key, so that URLs that differ only in the case of the scheme and host,
+// an explicit default port, the order of query parameters, the escaping of query parameters, or the fragment share a
+// cache entry.
+//
+// Returns the normalized URL.
+func NormalizeUrl(requestUrl *url.URL) string {
+	normalized := *requestUrl
+	normalized.Scheme = strings.ToLower(normalized.Scheme)
+	host := strings.ToLower(normalized.Host)
+	if port := normalized.Port(); (normalized.Scheme == "http" && port == "80") ||
+		(normalized.Scheme == "https" && port == "443") {
+		host = strings.TrimSuffix(host, ":"+port)
+	}
+	normalized.Host = host
+	normalized.Fragment = ""
+	normalized.RawFragment = ""
+	// Encoding sorts the query parameters by name, and escapes them consistently.
+	normalized.RawQuery = normalized.Query().Encode()
+	return normalized.String()
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Get a URL with a client, and return the response body.
+func get(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	response, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// The cache serves fresh responses without contacting the server, and revalidates stale responses with the ETag.
+func TestResponseCache(t *testing.T) {
+	var requests, conditional atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("feed for " + r.URL.Query().Get("search_query")))
+	}))
+	defer server.Close()
+
+	responseCache, err := OpenResponseCache(filepath.Join(t.TempDir(), "arxiv.db"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer responseCache.Close()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	responseCache.now = func() time.Time { return now }
+	client := responseCache.Client(http.DefaultClient)
+
+	want := "feed for all:agents"
+	if got := get(t, client, server.URL+"/api/query?search_query=all:agents&max_results=2"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// Reordered query parameters normalize to the same cache entry.
+	if got := get(t, client, server.URL+"/api/query?max_results=2&search_query=all%3Aagents"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("server saw %d requests, want 1", requests.Load())
+	}
+
+	now = now.Add(2 * time.Hour)
+	if got := get(t, client, server.URL+"/api/query?search_query=all:agents&max_results=2"); got != want {
+		t.Errorf("got %q after revalidation, want %q", got, want)
+	}
+	if requests.Load() != 2 || conditional.Load() != 1 {
+		t.Errorf("server saw %d requests (%d conditional), want 2 (1)", requests.Load(), conditional.Load())
+	}
+
+	responseCache.Refresh = true
+	get(t, client, server.URL+"/api/query?search_query=all:agents&max_results=2")
+	if requests.Load() != 3 || conditional.Load() != 1 {
+		t.Errorf("server saw %d requests (%d conditional), want 3 (1)", requests.Load(), conditional.Load())
+	}
+
+	stats, err := responseCache.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Hits != 1 || stats.Revalidations != 1 || stats.Misses != 2 || stats.Entries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if removed, err := responseCache.Prune(now.Add(time.Minute)); err != nil || removed != 1 {
+		t.Errorf("pruned %d entries (%v), want 1", removed, err)
+	}
+}
+
+func TestNormalizeUrl(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{
+			"HTTP://Export.arXiv.org:80/api/query?start=0&search_query=all:a+b#top",
+			"http://export.arxiv.org/api/query?search_query=all%3Aa+b&start=0",
+		},
+		{"https://example.org:443/x?b=2&a=1", "https://example.org/x?a=1&b=2"},
+		{"http://example.org:8080/x", "http://example.org:8080/x"},
+	}
+	for _, test := range tests {
+		parsed, err := url.Parse(test.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := NormalizeUrl(parsed); got != test.want {
+			t.Errorf("normalized %s to %s, want %s", test.raw, got, test.want)
+		}
+	}
+}
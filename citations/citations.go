@@ -0,0 +1,161 @@
+package citations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Identifies a paper to look up in a scholarly database, by arXiv ID, DOI, or both. Clients prefer the arXiv ID where
+// the database supports it.
+type PaperId struct {
+	// The arXiv ID of the paper without a version suffix, e.g., "2210.03629".
+	ArxivId string
+	// The DOI of the paper, e.g., "10.18653/v1/N19-1423".
+	Doi string
+}
+
+// Parse a paper identifier, which may be an arXiv ID (e.g., "2210.03629", "2210.03629v3", or "arXiv:2210.03629"), a
+// DOI (e.g., "10.18653/v1/N19-1423" or "doi:10.18653/v1/N19-1423"), or an arXiv or DOI URL.
+//
+// Returns the identifier.
+func ParseId(text string) PaperId {
+	text = strings.TrimSpace(text)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "doi:", "DOI:"} {
+		if doi, found := strings.CutPrefix(text, prefix); found {
+			return PaperId{Doi: doi}
+		}
+	}
+	if strings.HasPrefix(text, "10.") && strings.Contains(text, "/") {
+		return PaperId{Doi: text}
+	}
+	if _, id, found := strings.Cut(text, "arxiv.org/abs/"); found {
+		text = id
+	}
+	for _, prefix := range []string{"arXiv:", "arxiv:"} {
+		text = strings.TrimPrefix(text, prefix)
+	}
+	return PaperId{ArxivId: trimVersion(text)}
+}
+
+// Get the identifier as a string, preferring the arXiv ID, e.g., "arXiv:2210.03629" or "DOI:10.18653/v1/N19-1423".
+//
+// Returns the identifier, or an empty string if the identifier is empty.
+func (id PaperId) String() string {
+	switch {
+	case id.ArxivId != "":
+		return "arXiv:" + id.ArxivId
+	case id.Doi != "":
+		return "DOI:" + id.Doi
+	default:
+		return ""
+	}
+}
+
+// A paper (or other scholarly work) as described by a scholarly database.
+type Work struct {
+	Title   string   `json:"title"`
+	Authors []string `json:"authors,omitempty"`
+	Year    int      `json:"year,omitempty"`
+	// The journal or conference in which the work was published, if any.
+	Venue string `json:"venue,omitempty"`
+	// The arXiv ID of the work without a version suffix, if the work is on arXiv.
+	ArxivId        string   `json:"arxiv_id,omitempty"`
+	Doi            string   `json:"doi,omitempty"`
+	CitationCount  int      `json:"citation_count"`
+	ReferenceCount int      `json:"reference_count"`
+	FieldsOfStudy  []string `json:"fields_of_study,omitempty"`
+}
+
+// Get the identifier of the work, for looking it up again.
+//
+// Returns the identifier, which is empty if the database knows neither an arXiv ID nor a DOI for the work.
+func (work Work) Id() PaperId {
+	return PaperId{ArxivId: work.ArxivId, Doi: work.Doi}
+}
+
+// A client of a scholarly database.
+type Client interface {
+	// Look up a paper.
+	//
+	// Returns the paper if the database holds it, an error wrapping [ErrNotFound] if it does not, otherwise returns an
+	// error.
+	Work(ctx context.Context, id PaperId) (Work, error)
+	// Look up the papers that a paper cites.
+	//
+	// Returns up to the given number of references if the database holds the paper, an error wrapping [ErrNotFound] if
+	// it does not, otherwise returns an error.
+	References(ctx context.Context, id PaperId, limit int) ([]Work, error)
+	// Look up the papers that cite a paper.
+	//
+	// Returns up to the given number of citing papers if the database holds the paper, an error wrapping [ErrNotFound]
+	// if it does not, otherwise returns an error.
+	Citations(ctx context.Context, id PaperId, limit int) ([]Work, error)
+}
+
+// Returned when a scholarly database holds no paper with a requested identifier.
+var ErrNotFound = errors.New("paper not found in scholarly database")
+
+// Returned when a scholarly database rejects a request because the client made too many requests, in which case the
+// caller should slow down, or (for Semantic Scholar) use an API key.
+var ErrRateLimited = errors.New("scholarly database rate limit exceeded")
+
+// The names of the scholarly databases we support.
+const (
+	SemanticScholarSource = "semanticscholar"
+	OpenAlexSource        = "openalex"
+)
+
+// Send a GET request to a scholarly database and decode its JSON response.
+//
+// Returns nil if the request succeeds, an error wrapping [ErrNotFound] or [ErrRateLimited] if the database answers
+// with the corresponding status, otherwise returns an error.
+func getJson(ctx context.Context, client *http.Client, url string, header http.Header, value any) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed while querying scholarly database: %w", err)
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	request.Header.Set("Accept", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed while querying scholarly database: %w", err)
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	default:
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("failed while querying scholarly database: %s: %s", response.Status,
+			strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(response.Body).Decode(value); err != nil {
+		return fmt.Errorf("failed while decoding scholarly database response: %w", err)
+	}
+	return nil
+}
+
+// Get an arXiv identifier without its version suffix, e.g., "2210.03629" from "2210.03629v3".
+//
+// Returns the identifier without any version suffix.
+func trimVersion(id string) string {
+	if i := strings.LastIndexByte(id, 'v'); i > 0 && i < len(id)-1 {
+		if strings.Trim(id[i+1:], "0123456789") == "" {
+			return id[:i]
+		}
+	}
+	return id
+}
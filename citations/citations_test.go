@@ -0,0 +1,163 @@
+package citations
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// Paper identifiers parse from arXiv IDs, DOIs, and their URLs.
+func TestParseId(t *testing.T) {
+	for text, want := range map[string]PaperId{
+		"2210.03629":                           {ArxivId: "2210.03629"},
+		"2210.03629v3":                         {ArxivId: "2210.03629"},
+		"arXiv:2210.03629":                     {ArxivId: "2210.03629"},
+		"http://arxiv.org/abs/2210.03629v1":    {ArxivId: "2210.03629"},
+		"hep-th/9901001v2":                     {ArxivId: "hep-th/9901001"},
+		"10.18653/v1/N19-1423":                 {Doi: "10.18653/v1/N19-1423"},
+		"doi:10.18653/v1/N19-1423":             {Doi: "10.18653/v1/N19-1423"},
+		"https://doi.org/10.18653/v1/N19-1423": {Doi: "10.18653/v1/N19-1423"},
+	} {
+		if got := ParseId(text); got != want {
+			t.Errorf("ParseId(%q) = %+v, want %+v", text, got, want)
+		}
+	}
+}
+
+// Serve canned JSON responses by request path (and OpenAlex filter), and 404 for any other request. The server saves
+// the headers of the latest request to the given header, if any.
+func serve(t *testing.T, responses map[string]string, header *http.Header) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header != nil {
+			*header = r.Header.Clone()
+		}
+		key := r.URL.Path
+		if filter := r.URL.Query().Get("filter"); filter != "" {
+			key += "?filter=" + filter
+		}
+		response, ok := responses[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// The Semantic Scholar client looks papers up by arXiv ID, and skips unresolved references.
+func TestSemanticScholar(t *testing.T) {
+	var header http.Header
+	server := serve(t, map[string]string{
+		"/paper/ARXIV:2210.03629": `{"title": "ReAct", "authors": [{"name": "Shunyu Yao"}], "year": 2022,
+			"venue": "ICLR", "externalIds": {"ArXiv": "2210.03629", "DOI": "10.48550/arXiv.2210.03629"},
+			"citationCount": 1500, "referenceCount": 2, "fieldsOfStudy": ["Computer Science"]}`,
+		"/paper/ARXIV:2210.03629/references": `{"data": [
+			{"citedPaper": {"title": "Chain of Thought", "externalIds": {"ArXiv": "2201.11903"},
+				"citationCount": 5000}},
+			{"citedPaper": {"paperId": null, "title": null}}]}`,
+		"/paper/DOI:10.18653/v1/N19-1423/citations": `{"data": [
+			{"citingPaper": {"title": "RoBERTa", "externalIds": {"ArXiv": "1907.11692v1"}}}]}`,
+	}, &header)
+	client := NewSemanticScholar(server.URL, "secret")
+	ctx := context.Background()
+
+	work, err := client.Work(ctx, ParseId("2210.03629v3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Work{
+		Title:          "ReAct",
+		Authors:        []string{"Shunyu Yao"},
+		Year:           2022,
+		Venue:          "ICLR",
+		ArxivId:        "2210.03629",
+		Doi:            "10.48550/arXiv.2210.03629",
+		CitationCount:  1500,
+		ReferenceCount: 2,
+		FieldsOfStudy:  []string{"Computer Science"},
+	}
+	if !reflect.DeepEqual(work, want) {
+		t.Errorf("got %+v, want %+v", work, want)
+	}
+	if apiKey := header.Get("x-api-key"); apiKey != "secret" {
+		t.Errorf("got API key %q, want %q", apiKey, "secret")
+	}
+
+	references, err := client.References(ctx, ParseId("2210.03629"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(references) != 1 || references[0].ArxivId != "2201.11903" || references[0].CitationCount != 5000 {
+		t.Errorf("got references %+v, want Chain of Thought only", references)
+	}
+	citing, err := client.Citations(ctx, ParseId("10.18653/v1/N19-1423"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(citing) != 1 || citing[0].ArxivId != "1907.11692" {
+		t.Errorf("got citations %+v, want RoBERTa", citing)
+	}
+
+	if _, err := client.Work(ctx, ParseId("9999.99999")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+}
+
+// The OpenAlex client looks arXiv papers up by their arXiv DOIs, falling back to the DOI of the published version.
+func TestOpenAlex(t *testing.T) {
+	server := serve(t, map[string]string{
+		"/works/doi:10.18653/v1/N19-1423": `{"id": "https://openalex.org/W2963341956",
+			"doi": "https://doi.org/10.18653/v1/n19-1423", "title": "BERT", "publication_year": 2019,
+			"authorships": [{"author": {"display_name": "Jacob Devlin"}}],
+			"primary_location": {"landing_page_url": "https://doi.org/10.18653/v1/n19-1423",
+				"source": {"display_name": "North American Chapter of the ACL"}},
+			"locations": [{"landing_page_url": "https://arxiv.org/abs/1810.04805v2",
+				"source": {"display_name": "arXiv (Cornell University)"}}],
+			"cited_by_count": 90000, "referenced_works_count": 40,
+			"topics": [{"field": {"display_name": "Computer Science"}},
+				{"field": {"display_name": "Computer Science"}}]}`,
+		"/works?filter=cites:W2963341956": `{"results": [{"id": "https://openalex.org/W1",
+			"doi": "https://doi.org/10.48550/arxiv.1907.11692", "title": "RoBERTa", "publication_year": 2019,
+			"primary_location": {"source": {"display_name": "arXiv (Cornell University)"}}}]}`,
+	}, nil)
+	client := NewOpenAlex(server.URL+"/", "me@example.com")
+	ctx := context.Background()
+
+	work, err := client.Work(ctx, PaperId{ArxivId: "1810.04805", Doi: "10.18653/v1/N19-1423"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Work{
+		Title:          "BERT",
+		Authors:        []string{"Jacob Devlin"},
+		Year:           2019,
+		Venue:          "North American Chapter of the ACL",
+		ArxivId:        "1810.04805",
+		Doi:            "10.18653/v1/n19-1423",
+		CitationCount:  90000,
+		ReferenceCount: 40,
+		FieldsOfStudy:  []string{"Computer Science"},
+	}
+	if !reflect.DeepEqual(work, want) {
+		t.Errorf("got %+v, want %+v", work, want)
+	}
+
+	citing, err := client.Citations(ctx, PaperId{Doi: "10.18653/v1/N19-1423"}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(citing) != 1 || citing[0].ArxivId != "1907.11692" || citing[0].Venue != "" {
+		t.Errorf("got citations %+v, want RoBERTa on arXiv only", citing)
+	}
+
+	if _, err := client.References(ctx, ParseId("9999.99999"), 5); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got error %v, want ErrNotFound", err)
+	}
+}
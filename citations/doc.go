@@ -0,0 +1,7 @@
+// Package citations provides clients for scholarly databases, such as Semantic Scholar and OpenAlex, that complement
+// arXiv metadata with what arXiv does not track: how often papers are cited, which papers they cite and are cited by,
+// where they were published, and which fields of study they belong to.
+//
+// Every client accepts the base URL of its API, so that tests can point the client to a local fake server, e.g., one
+// started with [net/http/httptest].
+package citations
@@ -0,0 +1,219 @@
+package citations
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// The base URL of the OpenAlex API.
+const OpenAlexBaseUrl = "https://api.openalex.org"
+
+// The fields of works we ask OpenAlex for.
+const openAlexFields = "id,doi,title,publication_year,authorships,primary_location,locations,cited_by_count," +
+	"referenced_works_count,topics"
+
+// The most works OpenAlex returns per request.
+const openAlexPageLimit = 200
+
+// The prefix of the DOIs that arXiv registers for its papers, e.g., "10.48550/arXiv.2210.03629".
+const arxivDoiPrefix = "10.48550/arxiv."
+
+// A client of the [OpenAlex API]. OpenAlex looks papers up by DOI, so we look arXiv papers up by the DOIs that arXiv
+// registers for them, falling back to the DOI of the published version of the paper, if any. OpenAlex needs no API
+// key, but answers faster if we identify ourselves with an email address.
+//
+// Implements the [Client] interface.
+//
+// [OpenAlex API]: https://docs.openalex.org
+type OpenAlex struct {
+	baseUrl string
+	email   string
+	// The HTTP client with which we send requests. If nil, we use [http.DefaultClient].
+	HttpClient *http.Client
+}
+
+var _ Client = (*OpenAlex)(nil)
+
+// Create a new OpenAlex client for the API at the given base URL (or [OpenAlexBaseUrl] if empty), which identifies
+// itself with the given email address (or, if empty, sends anonymous requests).
+//
+// Returns the client.
+func NewOpenAlex(baseUrl string, email string) *OpenAlex {
+	return &OpenAlex{
+		baseUrl: strings.TrimSuffix(cmp.Or(baseUrl, OpenAlexBaseUrl), "/"),
+		email:   email,
+	}
+}
+
+// A work as described by OpenAlex.
+type openAlexWork struct {
+	Id              string `json:"id"`
+	Doi             string `json:"doi"`
+	Title           string `json:"title"`
+	PublicationYear int    `json:"publication_year"`
+	Authorships     []struct {
+		Author struct {
+			DisplayName string `json:"display_name"`
+		} `json:"author"`
+	} `json:"authorships"`
+	PrimaryLocation      *openAlexLocation  `json:"primary_location"`
+	Locations            []openAlexLocation `json:"locations"`
+	CitedByCount         int                `json:"cited_by_count"`
+	ReferencedWorksCount int                `json:"referenced_works_count"`
+	Topics               []struct {
+		Field struct {
+			DisplayName string `json:"display_name"`
+		} `json:"field"`
+	} `json:"topics"`
+}
+
+// A location at which OpenAlex knows a work to be published, e.g., a journal or a preprint server.
+type openAlexLocation struct {
+	LandingPageUrl string `json:"landing_page_url"`
+	Source         *struct {
+		DisplayName string `json:"display_name"`
+	} `json:"source"`
+}
+
+// Convert an OpenAlex work to a [Work]. We take the venue from the first location that is not arXiv itself, and the
+// arXiv ID from the DOI of the work, or failing that from a location on arXiv.
+//
+// Returns the work.
+func (work openAlexWork) work() Work {
+	result := Work{
+		Title:          work.Title,
+		Year:           work.PublicationYear,
+		CitationCount:  work.CitedByCount,
+		ReferenceCount: work.ReferencedWorksCount,
+	}
+	doi := strings.TrimPrefix(strings.TrimPrefix(work.Doi, "https://doi.org/"), "http://doi.org/")
+	if id, found := strings.CutPrefix(strings.ToLower(doi), arxivDoiPrefix); found {
+		result.ArxivId = doi[len(doi)-len(id):]
+	} else {
+		result.Doi = doi
+	}
+	for _, authorship := range work.Authorships {
+		result.Authors = append(result.Authors, authorship.Author.DisplayName)
+	}
+	locations := work.Locations
+	if work.PrimaryLocation != nil {
+		locations = append([]openAlexLocation{*work.PrimaryLocation}, locations...)
+	}
+	for _, location := range locations {
+		if _, id, found := strings.Cut(location.LandingPageUrl, "arxiv.org/abs/"); found && result.ArxivId == "" {
+			result.ArxivId = trimVersion(id)
+		}
+		if location.Source != nil && result.Venue == "" &&
+			!strings.Contains(strings.ToLower(location.Source.DisplayName), "arxiv") {
+			result.Venue = location.Source.DisplayName
+		}
+	}
+	for _, topic := range work.Topics {
+		if field := topic.Field.DisplayName; field != "" && !slices.Contains(result.FieldsOfStudy, field) {
+			result.FieldsOfStudy = append(result.FieldsOfStudy, field)
+		}
+	}
+	return result
+}
+
+// Look up a paper in OpenAlex.
+//
+// Implements the [Client.Work] API call.
+func (client *OpenAlex) Work(ctx context.Context, id PaperId) (Work, error) {
+	work, err := client.lookup(ctx, id)
+	if err != nil {
+		return Work{}, err
+	}
+	return work.work(), nil
+}
+
+// Look up the papers that a paper cites in OpenAlex.
+//
+// Implements the [Client.References] API call.
+func (client *OpenAlex) References(ctx context.Context, id PaperId, limit int) ([]Work, error) {
+	return client.related(ctx, id, "cited_by", "", limit)
+}
+
+// Look up the papers that cite a paper in OpenAlex, most recent first.
+//
+// Implements the [Client.Citations] API call.
+func (client *OpenAlex) Citations(ctx context.Context, id PaperId, limit int) ([]Work, error) {
+	return client.related(ctx, id, "cites", "publication_date:desc", limit)
+}
+
+// Look up the works related to a paper by the given OpenAlex filter ("cited_by" or "cites"), in the given sort order
+// (or the default order if empty).
+//
+// Returns up to the given number of related works if we look them up successfully, otherwise returns an error.
+func (client *OpenAlex) related(
+	ctx context.Context,
+	id PaperId,
+	filter string,
+	sort string,
+	limit int,
+) ([]Work, error) {
+	work, err := client.lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{
+		"filter":   {filter + ":" + path.Base(work.Id)},
+		"select":   {openAlexFields},
+		"per-page": {fmt.Sprint(min(max(limit, 1), openAlexPageLimit))},
+	}
+	if sort != "" {
+		query.Set("sort", sort)
+	}
+	var response struct {
+		Results []openAlexWork `json:"results"`
+	}
+	if err := client.get(ctx, "/works", query, &response); err != nil {
+		return nil, err
+	}
+	works := make([]Work, len(response.Results))
+	for i, result := range response.Results {
+		works[i] = result.work()
+	}
+	return works, nil
+}
+
+// Look up a paper in OpenAlex by each of its DOIs in turn: the DOI that arXiv registers for the paper, then the DOI
+// of its published version.
+//
+// Returns the work if OpenAlex holds the paper, an error wrapping [ErrNotFound] if it does not, otherwise returns an
+// error.
+func (client *OpenAlex) lookup(ctx context.Context, id PaperId) (openAlexWork, error) {
+	var dois []string
+	if id.ArxivId != "" {
+		dois = append(dois, "10.48550/arXiv."+id.ArxivId)
+	}
+	if id.Doi != "" {
+		dois = append(dois, id.Doi)
+	}
+	for _, doi := range dois {
+		var work openAlexWork
+		err := client.get(ctx, "/works/doi:"+doi, url.Values{"select": {openAlexFields}}, &work)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return work, err
+	}
+	return openAlexWork{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Send a request to OpenAlex.
+//
+// Returns nil if the request succeeds, otherwise returns an error.
+func (client *OpenAlex) get(ctx context.Context, endpoint string, query url.Values, value any) error {
+	if client.email != "" {
+		query.Set("mailto", client.email)
+	}
+	return getJson(ctx, client.HttpClient, client.baseUrl+endpoint+"?"+query.Encode(), nil, value)
+}
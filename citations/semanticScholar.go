@@ -0,0 +1,162 @@
+package citations
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The base URL of the Semantic Scholar Academic Graph API.
+const SemanticScholarBaseUrl = "https://api.semanticscholar.org/graph/v1"
+
+// The fields of papers we ask Semantic Scholar for.
+const semanticScholarFields = "title,authors,year,venue,externalIds,citationCount,referenceCount,fieldsOfStudy"
+
+// The most references or citations Semantic Scholar returns per request.
+const semanticScholarPageLimit = 1000
+
+// A client of the [Semantic Scholar Academic Graph API]. Semantic Scholar looks papers up by arXiv ID directly, and
+// answers a limited number of requests without an API key.
+//
+// Implements the [Client] interface.
+//
+// [Semantic Scholar Academic Graph API]: https://api.semanticscholar.org/api-docs/graph
+type SemanticScholar struct {
+	baseUrl string
+	apiKey  string
+	// The HTTP client with which we send requests. If nil, we use [http.DefaultClient].
+	HttpClient *http.Client
+}
+
+var _ Client = (*SemanticScholar)(nil)
+
+// Create a new Semantic Scholar client for the API at the given base URL (or [SemanticScholarBaseUrl] if empty), which
+// authenticates with the given API key (or, if empty, sends unauthenticated requests).
+//
+// Returns the client.
+func NewSemanticScholar(baseUrl string, apiKey string) *SemanticScholar {
+	return &SemanticScholar{
+		baseUrl: strings.TrimSuffix(cmp.Or(baseUrl, SemanticScholarBaseUrl), "/"),
+		apiKey:  apiKey,
+	}
+}
+
+// A paper as described by Semantic Scholar.
+type semanticScholarPaper struct {
+	Title   string `json:"title"`
+	Authors []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Year           int               `json:"year"`
+	Venue          string            `json:"venue"`
+	ExternalIds    map[string]string `json:"externalIds"`
+	CitationCount  int               `json:"citationCount"`
+	ReferenceCount int               `json:"referenceCount"`
+	FieldsOfStudy  []string          `json:"fieldsOfStudy"`
+}
+
+// Convert a Semantic Scholar paper to a [Work].
+//
+// Returns the work.
+func (paper semanticScholarPaper) work() Work {
+	work := Work{
+		Title:          paper.Title,
+		Year:           paper.Year,
+		Venue:          paper.Venue,
+		ArxivId:        trimVersion(paper.ExternalIds["ArXiv"]),
+		Doi:            paper.ExternalIds["DOI"],
+		CitationCount:  paper.CitationCount,
+		ReferenceCount: paper.ReferenceCount,
+		FieldsOfStudy:  paper.FieldsOfStudy,
+	}
+	for _, author := range paper.Authors {
+		work.Authors = append(work.Authors, author.Name)
+	}
+	return work
+}
+
+// Look up a paper in Semantic Scholar.
+//
+// Implements the [Client.Work] API call.
+func (client *SemanticScholar) Work(ctx context.Context, id PaperId) (Work, error) {
+	var paper semanticScholarPaper
+	if err := client.get(ctx, id, "", url.Values{"fields": {semanticScholarFields}}, &paper); err != nil {
+		return Work{}, err
+	}
+	return paper.work(), nil
+}
+
+// Look up the papers that a paper cites in Semantic Scholar.
+//
+// Implements the [Client.References] API call.
+func (client *SemanticScholar) References(ctx context.Context, id PaperId, limit int) ([]Work, error) {
+	return client.related(ctx, id, "references", "citedPaper", limit)
+}
+
+// Look up the papers that cite a paper in Semantic Scholar.
+//
+// Implements the [Client.Citations] API call.
+func (client *SemanticScholar) Citations(ctx context.Context, id PaperId, limit int) ([]Work, error) {
+	return client.related(ctx, id, "citations", "citingPaper", limit)
+}
+
+// Look up the papers related to a paper by the given relation ("references" or "citations"), each of which Semantic
+// Scholar returns under the given key of an entry of the response.
+//
+// Returns up to the given number of related papers if we look them up successfully, otherwise returns an error.
+func (client *SemanticScholar) related(
+	ctx context.Context,
+	id PaperId,
+	relation string,
+	key string,
+	limit int,
+) ([]Work, error) {
+	var response struct {
+		Data []map[string]*semanticScholarPaper `json:"data"`
+	}
+	query := url.Values{
+		"fields": {semanticScholarFields},
+		"limit":  {fmt.Sprint(min(max(limit, 1), semanticScholarPageLimit))},
+	}
+	if err := client.get(ctx, id, "/"+relation, query, &response); err != nil {
+		return nil, err
+	}
+	works := []Work{}
+	for _, entry := range response.Data {
+		// Semantic Scholar returns placeholder entries without titles for references it could not resolve.
+		if paper := entry[key]; paper != nil && paper.Title != "" {
+			works = append(works, paper.work())
+		}
+	}
+	return works, nil
+}
+
+// Send a request for a paper, or a relation of the paper given as a path suffix, to Semantic Scholar.
+//
+// Returns nil if the request succeeds, otherwise returns an error.
+func (client *SemanticScholar) get(ctx context.Context, id PaperId, suffix string, query url.Values, value any) error {
+	var paperId string
+	switch {
+	case id.ArxivId != "":
+		paperId = "ARXIV:" + id.ArxivId
+	case id.Doi != "":
+		paperId = "DOI:" + id.Doi
+	default:
+		return fmt.Errorf("%w: empty paper ID", ErrNotFound)
+	}
+	// Keep the slashes of DOIs and old-style arXiv IDs (e.g., "hep-th/9901001"), which Semantic Scholar expects.
+	path := strings.ReplaceAll(url.PathEscape(paperId), "%2F", "/")
+	header := http.Header{}
+	if client.apiKey != "" {
+		header.Set("x-api-key", client.apiKey)
+	}
+	err := getJson(ctx, client.HttpClient, client.baseUrl+"/paper/"+path+suffix+"?"+query.Encode(), header, value)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return err
+}
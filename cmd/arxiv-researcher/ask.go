@@ -0,0 +1,353 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tmc/langchaingo/agents"
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/chains"
+	lcgTools "github.com/tmc/langchaingo/tools"
+	"tmwong.org/arxiv-researcher-go/agent"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/logging"
+	"tmwong.org/arxiv-researcher-go/prompts"
+	"tmwong.org/arxiv-researcher-go/runs"
+	"tmwong.org/arxiv-researcher-go/telemetry"
+	"tmwong.org/arxiv-researcher-go/tools"
+	"tmwong.org/arxiv-researcher-go/usage"
+	"tmwong.org/arxiv-researcher-go/watch"
+)
+
+// Resolve the "auto" agent mode to the mode that suits the LLM: "functions" if the LLM supports native tool calling,
+// otherwise "react".
+//
+// Returns the resolved mode.
+func agentMode(mode string) string {
+	if mode != "auto" {
+		return mode
+	}
+	if constants.SupportsToolCalling(constants.LlmModel) {
+		return "functions"
+	}
+	return "react"
+}
+
+// Create an agent that drives the given tools in the given mode with the given prompts. ReAct agents report their
+// chains to the given introspection handler. Agents of either mode report their LLM calls to [tools.Logger].
+//
+// Returns the agent if the mode is valid, otherwise returns an error.
+func newAgent(
+	mode string,
+	prompt prompts.Rendered,
+	agentTools []lcgTools.Tool,
+	handler callbacks.Handler,
+) (agents.Agent, error) {
+	switch agentMode(mode) {
+	case "functions":
+		return agent.NewFunctionsAgent(constants.Llm, agentTools, prompt.Instructions, tools.Logger), nil
+	case "react":
+		// Create a new one-shot agent that uses our prompt templates. The LangChainGo [agents.OneShotZeroAgent]
+		// prepares a prompt for the LLM using a prefix, a set of format instructions, and a suffix. Our agent does
+		// not use LLM responses directly, so we keep the default format instructions.
+		return agents.NewOneShotAgent(
+			// The chains of ReAct agents do not report the LLM calls they make, so the LLM reports them itself.
+			agent.NewReportingModel(constants.Llm, tools.Logger),
+			agentTools,
+			// Callbacks for introspection of agent execution, as opposed to callbacks for tool execution.
+			agents.WithCallbacksHandler(handler),
+			agents.WithPromptPrefix(prompt.Prefix),
+			agents.WithPromptSuffix(prompt.Suffix),
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown agent mode '%s'", mode)
+	}
+}
+
+// The clock used to tell the agent today's date. Tests may replace the clock to make prompts deterministic.
+var now = time.Now
+
+// Gather what the prompts of an agent run in the given mode know about the run: the date, the model, the size of the
+// knowledge database, and the preferences and watchlist topics of the user. The prompts can do without the size of
+// the knowledge database, so we only log failures to count its papers.
+//
+// Returns the context if we gather it successfully, otherwise returns an error.
+func promptContext(ctx context.Context, mode string) (prompts.Context, error) {
+	promptContext := prompts.Context{
+		Today: now().Format(time.DateOnly),
+		Model: constants.LlmModel,
+		Agent: agentMode(mode),
+	}
+	index, err := tools.IndexFromContext(ctx)
+	if err == nil {
+		promptContext.Papers, err = index.Count(ctx)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "Failed while counting papers for prompt", "error", err)
+	}
+	if promptContext.Preferences, err = prompts.LoadPreferences(); err != nil {
+		return prompts.Context{}, err
+	}
+	path, err := watch.DefaultStatePath()
+	if err != nil {
+		return prompts.Context{}, err
+	}
+	state, err := watch.Load(path)
+	if err != nil {
+		return prompts.Context{}, err
+	}
+	for _, list := range state.Watchlists {
+		promptContext.Topics = append(promptContext.Topics, list.Query)
+	}
+	return promptContext, nil
+}
+
+// Run the research agent on a topic phrase, using the given agent mode and prompt. In the "auto" mode, if the LLM
+// rejects the tools of a function-calling agent, we run a ReAct agent instead. A non-zero tool timeout overrides
+// the default time limits of the tools. If given recorded results of an earlier run, the tools answer from them
+// instead of calling external services. If given an environment, the tools use its services instead of the shared
+// ones (see [tools.Environment]).
+//
+// Returns the final answer of the agent if it runs successfully, otherwise returns an error.
+func research(
+	ctx context.Context,
+	mode string,
+	prompt prompts.Prompt,
+	query string,
+	toolTimeout time.Duration,
+	recorded *tools.RecordedResults,
+	environment *tools.Environment,
+) (string, error) {
+	if err := constants.Ready(); err != nil {
+		return "", err
+	}
+	if environment != nil {
+		ctx = tools.WithEnvironment(ctx, environment)
+	}
+	promptContext, err := promptContext(ctx, mode)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := prompt.Render(promptContext)
+	if err != nil {
+		return "", err
+	}
+	// Declare the tools that the agent can use to access external data sources.
+	agentTools := []lcgTools.Tool{
+		configureTool(tools.ArxivSearcher, toolTimeout, recorded),
+		configureTool(tools.IndexSearcher, toolTimeout, recorded),
+		configureTool(tools.SimilarPapers, toolTimeout, recorded),
+		configureTool(tools.PaperDownloader, toolTimeout, recorded),
+		configureTool(tools.PaperReferences, toolTimeout, recorded),
+		configureTool(tools.CitingPapers, toolTimeout, recorded),
+		configureTool(tools.AskPaper, toolTimeout, recorded),
+		configureTool(tools.PaperSummarizer, toolTimeout, recorded),
+	}
+	// The LLM reports its own calls to telemetry (see [configure]), so only the chains and the executor report to it
+	// here.
+	handler := callbacks.CombiningHandler{
+		Callbacks: []callbacks.Handler{tools.Logger, telemetry.NewHandler(constants.LlmModel)},
+	}
+	researcher, err := newAgent(mode, rendered, agentTools, handler)
+	if err != nil {
+		return "", err
+	}
+	executor := agent.NewExecutor(
+		researcher,
+		agent.WithMaxIterations(25),
+		agent.WithCallbacksHandler(handler),
+	)
+	outputs, err := chains.Call(ctx, executor, map[string]any{"input": query})
+	if errors.Is(err, agent.ErrToolCallingUnsupported) && mode == "auto" {
+		// The LLM rejected tools before the agent called any, so we can start over with ReAct prompting.
+		slog.WarnContext(ctx, "Falling back to ReAct agent", "model", constants.LlmModel, "error", err)
+		return research(ctx, "react", prompt, query, toolTimeout, recorded, environment)
+	}
+	if err != nil {
+		return "", err
+	}
+	answer, _ := outputs["output"].(string)
+	return answer, nil
+}
+
+// Get a copy of a tool of the agent with the given time limit, if non-zero, that answers from the given recorded
+// results, if any.
+//
+// Returns the configured tool.
+func configureTool[T any](tool tools.Tool[T], timeout time.Duration, recorded *tools.RecordedResults) tools.Tool[T] {
+	if timeout > 0 {
+		tool = tool.WithTimeout(timeout)
+	}
+	return tool.WithRecordedResults(recorded)
+}
+
+// Options of the ask command.
+type askOptions struct {
+	mode        string
+	toolTimeout time.Duration
+	prompt      string
+	transcript  string
+	replay      string
+	noHistory   bool
+	format      string
+	// The directory of the run history in which to record the run, if not the default one.
+	history string
+	// The services that the tools of the run use, if not the shared ones.
+	environment *tools.Environment
+}
+
+// Run the research agent for the ask command, and record the run in the run history and the transcript file, if any.
+// When replaying an earlier run, the query defaults to the query of the run, and the tools answer from its recorded
+// results.
+//
+// Returns the query of the run, along with the final answer of the agent, if the agent runs successfully, otherwise
+// returns an error.
+func recordResearch(ctx context.Context, options *askOptions, query string) (string, string, error) {
+	directory := options.history
+	if directory == "" {
+		var err error
+		if directory, err = runs.DefaultDirectory(); err != nil {
+			return "", "", err
+		}
+	}
+	promptDirectory, err := prompts.DefaultDirectory()
+	if err != nil {
+		return "", "", err
+	}
+	prompt, err := prompts.Find(promptDirectory, cmp.Or(options.prompt, os.Getenv("ARXIV_RESEARCHER_PROMPT"),
+		prompts.DefaultName))
+	if err != nil {
+		return "", "", err
+	}
+	settings := runs.Settings{
+		Query:  query,
+		Mode:   agentMode(options.mode),
+		Model:  constants.LlmModel,
+		Prompt: prompt.Id(),
+	}
+	var recorded *tools.RecordedResults
+	if options.replay != "" {
+		replayed, err := runs.Load(directory, options.replay)
+		if err != nil {
+			return "", "", err
+		}
+		settings.Query = cmp.Or(query, replayed.Query)
+		settings.ReplayOf = replayed.Id
+		recorded = tools.NewRecordedResults(replayed.Events)
+	}
+	var transcripts []io.Writer
+	if options.transcript != "" {
+		file, err := os.Create(options.transcript)
+		if err != nil {
+			return "", "", fmt.Errorf("failed while creating transcript: %w", err)
+		}
+		defer file.Close()
+		transcripts = append(transcripts, file)
+	}
+	var recorder *runs.Recorder
+	if !options.noHistory {
+		if recorder, err = runs.Start(directory, logging.RunId(ctx), settings); err != nil {
+			return "", "", err
+		}
+		transcripts = append(transcripts, recorder)
+	}
+	if len(transcripts) > 0 {
+		tools.Logger.SetTranscript(io.MultiWriter(transcripts...))
+	}
+	answer, err := research(ctx, options.mode, prompt, settings.Query, options.toolTimeout, recorded,
+		options.environment)
+	tools.Logger.SetTranscript(nil)
+	if recorder != nil {
+		if finishErr := recorder.Finish(answer, err, usage.FromContext(ctx).Report()); finishErr != nil {
+			slog.WarnContext(ctx, "Failed while recording run", "run_id", recorder.Id(), "error", finishErr)
+		}
+	}
+	return settings.Query, answer, err
+}
+
+// Create the ask command, which runs the research agent on a topic phrase.
+//
+// Returns the command.
+func newAskCommand() *cobra.Command {
+	options := &askOptions{}
+	cmd := &cobra.Command{
+		Use:   "ask <topic phrase>",
+		Short: "Ask the research agent to find (and download) papers on a topic",
+		Long: `Ask the research agent to find papers on a topic.
+
+The agent searches the knowledge database for papers relevant to the topic phrase, and expands its search to arXiv if
+it finds none. It then lists the relevant papers it found, and downloads them to the papers directory.
+
+The --agent flag selects how the agent drives its tools: "functions" uses native LLM tool calling, "react" uses
+text-based ReAct prompting, and "auto" (the default) uses native tool calling if the LLM supports it and falls back to
+ReAct prompting otherwise, or if the LLM rejects tools on its first request.
+
+The --prompt flag (or ARXIV_RESEARCHER_PROMPT) selects the prompt of the agent by name, e.g., "research" for its latest
+version, or "research.v1" for a given version, or by the path of a prompt file. Prompts see the date, the model, the
+size of the knowledge database, the topics of the watchlists, and the preferences of the user (see the prompts
+command).
+
+The --transcript flag writes every event of the run, e.g., each LLM call with its prompt and response, and each tool
+call with its input, output, and duration, to a file as JSON lines.
+
+Every run is also recorded in the run history in the data directory (unless --no-history is given), where the runs
+command shows it. To debug prompts, --replay re-runs the agent on the query of an earlier run, but answers tool calls
+from the results recorded in the run instead of calling external services; the replay is recorded as a new run.
+
+Once the agent answers, the command reports the tokens it spent by model, along with their estimated cost, to standard
+error (or in the JSON output). --max-tokens and --max-cost abort runs that spend more.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && options.replay == "" {
+				return fmt.Errorf("requires a topic phrase, or a run to replay")
+			}
+			return nil
+		},
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			query, answer, err := recordResearch(ctx, options, strings.Join(args, " "))
+			if err != nil {
+				return err
+			}
+			if options.format == "json" {
+				return writeJson(cmd.OutOrStdout(), map[string]any{
+					"run_id": logging.RunId(ctx),
+					"query":  query,
+					"answer": answer,
+					"usage":  usage.FromContext(cmd.Context()).Report(),
+				})
+			}
+			if _, err := fmt.Fprintln(cmd.OutOrStdout(), answer); err != nil {
+				return err
+			}
+			return writeUsage(cmd)
+		}),
+	}
+	flags := cmd.Flags()
+	flags.Var(newChoice(&options.mode, "auto", "auto", "functions", "react"), "agent",
+		"how the agent drives its tools: auto, functions, or react")
+	cmd.RegisterFlagCompletionFunc("agent", cobra.FixedCompletions(
+		[]string{"auto", "functions", "react"},
+		cobra.ShellCompDirectiveNoFileComp,
+	))
+	flags.DurationVar(&options.toolTimeout, "tool-timeout", 0,
+		"limit on the time each tool call may take (0 for tool defaults)")
+	flags.StringVar(&options.prompt, "prompt", "",
+		"`name` (or version, or file) of the prompt of the agent (default $ARXIV_RESEARCHER_PROMPT or "+
+			prompts.DefaultName+")")
+	flags.StringVar(&options.transcript, "transcript", "",
+		"write every LLM call, chain, agent action, and tool call of the run to a JSON lines `file`")
+	flags.StringVar(&options.replay, "replay", "",
+		"re-run the agent on the query of an earlier `run` (by ID or unique ID prefix), answering tool calls from the "+
+			"results recorded in the run")
+	flags.BoolVar(&options.noHistory, "no-history", false, "do not record the run in the run history")
+	addFormatFlag(cmd, &options.format, "text", "json")
+	return cmd
+}
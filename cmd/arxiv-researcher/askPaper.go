@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the ask-paper command, which answers a question about a paper from the full text of the paper.
+//
+// Returns the command.
+func newAskPaperCommand() *cobra.Command {
+	var showPassages bool
+	cmd := &cobra.Command{
+		Use:   "ask-paper <arXiv ID or file name> <question>",
+		Short: "Answer a question about a paper from its full text",
+		Long: `Answer a question about a paper from its full text, citing the pages the answer draws on as (p. N).
+
+The paper is either an arXiv ID (e.g., 2210.03629), which the command downloads to the papers directory unless it is
+already there, or the file name of a paper in the papers directory. The text extracted from each paper is cached, so
+later questions about the same paper need no extraction. Pass --passages to show the passages the answer draws on.`,
+		Args: cobra.MinimumNArgs(2),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			if err := constants.Ready(); err != nil {
+				return err
+			}
+			answer, err := tools.AskPaperQuestion(cmd.Context(), constants.Llm, args[0], strings.Join(args[1:], " "))
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(cmd.OutOrStdout(), answer.Answer); err != nil {
+				return err
+			}
+			if showPassages {
+				for _, passage := range answer.Passages {
+					_, err := fmt.Fprintf(cmd.OutOrStdout(), "\n(p. %d) %s\n", passage.Page, passage.Text)
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}),
+	}
+	cmd.Flags().BoolVar(&showPassages, "passages", false, "show the passages the answer draws on")
+	return cmd
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/agent"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/fakes"
+	"tmwong.org/arxiv-researcher-go/prompts"
+	"tmwong.org/arxiv-researcher-go/replay"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Install offline stand-ins for the singletons used by the agent and its tools: the LLM, the HTTP client, and the
+// document index. The LLM calls, HTTP traffic, and index searches replay from a cassette; in [replay.Record] mode, the
+// given LLM answers the calls instead, and the cassette records its answers. We also run the test in a temporary
+// directory so that downloaded papers do not litter the repository, with a data directory of its own so that the
+// watchlists and preferences of the user do not leak into prompts, and pin the clock so that prompts do not change from
+// day to day.
+//
+// Returns a recorder of the prompts of the LLM calls.
+func setup(t *testing.T, cassette *replay.Cassette, llm llms.Model) *promptRecorder {
+	t.Helper()
+	savedLlm, savedClient, savedNow := constants.Llm, tools.HttpClient, now
+	t.Cleanup(func() {
+		constants.Llm, tools.HttpClient, now = savedLlm, savedClient, savedNow
+	})
+	recorder := &promptRecorder{Model: cassette.LLM(llm)}
+	constants.Llm = recorder
+	tools.HttpClient = cassette.Client()
+	now = func() time.Time { return time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC) }
+	tools.SetIndex(cassette.VectorStore(fakes.NewVectorStore(fakes.NewEmbedder(), "")))
+	t.Chdir(t.TempDir())
+	t.Setenv("ARXIV_RESEARCHER_DATA_DIR", t.TempDir())
+	t.Setenv("ARXIV_RESEARCHER_PREFERENCES", "")
+	return recorder
+}
+
+// An LLM that keeps the messages of every call to the LLM it wraps, so that tests can assert on the prompts of calls
+// that replay from a cassette.
+//
+// Implements the [llms.Model] interface.
+type promptRecorder struct {
+	llms.Model
+	mutex sync.Mutex
+	calls [][]llms.MessageContent
+}
+
+// Keep the messages of a call, and pass the call on to the wrapped LLM.
+//
+// Implements the [llms.Model.GenerateContent] API call.
+func (recorder *promptRecorder) GenerateContent(
+	ctx context.Context,
+	messages []llms.MessageContent,
+	options ...llms.CallOption,
+) (*llms.ContentResponse, error) {
+	recorder.mutex.Lock()
+	recorder.calls = append(recorder.calls, messages)
+	recorder.mutex.Unlock()
+	return recorder.Model.GenerateContent(ctx, messages, options...)
+}
+
+// Keep the prompt of a single-prompt call, and pass the call on to the wrapped LLM.
+//
+// Implements the [llms.Model.Call] API call.
+func (recorder *promptRecorder) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, recorder, prompt, options...)
+}
+
+// Get the messages of every call to the LLM so far, in call order.
+func (recorder *promptRecorder) Calls() [][]llms.MessageContent {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	return append([][]llms.MessageContent(nil), recorder.calls...)
+}
+
+// Find a built-in prompt by name.
+func findPrompt(t *testing.T, name string) prompts.Prompt {
+	t.Helper()
+	prompt, err := prompts.Find("", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prompt
+}
+
+// Check that a paper exists in the papers directory and is a PDF.
+func checkDownloaded(t *testing.T, fileName string) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(tools.PapersDirectory, fileName))
+	if err != nil {
+		t.Fatalf("paper '%s' not downloaded: %s", fileName, err)
+	}
+	if !strings.HasPrefix(string(content), "%PDF") {
+		t.Errorf("paper '%s' is not a PDF: %q", fileName, content[:min(len(content), 16)])
+	}
+}
+
+// The function-calling agent finds papers in the index, downloads them with parallel tool calls, and reports them. The
+// LLM calls, index searches, and HTTP traffic replay from a fixture, which records the responses of the scripted LLM
+// below; re-record the fixture against arXiv with
+//
+//	$ REPLAY_MODE=record go test ./cmd/arxiv-researcher -run TestResearchWithFunctionsAgent
+func TestResearchWithFunctionsAgent(t *testing.T) {
+	cassette := replay.Load(t, filepath.Join("testdata", "functions.json"))
+	script := fakes.NewLLM(
+		fakes.ToolCalls("IndexSearcher", `{"query": "language model agents", "n": 2}`),
+		fakes.ToolCalls(
+			"PaperDownloader", `{"fileName": "react.pdf", "url": "http://arxiv.org/pdf/2210.03629v3"}`,
+			"PaperDownloader", `{"fileName": "toolformer.pdf", "url": "http://arxiv.org/pdf/2302.04761v1"}`,
+		),
+		fakes.Answer("Found and downloaded: ReAct; Toolformer."),
+	)
+	llm := setup(t, cassette, script)
+	index, err := tools.GetIndex(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = index.AddPapers(t.Context(), []tools.Paper{
+		{Id: "2210.03629v3", Title: "ReAct: Synergizing Reasoning and Acting in Language Models",
+			PdfUrl: "http://arxiv.org/pdf/2210.03629v3"},
+		{Id: "2302.04761v1", Title: "Toolformer: Language Models Can Teach Themselves to Use Tools",
+			PdfUrl: "http://arxiv.org/pdf/2302.04761v1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	answer, err := research(context.Background(), "functions", findPrompt(t, prompts.DefaultName),
+		"language model agents", 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "Found and downloaded: ReAct; Toolformer." {
+		t.Errorf("unexpected answer %q", answer)
+	}
+	checkDownloaded(t, "react.pdf")
+	checkDownloaded(t, "toolformer.pdf")
+
+	calls := llm.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 LLM calls, got %d", len(calls))
+	}
+	// The final call replays both parallel downloads as tool responses after the assistant message requesting them.
+	final := calls[2]
+	var responses []string
+	for _, message := range final {
+		if message.Role != llms.ChatMessageTypeTool {
+			continue
+		}
+		for _, part := range message.Parts {
+			if response, ok := part.(llms.ToolCallResponse); ok {
+				responses = append(responses, response.Content)
+			}
+		}
+	}
+	if len(responses) != 3 {
+		t.Fatalf("expected 3 tool responses in final prompt, got %d", len(responses))
+	}
+	if !strings.Contains(responses[0], "Toolformer") {
+		t.Errorf("index search result does not mention indexed paper: %s", responses[0])
+	}
+	for _, response := range responses[1:] {
+		if !strings.Contains(response, "successfully") {
+			t.Errorf("download failed: %s", response)
+		}
+	}
+}
+
+// The ReAct agent finds nothing in the empty index, falls back to arXiv, and downloads a paper. The LLM calls, index
+// searches, and HTTP traffic replay from a fixture keyed by prompt, query, and URL, which records the responses of the
+// scripted LLM below; re-record the fixture against arXiv with
+//
+//	$ REPLAY_MODE=record go test ./cmd/arxiv-researcher -run TestResearchWithReActAgent
+func TestResearchWithReActAgent(t *testing.T) {
+	cassette := replay.Load(t, filepath.Join("testdata", "react.json"))
+	llm := fakes.NewLLM(
+		fakes.Answer("Thought: I should search my knowledge database first.\nAction: IndexSearcher\n"+
+			`Action Input: {"query": "one-shot agents", "n": 2}`),
+		fakes.Answer("Thought: My database has no relevant papers, so I should search arXiv.\nAction: ArxivSearcher\n"+
+			`Action Input: {"query": "one-shot agents", "n": 2}`),
+		fakes.Answer("Thought: The ReAct paper is relevant, so I should download it.\nAction: PaperDownloader\n"+
+			`Action Input: {"fileName": "2210.03629v3.pdf", "url": "http://arxiv.org/pdf/2210.03629v3"}`),
+		fakes.Answer("Thought: I now know the final answer\nFinal Answer: I found one relevant paper on arXiv and "+
+			"downloaded it to 2210.03629v3.pdf.\n\n"+
+			"- Title: ReAct: Synergizing Reasoning and Acting in Language Models"),
+	)
+	setup(t, cassette, llm)
+
+	answer, err := research(context.Background(), "react", findPrompt(t, "research.v1"), "one-shot agents", 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(answer, "ReAct") {
+		t.Errorf("answer does not mention the paper found on arXiv: %q", answer)
+	}
+	checkDownloaded(t, "2210.03629v3.pdf")
+}
+
+// The auto mode picks native tool calling only for models that support it.
+func TestAgentMode(t *testing.T) {
+	savedModel := constants.LlmModel
+	t.Cleanup(func() { constants.LlmModel = savedModel })
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o-mini", "functions"},
+		{"o1", "functions"},
+		{"o1-2024-12-17", "functions"},
+		{"o1-mini", "react"},
+		{"o1-preview-2024-09-12", "react"},
+		{"davinci-002", "react"},
+	}
+	for _, test := range tests {
+		constants.LlmModel = test.model
+		if got := agentMode("auto"); got != test.want {
+			t.Errorf("agentMode(auto) for %s = %s, want %s", test.model, got, test.want)
+		}
+	}
+}
+
+// A scripted LLM that rejects requests with tools, as OpenAI does for models without tool calling.
+//
+// Implements the [llms.Model] interface.
+type toolRejectingLLM struct {
+	*fakes.LLM
+}
+
+// Reject requests with tools, and answer the others from the script.
+//
+// Implements the [llms.Model.GenerateContent] API call.
+func (llm toolRejectingLLM) GenerateContent(
+	ctx context.Context,
+	messages []llms.MessageContent,
+	options ...llms.CallOption,
+) (*llms.ContentResponse, error) {
+	var callOptions llms.CallOptions
+	for _, option := range options {
+		option(&callOptions)
+	}
+	if len(callOptions.Tools) > 0 {
+		return nil, errors.New("API returned unexpected status code: 400: tools is not supported in this model")
+	}
+	return llm.LLM.GenerateContent(ctx, messages, options...)
+}
+
+// Answer a single prompt from the script.
+//
+// Implements the [llms.Model.Call] API call.
+func (llm toolRejectingLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, llm, prompt, options...)
+}
+
+// In the auto mode, a run whose LLM rejects tools falls back to a ReAct agent, while a run that asks for a
+// function-calling agent fails.
+func TestResearchFallsBackToReAct(t *testing.T) {
+	savedLlm, savedModel, savedNow := constants.Llm, constants.LlmModel, now
+	t.Cleanup(func() {
+		constants.Llm, constants.LlmModel, now = savedLlm, savedModel, savedNow
+	})
+	llm := fakes.NewLLM(fakes.Answer("Thought: I now know the final answer\nFinal Answer: ReAct reasons and acts."))
+	constants.Llm = toolRejectingLLM{llm}
+	constants.LlmModel = "gpt-4o-mini"
+	now = func() time.Time { return time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC) }
+	t.Setenv("ARXIV_RESEARCHER_DATA_DIR", t.TempDir())
+	t.Setenv("ARXIV_RESEARCHER_PREFERENCES", "")
+	environment := &tools.Environment{Index: tools.NewIndex(fakes.NewVectorStore(fakes.NewEmbedder(), ""))}
+	prompt := findPrompt(t, prompts.DefaultName)
+
+	answer, err := research(t.Context(), "auto", prompt, "What is ReAct?", 0, nil, environment)
+	if err != nil || answer != "ReAct reasons and acts." {
+		t.Fatalf("unexpected answer %q, error %v", answer, err)
+	}
+	if calls := llm.Calls(); len(calls) != 1 {
+		t.Errorf("expected a single ReAct call, got %d", len(calls))
+	}
+	_, err = research(t.Context(), "functions", prompt, "What is ReAct?", 0, nil, environment)
+	if !errors.Is(err, agent.ErrToolCallingUnsupported) {
+		t.Errorf("expected the function-calling agent to fail, got %v", err)
+	}
+}
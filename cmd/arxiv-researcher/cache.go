@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the cache command, which groups the commands that manage the on-disk caches.
+//
+// Returns the command.
+func newCacheCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the on-disk embedding and arXiv response caches",
+	}
+	cmd.AddCommand(newCachePruneCommand())
+	return cmd
+}
+
+// Create the cache prune command, which removes old entries from the caches.
+//
+// Returns the command.
+func newCachePruneCommand() *cobra.Command {
+	var olderThan time.Duration
+	var model string
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove old embeddings and arXiv responses from the caches",
+		Long: `Remove embeddings and arXiv responses cached longer ago than --older-than (e.g., 720h), and every
+embedding computed by the --model embedding model (e.g., after switching models).`,
+		Args: cobra.NoArgs,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			var cutoff time.Time
+			if olderThan > 0 {
+				cutoff = time.Now().Add(-olderThan)
+			}
+			embeddingCache, err := tools.OpenEmbeddingCache()
+			if err != nil {
+				return err
+			}
+			defer embeddingCache.Close()
+			removed, err := embeddingCache.Prune(cutoff, model)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %d embeddings from the cache.\n", removed)
+			if olderThan == 0 {
+				return nil
+			}
+			arxivCache := tools.ArxivCache
+			if arxivCache == nil {
+				if arxivCache, err = tools.OpenArxivCache(tools.DefaultArxivCacheTTL); err != nil {
+					return err
+				}
+			}
+			removed, err = arxivCache.Prune(cutoff)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Removed %d arXiv responses from the cache.\n", removed)
+			return err
+		}),
+	}
+	cmd.Flags().DurationVar(&olderThan, "older-than", 0, "remove entries cached longer ago than this duration")
+	cmd.Flags().StringVar(&model, "model", "", "remove every embedding computed by this embedding model")
+	cmd.MarkFlagsOneRequired("older-than", "model")
+	return cmd
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/citations"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the enrich command, which adds citation metadata from a scholarly database to papers in the knowledge
+// database.
+//
+// Returns the command.
+func newEnrichCommand() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "enrich [<arXiv ID>...]",
+		Short: "Add citation counts, references, venues, and fields of study to papers in the knowledge database",
+		Long: `Look up papers in the knowledge database in a scholarly database (--citation-source semanticscholar or
+openalex), and store their citation counts, references, venues, and fields of study alongside their arXiv metadata.
+Without arXiv IDs, enrich every paper in the knowledge database.
+
+Papers enriched before are skipped unless --force is given, so that running the command again after hitting the rate
+limit of the scholarly database picks up where it left off. Set SEMANTIC_SCHOLAR_API_KEY for a higher Semantic Scholar
+rate limit, or OPENALEX_EMAIL to identify yourself to OpenAlex.`,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			client, err := tools.NewCitationClient("")
+			if err != nil {
+				return err
+			}
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			result, err := index.EnrichPapers(cmd.Context(), client, args, force)
+			if err != nil {
+				return fmt.Errorf("failed after enriching %d papers: %w", result.Enriched, err)
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Enriched %d papers, skipped %d enriched before.\n",
+				result.Enriched, result.Skipped)
+			if len(result.NotFound) > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Not found in the scholarly database: %s\n",
+					strings.Join(result.NotFound, ", "))
+			}
+			return err
+		}),
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "enrich papers again even if enriched before")
+	return cmd
+}
+
+// Create the references command, which lists the papers that a paper cites.
+//
+// Returns the command.
+func newReferencesCommand() *cobra.Command {
+	return newCitationsCommand(
+		"references",
+		"List the papers that a paper cites",
+		citations.Client.References,
+	)
+}
+
+// Create the cited-by command, which lists the papers that cite a paper.
+//
+// Returns the command.
+func newCitedByCommand() *cobra.Command {
+	return newCitationsCommand(
+		"cited-by",
+		"List the papers that cite a paper",
+		citations.Client.Citations,
+	)
+}
+
+// Create a command that lists the papers related to a paper with a lookup method of the citation client.
+//
+// Returns the command.
+func newCitationsCommand(
+	name string,
+	short string,
+	lookup func(citations.Client, context.Context, citations.PaperId, int) ([]citations.Work, error),
+) *cobra.Command {
+	var limit int
+	var format string
+	cmd := &cobra.Command{
+		Use:   name + " <arXiv ID or DOI>",
+		Short: short,
+		Long: short + ` (e.g., 2210.03629 or 10.18653/v1/N19-1423), as recorded by a scholarly database
+(--citation-source semanticscholar or openalex). The paper need not be in the knowledge database.`,
+		Args: cobra.ExactArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			client, err := tools.NewCitationClient("")
+			if err != nil {
+				return err
+			}
+			works, err := lookup(client, cmd.Context(), citations.ParseId(args[0]), limit)
+			if err != nil {
+				return err
+			}
+			return writeWorks(cmd.OutOrStdout(), works, format)
+		}),
+	}
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "number of papers to list")
+	addFormatFlag(cmd, &format, "text", "json", "jsonl")
+	return cmd
+}
+
+// Write a list of works from a scholarly database in an output format: "text" (a numbered list for reading in a
+// terminal), "json" (an array of work objects), or "jsonl" (one work object per line).
+//
+// Returns nil if we write the works successfully, otherwise returns an error.
+func writeWorks(w io.Writer, works []citations.Work, format string) error {
+	switch format {
+	case "json":
+		return writeJson(w, works)
+	case "jsonl":
+		encoder := json.NewEncoder(w)
+		for _, work := range works {
+			if err := encoder.Encode(work); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if len(works) == 0 {
+			_, err := fmt.Fprintln(w, "No papers found.")
+			return err
+		}
+		for i, work := range works {
+			details := []string{fmt.Sprintf("%d citations", work.CitationCount)}
+			if work.Year != 0 {
+				details = append([]string{fmt.Sprint(work.Year)}, details...)
+			}
+			if work.Venue != "" {
+				details = append([]string{work.Venue}, details...)
+			}
+			_, err := fmt.Fprintf(w, "%d. %s [%s]\n   %s\n   %s\n",
+				i+1, work.Title, work.Id(), strings.Join(work.Authors, ", "), strings.Join(details, ", "))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
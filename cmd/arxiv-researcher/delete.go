@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the delete command, which deletes papers from the knowledge database by arXiv ID or by filter.
+//
+// Returns the command.
+func newDeleteCommand() *cobra.Command {
+	var filter tools.PaperFilter
+	var yes, dryRun bool
+	cmd := &cobra.Command{
+		Use:   "delete [<arXiv ID>...]",
+		Short: "Delete papers from the knowledge database by arXiv ID or filter",
+		Long: `Delete papers from the knowledge database by arXiv ID (e.g., 2210.03629), or every paper that passes a
+filter (e.g., --category cs.CL --until 2020-12-31). The database holds one version of each paper, so deleting a paper
+by a versioned ID (e.g., 2210.03629v3) deletes whichever version the database holds. Deleting by filter needs --yes,
+and --dry-run counts the papers that the command would delete without deleting them.`,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			if !filter.IsEmpty() && !yes && !dryRun {
+				return errors.New("deleting by filter may delete many papers; pass --dry-run to count them or --yes " +
+					"to confirm")
+			}
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			var deleted int
+			if len(args) > 0 {
+				deleted, err = index.DeletePapers(cmd.Context(), args, dryRun)
+			} else {
+				deleted, err = index.DeletePapersWhere(cmd.Context(), filter, dryRun)
+			}
+			if err != nil {
+				return fmt.Errorf("failed while deleting papers from index: %w", err)
+			}
+			if dryRun {
+				_, err = fmt.Fprintf(cmd.OutOrStdout(), "Would delete %d papers from the index.\n", deleted)
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Deleted %d papers from the index.\n", deleted)
+			return err
+		}),
+	}
+	cmd.Args = func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && filter.IsEmpty() {
+			return errors.New("requires arXiv IDs or at least one of --category, --since, or --until")
+		}
+		if len(args) > 0 && !filter.IsEmpty() {
+			return errors.New("cannot combine arXiv IDs with a filter")
+		}
+		return validateFilter(filter)
+	}
+	addFilterFlags(cmd, &filter)
+	cmd.Flags().BoolVar(&yes, "yes", false, "confirm that every paper that passes the filter should be deleted")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "count the papers to delete without deleting them")
+	return cmd
+}
+
+// Add flags that set a paper filter to a command.
+func addFilterFlags(cmd *cobra.Command, filter *tools.PaperFilter) {
+	cmd.Flags().StringVar(&filter.Category, "category", "", "only papers listed under an arXiv category (e.g., cs.CL)")
+	cmd.Flags().StringVar(&filter.Since, "since", "", "only papers published on or after a date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&filter.Until, "until", "", "only papers published on or before a date (YYYY-MM-DD)")
+}
+
+// Check that the dates of a paper filter are well-formed, so that a typo does not silently select the wrong papers.
+//
+// Returns nil if the filter is valid, otherwise returns an error.
+func validateFilter(filter tools.PaperFilter) error {
+	for _, date := range []string{filter.Since, filter.Until} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return fmt.Errorf("invalid date '%s', want YYYY-MM-DD", date)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the download command, which downloads papers from arXiv by arXiv ID.
+//
+// Returns the command.
+func newDownloadCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "download <arXiv ID>...",
+		Short: "Download papers from arXiv by arXiv ID",
+		Long: `Download papers from arXiv by arXiv ID (e.g., 2210.03629 or hep-th/9901001v1) to the papers directory.
+
+Each paper goes to a file named after its ID, with any slashes in old-style IDs replaced by underscores.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			for _, id := range args {
+				fileName := strings.ReplaceAll(id, "/", "_") + ".pdf"
+				if err := tools.DownloadPaper(cmd.Context(), fileName, "https://arxiv.org/pdf/"+id); err != nil {
+					return err
+				}
+				_, err := fmt.Fprintf(cmd.OutOrStdout(), "Downloaded %s to '%s'.\n",
+					id, filepath.Join(tools.PapersDirectory, fileName))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Create the eval command, whose subcommands evaluate the research agent, its prompts, the retrieval of papers, and
+// the behavior of the agent in scripted scenarios.
+//
+// Returns the command.
+func newEvalCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "eval",
+		Short: "Evaluate the research agent, its prompts, its retrieval, and its behavior",
+	}
+	cmd.AddCommand(newEvalPromptsCommand(), newEvalRetrievalCommand(), newEvalScenariosCommand())
+	return cmd
+}
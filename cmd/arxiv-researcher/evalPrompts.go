@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/logging"
+	"tmwong.org/arxiv-researcher-go/prompts"
+	"tmwong.org/arxiv-researcher-go/runs"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// The topics on which `eval prompts` runs the agent unless told otherwise.
+var defaultEvalTopics = []string{
+	"language model agents",
+	"retrieval-augmented generation",
+	"diffusion models for image generation",
+	"graph neural networks for molecular property prediction",
+	"reinforcement learning from human feedback",
+}
+
+// Matches arXiv IDs of new-style papers in answers, e.g., "2210.03629" or "2210.03629v3".
+var answerArxivId = regexp.MustCompile(`\b(\d{4}\.\d{4,5})(?:v\d+)?\b`)
+
+// The outcome of one agent run of a prompt evaluation.
+type promptOutcome struct {
+	Prompt     string  `json:"prompt"`
+	Topic      string  `json:"topic"`
+	RunId      string  `json:"run_id"`
+	Answer     string  `json:"answer,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	LlmCalls   int     `json:"llm_calls"`
+	ToolCalls  int     `json:"tool_calls"`
+	// The number of distinct arXiv IDs in the answer.
+	Papers int     `json:"papers"`
+	Tokens int     `json:"tokens"`
+	Cost   float64 `json:"cost_usd"`
+}
+
+// The outcomes of the runs of one prompt in a prompt evaluation, aggregated over the topics.
+type promptSummary struct {
+	Prompt   string `json:"prompt"`
+	Runs     int    `json:"runs"`
+	Failures int    `json:"failures"`
+	// The number of successful runs whose answers name no arXiv IDs.
+	Empty          int     `json:"empty"`
+	MeanPapers     float64 `json:"mean_papers"`
+	MeanLlmCalls   float64 `json:"mean_llm_calls"`
+	MeanToolCalls  float64 `json:"mean_tool_calls"`
+	MeanDurationMs float64 `json:"mean_duration_ms"`
+	Tokens         int     `json:"tokens"`
+	Cost           float64 `json:"cost_usd"`
+}
+
+// The result of a prompt evaluation.
+type promptEvaluation struct {
+	Summaries []promptSummary `json:"summaries"`
+	Outcomes  []promptOutcome `json:"outcomes"`
+	Usage     usage.Report    `json:"usage"`
+}
+
+// Create the eval prompts command, which compares prompts by running the agent with each prompt on a set of topics.
+//
+// Returns the command.
+func newEvalPromptsCommand() *cobra.Command {
+	options := &askOptions{}
+	var promptNames []string
+	var topicsPath, format string
+	cmd := &cobra.Command{
+		Use:   "prompts [topic phrase]...",
+		Short: "Compare prompts by running the agent with each prompt on a set of topics",
+		Long: `Compare prompts by running the agent with each prompt on a set of topics, and report the outcomes of each
+prompt: how many runs failed, how many papers (distinct arXiv IDs) the answers name, how many answers name none, the
+LLM calls, tool calls, and time that runs take, and the tokens and cost that they spend.
+
+The prompts come from --prompt, which may be given several times (see the prompts command), or are every version of
+the ` + prompts.DefaultName + ` prompt otherwise. The topics come from the arguments, or from a file given by --topics
+with a topic phrase per line (skipping blank lines and lines starting with #), or are a built-in set otherwise. Each
+run is recorded in the run history, where the runs command shows it.`,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			topics, err := evalTopics(args, topicsPath)
+			if err != nil {
+				return err
+			}
+			variants, err := evalPrompts(promptNames)
+			if err != nil {
+				return err
+			}
+			evaluation, err := evaluatePrompts(cmd.Context(), options, variants, topics, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			evaluation.Usage = usage.FromContext(cmd.Context()).Report()
+			switch format {
+			case "json":
+				return writeJson(cmd.OutOrStdout(), evaluation)
+			case "markdown":
+				return writePromptEvaluationMarkdown(cmd.OutOrStdout(), evaluation)
+			}
+			if err := writePromptEvaluationText(cmd.OutOrStdout(), evaluation); err != nil {
+				return err
+			}
+			return writeUsage(cmd)
+		}),
+	}
+	flags := cmd.Flags()
+	flags.StringArrayVar(&promptNames, "prompt", nil,
+		"`name` (or version, or file) of a prompt to evaluate (may be repeated)")
+	flags.StringVar(&topicsPath, "topics", "", "`file` of topic phrases, one per line")
+	flags.Var(newChoice(&options.mode, "auto", "auto", "functions", "react"), "agent",
+		"how the agent drives its tools: auto, functions, or react")
+	flags.DurationVar(&options.toolTimeout, "tool-timeout", 0,
+		"limit on the time each tool call may take (0 for tool defaults)")
+	addFormatFlag(cmd, &format, "text", "json", "markdown")
+	return cmd
+}
+
+// Get the topics of a prompt evaluation: the arguments, or the topics in a topics file, or the default topics.
+//
+// Returns the topics if we read them successfully, otherwise returns an error.
+func evalTopics(args []string, path string) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+	if path == "" {
+		return defaultEvalTopics, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed while opening topics file: %w", err)
+	}
+	defer file.Close()
+	var topics []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if topic := strings.TrimSpace(scanner.Text()); topic != "" && !strings.HasPrefix(topic, "#") {
+			topics = append(topics, topic)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed while reading topics file: %w", err)
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("found no topics in '%s'", path)
+	}
+	return topics, nil
+}
+
+// Get the prompts of a prompt evaluation by name, or every version of the default prompt if no names are given.
+//
+// Returns the prompts, along with the names that select them, if we find them, otherwise returns an error.
+func evalPrompts(names []string) (map[string]prompts.Prompt, error) {
+	directory, err := prompts.DefaultDirectory()
+	if err != nil {
+		return nil, err
+	}
+	variants := map[string]prompts.Prompt{}
+	if len(names) == 0 {
+		list, err := prompts.List(directory)
+		if err != nil {
+			return nil, err
+		}
+		for _, prompt := range list {
+			if prompt.Name == prompts.DefaultName {
+				variants[prompt.Id()] = prompt
+			}
+		}
+		return variants, nil
+	}
+	ids := map[string]string{}
+	for _, name := range names {
+		prompt, err := prompts.Find(directory, name)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := ids[prompt.Id()]; ok {
+			return nil, fmt.Errorf("prompts '%s' and '%s' are both %s", other, name, prompt.Id())
+		}
+		ids[prompt.Id()] = name
+		variants[name] = prompt
+	}
+	return variants, nil
+}
+
+// Run the agent with each prompt on each topic, reporting progress to a writer. Runs alternate between the prompts on
+// each topic, so that the prompts share the changes of external services over time alike. Runs that fail count
+// against their prompt, but a cancelled evaluation, e.g., one that exceeds its budget, stops.
+//
+// Returns the evaluation if the evaluation runs to completion, otherwise returns an error.
+func evaluatePrompts(
+	ctx context.Context,
+	options *askOptions,
+	variants map[string]prompts.Prompt,
+	topics []string,
+	progress io.Writer,
+) (promptEvaluation, error) {
+	directory, err := runs.DefaultDirectory()
+	if err != nil {
+		return promptEvaluation{}, err
+	}
+	names := make([]string, 0, len(variants))
+	for name := range variants {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(x, y string) int { return strings.Compare(variants[x].Id(), variants[y].Id()) })
+	evaluation := promptEvaluation{Outcomes: []promptOutcome{}}
+	for _, topic := range topics {
+		for _, name := range names {
+			runOptions := *options
+			runOptions.prompt = name
+			outcome := promptOutcome{Prompt: variants[name].Id(), Topic: topic, RunId: logging.NewRunId()}
+			runCtx := logging.WithRunId(ctx, outcome.RunId)
+			meter := usage.FromContext(ctx).NewChild()
+			runCtx, release := meter.Attach(runCtx)
+			started := time.Now()
+			_, answer, err := recordResearch(runCtx, &runOptions, topic)
+			release()
+			if ctx.Err() != nil {
+				return promptEvaluation{}, context.Cause(ctx)
+			}
+			outcome.DurationMs = float64(time.Since(started)) / float64(time.Millisecond)
+			if err != nil {
+				outcome.Error = err.Error()
+			} else {
+				outcome.Answer = answer
+				outcome.Papers = countArxivIds(answer)
+			}
+			report := meter.Report()
+			outcome.Tokens, outcome.Cost = report.TotalTokens, report.TotalCost
+			if recorded, err := runs.Load(directory, outcome.RunId); err == nil {
+				outcome.LlmCalls, outcome.ToolCalls = recorded.LlmCalls, recorded.ToolCalls
+			}
+			fmt.Fprintf(progress, "%s on '%s': %s\n", outcome.Prompt, topic, outcomeStatus(outcome))
+			evaluation.Outcomes = append(evaluation.Outcomes, outcome)
+		}
+	}
+	for _, name := range names {
+		evaluation.Summaries = append(evaluation.Summaries, summarizeOutcomes(variants[name].Id(), evaluation.Outcomes))
+	}
+	return evaluation, nil
+}
+
+// Count the distinct arXiv IDs, ignoring versions, in the answer of the agent.
+//
+// Returns the count.
+func countArxivIds(answer string) int {
+	ids := map[string]bool{}
+	for _, match := range answerArxivId.FindAllStringSubmatch(answer, -1) {
+		ids[match[1]] = true
+	}
+	return len(ids)
+}
+
+// Describe the outcome of a run of a prompt evaluation.
+//
+// Returns the description.
+func outcomeStatus(outcome promptOutcome) string {
+	if outcome.Error != "" {
+		return "failed: " + outcome.Error
+	}
+	return fmt.Sprintf("%d papers in %s", outcome.Papers, formatMilliseconds(outcome.DurationMs))
+}
+
+// Aggregate the outcomes of the runs of a prompt.
+//
+// Returns the summary.
+func summarizeOutcomes(prompt string, outcomes []promptOutcome) promptSummary {
+	summary := promptSummary{Prompt: prompt}
+	for _, outcome := range outcomes {
+		if outcome.Prompt != prompt {
+			continue
+		}
+		summary.Runs++
+		summary.Tokens += outcome.Tokens
+		summary.Cost += outcome.Cost
+		summary.MeanLlmCalls += float64(outcome.LlmCalls)
+		summary.MeanToolCalls += float64(outcome.ToolCalls)
+		summary.MeanDurationMs += outcome.DurationMs
+		summary.MeanPapers += float64(outcome.Papers)
+		if outcome.Error != "" {
+			summary.Failures++
+		} else if outcome.Papers == 0 {
+			summary.Empty++
+		}
+	}
+	if summary.Runs > 0 {
+		count := float64(summary.Runs)
+		summary.MeanLlmCalls /= count
+		summary.MeanToolCalls /= count
+		summary.MeanDurationMs /= count
+		summary.MeanPapers /= count
+	}
+	return summary
+}
+
+// Write a prompt evaluation for reading in a terminal: a table comparing the prompts, followed by a table of the runs.
+//
+// Returns nil if we write the evaluation successfully, otherwise returns an error.
+func writePromptEvaluationText(w io.Writer, evaluation promptEvaluation) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "Prompt\tRuns\tFailed\tEmpty\tPapers\tLLM calls\tTool calls\tDuration\tTokens\tCost (USD)")
+	for _, summary := range evaluation.Summaries {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%.1f\t%.1f\t%.1f\t%s\t%d\t$%.6f\n", summary.Prompt, summary.Runs,
+			summary.Failures, summary.Empty, summary.MeanPapers, summary.MeanLlmCalls, summary.MeanToolCalls,
+			formatMilliseconds(summary.MeanDurationMs), summary.Tokens, summary.Cost)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	table = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "\nPrompt\tTopic\tRun\tOutcome")
+	for _, outcome := range evaluation.Outcomes {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", outcome.Prompt, outcome.Topic, outcome.RunId, outcomeStatus(outcome))
+	}
+	return table.Flush()
+}
+
+// Write a prompt evaluation as Markdown: a table comparing the prompts, followed by a table of the runs.
+//
+// Returns nil if we write the evaluation successfully, otherwise returns an error.
+func writePromptEvaluationMarkdown(w io.Writer, evaluation promptEvaluation) error {
+	var text strings.Builder
+	text.WriteString("| Prompt | Runs | Failed | Empty | Papers | LLM calls | Tool calls | Duration | Tokens | " +
+		"Cost (USD) |\n|---|--:|--:|--:|--:|--:|--:|--:|--:|--:|\n")
+	for _, summary := range evaluation.Summaries {
+		fmt.Fprintf(&text, "| %s | %d | %d | %d | %.1f | %.1f | %.1f | %s | %d | $%.6f |\n", summary.Prompt,
+			summary.Runs, summary.Failures, summary.Empty, summary.MeanPapers, summary.MeanLlmCalls,
+			summary.MeanToolCalls, formatMilliseconds(summary.MeanDurationMs), summary.Tokens, summary.Cost)
+	}
+	text.WriteString("\n| Prompt | Topic | Run | Outcome |\n|---|---|---|---|\n")
+	for _, outcome := range evaluation.Outcomes {
+		fmt.Fprintf(&text, "| %s | %s | `%s` | %s |\n", outcome.Prompt, markdownCell(outcome.Topic), outcome.RunId,
+			markdownCell(outcomeStatus(outcome)))
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// Escape text for a cell of a Markdown table.
+//
+// Returns the escaped text.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(text), " "), "|", `\|`)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/evaluation"
+	"tmwong.org/arxiv-researcher-go/tools"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// The result of a retrieval evaluation: a report per retrieval strategy.
+type retrievalEvaluation struct {
+	Dataset        string                       `json:"dataset"`
+	EmbeddingModel string                       `json:"embedding_model"`
+	Reports        []evaluation.RetrievalReport `json:"reports"`
+	Usage          usage.Report                 `json:"usage"`
+}
+
+// Create the eval retrieval command, which measures how well retrieval strategies find the relevant papers of a
+// gold-standard dataset.
+//
+// Returns the command.
+func newEvalRetrievalCommand() *cobra.Command {
+	var strategies []string
+	var cutoffs []int
+	var format, output string
+	cmd := &cobra.Command{
+		Use:   "retrieval <dataset>",
+		Short: "Measure how well retrieval strategies find the relevant papers of a dataset",
+		Long: `Measure how well retrieval strategies find the relevant papers of a gold-standard dataset in the
+knowledge database, and report the recall at each cutoff, the mean reciprocal rank (MRR), the normalized discounted
+cumulative gain (nDCG) at each cutoff, and the latency of each strategy, so that changes to chunking, embedding models,
+or prompts can be compared and regressions caught.
+
+The dataset is a JSON Lines file with a query and the arXiv IDs of the papers relevant to it per line, e.g.:
+
+  {"query": "language model agents that reason and act", "relevant": ["2210.03629", "2303.11366"]}
+
+IDs match papers in any version. The strategies are:
+
+  vector     search by embedding similarity, as the agent's index search tool does
+  hybrid     fuse the vector search with a lexical (BM25) ranking of its candidates
+  reranked   have the LLM re-rank the candidates of the vector search
+
+Queries that fail count as retrieving no papers. Save a report with --format json and --output to compare it with later
+runs.`,
+		Args: cobra.ExactArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			// We evaluate each strategy once, in the order first given.
+			var unique []string
+			seen := map[string]bool{}
+			for _, strategy := range strategies {
+				if !slices.Contains(tools.RetrievalStrategies, strategy) {
+					return fmt.Errorf("unknown retrieval strategy '%s' (want one of %s)", strategy,
+						strings.Join(tools.RetrievalStrategies, ", "))
+				}
+				if !seen[strategy] {
+					seen[strategy] = true
+					unique = append(unique, strategy)
+				}
+			}
+			if seen[tools.RerankedRetrieval] {
+				if err := constants.Ready(); err != nil {
+					return err
+				}
+			}
+			cases, err := evaluation.LoadDataset(args[0])
+			if err != nil {
+				return err
+			}
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			result := retrievalEvaluation{Dataset: args[0], EmbeddingModel: index.EmbeddingModel()}
+			for _, strategy := range unique {
+				report, err := evaluation.EvaluateRetrieval(cmd.Context(), strategy,
+					paperRetriever(index, strategy), cases, cutoffs)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s: %d queries, %d failed, MRR %.3f, mean latency %s\n", strategy,
+					report.Queries, report.Failures, report.Mrr, formatMilliseconds(report.Latency.MeanMs))
+				result.Reports = append(result.Reports, report)
+			}
+			result.Usage = usage.FromContext(cmd.Context()).Report()
+			write := func(w io.Writer) error {
+				switch format {
+				case "json":
+					return writeJson(w, result)
+				case "markdown":
+					return writeRetrievalEvaluationMarkdown(w, result)
+				}
+				return writeRetrievalEvaluationText(w, result)
+			}
+			if output != "" {
+				if err := writeFile(output, write); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Wrote the evaluation to '%s'.\n", output)
+			} else if err := write(cmd.OutOrStdout()); err != nil {
+				return err
+			}
+			return writeUsage(cmd)
+		}),
+	}
+	flags := cmd.Flags()
+	flags.StringSliceVar(&strategies, "strategy", slices.Clone(tools.RetrievalStrategies),
+		"retrieval `strategies` to evaluate: "+strings.Join(tools.RetrievalStrategies, ", "))
+	flags.IntSliceVarP(&cutoffs, "cutoffs", "k", []int{1, 5, 10}, "`ranks` at which to measure recall and nDCG")
+	flags.StringVarP(&output, "output", "o", "", "file to write (default standard output)")
+	addFormatFlag(cmd, &format, "text", "json", "markdown")
+	cmd.RegisterFlagCompletionFunc("strategy",
+		cobra.FixedCompletions(tools.RetrievalStrategies, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// Create a retriever of the arXiv IDs of papers in the index with a retrieval strategy.
+//
+// Returns the retriever.
+func paperRetriever(index *tools.Index, strategy string) evaluation.Retriever {
+	return func(ctx context.Context, query string, count int) ([]string, error) {
+		papers, err := index.RetrievePapers(ctx, constants.Llm, strategy, query, count)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(papers))
+		for i, paper := range papers {
+			ids[i] = paper.Id
+		}
+		return ids, nil
+	}
+}
+
+// Get the column headings of the metrics of a retrieval evaluation, e.g., "Recall@5".
+//
+// Returns the headings.
+func retrievalMetricHeadings(cutoffs []int) []string {
+	var headings []string
+	for _, k := range cutoffs {
+		headings = append(headings, fmt.Sprintf("Recall@%d", k))
+	}
+	headings = append(headings, "MRR")
+	for _, k := range cutoffs {
+		headings = append(headings, fmt.Sprintf("nDCG@%d", k))
+	}
+	return append(headings, "Mean latency", "p50", "p95", "Max")
+}
+
+// Get the cells of the metrics of a retrieval report, in the order of [retrievalMetricHeadings].
+//
+// Returns the cells.
+func retrievalMetricCells(report evaluation.RetrievalReport) []string {
+	var cells []string
+	for _, k := range report.Cutoffs {
+		cells = append(cells, fmt.Sprintf("%.3f", report.Recall[k]))
+	}
+	cells = append(cells, fmt.Sprintf("%.3f", report.Mrr))
+	for _, k := range report.Cutoffs {
+		cells = append(cells, fmt.Sprintf("%.3f", report.Ndcg[k]))
+	}
+	for _, milliseconds := range []float64{
+		report.Latency.MeanMs, report.Latency.P50Ms, report.Latency.P95Ms, report.Latency.MaxMs,
+	} {
+		cells = append(cells, formatMilliseconds(milliseconds))
+	}
+	return cells
+}
+
+// Describe the outcome of a query of a retrieval evaluation: the rank of the first relevant paper.
+//
+// Returns the description.
+func queryOutcome(result evaluation.QueryResult) string {
+	if result.Error != "" {
+		return "failed: " + result.Error
+	}
+	if result.ReciprocalRank == 0 {
+		return "no relevant papers"
+	}
+	return fmt.Sprintf("first relevant at rank %.0f", 1/result.ReciprocalRank)
+}
+
+// Write a retrieval evaluation for reading in a terminal: a table comparing the strategies, followed by a table of the
+// queries.
+//
+// Returns nil if we write the evaluation successfully, otherwise returns an error.
+func writeRetrievalEvaluationText(w io.Writer, result retrievalEvaluation) error {
+	if len(result.Reports) == 0 {
+		return nil
+	}
+	cutoffs := result.Reports[0].Cutoffs
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "Strategy\tQueries\tFailed\t%s\n", strings.Join(retrievalMetricHeadings(cutoffs), "\t"))
+	for _, report := range result.Reports {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%s\n", report.Strategy, report.Queries, report.Failures,
+			strings.Join(retrievalMetricCells(report), "\t"))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	k := cutoffs[len(cutoffs)-1]
+	table = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "\nStrategy\tQuery\tRecall@%d\tLatency\tOutcome\n", k)
+	for _, report := range result.Reports {
+		for _, query := range report.Results {
+			fmt.Fprintf(table, "%s\t%s\t%.3f\t%s\t%s\n", report.Strategy, query.Query, query.Recall[k],
+				formatMilliseconds(query.LatencyMs), queryOutcome(query))
+		}
+	}
+	return table.Flush()
+}
+
+// Write a retrieval evaluation as Markdown: a table comparing the strategies, followed by a table of the queries.
+//
+// Returns nil if we write the evaluation successfully, otherwise returns an error.
+func writeRetrievalEvaluationMarkdown(w io.Writer, result retrievalEvaluation) error {
+	if len(result.Reports) == 0 {
+		return nil
+	}
+	cutoffs := result.Reports[0].Cutoffs
+	headings := retrievalMetricHeadings(cutoffs)
+	var text strings.Builder
+	fmt.Fprintf(&text, "Dataset `%s`, embedding model `%s`.\n\n", result.Dataset, result.EmbeddingModel)
+	fmt.Fprintf(&text, "| Strategy | Queries | Failed | %s |\n|---|--:|--:|%s\n", strings.Join(headings, " | "),
+		strings.Repeat("--:|", len(headings)))
+	for _, report := range result.Reports {
+		fmt.Fprintf(&text, "| %s | %d | %d | %s |\n", report.Strategy, report.Queries, report.Failures,
+			strings.Join(retrievalMetricCells(report), " | "))
+	}
+	k := cutoffs[len(cutoffs)-1]
+	fmt.Fprintf(&text, "\n| Strategy | Query | Recall@%d | Latency | Outcome |\n|---|---|--:|--:|---|\n", k)
+	for _, report := range result.Reports {
+		for _, query := range report.Results {
+			fmt.Fprintf(&text, "| %s | %s | %.3f | %s | %s |\n", report.Strategy, markdownCell(query.Query),
+				query.Recall[k], formatMilliseconds(query.LatencyMs), markdownCell(queryOutcome(query)))
+		}
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/evaluation"
+	"tmwong.org/arxiv-researcher-go/logging"
+	"tmwong.org/arxiv-researcher-go/runs"
+	"tmwong.org/arxiv-researcher-go/stores"
+	"tmwong.org/arxiv-researcher-go/tools"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// The outcome of one scenario of a scenario evaluation.
+type scenarioOutcome struct {
+	Name       string                `json:"name"`
+	Query      string                `json:"query"`
+	RunId      string                `json:"run_id"`
+	Passed     bool                  `json:"passed"`
+	Failures   []string              `json:"failures,omitempty"`
+	ToolCalls  []evaluation.ToolCall `json:"tool_calls"`
+	Answer     string                `json:"answer,omitempty"`
+	Error      string                `json:"error,omitempty"`
+	Verdict    *evaluation.Verdict   `json:"verdict,omitempty"`
+	DurationMs float64               `json:"duration_ms"`
+	Tokens     int                   `json:"tokens"`
+	Cost       float64               `json:"cost_usd"`
+}
+
+// The result of a scenario evaluation.
+type scenarioEvaluation struct {
+	Passed   int               `json:"passed"`
+	Failed   int               `json:"failed"`
+	Outcomes []scenarioOutcome `json:"outcomes"`
+	Usage    usage.Report      `json:"usage"`
+}
+
+// Create the eval scenarios command, which runs the agent on scripted scenarios and checks what it does.
+//
+// Returns the command.
+func newEvalScenariosCommand() *cobra.Command {
+	options := &askOptions{}
+	var names []string
+	var judge bool
+	var format, output string
+	cmd := &cobra.Command{
+		Use:   "scenarios <scenarios file>",
+		Short: "Run the agent on scripted scenarios and check its tool calls and answers",
+		Long: `Run the agent on scripted scenarios and check its tool calls and answers, to test the decision logic of
+the agent, e.g., whether it searches the knowledge database first, falls back to arXiv only when the database holds
+nothing relevant, and downloads the right papers.
+
+The scenarios file is a JSON array of scenarios, e.g.:
+
+  [{"name": "index first", "query": "language model agents",
+    "index": [{"id": "2210.03629v3", "title": "ReAct: Synergizing Reasoning and Acting in Language Models"}],
+    "arxiv": [{"id": "2302.04761v1", "title": "Toolformer: Language Models Can Teach Themselves to Use Tools"}],
+    "expect": {"sequence": [{"tool": "IndexSearcher"}, {"tool": "PaperDownloader", "input": "2210.03629"}],
+               "forbidden": [{"tool": "ArxivSearcher"}], "answer_contains": ["ReAct"],
+               "judge": "The answer recommends ReAct and nothing else."}}]
+
+While the agent answers a scenario, the knowledge database holds only the papers under "index", embedded with a
+deterministic bag-of-words embedder, and arXiv holds only the papers under "arxiv", which searches find by the words of
+their titles and summaries; other services fail, and downloads go to a temporary directory. A scenario passes if the
+agent makes the tool calls of "sequence" in order (with other calls between them, unless "exact" is true), makes none of
+the "forbidden" calls, where an "input" matches calls whose input contains it, and answers with the texts of
+"answer_contains" and without those of "answer_excludes", ignoring case. With --judge, the LLM also judges each run on
+the "judge" criteria of its scenario, and the scenario fails if the LLM finds that the run falls short.
+
+Each run is recorded in a temporary run history, from which the command reads the tool calls of the run, so that
+scenarios leave the run history alone. The command fails if any scenario fails.`,
+		Args: cobra.ExactArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			scenarios, err := evaluation.LoadScenarios(args[0])
+			if err != nil {
+				return err
+			}
+			if len(names) > 0 {
+				for _, name := range names {
+					if !slices.ContainsFunc(scenarios, func(s evaluation.Scenario) bool { return s.Name == name }) {
+						return fmt.Errorf("no scenario named '%s' in '%s'", name, args[0])
+					}
+				}
+				scenarios = slices.DeleteFunc(scenarios, func(s evaluation.Scenario) bool {
+					return !slices.Contains(names, s.Name)
+				})
+			}
+			if err := constants.Ready(); err != nil {
+				return err
+			}
+			result, err := evaluateScenarios(cmd.Context(), options, scenarios, judge, cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+			result.Usage = usage.FromContext(cmd.Context()).Report()
+			write := func(w io.Writer) error {
+				switch format {
+				case "json":
+					return writeJson(w, result)
+				case "markdown":
+					return writeScenarioEvaluationMarkdown(w, result)
+				}
+				return writeScenarioEvaluationText(w, result)
+			}
+			if output != "" {
+				if err := writeFile(output, write); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Wrote the evaluation to '%s'.\n", output)
+			} else if err := write(cmd.OutOrStdout()); err != nil {
+				return err
+			}
+			if err := writeUsage(cmd); err != nil {
+				return err
+			}
+			if result.Failed > 0 {
+				return fmt.Errorf("%d of %d scenarios failed", result.Failed, len(result.Outcomes))
+			}
+			return nil
+		}),
+	}
+	flags := cmd.Flags()
+	flags.StringArrayVar(&names, "scenario", nil, "`name` of a scenario to run (may be repeated; default all)")
+	flags.BoolVar(&judge, "judge", false, "have the LLM judge each run on the judging criteria of its scenario")
+	flags.StringVar(&options.prompt, "prompt", "", "`name` (or version, or file) of the prompt of the agent")
+	flags.Var(newChoice(&options.mode, "auto", "auto", "functions", "react"), "agent",
+		"how the agent drives its tools: auto, functions, or react")
+	flags.DurationVar(&options.toolTimeout, "tool-timeout", 0,
+		"limit on the time each tool call may take (0 for tool defaults)")
+	flags.StringVarP(&output, "output", "o", "", "file to write (default standard output)")
+	addFormatFlag(cmd, &format, "text", "json", "markdown")
+	return cmd
+}
+
+// The number of dimensions of the embeddings of the knowledge databases of scenarios.
+const scenarioEmbeddingDimension = 64
+
+// Run the agent on each scenario in a sandbox of its own (see [runScenario]), in a temporary directory that holds the
+// knowledge database of each scenario, the papers it downloads, and the run history in which we record its run. The
+// sandboxes leave the shared index, HTTP client, and arXiv response cache, the working directory, and the run history
+// alone. Scenarios whose runs fail count as failed, but a cancelled evaluation, e.g., one that exceeds its budget,
+// stops.
+//
+// Returns the evaluation if the evaluation runs to completion, otherwise returns an error.
+func evaluateScenarios(
+	ctx context.Context,
+	options *askOptions,
+	scenarios []evaluation.Scenario,
+	judge bool,
+	progress io.Writer,
+) (scenarioEvaluation, error) {
+	workspace, err := os.MkdirTemp("", "arxiv-researcher-scenarios-")
+	if err != nil {
+		return scenarioEvaluation{}, fmt.Errorf("failed while creating scenario directory: %w", err)
+	}
+	defer os.RemoveAll(workspace)
+	runOptions := *options
+	runOptions.history = filepath.Join(workspace, runs.DirectoryName)
+	result := scenarioEvaluation{Outcomes: []scenarioOutcome{}}
+	for _, scenario := range scenarios {
+		outcome, err := runScenario(ctx, &runOptions, scenario, judge, workspace)
+		if err != nil {
+			return scenarioEvaluation{}, err
+		}
+		if outcome.Passed {
+			result.Passed++
+			fmt.Fprintf(progress, "%s: passed in %s\n", scenario.Name, formatMilliseconds(outcome.DurationMs))
+		} else {
+			result.Failed++
+			fmt.Fprintf(progress, "%s: failed: %s\n", scenario.Name, strings.Join(outcome.Failures, "; "))
+		}
+		result.Outcomes = append(result.Outcomes, outcome)
+	}
+	return result, nil
+}
+
+// Run the agent on a scenario in a sandbox, in a new directory in the given workspace: a knowledge database of its own
+// that holds the papers of the scenario, and an HTTP client that reaches only a fake arXiv that holds the papers of the
+// scenario, with which the tools download papers to the directory. We then check the run against what the scenario
+// expects, asking the LLM to judge the run if told to.
+//
+// Returns the outcome of the scenario if the scenario runs, even if the agent fails, otherwise returns an error.
+func runScenario(
+	ctx context.Context,
+	options *askOptions,
+	scenario evaluation.Scenario,
+	judge bool,
+	workspace string,
+) (scenarioOutcome, error) {
+	directory, err := os.MkdirTemp(workspace, "scenario-")
+	if err != nil {
+		return scenarioOutcome{}, fmt.Errorf("failed while creating directory of scenario '%s': %w", scenario.Name, err)
+	}
+	store, err := stores.OpenLocal(filepath.Join(directory, tools.LocalIndexFileName),
+		stores.WordEmbedder{Dimension: scenarioEmbeddingDimension}, "")
+	if err != nil {
+		return scenarioOutcome{}, err
+	}
+	index := tools.NewIndex(store)
+	defer index.Close()
+	indexPapers := scenarioPapers(scenario.Index)
+	if err := index.AddPapers(ctx, indexPapers); err != nil {
+		return scenarioOutcome{}, fmt.Errorf("failed while indexing papers of scenario '%s': %w", scenario.Name, err)
+	}
+	runOptions := *options
+	runOptions.environment = &tools.Environment{
+		Index: index,
+		HttpClient: &http.Client{Transport: &scenarioArxiv{
+			papers:    scenarioPapers(scenario.Arxiv),
+			downloads: append(slices.Clone(indexPapers), scenarioPapers(scenario.Arxiv)...),
+		}},
+		PapersDirectory: filepath.Join(directory, tools.PapersDirectory),
+	}
+
+	outcome := scenarioOutcome{Name: scenario.Name, Query: scenario.Query, RunId: logging.NewRunId()}
+	runCtx := logging.WithRunId(ctx, outcome.RunId)
+	meter := usage.FromContext(ctx).NewChild()
+	runCtx, release := meter.Attach(runCtx)
+	defer release()
+	started := time.Now()
+	_, answer, err := recordResearch(runCtx, &runOptions, scenario.Query)
+	if ctx.Err() != nil {
+		return scenarioOutcome{}, context.Cause(ctx)
+	}
+	outcome.DurationMs = float64(time.Since(started)) / float64(time.Millisecond)
+	outcome.ToolCalls = []evaluation.ToolCall{}
+	if recorded, err := runs.Load(options.history, outcome.RunId); err == nil {
+		outcome.ToolCalls = evaluation.ToolCalls(recorded.Events)
+	}
+	if err != nil {
+		outcome.Error = err.Error()
+		outcome.Failures = append(outcome.Failures, "run failed: "+err.Error())
+	} else {
+		outcome.Answer = answer
+		outcome.Failures = append(outcome.Failures, evaluation.Check(scenario.Expect, outcome.ToolCalls, answer)...)
+		if judge && scenario.Expect.Judge != "" {
+			verdict, err := evaluation.Judge(runCtx, constants.Llm, scenario, outcome.ToolCalls, answer)
+			if ctx.Err() != nil {
+				return scenarioOutcome{}, context.Cause(ctx)
+			}
+			if err != nil {
+				outcome.Failures = append(outcome.Failures, "judge failed: "+err.Error())
+			} else {
+				outcome.Verdict = &verdict
+				if !verdict.Pass {
+					outcome.Failures = append(outcome.Failures, "judge: "+verdict.Reason)
+				}
+			}
+		}
+	}
+	outcome.Passed = len(outcome.Failures) == 0
+	report := meter.Report()
+	outcome.Tokens, outcome.Cost = report.TotalTokens, report.TotalCost
+	return outcome, nil
+}
+
+// Fill in the URLs of the papers of a scenario from their arXiv IDs, where the scenario leaves them out.
+//
+// Returns the papers with their URLs.
+func scenarioPapers(papers []tools.Paper) []tools.Paper {
+	filled := make([]tools.Paper, len(papers))
+	for i, paper := range papers {
+		paper.ArxivUrl = cmp.Or(paper.ArxivUrl, "http://arxiv.org/abs/"+paper.Id)
+		paper.PdfUrl = cmp.Or(paper.PdfUrl, strings.Replace(paper.ArxivUrl, "/abs/", "/pdf/", 1))
+		filled[i] = paper
+	}
+	return filled
+}
+
+// A fake arXiv that holds the papers of a scenario. Searches find the papers that share words of four or more letters
+// with the query, most shared words first, and downloads of papers of the scenario get a placeholder PDF; every other
+// request fails.
+//
+// Implements the [http.RoundTripper] interface.
+type scenarioArxiv struct {
+	// The papers that searches find.
+	papers []tools.Paper
+	// The papers that may be downloaded.
+	downloads []tools.Paper
+}
+
+// An Atom feed of arXiv query results, holding the fields of papers that [tools.ParsePapers] reads.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Id         string         `xml:"id"`
+	Published  string         `xml:"published,omitempty"`
+	Title      string         `xml:"title"`
+	Summary    string         `xml:"summary"`
+	Authors    []atomAuthor   `xml:"author"`
+	Link       atomLink       `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func (arxiv *scenarioArxiv) RoundTrip(request *http.Request) (*http.Response, error) {
+	respond := func(status int, body string) (*http.Response, error) {
+		return &http.Response{StatusCode: status, Status: http.StatusText(status), Request: request,
+			Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+	if strings.HasSuffix(request.URL.Host, "arxiv.org") && strings.HasPrefix(request.URL.Path, "/pdf/") {
+		id := strings.TrimPrefix(request.URL.Path, "/pdf/")
+		if slices.ContainsFunc(arxiv.downloads, func(paper tools.Paper) bool { return matchesArxivId(paper, id) }) {
+			return respond(http.StatusOK, "%PDF-1.4\n% Placeholder for "+id+" in an evaluation scenario\n%%EOF\n")
+		}
+		return respond(http.StatusNotFound, "")
+	}
+	if request.URL.Host != "export.arxiv.org" || request.URL.Path != "/api/query" {
+		return nil, fmt.Errorf("no service at %s in evaluation scenarios", request.URL.Host)
+	}
+	query := request.URL.Query()
+	var papers []tools.Paper
+	if ids := query.Get("id_list"); ids != "" {
+		for _, id := range strings.Split(ids, ",") {
+			i := slices.IndexFunc(arxiv.downloads, func(paper tools.Paper) bool { return matchesArxivId(paper, id) })
+			if i >= 0 {
+				papers = append(papers, arxiv.downloads[i])
+			}
+		}
+	} else {
+		papers = searchScenarioPapers(arxiv.papers, query.Get("search_query"))
+	}
+	if limit, err := strconv.Atoi(query.Get("max_results")); err == nil {
+		papers = papers[:min(max(limit, 0), len(papers))]
+	}
+	feed := atomFeed{}
+	for _, paper := range papers {
+		entry := atomEntry{Id: paper.ArxivUrl, Published: paper.Published, Title: paper.Title, Summary: paper.Summary,
+			Link: atomLink{Href: paper.ArxivUrl, Rel: "alternate"}}
+		for _, author := range paper.Authors {
+			entry.Authors = append(entry.Authors, atomAuthor{Name: author})
+		}
+		for _, category := range paper.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	body, err := xml.Marshal(feed)
+	if err != nil {
+		return nil, err
+	}
+	return respond(http.StatusOK, xml.Header+string(body))
+}
+
+// Check whether a paper has an arXiv ID, in any version if the ID has none.
+func matchesArxivId(paper tools.Paper, id string) bool {
+	return paper.Id == id || strings.HasPrefix(paper.Id, id+"v")
+}
+
+// Search the papers of a scenario for an arXiv search query, e.g., "all:language model agents". Words of fewer than
+// four letters, and field filters such as "cat:cs.CL", do not count.
+//
+// Returns the papers that share words with the query, most shared words first.
+func searchScenarioPapers(papers []tools.Paper, searchQuery string) []tools.Paper {
+	words := map[string]bool{}
+	for _, word := range strings.Fields(strings.ToLower(strings.ReplaceAll(searchQuery, "all:", " "))) {
+		word = strings.Trim(word, "()\"'.,;")
+		if len(word) >= 4 && !strings.Contains(word, ":") {
+			words[word] = true
+		}
+	}
+	type match struct {
+		paper  tools.Paper
+		shared int
+	}
+	var matches []match
+	for _, paper := range papers {
+		shared := 0
+		for word := range words {
+			if strings.Contains(strings.ToLower(paper.Title+" "+paper.Summary), word) {
+				shared++
+			}
+		}
+		if shared > 0 {
+			matches = append(matches, match{paper: paper, shared: shared})
+		}
+	}
+	slices.SortStableFunc(matches, func(x, y match) int { return cmp.Compare(y.shared, x.shared) })
+	found := make([]tools.Paper, len(matches))
+	for i, match := range matches {
+		found[i] = match.paper
+	}
+	return found
+}
+
+// Describe the tool calls of a run by the names of the tools, in order.
+//
+// Returns the description.
+func toolCallNames(calls []evaluation.ToolCall) string {
+	if len(calls) == 0 {
+		return "none"
+	}
+	names := make([]string, len(calls))
+	for i, call := range calls {
+		names[i] = call.Tool
+	}
+	return strings.Join(names, ", ")
+}
+
+// Describe the result of a scenario.
+//
+// Returns the description.
+func scenarioStatus(outcome scenarioOutcome) string {
+	if outcome.Passed {
+		return "passed"
+	}
+	return "failed: " + strings.Join(outcome.Failures, "; ")
+}
+
+// Write a scenario evaluation for reading in a terminal: a table of the scenarios, followed by a count of the passed
+// and failed scenarios.
+//
+// Returns nil if we write the evaluation successfully, otherwise returns an error.
+func writeScenarioEvaluationText(w io.Writer, result scenarioEvaluation) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "Scenario\tRun\tTool calls\tDuration\tResult")
+	for _, outcome := range result.Outcomes {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", outcome.Name, outcome.RunId, toolCallNames(outcome.ToolCalls),
+			formatMilliseconds(outcome.DurationMs), scenarioStatus(outcome))
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d passed, %d failed\n", result.Passed, result.Failed)
+	return err
+}
+
+// Write a scenario evaluation as Markdown: a table of the scenarios, followed by a count of the passed and failed
+// scenarios.
+//
+// Returns nil if we write the evaluation successfully, otherwise returns an error.
+func writeScenarioEvaluationMarkdown(w io.Writer, result scenarioEvaluation) error {
+	var text strings.Builder
+	text.WriteString("| Scenario | Run | Tool calls | Duration | Result |\n|---|---|---|--:|---|\n")
+	for _, outcome := range result.Outcomes {
+		fmt.Fprintf(&text, "| %s | `%s` | %s | %s | %s |\n", markdownCell(outcome.Name), outcome.RunId,
+			toolCallNames(outcome.ToolCalls), formatMilliseconds(outcome.DurationMs),
+			markdownCell(scenarioStatus(outcome)))
+	}
+	fmt.Fprintf(&text, "\n%d passed, %d failed\n", result.Passed, result.Failed)
+	_, err := io.WriteString(w, text.String())
+	return err
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Create the export command, which exports papers on a topic from the knowledge database (or arXiv) to a file.
+//
+// Returns the command.
+func newExportCommand() *cobra.Command {
+	options := &searchOptions{}
+	var output string
+	cmd := &cobra.Command{
+		Use:   "export <query>",
+		Short: "Export papers on a topic as JSON, JSON lines, BibTeX, or Markdown",
+		Long: `Export papers on a topic as JSON, JSON lines, BibTeX, or Markdown.
+
+The exporter searches the knowledge database (or arXiv, with --arxiv) for papers relevant to the query, and writes
+them to the output file, or to standard output if there is no output file.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			papers, err := searchPapers(cmd.Context(), strings.Join(args, " "), options)
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				return writePapers(cmd.OutOrStdout(), papers, options.format)
+			}
+			err = writeFile(output, func(w io.Writer) error {
+				return writePapers(w, papers, options.format)
+			})
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d papers to '%s'.\n", len(papers), output)
+			return err
+		}),
+	}
+	cmd.Flags().IntVarP(&options.count, "count", "n", 10, "maximum number of papers to export")
+	cmd.Flags().BoolVar(&options.arxiv, "arxiv", false, "export papers from arXiv instead of the knowledge database")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write (default standard output)")
+	addFormatFlag(cmd, &options.format, "json", "jsonl", "bibtex", "markdown")
+	return cmd
+}
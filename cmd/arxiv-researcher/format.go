@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Write a value as indented JSON.
+//
+// Returns nil if we write the value successfully, otherwise returns an error.
+func writeJson(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// Write a list of papers in an output format: "text" (a numbered list for reading in a terminal), "json" (an array of
+// paper objects), "jsonl" (one paper object per line), "markdown" (a bulleted list of links), or "bibtex" (one entry
+// per paper).
+//
+// Returns nil if we write the papers successfully, otherwise returns an error.
+func writePapers(w io.Writer, papers []tools.Paper, format string) error {
+	switch format {
+	case "json":
+		return writeJson(w, papers)
+	case "jsonl":
+		encoder := json.NewEncoder(w)
+		for _, paper := range papers {
+			if err := encoder.Encode(paper); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "markdown":
+		for _, paper := range papers {
+			_, err := fmt.Fprintf(w, "- [%s](%s) — %s (%s)\n",
+				paper.Title, paper.ArxivUrl, strings.Join(paper.Authors, ", "), year(paper))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "bibtex":
+		for _, paper := range papers {
+			if _, err := io.WriteString(w, bibtex(paper)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if len(papers) == 0 {
+			_, err := fmt.Fprintln(w, "No papers found.")
+			return err
+		}
+		for i, paper := range papers {
+			_, err := fmt.Fprintf(w, "%d. %s [%s]\n   %s\n   %s\n",
+				i+1, paper.Title, paper.Id, strings.Join(paper.Authors, ", "), paper.PdfUrl)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Get the year in which a paper was published.
+//
+// Returns the year, or an empty string if the paper carries no publication date.
+func year(paper tools.Paper) string {
+	if len(paper.Published) < 4 {
+		return ""
+	}
+	return paper.Published[:4]
+}
+
+// Format a paper as a BibTeX entry, following the conventions arXiv uses for its own BibTeX exports.
+//
+// Returns the entry.
+func bibtex(paper tools.Paper) string {
+	var entry strings.Builder
+	key := strings.NewReplacer("/", "_", ".", "_").Replace(paper.Id)
+	fmt.Fprintf(&entry, "@misc{arxiv_%s,\n", key)
+	field := func(name string, value string) {
+		if value != "" {
+			fmt.Fprintf(&entry, "  %s = {%s},\n", name, value)
+		}
+	}
+	field("title", paper.Title)
+	field("author", strings.Join(paper.Authors, " and "))
+	field("year", year(paper))
+	field("eprint", paper.Id)
+	field("archivePrefix", "arXiv")
+	field("primaryClass", paper.PrimaryCategory)
+	field("doi", paper.Doi)
+	field("url", paper.ArxivUrl)
+	entry.WriteString("}\n")
+	return entry.String()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// Create the index command, which populates the knowledge database with papers from arXiv on a topic.
+//
+// Returns the command.
+func newIndexCommand() *cobra.Command {
+	var count int
+	var format string
+	cmd := &cobra.Command{
+		Use:   "index <topic phrase>",
+		Short: "Add papers from arXiv on a topic to the knowledge database",
+		Long: `Add papers from arXiv on a topic to the knowledge database.
+
+The indexer searches arXiv for papers relevant to the topic phrase, and saves metadata for the papers (including
+abstracts) in the knowledge database. Indexing a paper again replaces the earlier copy.
+
+Once done, the indexer reports the embedding tokens it spent, along with their estimated cost, to standard error (or
+in the JSON output).`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			papers, err := tools.FetchPapers(cmd.Context(), strings.Join(args, " "), count)
+			if err != nil {
+				return err
+			}
+			if len(papers) == 0 {
+				return fmt.Errorf("found no papers on arXiv")
+			}
+			if err := index.AddPapers(cmd.Context(), papers); err != nil {
+				return fmt.Errorf("failed while adding papers to index: %w", err)
+			}
+			if format == "json" {
+				return writeJson(cmd.OutOrStdout(), map[string]any{
+					"added": len(papers),
+					"usage": usage.FromContext(cmd.Context()).Report(),
+				})
+			}
+			if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Added %d papers to the index.\n", len(papers)); err != nil {
+				return err
+			}
+			return writeUsage(cmd)
+		}),
+	}
+	cmd.Flags().IntVarP(&count, "count", "n", 10, "number of papers to fetch from arXiv")
+	addFormatFlag(cmd, &format, "text", "json")
+	return cmd
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// Create the ingest command, which adds the papers announced in the arXiv daily listings of categories to the knowledge
+// database.
+//
+// Returns the command.
+func newIngestCommand() *cobra.Command {
+	var format string
+	var replacements bool
+	cmd := &cobra.Command{
+		Use:   "ingest <arXiv category>...",
+		Short: "Add the papers in the arXiv daily listings of categories to the knowledge database",
+		Long: `Add the papers in the arXiv daily listings of categories (e.g., cs.CL) to the knowledge database.
+
+arXiv announces the papers submitted to each category every weekday in a listing, along with the papers cross-listed
+to the category, and new versions of papers announced before. Ingesting a listing adds the new and cross-listed papers,
+and replaces the papers in the knowledge database whose new versions the listing announces, with one request per
+category. Papers in several of the listings are added once. New versions of papers outside the knowledge database are
+skipped, unless --replacements is given.
+
+Run the command daily, e.g., from cron, to keep the knowledge database current.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			result, err := index.IngestListings(cmd.Context(), args, replacements)
+			if err != nil {
+				return err
+			}
+			if format == "json" {
+				return writeJson(cmd.OutOrStdout(), struct {
+					tools.IngestResult
+					Usage usage.Report `json:"usage"`
+				}{result, usage.FromContext(cmd.Context()).Report()})
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Added %d papers, updated %d papers, and skipped %d entries.\n",
+				result.Added, result.Updated, result.Skipped)
+			if err != nil {
+				return err
+			}
+			return writeUsage(cmd)
+		}),
+	}
+	cmd.Flags().BoolVar(&replacements, "replacements", false,
+		"also add new versions of papers that the knowledge database does not hold")
+	addFormatFlag(cmd, &format, "text", "json")
+	return cmd
+}
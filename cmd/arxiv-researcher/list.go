@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the list command, which lists the papers in the knowledge database a page at a time.
+//
+// Returns the command.
+func newListCommand() *cobra.Command {
+	var limit int
+	var token string
+	var format string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the papers in the knowledge database",
+		Long: `List the papers in the knowledge database, a page at a time, in the order of the backend.
+
+If there are more papers, the command prints the token for the next page to standard error; pass the token to
+--page-token to list the next page.`,
+		Args: cobra.NoArgs,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			papers, next, err := index.ListPapers(cmd.Context(), limit, token)
+			if err != nil {
+				return fmt.Errorf("failed while listing papers: %w", err)
+			}
+			if err := writePapers(cmd.OutOrStdout(), papers, format); err != nil {
+				return err
+			}
+			if next != "" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "More papers follow; list them with --page-token %s\n", next)
+			}
+			return nil
+		}),
+	}
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "number of papers per page")
+	cmd.Flags().StringVar(&token, "page-token", "", "token for the page to list, printed by the previous page")
+	addFormatFlag(cmd, &format, "text", "json", "jsonl", "markdown", "bibtex")
+	return cmd
+}
+
+// Create the get command, which shows papers in the knowledge database by arXiv ID.
+//
+// Returns the command.
+func newGetCommand() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "get <arXiv ID>...",
+		Short: "Show papers in the knowledge database by arXiv ID",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			papers := make([]tools.Paper, len(args))
+			for i, id := range args {
+				if papers[i], err = index.FetchPaper(cmd.Context(), id); err != nil {
+					return err
+				}
+			}
+			return writePapers(cmd.OutOrStdout(), papers, format)
+		}),
+	}
+	addFormatFlag(cmd, &format, "json", "jsonl", "text", "markdown", "bibtex")
+	return cmd
+}
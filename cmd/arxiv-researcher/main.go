@@ -0,0 +1,114 @@
+/*
+Search, index, and ask questions about research papers from arXiv.
+
+Usage:
+
+	$ arxiv-researcher <command> [flags] [arguments]
+
+The commands are:
+
+	index      Add papers from arXiv on a topic to the knowledge database
+	ingest     Add the papers in the arXiv daily listings of categories to the knowledge database
+	search     Search the knowledge database (or arXiv) for papers on a topic
+	similar    Find papers in the knowledge database similar to a paper
+	ask        Ask the research agent to find (and download) papers on a topic
+	runs       Inspect the history of agent runs
+	prompts    List and show the prompts of the research agent
+	eval       Evaluate the research agent, its prompts, its retrieval, and its behavior
+	ask-paper  Answer a question about a paper from its full text
+	summarize  Summarize papers as their problem, method, datasets, results, limitations, and contributions
+	review     Write a literature review of a topic as a Markdown or HTML report
+	watch      Watch topics for new papers on arXiv
+	download   Download papers from arXiv by arXiv ID
+	export     Export papers on a topic as JSON, JSON lines, BibTeX, or Markdown
+	stats      Show statistics about the knowledge database and caches
+	list       List the papers in the knowledge database
+	get        Show papers in the knowledge database by arXiv ID
+	delete     Delete papers from the knowledge database by arXiv ID or filter
+	namespace  List, copy, and drop namespaces of the knowledge database
+	snapshot   Back up and restore the knowledge database as a snapshot file
+	reindex    Re-embed every paper in the knowledge database with a new embedding model
+	enrich     Add citation counts, references, venues, and fields of study to papers in the knowledge database
+	references List the papers that a paper cites
+	cited-by   List the papers that cite a paper
+	cache      Manage the on-disk embedding and arXiv response caches
+
+Run "arxiv-researcher help <command>" for the flags of each command, and "arxiv-researcher completion --help" to set up
+shell completion. Every command accepts the global flags --backend, --namespace, --embedding-model, and
+--citation-source, the logging flags --verbose, --log-level, --log-format, and --log-redact, the OpenTelemetry flag
+--telemetry, the token accounting flags --prices, --max-tokens, and --max-cost, and the arXiv response cache flags
+--arxiv-cache-ttl, --refresh-arxiv-cache, and --no-arxiv-cache.
+
+The command exits with status 0 on success, 1 if the command fails, 2 if the command line is invalid, 124 if the
+command times out, and 130 if the user interrupts the command (e.g., with Ctrl-C).
+*/
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"tmwong.org/arxiv-researcher-go/constants"
+)
+
+// The exit statuses of the command.
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitUsage       = 2
+	exitTimeout     = 124
+	exitInterrupted = 130
+)
+
+// Wraps an error returned by a command after it parsed its command line successfully, to tell failures of the command
+// apart from invalid command lines.
+type commandError struct {
+	err error
+}
+
+func (e *commandError) Error() string { return e.err.Error() }
+func (e *commandError) Unwrap() error { return e.err }
+
+// Run the command with the given arguments (excluding the program name), writing output to the given writers.
+//
+// Returns the exit status of the command.
+func execute(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	root := newRootCommand()
+	root.SetArgs(args)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+	err := root.ExecuteContext(ctx)
+	closeResources(err)
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintln(stderr, "Error:", err)
+	var failure *commandError
+	switch {
+	case !errors.As(err, &failure):
+		fmt.Fprintln(stderr, "Run 'arxiv-researcher help' for usage.")
+		return exitUsage
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case ctx.Err() != nil:
+		return exitInterrupted
+	default:
+		return exitFailure
+	}
+}
+
+func main() {
+	if err := constants.Init(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitFailure)
+	}
+	// Cancel in-flight requests cleanly when the user interrupts the command.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	status := execute(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(status)
+}
@@ -0,0 +1,714 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/fakes"
+	"tmwong.org/arxiv-researcher-go/prompts"
+	"tmwong.org/arxiv-researcher-go/runs"
+	"tmwong.org/arxiv-researcher-go/tools"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// Point the data and cache directories at temporary directories, embed with a fake embedder, and use the local backend,
+// so that commands run against a fresh local index.
+func setupLocal(t *testing.T) {
+	t.Helper()
+	savedClient := constants.EmbedderClient
+	t.Cleanup(func() {
+		constants.EmbedderClient = savedClient
+		tools.CloseIndex()
+	})
+	constants.EmbedderClient = fakes.NewEmbedder()
+	t.Setenv("ARXIV_RESEARCHER_DATA_DIR", t.TempDir())
+	t.Setenv("ARXIV_RESEARCHER_CACHE_DIR", t.TempDir())
+	t.Setenv("ARXIV_RESEARCHER_BACKEND", tools.LocalBackend)
+	t.Setenv("ARXIV_RESEARCHER_PROMPT", "")
+	t.Setenv("ARXIV_RESEARCHER_PREFERENCES", "")
+	tools.CloseIndex()
+}
+
+// Run the command, and return its exit status and standard output.
+func executeForTest(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	status := execute(t.Context(), args, &stdout, &stderr)
+	if status != exitOK {
+		t.Logf("%s: %s", strings.Join(args, " "), stderr.String())
+	}
+	return status, stdout.String()
+}
+
+func TestExitStatus(t *testing.T) {
+	setupLocal(t)
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"stats"}, exitOK},
+		{[]string{"frobnicate"}, exitUsage},
+		{[]string{"search"}, exitUsage},
+		{[]string{"search", "--format", "yaml", "agents"}, exitUsage},
+		{[]string{"stats", "--no-such-flag"}, exitUsage},
+		{[]string{"--backend", "sqlite", "stats"}, exitUsage},
+		{[]string{"delete"}, exitUsage},
+		{[]string{"delete", "--since", "2023-13-01"}, exitUsage},
+		{[]string{"delete", "--category", "cs.CL", "2210.03629"}, exitUsage},
+		{[]string{"delete", "--category", "cs.CL"}, exitFailure},
+		{[]string{"delete", "--dry-run", "--category", "cs.CL"}, exitOK},
+		{[]string{"namespace", "drop", "backup"}, exitFailure},
+		{[]string{"--timeout", "1ns", "search", "agents"}, exitTimeout},
+	}
+	for _, test := range tests {
+		if status, _ := executeForTest(t, test.args...); status != test.want {
+			t.Errorf("%v exited with %d, want %d", test.args, status, test.want)
+		}
+	}
+
+	// Commands that fail after parsing their command line exit with a failure status, not a usage status.
+	notDirectory := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(notDirectory, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ARXIV_RESEARCHER_DATA_DIR", notDirectory)
+	if status, _ := executeForTest(t, "stats"); status != exitFailure {
+		t.Errorf("stats with an unusable data directory exited with %d, want %d", status, exitFailure)
+	}
+	// So do commands whose setup fails, e.g., on a bad environment variable.
+	t.Setenv("ARXIV_RESEARCHER_TELEMETRY", "carrier-pigeon")
+	if status, _ := executeForTest(t, "stats"); status != exitFailure {
+		t.Errorf("stats with an unknown telemetry exporter exited with %d, want %d", status, exitFailure)
+	}
+}
+
+// Papers added to the local index show up in searches and statistics until deleted.
+func TestSearchAndDeleteLocalIndex(t *testing.T) {
+	setupLocal(t)
+	index, err := tools.GetIndex(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = index.AddPapers(t.Context(), []tools.Paper{
+		{Id: "2210.03629v3", Title: "ReAct: Synergizing Reasoning and Acting in Language Models",
+			Authors: []string{"Shunyu Yao", "Jeffrey Zhao"}, Summary: "Reasoning and acting.",
+			ArxivUrl: "http://arxiv.org/abs/2210.03629v3"},
+		{Id: "2302.04761v1", Title: "Toolformer: Language Models Can Teach Themselves to Use Tools",
+			Authors: []string{"Timo Schick"}, Summary: "Tool use.",
+			ArxivUrl: "http://arxiv.org/abs/2302.04761v1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools.CloseIndex()
+
+	status, output := executeForTest(t, "search", "--count", "1", "--format", "json", "reasoning", "acting")
+	if status != exitOK {
+		t.Fatalf("search exited with %d", status)
+	}
+	var papers []tools.Paper
+	if err := json.Unmarshal([]byte(output), &papers); err != nil {
+		t.Fatal(err)
+	}
+	if len(papers) != 1 || papers[0].Id != "2210.03629v3" || papers[0].Summary != "Reasoning and acting." {
+		t.Errorf("unexpected search results %+v", papers)
+	}
+
+	status, output = executeForTest(t, "similar", "--format", "json", "2210.03629")
+	if status != exitOK {
+		t.Fatalf("similar exited with %d", status)
+	}
+	if err := json.Unmarshal([]byte(output), &papers); err != nil {
+		t.Fatal(err)
+	}
+	if len(papers) != 1 || papers[0].Id != "2302.04761v1" {
+		t.Errorf("unexpected similar papers %+v", papers)
+	}
+
+	// Only papers that the index holds count as deleted.
+	status, output = executeForTest(t, "delete", "2210.03629v3", "1706.03762")
+	if status != exitOK {
+		t.Fatalf("delete exited with %d", status)
+	}
+	if output != "Deleted 1 papers from the index.\n" {
+		t.Errorf("unexpected delete output %q", output)
+	}
+	status, output = executeForTest(t, "stats", "--breakdown", "--format", "json")
+	if status != exitOK {
+		t.Fatalf("stats exited with %d", status)
+	}
+	var stats statistics
+	if err := json.Unmarshal([]byte(output), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Backend != tools.LocalBackend || stats.Papers != 1 || len(stats.ByCategory) != 0 {
+		t.Errorf("unexpected statistics %+v", stats)
+	}
+}
+
+// Re-indexing switches the knowledge database to the new embedding model, after which the database refuses to run with
+// the old model.
+func TestReindex(t *testing.T) {
+	setupLocal(t)
+	saved := tools.EmbedderClientFor
+	t.Cleanup(func() { tools.EmbedderClientFor = saved })
+	tools.EmbedderClientFor = func(model string) (embeddings.EmbedderClient, error) {
+		if model == constants.EmbeddingModel {
+			return constants.EmbedderClient, nil
+		}
+		return &fakes.Embedder{Dimension: 32}, nil
+	}
+	index, err := tools.GetIndex(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = index.AddPapers(t.Context(), []tools.Paper{{Id: "2302.04761v1", Title: "Toolformer",
+		Summary: "Tool use.", ArxivUrl: "http://arxiv.org/abs/2302.04761v1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools.CloseIndex()
+
+	// Re-indexing into the namespace that holds the papers would drop them before reading them.
+	if status, _ := executeForTest(t, "--namespace", "papers", "reindex", "--model", "model-b", "--to", "papers",
+		"--overwrite"); status != exitFailure {
+		t.Errorf("reindex into the configured namespace exited with %d, want %d", status, exitFailure)
+	}
+	// The configuration may already name the new model.
+	t.Setenv("OPENAI_EMBEDDING_MODEL", "model-b")
+	if status, _ := executeForTest(t, "reindex", "--model", "model-b"); status != exitOK {
+		t.Fatalf("reindex exited with %d", status)
+	}
+	t.Setenv("OPENAI_EMBEDDING_MODEL", "")
+	status, output := executeForTest(t, "stats", "--format", "json")
+	if status != exitOK {
+		t.Fatalf("stats exited with %d", status)
+	}
+	var stats statistics
+	if err := json.Unmarshal([]byte(output), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Papers != 1 || stats.Manifest == nil || stats.Manifest.EmbeddingModel != "model-b" ||
+		stats.Manifest.Dimension != 32 || stats.Manifest.NameSpace != "default-model-b" {
+		t.Errorf("unexpected statistics %+v", stats)
+	}
+	if status, _ := executeForTest(t, "search", "tool", "use"); status != exitOK {
+		t.Errorf("search with the new model exited with %d", status)
+	}
+	if status, _ := executeForTest(t, "--embedding-model", constants.EmbeddingModel, "stats"); status != exitFailure {
+		t.Errorf("stats with the old model exited with %d, want %d", status, exitFailure)
+	}
+	if status, _ := executeForTest(t, "reindex", "--model", "model-b"); status != exitFailure {
+		t.Errorf("reindex into the current namespace exited with %d, want %d", status, exitFailure)
+	}
+}
+
+// Summaries of papers in the local index are stored with the papers, where the get command shows them.
+func TestSummarize(t *testing.T) {
+	setupLocal(t)
+	savedLlm := constants.Llm
+	t.Cleanup(func() { constants.Llm = savedLlm })
+	constants.Llm = fakes.NewLLM(fakes.Answer(`{"problem": "Reasoning and acting in isolation.",
+		"method": "Interleave reasoning traces and actions.", "datasets": ["HotpotQA", "ALFWorld"],
+		"results": "Beats imitation learning.", "limitations": "", "contributions": ["ReAct prompting"]}`))
+	index, err := tools.GetIndex(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = index.AddPapers(t.Context(), []tools.Paper{
+		{Id: "2210.03629v3", Title: "ReAct: Synergizing Reasoning and Acting in Language Models",
+			Authors: []string{"Shunyu Yao"}, Summary: "Reasoning and acting.",
+			ArxivUrl: "http://arxiv.org/abs/2210.03629v3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools.CloseIndex()
+
+	status, output := executeForTest(t, "summarize", "--format", "markdown", "2210.03629")
+	if status != exitOK {
+		t.Fatalf("summarize exited with %d", status)
+	}
+	want := "## [ReAct: Synergizing Reasoning and Acting in Language Models](http://arxiv.org/abs/2210.03629v3)\n\n" +
+		"**Problem:** Reasoning and acting in isolation.\n\n"
+	if !strings.HasPrefix(output, want) || !strings.Contains(output, "**Datasets:**\n\n- HotpotQA\n- ALFWorld\n") {
+		t.Errorf("unexpected summary %s", output)
+	}
+
+	status, output = executeForTest(t, "get", "2210.03629")
+	if status != exitOK {
+		t.Fatalf("get exited with %d", status)
+	}
+	var papers []tools.Paper
+	if err := json.Unmarshal([]byte(output), &papers); err != nil {
+		t.Fatal(err)
+	}
+	if len(papers) != 1 || papers[0].StructuredSummary == nil ||
+		papers[0].StructuredSummary.Contributions[0] != "ReAct prompting" {
+		t.Errorf("expected the summary to be stored, got %s", output)
+	}
+}
+
+// Reviews of the papers in the local index go to the output file.
+func TestReview(t *testing.T) {
+	setupLocal(t)
+	savedLlm := constants.Llm
+	t.Cleanup(func() { constants.Llm = savedLlm })
+	constants.Llm = fakes.NewLLM(fakes.Answer("Theme: Tool-using agents\nSummary: Agents reason and act [1]."))
+	index, err := tools.GetIndex(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = index.AddPapers(t.Context(), []tools.Paper{
+		{Id: "2210.03629v3", Title: "ReAct: Synergizing Reasoning and Acting in Language Models",
+			Authors: []string{"Shunyu Yao"}, Published: "2022-10-06T01:00:00Z", Summary: "Reasoning and acting.",
+			ArxivUrl: "http://arxiv.org/abs/2210.03629v3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools.CloseIndex()
+
+	output := filepath.Join(t.TempDir(), "review.html")
+	status, _ := executeForTest(t, "review", "--arxiv", "0", "--format", "html", "--output", output, "agents")
+	if status != exitOK {
+		t.Fatalf("review exited with %d", status)
+	}
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "<h3>1. Tool-using agents</h3>") ||
+		!strings.Contains(string(content), "<strong>2022</strong>: ReAct") {
+		t.Errorf("unexpected review\n%s", content)
+	}
+}
+
+// Serves an arXiv feed fixture in answer to every request.
+type feedTransport struct {
+	fixture string
+}
+
+func (transport feedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	feed, err := os.Open(filepath.Join("..", "..", "tools", "testdata", "arxiv", transport.fixture))
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: feed}, nil
+}
+
+// Watchlists persist in the data directory, and report each new paper once.
+func TestWatch(t *testing.T) {
+	setupLocal(t)
+	savedClient := tools.HttpClient
+	t.Cleanup(func() { tools.HttpClient = savedClient })
+	tools.HttpClient = &http.Client{Transport: feedTransport{fixture: "multi_version.atom"}}
+
+	if status, _ := executeForTest(t, "watch", "add", "agents", "reasoning", "acting"); status != exitOK {
+		t.Fatalf("watch add exited with %d", status)
+	}
+	if status, _ := executeForTest(t, "watch", "add", "agents", "again"); status != exitFailure {
+		t.Errorf("adding a duplicate watchlist exited with %d, want %d", status, exitFailure)
+	}
+	if status, _ := executeForTest(t, "watch", "run", "--email", "me@localhost"); status != exitUsage {
+		t.Errorf("emailing without an SMTP server exited with %d, want %d", status, exitUsage)
+	}
+	status, output := executeForTest(t, "watch", "run")
+	if status != exitOK {
+		t.Fatalf("watch run exited with %d", status)
+	}
+	if !strings.HasPrefix(output, "# 1 new paper on reasoning acting\n") || !strings.Contains(output, "## [ReAct") {
+		t.Errorf("unexpected digest %s", output)
+	}
+	if status, output = executeForTest(t, "watch", "run"); status != exitOK || output != "" {
+		t.Errorf("expected no new papers, got status %d, output %s", status, output)
+	}
+	status, output = executeForTest(t, "watch", "list")
+	if status != exitOK || !strings.HasPrefix(output, "agents\treasoning acting\t1 papers seen, last checked ") {
+		t.Errorf("unexpected watchlists %s", output)
+	}
+	if status, output = executeForTest(t, "get", "--format", "text", "2210.03629"); !strings.Contains(output, "ReAct") {
+		t.Errorf("expected the new paper to be indexed, got status %d, output %s", status, output)
+	}
+	if status, _ := executeForTest(t, "watch", "remove", "agents"); status != exitOK {
+		t.Errorf("watch remove exited with %d", status)
+	}
+}
+
+// Listings add their new papers once, and report what they did.
+func TestIngest(t *testing.T) {
+	setupLocal(t)
+	savedClient := tools.HttpClient
+	t.Cleanup(func() { tools.HttpClient = savedClient })
+	tools.HttpClient = &http.Client{Transport: feedTransport{fixture: "listing.rss"}}
+
+	status, output := executeForTest(t, "ingest", "cs.CL", "cs.AI")
+	if status != exitOK || output != "Added 2 papers, updated 0 papers, and skipped 6 entries.\n" {
+		t.Errorf("unexpected status %d, output %s", status, output)
+	}
+	status, output = executeForTest(t, "ingest", "--replacements", "--format", "json", "cs.CL")
+	if status != exitOK || !strings.Contains(output, `"added": 2`) || !strings.Contains(output, `"updated": 2`) {
+		t.Errorf("unexpected status %d, output %s", status, output)
+	}
+	if status, _ := executeForTest(t, "ingest"); status != exitUsage {
+		t.Errorf("ingesting no categories exited with %d, want %d", status, exitUsage)
+	}
+}
+
+// Indexing jobs report the embedding tokens they spend, and fail once they exceed their budget.
+func TestTokenUsage(t *testing.T) {
+	setupLocal(t)
+	savedClient := tools.HttpClient
+	t.Cleanup(func() { tools.HttpClient = savedClient })
+	tools.HttpClient = &http.Client{Transport: feedTransport{fixture: "listing.rss"}}
+
+	status, output := executeForTest(t, "ingest", "--format", "json", "cs.CL")
+	var result struct {
+		Added int          `json:"added"`
+		Usage usage.Report `json:"usage"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatal(err)
+	}
+	models := result.Usage.Models
+	if status != exitOK || len(models) != 1 || models[0].Model != constants.EmbeddingModel ||
+		models[0].EmbeddingTokens == 0 || result.Usage.TotalCost <= 0 {
+		t.Errorf("unexpected status %d, usage %+v", status, result.Usage)
+	}
+
+	// Start over, since the embedding cache now answers for the papers of the listing.
+	setupLocal(t)
+	var stdout, stderr bytes.Buffer
+	status = execute(t.Context(), []string{"--max-tokens", "10", "ingest", "cs.CL"}, &stdout, &stderr)
+	if status != exitFailure || !strings.Contains(stderr.String(), "budget exceeded") ||
+		!strings.Contains(stderr.String(), "Token usage") {
+		t.Errorf("unexpected status %d, error %s", status, stderr.String())
+	}
+}
+
+// Log records go to standard error as JSON, and every record of a run carries the same run ID. (The JSON output keeps
+// the usage report off standard error.)
+func TestLogging(t *testing.T) {
+	setupLocal(t)
+	savedClient := tools.HttpClient
+	t.Cleanup(func() { tools.HttpClient = savedClient })
+	tools.HttpClient = &http.Client{Transport: feedTransport{fixture: "listing.rss"}}
+
+	var stdout, stderr bytes.Buffer
+	if status := execute(t.Context(), []string{"--log-level", "info", "--log-format", "json", "--no-arxiv-cache",
+		"ingest", "--format", "json", "cs.CL"}, &stdout, &stderr); status != exitOK {
+		t.Fatalf("ingest exited with %d: %s", status, stderr.String())
+	}
+	runIds := map[string]bool{}
+	for line := range strings.Lines(stderr.String()) {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("expected JSON records, got %s", line)
+		}
+		if id, ok := record["run_id"].(string); ok {
+			runIds[id] = true
+		}
+	}
+	if !strings.Contains(stderr.String(), `"msg":"Ingested listings"`) || len(runIds) != 1 {
+		t.Errorf("expected records of one run, got %s", stderr.String())
+	}
+	if status, _ := executeForTest(t, "--log-level", "verbose", "stats"); status != exitUsage {
+		t.Errorf("an invalid log level exited with %d, want %d", status, exitUsage)
+	}
+}
+
+// The stdout exporter writes the trace of a command to standard error, leaving standard output alone.
+func TestTelemetry(t *testing.T) {
+	setupLocal(t)
+	var stdout, stderr bytes.Buffer
+	if status := execute(t.Context(), []string{"--telemetry", "stdout", "stats"}, &stdout, &stderr); status != exitOK {
+		t.Fatalf("stats exited with %d: %s", status, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), `"Name":"command"`) || strings.Contains(stdout.String(), `"Name"`) {
+		t.Errorf("unexpected output %s, telemetry %s", stdout.String(), stderr.String())
+	}
+	if status, _ := executeForTest(t, "--telemetry", "zipkin", "stats"); status != exitUsage {
+		t.Errorf("an invalid exporter exited with %d, want %d", status, exitUsage)
+	}
+}
+
+// A transport whose requests all fail, to check that replayed runs make no requests.
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("unexpected request to %s", request.URL)
+}
+
+// Agent runs go to the run history, which the runs command shows, and replays answer tool calls from the history.
+func TestRuns(t *testing.T) {
+	setupLocal(t)
+	savedLlm, savedClient := constants.Llm, tools.HttpClient
+	t.Cleanup(func() { constants.Llm, tools.HttpClient = savedLlm, savedClient })
+	script := []llms.ContentChoice{
+		fakes.ToolCalls("ArxivSearcher", `{"query": "reasoning and acting", "n": 1}`),
+		fakes.Answer("Found ReAct."),
+	}
+	constants.Llm = fakes.NewLLM(script...)
+	tools.HttpClient = &http.Client{Transport: feedTransport{fixture: "multi_version.atom"}}
+
+	status, output := executeForTest(t, "--no-arxiv-cache", "ask", "--agent", "functions", "--format", "json",
+		"reasoning and acting")
+	if status != exitOK {
+		t.Fatalf("ask exited with %d: %s", status, output)
+	}
+	runId := runIdOf(t, output)
+
+	status, output = executeForTest(t, "runs", "list")
+	if status != exitOK || !strings.HasPrefix(output, runId) ||
+		!strings.Contains(output, "2 LLM calls, 1 tool calls\treasoning and acting") {
+		t.Errorf("unexpected status %d, runs %s", status, output)
+	}
+	status, output = executeForTest(t, "runs", "show", runId[:6])
+	if status != exitOK || !strings.Contains(output, `calls ArxivSearcher {"query": "reasoning and acting"`) ||
+		!strings.Contains(output, `tool_end        ArxivSearcher [ { "Authors": "Shunyu Yao`) ||
+		!strings.HasSuffix(output, "Answer:\nFound ReAct.\n") {
+		t.Errorf("unexpected status %d, run %s", status, output)
+	}
+
+	// The replay makes the same tool call, which its recorded result answers without a request to arXiv.
+	constants.Llm = fakes.NewLLM(script...)
+	tools.HttpClient = &http.Client{Transport: failingTransport{}}
+	status, output = executeForTest(t, "--no-arxiv-cache", "ask", "--agent", "functions", "--format", "json",
+		"--replay", runId)
+	if status != exitOK || !strings.Contains(output, `"query": "reasoning and acting"`) {
+		t.Fatalf("replay exited with %d: %s", status, output)
+	}
+	var replay runs.Run
+	status, output = executeForTest(t, "runs", "show", "--format", "json", runIdOf(t, output))
+	if err := json.Unmarshal([]byte(output), &replay); status != exitOK || err != nil {
+		t.Fatalf("runs show exited with %d: %s", status, output)
+	}
+	var results []string
+	for _, event := range replay.Events {
+		if event.Event == tools.ToolEndEvent {
+			results = append(results, event.Output)
+		}
+	}
+	if replay.ReplayOf != runId || len(results) != 1 || !strings.Contains(results[0], "ReAct: Synergizing") {
+		t.Errorf("unexpected replay %+v", replay)
+	}
+	if status, _ := executeForTest(t, "runs", "show", "ffffffffffffffff"); status != exitFailure {
+		t.Errorf("showing an unknown run exited with %d, want %d", status, exitFailure)
+	}
+}
+
+// Get the run ID from the JSON output of the ask command.
+func runIdOf(t *testing.T, output string) string {
+	t.Helper()
+	var result struct {
+		RunId string `json:"run_id"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result.RunId
+}
+
+// Prompt evaluations run the agent with each prompt on each topic, and compare the answers of the prompts.
+func TestEvalPrompts(t *testing.T) {
+	setupLocal(t)
+	savedLlm := constants.Llm
+	t.Cleanup(func() { constants.Llm = savedLlm })
+	llm := fakes.NewLLM(fakes.Answer("Found 2210.03629v3 and 2302.04761."), fakes.Answer("No papers found."))
+	constants.Llm = llm
+	directory := filepath.Join(os.Getenv("ARXIV_RESEARCHER_DATA_DIR"), prompts.DirectoryName)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := os.WriteFile(filepath.Join(directory, "terse.v1.tmpl"), []byte("Be terse. Today is {{.Today}}.\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, output := executeForTest(t, "prompts", "list")
+	if status != exitOK || !strings.Contains(output, "research.v2  built-in") || !strings.Contains(output, "terse.v1") {
+		t.Errorf("unexpected status %d, prompts %s", status, output)
+	}
+	status, output = executeForTest(t, "prompts", "show", "--render", "terse")
+	if status != exitOK || !strings.HasPrefix(output, "Instructions:\nBe terse. Today is ") {
+		t.Errorf("unexpected status %d, prompt %s", status, output)
+	}
+
+	status, output = executeForTest(t, "eval", "prompts", "--agent", "functions", "--format", "json",
+		"--prompt", "research.v1", "--prompt", "terse", "language model agents")
+	var evaluation promptEvaluation
+	if err := json.Unmarshal([]byte(output), &evaluation); status != exitOK || err != nil {
+		t.Fatalf("eval prompts exited with %d: %s", status, output)
+	}
+	if len(evaluation.Summaries) != 2 || evaluation.Summaries[0].Prompt != "research.v1" ||
+		evaluation.Summaries[0].MeanPapers != 2 || evaluation.Summaries[1].Prompt != "terse.v1" ||
+		evaluation.Summaries[1].Empty != 1 {
+		t.Errorf("unexpected summaries %+v", evaluation.Summaries)
+	}
+	calls := llm.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 LLM calls, got %d", len(calls))
+	}
+	if system := calls[1][0].Parts[0].(llms.TextContent).Text; !strings.HasPrefix(system, "Be terse.") {
+		t.Errorf("unexpected system message %q", system)
+	}
+	// Each run of the evaluation is in the run history, along with its prompt.
+	var run runs.Run
+	status, output = executeForTest(t, "runs", "show", "--format", "json", evaluation.Outcomes[1].RunId)
+	if err := json.Unmarshal([]byte(output), &run); status != exitOK || err != nil || run.Prompt != "terse.v1" ||
+		run.LlmCalls != 1 {
+		t.Errorf("unexpected status %d, run %s", status, output)
+	}
+}
+
+func TestEvalRetrieval(t *testing.T) {
+	setupLocal(t)
+	savedLlm := constants.Llm
+	t.Cleanup(func() { constants.Llm = savedLlm })
+	constants.Llm = fakes.NewLLM(fakes.Answer("[2, 1]"), fakes.Answer("[2]"))
+	index, err := tools.GetIndex(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = index.AddPapers(t.Context(), []tools.Paper{
+		{Id: "2210.03629v3", Title: "ReAct: Synergizing Reasoning and Acting in Language Models",
+			Summary: "Reasoning and acting.", ArxivUrl: "http://arxiv.org/abs/2210.03629v3"},
+		{Id: "2302.04761v1", Title: "Toolformer: Language Models Can Teach Themselves to Use Tools",
+			Summary: "Tool use.", ArxivUrl: "http://arxiv.org/abs/2302.04761v1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools.CloseIndex()
+	dataset := filepath.Join(t.TempDir(), "dataset.jsonl")
+	err = os.WriteFile(dataset, []byte(`{"query": "reasoning and acting", "relevant": ["2210.03629"]}
+{"query": "tool use", "relevant": ["2302.04761v1"]}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, output := executeForTest(t, "eval", "retrieval", "--format", "json", "-k", "1,2", dataset)
+	var evaluation retrievalEvaluation
+	if err := json.Unmarshal([]byte(output), &evaluation); status != exitOK || err != nil {
+		t.Fatalf("eval retrieval exited with %d: %s", status, output)
+	}
+	if len(evaluation.Reports) != 3 {
+		t.Fatalf("expected a report per strategy, got %+v", evaluation.Reports)
+	}
+	for _, report := range evaluation.Reports {
+		if report.Queries != 2 || report.Failures != 0 || report.Recall[2] != 1 {
+			t.Errorf("unexpected report %+v", report)
+		}
+	}
+	// The vector search ranks the relevant paper first for both queries, but the LLM ranks it second.
+	if vector := evaluation.Reports[0]; vector.Mrr != 1 || vector.Ndcg[1] != 1 {
+		t.Errorf("unexpected vector report %+v", vector)
+	}
+	if reranked := evaluation.Reports[2]; reranked.Strategy != tools.RerankedRetrieval || reranked.Mrr != 0.5 ||
+		reranked.Recall[1] != 0 {
+		t.Errorf("unexpected re-ranked report %+v", reranked)
+	}
+
+	path := filepath.Join(t.TempDir(), "retrieval.md")
+	status, _ = executeForTest(t, "eval", "retrieval", "--strategy", "vector,hybrid", "--format", "markdown",
+		"-o", path, dataset)
+	report, err := os.ReadFile(path)
+	if status != exitOK || err != nil || !strings.Contains(string(report), "| Strategy | Queries | Failed | Recall@1 ") ||
+		!strings.Contains(string(report), "| hybrid | tool use | 1.000 |") {
+		t.Errorf("unexpected status %d, report %s, error %v", status, report, err)
+	}
+	status, output = executeForTest(t, "eval", "retrieval", "--strategy", "hybrid,vector,hybrid", "--format", "json",
+		dataset)
+	evaluation.Reports = nil
+	if err := json.Unmarshal([]byte(output), &evaluation); status != exitOK || err != nil ||
+		len(evaluation.Reports) != 2 || evaluation.Reports[0].Strategy != tools.HybridRetrieval {
+		t.Errorf("expected a report per distinct strategy, got status %d, output %s", status, output)
+	}
+	if status, _ := executeForTest(t, "eval", "retrieval", "--strategy", "keyword", dataset); status == exitOK {
+		t.Error("expected an unknown strategy to fail")
+	}
+}
+
+// Scenarios run the agent against a fake index and arXiv holding their papers, and check its tool calls and answers,
+// without touching the shared services or the run history.
+func TestEvalScenarios(t *testing.T) {
+	setupLocal(t)
+	t.Chdir(t.TempDir())
+	savedLlm, savedClient := constants.Llm, tools.HttpClient
+	t.Cleanup(func() { constants.Llm, tools.HttpClient = savedLlm, savedClient })
+	tools.HttpClient = &http.Client{Transport: failingTransport{}}
+	llm := fakes.NewLLM(
+		fakes.ToolCalls("IndexSearcher", `{"query": "language model agents", "n": 2}`),
+		fakes.ToolCalls("PaperDownloader", `{"fileName": "react.pdf", "url": "http://arxiv.org/pdf/2210.03629v3"}`),
+		fakes.Answer("Found and downloaded ReAct (2210.03629)."),
+		fakes.Answer(`{"pass": true, "reason": "The agent found ReAct in the index."}`),
+		fakes.ToolCalls("IndexSearcher", `{"query": "tool use", "n": 2}`),
+		fakes.ToolCalls("ArxivSearcher", `{"query": "tool use", "n": 2}`),
+		fakes.Answer("No papers found."),
+	)
+	constants.Llm = llm
+	path := filepath.Join(t.TempDir(), "scenarios.json")
+	err := os.WriteFile(path, []byte(`[
+  {"name": "index first", "query": "language model agents",
+   "index": [{"id": "2210.03629v3", "title": "ReAct: Synergizing Reasoning and Acting in Language Models"}],
+   "arxiv": [{"id": "2302.04761v1", "title": "Toolformer: Language Models Can Teach Themselves to Use Tools"}],
+   "expect": {"sequence": [{"tool": "IndexSearcher"}, {"tool": "PaperDownloader", "input": "2210.03629"}],
+              "forbidden": [{"tool": "ArxivSearcher"}], "answer_contains": ["react"],
+              "judge": "The agent recommends ReAct."}},
+  {"name": "arxiv fallback", "query": "tool use",
+   "arxiv": [{"id": "2302.04761v1", "title": "Toolformer: Language Models Can Teach Themselves to Use Tools",
+              "summary": "Language models teach themselves to use external tools."}],
+   "expect": {"sequence": [{"tool": "IndexSearcher"}, {"tool": "ArxivSearcher"}], "exact": true,
+              "answer_contains": ["Toolformer"]}}
+]`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, output := executeForTest(t, "eval", "scenarios", "--agent", "functions", "--judge", "--format", "json",
+		path)
+	var evaluation scenarioEvaluation
+	if err := json.Unmarshal([]byte(output), &evaluation); status != exitFailure || err != nil {
+		t.Fatalf("eval scenarios exited with %d: %s", status, output)
+	}
+	if evaluation.Passed != 1 || evaluation.Failed != 1 || len(evaluation.Outcomes) != 2 {
+		t.Fatalf("unexpected evaluation %+v", evaluation)
+	}
+	first, second := evaluation.Outcomes[0], evaluation.Outcomes[1]
+	if !first.Passed || first.Verdict == nil || !first.Verdict.Pass || len(first.ToolCalls) != 2 {
+		t.Errorf("unexpected outcome %+v", first)
+	}
+	if second.Passed || len(second.ToolCalls) != 2 ||
+		!slices.Equal(second.Failures, []string{"answer does not contain 'Toolformer'"}) {
+		t.Errorf("unexpected outcome %+v", second)
+	}
+	// The fake arXiv found Toolformer by the words of its summary, and the download went to a temporary directory.
+	calls := llm.Calls()
+	if final := calls[len(calls)-1]; !strings.Contains(fmt.Sprint(final[len(final)-1].Parts), "Toolformer") {
+		t.Errorf("expected arXiv to find Toolformer, got %v", final[len(final)-1].Parts)
+	}
+	if _, err := os.Stat(tools.PapersDirectory); !os.IsNotExist(err) {
+		t.Errorf("expected no papers in the working directory, got %v", err)
+	}
+	if tools.HttpClient.Transport != (failingTransport{}) || tools.CitationClient != nil {
+		t.Error("expected the shared HTTP client and citation client to be left alone")
+	}
+	directory, err := runs.DefaultDirectory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history, err := runs.List(directory); err != nil || len(history) != 0 {
+		t.Errorf("expected no runs in the run history, got %+v, error %v", history, err)
+	}
+}
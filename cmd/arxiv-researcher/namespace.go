@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the namespace command, whose subcommands list, copy, and drop namespaces of the knowledge database.
+//
+// Returns the command.
+func newNameSpaceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "namespace",
+		Short: "List, copy, and drop namespaces of the knowledge database",
+		Long: `List, copy, and drop namespaces of the knowledge database. Name the default namespace with an empty
+string (e.g., namespace copy "" backup).`,
+	}
+	cmd.AddCommand(newNameSpaceListCommand(), newNameSpaceCopyCommand(), newNameSpaceDropCommand())
+	return cmd
+}
+
+// Create the namespace list command, which lists the non-empty namespaces, along with the number of papers in each.
+//
+// Returns the command.
+func newNameSpaceListCommand() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the namespaces of the knowledge database",
+		Args:  cobra.NoArgs,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			counts, err := index.NameSpaces(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed while listing namespaces: %w", err)
+			}
+			if format == "json" {
+				return writeJson(cmd.OutOrStdout(), counts)
+			}
+			for _, nameSpace := range slices.Sorted(maps.Keys(counts)) {
+				name := nameSpace
+				if name == "" {
+					name = "(default)"
+				}
+				if _, err := fmt.Fprintf(cmd.OutOrStdout(), "%s\t%d papers\n", name, counts[nameSpace]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	}
+	addFormatFlag(cmd, &format, "text", "json")
+	return cmd
+}
+
+// Create the namespace copy command, which copies every paper, along with its embedding, to another namespace.
+//
+// Returns the command.
+func newNameSpaceCopyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "copy <from> <to>",
+		Short: "Copy every paper in a namespace to another namespace",
+		Long: `Copy every paper in a namespace, along with its embedding, to another namespace, replacing any copies of
+the same papers there. Copying does not embed the papers again.`,
+		Args: cobra.ExactArgs(2),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			copied, err := index.CopyNameSpace(cmd.Context(), args[0], args[1])
+			if err != nil {
+				return fmt.Errorf("failed while copying namespace: %w", err)
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Copied %d papers.\n", copied)
+			return err
+		}),
+	}
+}
+
+// Create the namespace drop command, which deletes every paper in a namespace.
+//
+// Returns the command.
+func newNameSpaceDropCommand() *cobra.Command {
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "drop <namespace>",
+		Short: "Delete every paper in a namespace",
+		Args:  cobra.ExactArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			if !yes {
+				return errors.New("dropping a namespace deletes every paper in it; pass --yes to confirm")
+			}
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if err := index.DropNameSpace(cmd.Context(), args[0]); err != nil {
+				return fmt.Errorf("failed while dropping namespace: %w", err)
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), "Dropped namespace.")
+			return err
+		}),
+	}
+	cmd.Flags().BoolVar(&yes, "yes", false, "confirm that every paper in the namespace should be deleted")
+	return cmd
+}
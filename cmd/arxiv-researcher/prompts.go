@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/prompts"
+)
+
+// Create the prompts command, whose subcommands list and show the prompts of the research agent.
+//
+// Returns the command.
+func newPromptsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prompts",
+		Short: "List and show the prompts of the research agent",
+		Long: `List and show the prompts of the research agent.
+
+Prompts are Go template files named by the prompt and its version, e.g., research.v2.tmpl. Built-in prompts ship with
+the program; to add prompts, or to override built-in prompts of the same name and version, put prompt files in the
+` + prompts.DirectoryName + ` directory of the data directory. The ask command selects a prompt with --prompt, and the
+eval prompts command compares prompts.
+
+A prompt file renders the instructions of the agent, and may override the ReAct prefix and suffix templates by
+defining templates named "prefix" and "suffix". Templates see the date as .Today, the model as .Model, the agent mode
+as .Agent, the number of papers in the knowledge database as .Papers, the topics of the watchlists as .Topics, and the
+preferences of the user as .Preferences (from the file named by ARXIV_RESEARCHER_PREFERENCES, or ` +
+			prompts.PreferencesFileName + ` in the data directory). ReAct prefixes refer to the descriptions of the
+tools as .Tools, and ReAct suffixes refer to the topic phrase as .Input and to the scratchpad of the agent as
+.Scratchpad. The join function joins lists, e.g., {{join .Topics ", "}}. The default sections are:
+
+` + prompts.DefaultSections,
+	}
+	cmd.AddCommand(newPromptsListCommand(), newPromptsShowCommand())
+	return cmd
+}
+
+// Create the prompts list command, which lists the prompts.
+//
+// Returns the command.
+func newPromptsListCommand() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the built-in prompts and the prompts in the prompts directory",
+		Args:  cobra.NoArgs,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			directory, err := prompts.DefaultDirectory()
+			if err != nil {
+				return err
+			}
+			list, err := prompts.List(directory)
+			if err != nil {
+				return err
+			}
+			if format == "json" {
+				for i := range list {
+					list[i].Text = ""
+				}
+				return writeJson(cmd.OutOrStdout(), list)
+			}
+			table := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(table, "Prompt\tSource")
+			for _, prompt := range list {
+				fmt.Fprintf(table, "%s\t%s\n", prompt.Id(), prompt.Source)
+			}
+			return table.Flush()
+		}),
+	}
+	addFormatFlag(cmd, &format, "text", "json")
+	return cmd
+}
+
+// Create the prompts show command, which shows the template of a prompt, or the prompts it renders.
+//
+// Returns the command.
+func newPromptsShowCommand() *cobra.Command {
+	var mode, format string
+	var render bool
+	cmd := &cobra.Command{
+		Use:   "show <prompt>",
+		Short: "Show the template of a prompt, or with --render, the prompts it renders",
+		Long: `Show the template of a prompt, given by name (for its latest version), by version (e.g., research.v1), or
+by file. With --render, show the instructions, ReAct prefix, and ReAct suffix that the prompt renders for an agent run
+now, which keep the placeholders that the agent fills in on each iteration, e.g., {{.input}}.`,
+		Args: cobra.ExactArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			directory, err := prompts.DefaultDirectory()
+			if err != nil {
+				return err
+			}
+			prompt, err := prompts.Find(directory, args[0])
+			if err != nil {
+				return err
+			}
+			if !render {
+				if format == "json" {
+					return writeJson(cmd.OutOrStdout(), prompt)
+				}
+				_, err := io.WriteString(cmd.OutOrStdout(), prompt.Text)
+				return err
+			}
+			data, err := promptContext(cmd.Context(), mode)
+			if err != nil {
+				return err
+			}
+			rendered, err := prompt.Render(data)
+			if err != nil {
+				return err
+			}
+			if format == "json" {
+				return writeJson(cmd.OutOrStdout(), rendered)
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Instructions:\n%s\nReAct prefix:\n%s\nReAct suffix:\n%s\n",
+				rendered.Instructions, rendered.Prefix, rendered.Suffix)
+			return err
+		}),
+	}
+	cmd.Flags().BoolVar(&render, "render", false, "show the prompts that the prompt renders instead of its template")
+	cmd.Flags().Var(newChoice(&mode, "auto", "auto", "functions", "react"), "agent",
+		"agent mode for which to render the prompt: auto, functions, or react")
+	addFormatFlag(cmd, &format, "text", "json")
+	return cmd
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the reindex command, which re-embeds every paper in the knowledge database with a new embedding model.
+//
+// Returns the command.
+func newReindexCommand() *cobra.Command {
+	var model string
+	var target string
+	var overwrite bool
+	var dropOld bool
+	cmd := &cobra.Command{
+		Use:   "reindex --model <embedding model>",
+		Short: "Re-embed every paper in the knowledge database with a new embedding model",
+		Long: `Re-embed every paper in the knowledge database with a new embedding model.
+
+The knowledge database records the embedding model that embedded its papers, and searches always embed queries with
+that model. To change models, reindex embeds every paper again with the new model into a new namespace (--to, by
+default named after the namespace and the model, and never the namespace that the knowledge database reads), and then
+switches the knowledge database over to the new namespace in a single step, so that searches never mix models. If
+reindexing fails part of the way through, the knowledge database keeps using the old model; run the command again with
+--overwrite to finish, reusing the cached embeddings.
+
+The Pinecone backend keeps every namespace of an index at the dimension of the index, so models of other dimensions
+need a new Pinecone index; move the papers there with "snapshot export" and "snapshot import --reembed".`,
+		Args: cobra.NoArgs,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			// The configuration may already ask for the new model, e.g., from $OPENAI_EMBEDDING_MODEL, but we read the
+			// papers with the model that embedded them.
+			tools.IndexConfig.AnyEmbeddingModel = true
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			configured := tools.IndexConfig.Resolve().NameSpace
+			if target == "" {
+				target = fmt.Sprintf("%s-%s", cmp.Or(configured, "default"), model)
+			}
+			oldNameSpace := ""
+			if old := index.Manifest(); old != nil {
+				oldNameSpace = old.NameSpace
+			}
+			// The configured namespace holds the manifest, and the old namespace (if another) the papers, so replacing
+			// or dropping either would lose the papers we are about to re-index.
+			if target == configured || target == oldNameSpace {
+				return fmt.Errorf("cannot re-index into namespace '%s', which the knowledge database reads", target)
+			}
+			counts, err := index.NameSpaces(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if counts[target] > 0 {
+				if !overwrite {
+					return fmt.Errorf("namespace '%s' already holds %d papers; pass --overwrite to replace them",
+						target, counts[target])
+				}
+				if err := index.DropNameSpace(cmd.Context(), target); err != nil {
+					return err
+				}
+			}
+			reindexed, err := index.Reindex(cmd.Context(), model, target)
+			if err != nil {
+				return fmt.Errorf("failed after re-indexing %d papers: %w", reindexed, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Re-indexed %d papers with '%s' into namespace '%s'.\n",
+				reindexed, model, target)
+			if !dropOld {
+				return nil
+			}
+			if err := index.DropNameSpace(cmd.Context(), oldNameSpace); err != nil {
+				return fmt.Errorf("failed while dropping old namespace: %w", err)
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), "Dropped the old namespace.")
+			return err
+		}),
+	}
+	cmd.Flags().StringVar(&model, "model", "", "embedding model with which to embed the papers")
+	cmd.Flags().StringVar(&target, "to", "", "namespace to hold the re-embedded papers (default <namespace>-<model>)")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "replace the papers in the target namespace, if any")
+	cmd.Flags().BoolVar(&dropOld, "drop-old", false, "delete the papers in the old namespace after switching over")
+	cmd.MarkFlagRequired("model")
+	return cmd
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/review"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the review command, which writes a literature review of a topic.
+//
+// Returns the command.
+func newReviewCommand() *cobra.Command {
+	var count, arxivCount, themes int
+	var format, output string
+	cmd := &cobra.Command{
+		Use:   "review <topic phrase>",
+		Short: "Write a literature review of a topic as a Markdown or HTML report",
+		Long: `Write a literature review of a topic as a Markdown or HTML report.
+
+The command gathers the papers in the knowledge database most relevant to the topic, along with papers on the topic
+from arXiv that the database does not hold, and clusters the papers into themes by their embeddings. The LLM names and
+summarizes each theme. The report holds a section on each theme listing its papers, a timeline of the papers by year,
+and a bibliography, and goes to the output file, or to standard output if there is no output file.
+
+Papers from arXiv are embedded with the embedding model of the knowledge database, but are not added to it; pass
+--arxiv 0 to review only the papers in the database.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			if err := constants.Ready(); err != nil {
+				return err
+			}
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			topic := strings.Join(args, " ")
+			papers, err := index.GatherPapers(cmd.Context(), topic, count, arxivCount)
+			if err != nil {
+				return err
+			}
+			literature, err := review.Build(cmd.Context(), constants.Llm, topic, papers, themes)
+			if err != nil {
+				return err
+			}
+			write := literature.WriteMarkdown
+			if format == "html" {
+				write = literature.WriteHtml
+			}
+			if output == "" {
+				return write(cmd.OutOrStdout())
+			}
+			if err := writeFile(output, write); err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(cmd.ErrOrStderr(), "Reviewed %d papers in %d themes in '%s'.\n",
+				len(literature.Bibliography), len(literature.Themes), output)
+			return err
+		}),
+	}
+	cmd.Flags().IntVarP(&count, "count", "n", 30, "maximum number of papers from the knowledge database")
+	cmd.Flags().IntVar(&arxivCount, "arxiv", 10, "maximum number of papers from arXiv")
+	cmd.Flags().IntVar(&themes, "themes", 0, "number of themes (default chosen by the number of papers)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write (default standard output)")
+	addFormatFlag(cmd, &format, "markdown", "html")
+	return cmd
+}
+
+// Create a file and write to it with a writer function.
+//
+// Returns nil if we write the file successfully, otherwise returns an error.
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed while creating file '%s': %w", path, err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed while writing file '%s': %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed while writing file '%s': %w", path, err)
+	}
+	return nil
+}
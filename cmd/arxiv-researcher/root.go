@@ -0,0 +1,321 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms/openai"
+	"go.opentelemetry.io/otel/attribute"
+	"tmwong.org/arxiv-researcher-go/citations"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/logging"
+	"tmwong.org/arxiv-researcher-go/telemetry"
+	"tmwong.org/arxiv-researcher-go/tools"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// Options shared by every command.
+type globalOptions struct {
+	backend        string
+	nameSpace      string
+	embeddingModel string
+	citationSource string
+	verbose        bool
+	logLevel       string
+	logFormat      string
+	redactedKeys   []string
+	telemetry      string
+	prices         string
+	maxTokens      int
+	maxCost        float64
+	cacheTtl       time.Duration
+	refreshCache   bool
+	noCache        bool
+}
+
+// Create the root command, along with all of its subcommands.
+//
+// Returns the root command.
+func newRootCommand() *cobra.Command {
+	options := &globalOptions{}
+	root := &cobra.Command{
+		Use:   "arxiv-researcher",
+		Short: "Search, index, and ask questions about research papers from arXiv",
+		Long: `Search, index, and ask questions about research papers from arXiv.
+
+The knowledge database lives in Pinecone (--backend pinecone, the default) or in a database file in the local data
+directory (--backend local). Both the indexer and the agent cache embeddings and arXiv responses on disk.`,
+		// We print errors ourselves, and only print usage for invalid command lines, not for failures of commands.
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Every log record of the command carries the same run ID, so that the records of concurrent runs, e.g.,
+			// in a shared log file, can be told apart.
+			cmd.SetContext(logging.WithRunId(cmd.Context(), logging.NewRunId()))
+			// The command line parsed, so a failure to set up the command is a failure of the command, not a usage
+			// error.
+			if err := configure(cmd, options); err != nil {
+				return &commandError{err: err}
+			}
+			return nil
+		},
+	}
+	flags := root.PersistentFlags()
+	flags.Var(newChoice(&options.backend, "", tools.PineconeBackend, tools.LocalBackend), "backend",
+		"vector store backend holding the knowledge database: pinecone or local "+
+			"(default $ARXIV_RESEARCHER_BACKEND or pinecone)")
+	flags.StringVar(&options.nameSpace, "namespace", "",
+		"namespace of the knowledge database (default $PINECONE_NAME_SPACE)")
+	flags.StringVar(&options.embeddingModel, "embedding-model", "",
+		"embedding model that the knowledge database must use (default $OPENAI_EMBEDDING_MODEL or the recorded model)")
+	flags.Var(newChoice(&options.citationSource, "", citations.SemanticScholarSource, citations.OpenAlexSource),
+		"citation-source", "scholarly database for citation metadata: semanticscholar or openalex "+
+			"(default $ARXIV_RESEARCHER_CITATION_SOURCE or semanticscholar)")
+	flags.BoolVarP(&options.verbose, "verbose", "v", false, "log progress to standard error (same as --log-level info)")
+	flags.Var(newChoice(&options.logLevel, "", "debug", "info", "warn", "error"), "log-level",
+		"lowest level of records to log to standard error: debug, info, warn, or error (default: no logging)")
+	flags.Var(newChoice(&options.logFormat, logging.TextFormat, logging.TextFormat, logging.JsonFormat), "log-format",
+		"format of log records: text or json")
+	flags.StringArrayVar(&options.redactedKeys, "log-redact", nil,
+		"`key` of log record attributes whose values to redact, besides API keys, passwords, secrets, and tokens "+
+			"(repeatable)")
+	flags.Var(newChoice(&options.telemetry, "", telemetry.NoExporter, telemetry.StdoutExporter, telemetry.OtlpExporter),
+		"telemetry", "exporter of OpenTelemetry spans and metrics: none, stdout (written to standard error), or otlp "+
+			"(default $ARXIV_RESEARCHER_TELEMETRY or none)")
+	flags.StringVar(&options.prices, "prices", "",
+		"JSON `file` of model prices in US dollars per million tokens, which override the built-in prices "+
+			"(default $ARXIV_RESEARCHER_PRICES)")
+	flags.IntVar(&options.maxTokens, "max-tokens", 0,
+		"abort the command once it spends more LLM and embedding tokens than this (0 for no limit)")
+	flags.Float64Var(&options.maxCost, "max-cost", 0,
+		"abort the command once its estimated cost exceeds this many US dollars (0 for no limit)")
+	flags.Duration("timeout", 0, "abort the command once it runs for longer than this (0 for no limit)")
+	flags.DurationVar(&options.cacheTtl, "arxiv-cache-ttl", tools.DefaultArxivCacheTTL,
+		"time for which to reuse cached arXiv responses")
+	flags.BoolVar(&options.refreshCache, "refresh-arxiv-cache", false,
+		"ignore cached arXiv responses, but cache new ones")
+	flags.BoolVar(&options.noCache, "no-arxiv-cache", false, "neither read nor write the arXiv response cache")
+	root.RegisterFlagCompletionFunc("backend", cobra.FixedCompletions(
+		[]string{tools.PineconeBackend, tools.LocalBackend},
+		cobra.ShellCompDirectiveNoFileComp,
+	))
+	root.RegisterFlagCompletionFunc("citation-source", cobra.FixedCompletions(
+		[]string{citations.SemanticScholarSource, citations.OpenAlexSource},
+		cobra.ShellCompDirectiveNoFileComp,
+	))
+
+	root.AddCommand(
+		newIndexCommand(),
+		newIngestCommand(),
+		newSearchCommand(),
+		newSimilarCommand(),
+		newAskCommand(),
+		newRunsCommand(),
+		newPromptsCommand(),
+		newEvalCommand(),
+		newAskPaperCommand(),
+		newSummarizeCommand(),
+		newReviewCommand(),
+		newWatchCommand(),
+		newDownloadCommand(),
+		newExportCommand(),
+		newStatsCommand(),
+		newListCommand(),
+		newGetCommand(),
+		newDeleteCommand(),
+		newNameSpaceCommand(),
+		newSnapshotCommand(),
+		newReindexCommand(),
+		newEnrichCommand(),
+		newReferencesCommand(),
+		newCitedByCommand(),
+		newCacheCommand(),
+	)
+	return root
+}
+
+// Apply the global options: configure logging to the standard error of a command, token accounting, telemetry, the
+// index connection, the citation source, and the arXiv response cache, and start the trace of the command.
+//
+// Returns nil if we apply the options successfully, otherwise returns an error.
+func configure(cmd *cobra.Command, options *globalOptions) error {
+	level := options.logLevel
+	if level == "" && options.verbose {
+		level = "info"
+	}
+	if level == "" {
+		logging.Disable()
+	} else {
+		logOptions := logging.Options{Format: options.logFormat, RedactedKeys: options.redactedKeys}
+		if err := logOptions.Level.UnmarshalText([]byte(level)); err != nil {
+			return err
+		}
+		logging.Setup(cmd.ErrOrStderr(), logOptions)
+	}
+	// Every model call of the command accounts for its tokens to the same meter, which cancels the command once it
+	// exceeds its budget.
+	meter, err := newMeter(options)
+	if err != nil {
+		return err
+	}
+	ctx, release := meter.Attach(cmd.Context())
+	cmd.SetContext(ctx)
+	releaseMeter = release
+	exporter := cmp.Or(options.telemetry, os.Getenv("ARXIV_RESEARCHER_TELEMETRY"), telemetry.NoExporter)
+	shutdown, err := telemetry.Setup(cmd.Context(), telemetry.Options{Exporter: exporter, Writer: cmd.ErrOrStderr()})
+	if err != nil {
+		return err
+	}
+	shutdownTelemetry = shutdown
+	// The OpenAI LLM reports every call, whether from an agent or not, along with its token counts.
+	if llm, ok := constants.Llm.(*openai.LLM); ok {
+		handlers := []callbacks.Handler{usage.Handler{Model: constants.LlmModel}}
+		if exporter != telemetry.NoExporter {
+			handlers = append(handlers, telemetry.NewHandler(constants.LlmModel))
+		}
+		llm.CallbacksHandler = callbacks.CombiningHandler{Callbacks: handlers}
+	}
+	// Every span of the command descends from the span of the command, so that each run is a single trace.
+	ctx, operation := telemetry.Start(cmd.Context(), "command", attribute.String("command", cmd.CommandPath()))
+	cmd.SetContext(ctx)
+	commandOperation = operation
+	tools.IndexConfig = tools.IndexOptions{
+		Backend:        options.backend,
+		NameSpace:      options.nameSpace,
+		EmbeddingModel: options.embeddingModel,
+	}
+	tools.CitationSource = options.citationSource
+	tools.CitationClient = nil
+	if !options.noCache {
+		arxivCache, err := tools.OpenArxivCache(options.cacheTtl)
+		if err != nil {
+			slog.WarnContext(cmd.Context(), "Continuing without arXiv cache", "error", err)
+		} else {
+			arxivCache.Refresh = options.refreshCache
+		}
+	}
+	return nil
+}
+
+// The operation that traces the running command, the function that flushes telemetry, and the function that releases
+// the usage meter of the command, set by [configure].
+var (
+	commandOperation  *telemetry.Operation
+	shutdownTelemetry func(context.Context) error
+	releaseMeter      context.CancelFunc
+)
+
+// End the trace of a command that ended with the given error, if any, and release the usage meter, index connection,
+// arXiv response cache, and telemetry exporters opened by the command.
+func closeResources(err error) {
+	if commandOperation != nil {
+		commandOperation.End(err)
+		commandOperation = nil
+	}
+	if releaseMeter != nil {
+		releaseMeter()
+		releaseMeter = nil
+	}
+	if err := tools.CloseIndex(); err != nil {
+		slog.Warn("Failed while closing index", "error", err)
+	}
+	if tools.ArxivCache != nil {
+		if stats, err := tools.ArxivCache.Stats(); err == nil {
+			slog.Info("Closing arXiv cache",
+				"hits", stats.Hits, "revalidations", stats.Revalidations, "misses", stats.Misses)
+		}
+		tools.ArxivCache.Close()
+		tools.ArxivCache = nil
+	}
+	if shutdownTelemetry != nil {
+		// Exporting may involve a collector that has gone away, so we give up on it after a while.
+		ctx, cancel := context.WithTimeout(context.Background(), telemetryShutdownTimeout)
+		if err := shutdownTelemetry(ctx); err != nil {
+			slog.Warn("Failed while exporting telemetry", "error", err)
+		}
+		cancel()
+		shutdownTelemetry = nil
+	}
+}
+
+// The longest we wait for telemetry to be exported when a command ends.
+const telemetryShutdownTimeout = 5 * time.Second
+
+// Wrap the function that runs a command, so that [execute] can tell failures of the command apart from invalid command
+// lines, and so that the command runs for no longer than the --timeout flag allows.
+//
+// Returns the wrapped function.
+func run(function func(cmd *cobra.Command, args []string) error) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if timeout, _ := cmd.Flags().GetDuration("timeout"); timeout > 0 {
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+			cmd.SetContext(ctx)
+		}
+		err := function(cmd, args)
+		// Commands that exceed their budget fail, even if their last model call completes them, since they overspent.
+		// The cancellation of their context tells why. Commands that complete report their usage themselves.
+		if cause := context.Cause(cmd.Context()); errors.Is(cause, usage.ErrBudgetExceeded) {
+			if err != nil {
+				writeUsage(cmd)
+			}
+			err = cause
+		}
+		if err != nil {
+			return &commandError{err: err}
+		}
+		return nil
+	}
+}
+
+// Add an output format flag to a command, which accepts (and completes) the given formats, the first of which is the
+// default.
+func addFormatFlag(cmd *cobra.Command, format *string, formats ...string) {
+	cmd.Flags().VarP(newChoice(format, formats[0], formats...), "format", "f",
+		"output format: "+strings.Join(formats, ", "))
+	cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(formats, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// A flag value that must be one of a fixed set of choices, so that invalid values fail flag parsing.
+//
+// Implements the [pflag.Value] interface.
+type choice struct {
+	value   *string
+	choices []string
+}
+
+// Create a flag value that must be one of the given choices, and set it to the given default value.
+//
+// Returns the flag value.
+func newChoice(value *string, defaultValue string, choices ...string) *choice {
+	*value = defaultValue
+	return &choice{value: value, choices: choices}
+}
+
+func (c *choice) String() string {
+	if c.value == nil {
+		return ""
+	}
+	return *c.value
+}
+
+func (c *choice) Set(value string) error {
+	if !slices.Contains(c.choices, value) {
+		return fmt.Errorf("must be one of %s", strings.Join(c.choices, ", "))
+	}
+	*c.value = value
+	return nil
+}
+
+func (c *choice) Type() string {
+	return "string"
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/logging"
+	"tmwong.org/arxiv-researcher-go/runs"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// The number of characters of prompts, tool inputs, and tool outputs that `runs show` prints without --full.
+const shownLength = 100
+
+// Create the runs command, whose subcommands inspect the history of agent runs.
+//
+// Returns the command.
+func newRunsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "Inspect the history of agent runs",
+		Long: `Inspect the history of agent runs.
+
+Every run of the ask command is recorded in the ` + runs.DirectoryName + ` directory of the data directory, along with
+its query, its prompts and LLM responses, the evolution of its scratchpad, its tool calls and their results, its final
+answer, its token usage, and the timing of each step. To debug prompts, "arxiv-researcher ask --replay <run ID>" re-runs
+the agent against the tool results recorded in a run.`,
+	}
+	cmd.AddCommand(newRunsListCommand(), newRunsShowCommand())
+	return cmd
+}
+
+// Create the runs list command, which lists the recorded runs.
+//
+// Returns the command.
+func newRunsListCommand() *cobra.Command {
+	var format string
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the recorded runs, most recent first",
+		Args:  cobra.NoArgs,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			directory, err := runs.DefaultDirectory()
+			if err != nil {
+				return err
+			}
+			history, err := runs.List(directory)
+			if err != nil {
+				return err
+			}
+			if limit > 0 && len(history) > limit {
+				history = history[:limit]
+			}
+			if format == "json" {
+				return writeJson(cmd.OutOrStdout(), history)
+			}
+			for _, run := range history {
+				_, err := fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\t%s\t%d LLM calls, %d tool calls\t%s\n",
+					run.Id, run.Started.Local().Format(time.DateTime), runStatus(run), run.LlmCalls, run.ToolCalls,
+					logging.Truncate(run.Query, shownLength))
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	}
+	cmd.Flags().IntVarP(&limit, "limit", "n", 20, "number of most recent runs to list (0 for all)")
+	addFormatFlag(cmd, &format, "text", "json")
+	return cmd
+}
+
+// Create the runs show command, which shows a recorded run step by step.
+//
+// Returns the command.
+func newRunsShowCommand() *cobra.Command {
+	var format string
+	var full bool
+	cmd := &cobra.Command{
+		Use:   "show <run ID>",
+		Short: "Show a recorded run step by step",
+		Long: `Show a recorded run step by step: its settings, each of its events with the time since the start of the
+run, its final answer or error, and its token usage. The run ID may be any unique prefix of the ID.`,
+		Args: cobra.ExactArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			directory, err := runs.DefaultDirectory()
+			if err != nil {
+				return err
+			}
+			recorded, err := runs.Load(directory, args[0])
+			if err != nil {
+				return err
+			}
+			if format == "json" {
+				return writeJson(cmd.OutOrStdout(), recorded)
+			}
+			return writeRun(cmd.OutOrStdout(), recorded, full)
+		}),
+	}
+	cmd.Flags().BoolVar(&full, "full", false, "show whole prompts, scratchpads, tool inputs, and tool outputs")
+	addFormatFlag(cmd, &format, "text", "json")
+	return cmd
+}
+
+// Describe how a run ended.
+//
+// Returns the description.
+func runStatus(run runs.Run) string {
+	switch {
+	case !run.Finished:
+		return "unfinished"
+	case run.Error != "":
+		return "failed after " + formatMilliseconds(run.DurationMs)
+	default:
+		return "answered in " + formatMilliseconds(run.DurationMs)
+	}
+}
+
+// Format a duration in milliseconds for reading.
+//
+// Returns the formatted duration.
+func formatMilliseconds(milliseconds float64) string {
+	return time.Duration(milliseconds * float64(time.Millisecond)).Round(time.Millisecond).String()
+}
+
+// Write a run for reading in a terminal: its settings, one line per event (or, with full, the whole of each event),
+// and its outcome.
+//
+// Returns nil if we write the run successfully, otherwise returns an error.
+func writeRun(w io.Writer, run *runs.Run, full bool) error {
+	var text strings.Builder
+	fmt.Fprintf(&text, "Run:      %s\n", run.Id)
+	fmt.Fprintf(&text, "Query:    %s\n", run.Query)
+	fmt.Fprintf(&text, "Agent:    %s (%s)\n", run.Mode, run.Model)
+	if run.Prompt != "" {
+		fmt.Fprintf(&text, "Prompt:   %s\n", run.Prompt)
+	}
+	if run.ReplayOf != "" {
+		fmt.Fprintf(&text, "Replays:  %s\n", run.ReplayOf)
+	}
+	fmt.Fprintf(&text, "Started:  %s\n", run.Started.Local().Format(time.DateTime))
+	fmt.Fprintf(&text, "Status:   %s\n", runStatus(*run))
+	if run.Usage != nil {
+		fmt.Fprintf(&text, "Usage:    %d tokens, $%.6f\n", run.Usage.TotalTokens, run.Usage.TotalCost)
+	}
+	text.WriteString("\n")
+	for _, event := range run.Events {
+		offset := event.Time.Sub(run.Started).Seconds()
+		fmt.Fprintf(&text, "%8.2fs  %-15s %s\n", offset, event.Event, describeEvent(event, full))
+	}
+	if run.Error != "" {
+		fmt.Fprintf(&text, "\nError: %s\n", run.Error)
+	} else if run.Finished {
+		fmt.Fprintf(&text, "\nAnswer:\n%s\n", run.Answer)
+	}
+	_, err := io.WriteString(w, text.String())
+	return err
+}
+
+// Describe an event of a run in a line, or, with full, in whole.
+//
+// Returns the description.
+func describeEvent(event tools.TranscriptEvent, full bool) string {
+	shorten := func(text string) string {
+		if full {
+			return text
+		}
+		return logging.Truncate(strings.Join(strings.Fields(text), " "), shownLength)
+	}
+	var parts []string
+	switch event.Event {
+	case tools.LlmStartEvent:
+		parts = append(parts, fmt.Sprintf("%d messages", len(event.Messages)+len(event.Prompts)))
+		if full {
+			for _, message := range event.Messages {
+				parts = append(parts, fmt.Sprintf("\n[%s]\n%s", message.Role, messageText(message)))
+			}
+			for _, prompt := range event.Prompts {
+				parts = append(parts, "\n"+prompt)
+			}
+		}
+	case tools.LlmEndEvent:
+		for _, choice := range event.Choices {
+			for _, call := range choice.ToolCalls {
+				parts = append(parts, fmt.Sprintf("calls %s %s", call.Name, shorten(call.Arguments)))
+			}
+			if choice.Content != "" {
+				parts = append(parts, shorten(choice.Content))
+			}
+		}
+	case tools.ChainStartEvent:
+		// The scratchpad of a ReAct agent records its reasoning so far, which grows with every iteration.
+		if scratchpad, ok := event.Values["agent_scratchpad"].(string); ok {
+			parts = append(parts, fmt.Sprintf("scratchpad of %d characters", len(scratchpad)))
+			if full && scratchpad != "" {
+				parts = append(parts, "\n"+scratchpad)
+			}
+		}
+	case tools.ChainEndEvent:
+		if output, ok := event.Values["text"].(string); ok {
+			parts = append(parts, shorten(output))
+		}
+	case tools.AgentActionEvent:
+		if event.Action != nil {
+			parts = append(parts, event.Action.Tool, shorten(event.Action.ToolInput))
+		}
+	case tools.AgentFinishEvent:
+		if event.Finish != nil {
+			output, _ := event.Finish.ReturnValues["output"].(string)
+			parts = append(parts, shorten(output))
+		}
+	case tools.ToolStartEvent:
+		parts = append(parts, event.Tool, shorten(event.Input))
+	case tools.RetrieverStartEvent:
+		parts = append(parts, shorten(event.Input))
+	case tools.ToolEndEvent:
+		parts = append(parts, event.Tool, shorten(event.Output))
+	case tools.RetrieverEndEvent:
+		parts = append(parts, fmt.Sprintf("%d documents", len(event.Documents)))
+	case tools.TextEvent:
+		parts = append(parts, shorten(event.Output))
+	}
+	if event.Error != "" {
+		parts = append(parts, "error: "+event.Error)
+	}
+	if event.DurationMs > 0 {
+		parts = append(parts, "("+formatMilliseconds(event.DurationMs)+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Get the text of an LLM message, i.e., its text, tool calls, and tool responses.
+//
+// Returns the text.
+func messageText(message tools.TranscriptMessage) string {
+	var texts []string
+	if message.Text != "" {
+		texts = append(texts, message.Text)
+	}
+	for _, call := range message.ToolCalls {
+		texts = append(texts, fmt.Sprintf("calls %s %s", call.Name, call.Arguments))
+	}
+	for _, response := range message.ToolResponses {
+		texts = append(texts, fmt.Sprintf("%s returned %s", response.Name, response.Content))
+	}
+	return strings.Join(texts, "\n")
+}
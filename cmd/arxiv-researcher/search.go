@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Options of the search and export commands.
+type searchOptions struct {
+	count  int
+	arxiv  bool
+	format string
+}
+
+// Search the knowledge database, or arXiv, for papers relevant to a query.
+//
+// Returns the papers, most relevant first, if the search succeeds, otherwise returns an error.
+func searchPapers(ctx context.Context, query string, options *searchOptions) ([]tools.Paper, error) {
+	if options.arxiv {
+		return tools.FetchPapers(ctx, query, options.count)
+	}
+	index, err := tools.GetIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return index.SearchPapers(ctx, query, options.count)
+}
+
+// Create the search command, which searches the knowledge database (or arXiv) for papers on a topic.
+//
+// Returns the command.
+func newSearchCommand() *cobra.Command {
+	options := &searchOptions{}
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Search the knowledge database (or arXiv) for papers on a topic",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			papers, err := searchPapers(cmd.Context(), strings.Join(args, " "), options)
+			if err != nil {
+				return err
+			}
+			return writePapers(cmd.OutOrStdout(), papers, options.format)
+		}),
+	}
+	cmd.Flags().IntVarP(&options.count, "count", "n", 5, "maximum number of papers to return")
+	cmd.Flags().BoolVar(&options.arxiv, "arxiv", false, "search arXiv instead of the knowledge database")
+	addFormatFlag(cmd, &options.format, "text", "json", "markdown")
+	return cmd
+}
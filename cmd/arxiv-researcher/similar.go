@@ -0,0 +1,45 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the similar command, which finds papers in the knowledge database similar to a given paper.
+//
+// Returns the command.
+func newSimilarCommand() *cobra.Command {
+	var count int
+	var filter tools.PaperFilter
+	var format string
+	cmd := &cobra.Command{
+		Use:   "similar <arXiv ID>",
+		Short: "Find papers in the knowledge database similar to a paper",
+		Long: `Find papers in the knowledge database similar to a paper, given by arXiv ID (e.g., 2210.03629),
+optionally only those that pass a filter (e.g., --category cs.CL --since 2023-01-01).
+
+If the knowledge database holds the paper, the search reuses its stored embedding; otherwise the paper comes from arXiv.
+With a filter, the search considers ever more candidates until --count of them pass the filter or none are left.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+				return err
+			}
+			return validateFilter(filter)
+		},
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			papers, err := index.SimilarPapers(cmd.Context(), args[0], count, filter)
+			if err != nil {
+				return err
+			}
+			return writePapers(cmd.OutOrStdout(), papers, format)
+		}),
+	}
+	cmd.Flags().IntVarP(&count, "count", "n", 5, "maximum number of papers to return")
+	addFilterFlags(cmd, &filter)
+	addFormatFlag(cmd, &format, "text", "json", "markdown")
+	return cmd
+}
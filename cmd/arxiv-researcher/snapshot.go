@@ -0,0 +1,119 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the snapshot command, whose subcommands export the knowledge database to a snapshot file and import it back.
+//
+// Returns the command.
+func newSnapshotCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Back up and restore the knowledge database as a snapshot file",
+		Long: `Back up and restore the knowledge database as a snapshot file.
+
+A snapshot is a JSON lines file that holds the ID, embedding, content, and metadata of every paper in a namespace,
+independent of the backend, so that snapshots also migrate papers between backends (e.g., from Pinecone to a local
+index). Snapshot files whose names end in .gz are compressed with gzip.`,
+	}
+	cmd.AddCommand(newSnapshotExportCommand(), newSnapshotImportCommand())
+	return cmd
+}
+
+// Create the snapshot export command, which writes every paper in the namespace to a snapshot file.
+//
+// Returns the command.
+func newSnapshotExportCommand() *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export every paper in the knowledge database to a snapshot file",
+		Args:  cobra.NoArgs,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if output == "" {
+				_, err := index.ExportSnapshot(cmd.Context(), cmd.OutOrStdout())
+				return err
+			}
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed while creating file '%s': %w", output, err)
+			}
+			defer file.Close()
+			var w io.WriteCloser = file
+			if strings.HasSuffix(output, ".gz") {
+				w = gzip.NewWriter(file)
+			}
+			exported, err := index.ExportSnapshot(cmd.Context(), w)
+			if err != nil {
+				return err
+			}
+			if err := w.Close(); err != nil {
+				return fmt.Errorf("failed while writing file '%s': %w", output, err)
+			}
+			if err := file.Close(); err != nil {
+				return fmt.Errorf("failed while writing file '%s': %w", output, err)
+			}
+			_, err = fmt.Fprintf(cmd.ErrOrStderr(), "Exported %d papers to '%s'.\n", exported, output)
+			return err
+		}),
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "snapshot file to write (default standard output)")
+	return cmd
+}
+
+// Create the snapshot import command, which adds every paper in a snapshot file to the namespace.
+//
+// Returns the command.
+func newSnapshotImportCommand() *cobra.Command {
+	var reembed bool
+	cmd := &cobra.Command{
+		Use:   "import <snapshot file>",
+		Short: "Import every paper in a snapshot file into the knowledge database",
+		Long: `Import every paper in a snapshot file into the knowledge database, replacing any copies of the same
+papers. Pass "-" to read the snapshot from standard input.
+
+Importing reuses the embeddings in the snapshot, and refuses snapshots whose embeddings a different embedding model
+computed. Pass --reembed to embed the papers again with the current model instead.`,
+		Args: cobra.ExactArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			var r io.Reader = cmd.InOrStdin()
+			if path := args[0]; path != "-" {
+				file, err := os.Open(path)
+				if err != nil {
+					return fmt.Errorf("failed while opening file '%s': %w", path, err)
+				}
+				defer file.Close()
+				r = file
+				if strings.HasSuffix(path, ".gz") {
+					if r, err = gzip.NewReader(file); err != nil {
+						return fmt.Errorf("failed while reading file '%s': %w", path, err)
+					}
+				}
+			}
+			imported, err := index.ImportSnapshot(cmd.Context(), r, reembed)
+			if err != nil {
+				return fmt.Errorf("imported %d papers before failing: %w", imported, err)
+			}
+			_, err = fmt.Fprintf(cmd.OutOrStdout(), "Imported %d papers.\n", imported)
+			return err
+		}),
+	}
+	cmd.Flags().BoolVar(&reembed, "reembed", false, "embed the papers again with the current embedding model")
+	return cmd
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/cache"
+	"tmwong.org/arxiv-researcher-go/stores"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Statistics about the knowledge database and caches.
+type statistics struct {
+	Backend        string                     `json:"backend"`
+	NameSpace      string                     `json:"namespace"`
+	Manifest       *stores.Manifest           `json:"manifest,omitempty"`
+	Papers         int                        `json:"papers"`
+	ByCategory     map[string]int             `json:"by_category,omitempty"`
+	ByYear         map[string]int             `json:"by_year,omitempty"`
+	EmbeddingCache *cache.EmbeddingCacheStats `json:"embedding_cache,omitempty"`
+	ArxivCache     *cache.ResponseCacheStats  `json:"arxiv_cache,omitempty"`
+}
+
+// Create the stats command, which shows statistics about the knowledge database and caches.
+//
+// Returns the command.
+func newStatsCommand() *cobra.Command {
+	var format string
+	var breakdown bool
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show statistics about the knowledge database and caches",
+		Long: `Show statistics about the knowledge database and caches. With --breakdown, also count the papers in each
+arXiv category and publication year, which reads every paper in the namespace.`,
+		Args: cobra.NoArgs,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			config := tools.IndexConfig.Resolve()
+			stats := statistics{Backend: config.Backend, NameSpace: config.NameSpace, Manifest: index.Manifest()}
+			if breakdown {
+				indexStats, err := index.Stats(cmd.Context())
+				if err != nil {
+					return fmt.Errorf("failed while counting papers: %w", err)
+				}
+				stats.Papers, stats.ByCategory, stats.ByYear =
+					indexStats.Papers, indexStats.ByCategory, indexStats.ByYear
+			} else if stats.Papers, err = index.Count(cmd.Context()); err != nil {
+				return fmt.Errorf("failed while counting papers: %w", err)
+			}
+			if embeddingCache := index.EmbeddingCache(); embeddingCache != nil {
+				embeddingStats, err := embeddingCache.Stats()
+				if err != nil {
+					return err
+				}
+				stats.EmbeddingCache = &embeddingStats
+			}
+			if tools.ArxivCache != nil {
+				arxivStats, err := tools.ArxivCache.Stats()
+				if err != nil {
+					return err
+				}
+				stats.ArxivCache = &arxivStats
+			}
+			if format == "json" {
+				return writeJson(cmd.OutOrStdout(), stats)
+			}
+			return writeStatistics(cmd.OutOrStdout(), stats)
+		}),
+	}
+	cmd.Flags().BoolVar(&breakdown, "breakdown", false, "count papers by arXiv category and publication year")
+	addFormatFlag(cmd, &format, "text", "json")
+	return cmd
+}
+
+// Write statistics for reading in a terminal.
+//
+// Returns nil if we write the statistics successfully, otherwise returns an error.
+func writeStatistics(w io.Writer, stats statistics) error {
+	nameSpace := stats.NameSpace
+	if nameSpace == "" {
+		nameSpace = "(default)"
+	}
+	fmt.Fprintf(w, "Backend:   %s\nNamespace: %s\nPapers:    %d\n", stats.Backend, nameSpace, stats.Papers)
+	if manifest := stats.Manifest; manifest != nil {
+		fmt.Fprintf(w, "Embedding: %s (%d dimensions)\n", manifest.EmbeddingModel, manifest.Dimension)
+		if manifest.NameSpace != "" {
+			fmt.Fprintf(w, "Papers in namespace: %s\n", manifest.NameSpace)
+		}
+	}
+	writeCounts(w, "By category", stats.ByCategory)
+	writeCounts(w, "By year", stats.ByYear)
+	if stats.EmbeddingCache != nil {
+		fmt.Fprintf(w, "Embedding cache: %d bytes\n", stats.EmbeddingCache.SizeBytes)
+		models := make([]string, 0, len(stats.EmbeddingCache.Entries))
+		for model := range stats.EmbeddingCache.Entries {
+			models = append(models, model)
+		}
+		slices.Sort(models)
+		for _, model := range models {
+			fmt.Fprintf(w, "  %s: %d embeddings\n", model, stats.EmbeddingCache.Entries[model])
+		}
+	}
+	if stats.ArxivCache != nil {
+		_, err := fmt.Fprintf(w, "arXiv cache: %d bytes\n  %d responses\n",
+			stats.ArxivCache.SizeBytes, stats.ArxivCache.Entries)
+		return err
+	}
+	return nil
+}
+
+// Write a titled table of counts, sorted by key, unless the table is empty.
+func writeCounts(w io.Writer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, key := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Fprintf(w, "  %s: %d\n", key, counts[key])
+	}
+}
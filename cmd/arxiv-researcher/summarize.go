@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Create the summarize command, which summarizes papers as structured summaries.
+//
+// Returns the command.
+func newSummarizeCommand() *cobra.Command {
+	var fullText, force bool
+	var templatePath string
+	var format string
+	cmd := &cobra.Command{
+		Use:   "summarize <arXiv ID>...",
+		Short: "Summarize papers as their problem, method, datasets, results, limitations, and contributions",
+		Long: `Summarize papers, given by arXiv ID (e.g., 2210.03629), as the problem each paper addresses, its method,
+the datasets it uses, its results, its limitations, and its key contributions.
+
+The summaries come from the abstracts of the papers, or with --full-text, from the full text of the papers, which the
+command downloads to the papers directory if necessary. The command stores the summaries of papers in the knowledge
+database with the papers, where the get command shows them, and reuses them unless --force is given.
+
+The prompt comes from a Go template file given by --template (or ARXIV_RESEARCHER_SUMMARY_TEMPLATE), or from the
+built-in template otherwise. Templates see the paper as .Paper, the opening of its full text (if any) as .Text, and the
+JSON schema of the summary as .Schema, and may join lists with the join function.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			if err := constants.Ready(); err != nil {
+				return err
+			}
+			prompt, err := tools.LoadSummaryTemplate(templatePath)
+			if err != nil {
+				return err
+			}
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			papers := make([]tools.Paper, len(args))
+			for i, id := range args {
+				papers[i], err = index.SummarizePaper(cmd.Context(), constants.Llm, prompt, id, fullText, force)
+				if err != nil {
+					return err
+				}
+			}
+			return writeSummaries(cmd.OutOrStdout(), papers, format)
+		}),
+	}
+	cmd.Flags().BoolVar(&fullText, "full-text", false,
+		"summarize the full text of the papers instead of their abstracts")
+	cmd.Flags().BoolVar(&force, "force", false, "summarize papers again even if the knowledge database holds summaries")
+	cmd.Flags().StringVar(&templatePath, "template", "", "Go template `file` of the summary prompt")
+	addFormatFlag(cmd, &format, "text", "json", "markdown")
+	return cmd
+}
+
+// Write the structured summaries of papers in an output format: "text" (for reading in a terminal), "json" (an array
+// of paper objects with their summaries), or "markdown" (a section per paper).
+//
+// Returns nil if we write the summaries successfully, otherwise returns an error.
+func writeSummaries(w io.Writer, papers []tools.Paper, format string) error {
+	if format == "json" {
+		return writeJson(w, papers)
+	}
+	var output strings.Builder
+	for i, paper := range papers {
+		summary := paper.StructuredSummary
+		if summary == nil {
+			continue
+		}
+		if i > 0 && format != "markdown" {
+			output.WriteString("\n")
+		}
+		field := func(name string, value string) {
+			if value == "" {
+				return
+			}
+			if format == "markdown" {
+				fmt.Fprintf(&output, "**%s:** %s\n\n", name, value)
+			} else {
+				fmt.Fprintf(&output, "%s: %s\n", name, value)
+			}
+		}
+		list := func(name string, values []string) {
+			if len(values) == 0 {
+				return
+			}
+			if format == "markdown" {
+				fmt.Fprintf(&output, "**%s:**\n\n", name)
+				for _, value := range values {
+					fmt.Fprintf(&output, "- %s\n", value)
+				}
+				output.WriteString("\n")
+			} else {
+				fmt.Fprintf(&output, "%s: %s\n", name, strings.Join(values, "; "))
+			}
+		}
+		if format == "markdown" {
+			fmt.Fprintf(&output, "## [%s](%s)\n\n", paper.Title, paper.ArxivUrl)
+		} else {
+			fmt.Fprintf(&output, "%s [%s]\n", paper.Title, paper.Id)
+		}
+		field("Problem", summary.Problem)
+		field("Method", summary.Method)
+		list("Datasets", summary.Datasets)
+		field("Results", summary.Results)
+		field("Limitations", summary.Limitations)
+		list("Contributions", summary.Contributions)
+	}
+	_, err := io.WriteString(w, output.String())
+	return err
+}
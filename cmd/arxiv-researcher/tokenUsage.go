@@ -0,0 +1,40 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// Create the usage meter of a run from the global options: its price table, from the --prices file or the file named
+// by $ARXIV_RESEARCHER_PRICES (or the default prices if neither is given), and its budget.
+//
+// Returns the meter if we load its price table successfully, otherwise returns an error.
+func newMeter(options *globalOptions) (*usage.Meter, error) {
+	prices := usage.DefaultPrices
+	if path := cmp.Or(options.prices, os.Getenv("ARXIV_RESEARCHER_PRICES")); path != "" {
+		var err error
+		if prices, err = usage.LoadPrices(path); err != nil {
+			return nil, err
+		}
+	}
+	return usage.NewMeter(prices, usage.Budget{MaxTokens: options.maxTokens, MaxCost: options.maxCost}), nil
+}
+
+// Write a report of the tokens that the run of a command spent, and their estimated cost, to the standard error of the
+// command, unless the run spent none.
+//
+// Returns nil if we write the report successfully, otherwise returns an error.
+func writeUsage(cmd *cobra.Command) error {
+	report := usage.FromContext(cmd.Context()).Report()
+	if len(report.Models) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(cmd.ErrOrStderr(), "\nToken usage (estimated cost):"); err != nil {
+		return err
+	}
+	return report.WriteText(cmd.ErrOrStderr())
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"tmwong.org/arxiv-researcher-go/tools"
+	"tmwong.org/arxiv-researcher-go/watch"
+)
+
+// Create the watch command, whose subcommands manage watchlists of saved topics and check them for new papers.
+//
+// Returns the command.
+func newWatchCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch topics for new papers on arXiv",
+		Long: `Watch topics for new papers on arXiv.
+
+Each watchlist saves a topic as an arXiv query, optionally restricted to an arXiv category. Checking a watchlist fetches
+the papers on the topic most recently submitted to arXiv, adds those it has not seen before to the knowledge database,
+ranks them by their relevance to the topic, and sends a digest of them to standard output, a directory of Markdown
+files, email, or a webhook. The watchlists, along with the papers they have seen, persist in ` + watch.StateFileName + `
+in the data directory.`,
+	}
+	cmd.AddCommand(newWatchAddCommand(), newWatchListCommand(), newWatchRemoveCommand(), newWatchRunCommand())
+	return cmd
+}
+
+// Load the watchlists from the state file in the data directory.
+//
+// Returns the state if we load it successfully, otherwise returns an error.
+func loadWatchlists() (*watch.State, error) {
+	path, err := watch.DefaultStatePath()
+	if err != nil {
+		return nil, err
+	}
+	return watch.Load(path)
+}
+
+// Create the watch add command, which adds a watchlist.
+//
+// Returns the command.
+func newWatchAddCommand() *cobra.Command {
+	list := watch.Watchlist{}
+	cmd := &cobra.Command{
+		Use:   "add <name> <query>",
+		Short: "Add a watchlist of a topic",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			state, err := loadWatchlists()
+			if err != nil {
+				return err
+			}
+			list.Name, list.Query = args[0], strings.Join(args[1:], " ")
+			if err := state.Add(list); err != nil {
+				return err
+			}
+			return state.Save()
+		}),
+	}
+	cmd.Flags().StringVar(&list.Category, "category", "", "only watch papers listed under this arXiv category")
+	cmd.Flags().IntVarP(&list.Count, "count", "n", 50, "number of most recent submissions to check")
+	cmd.Flags().Float64Var(&list.MinScore, "min-score", 0, "lowest relevance (cosine similarity) of papers to report")
+	return cmd
+}
+
+// Create the watch list command, which lists the watchlists.
+//
+// Returns the command.
+func newWatchListCommand() *cobra.Command {
+	var format string
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the watchlists",
+		Args:  cobra.NoArgs,
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			state, err := loadWatchlists()
+			if err != nil {
+				return err
+			}
+			if format == "json" {
+				return writeJson(cmd.OutOrStdout(), state.Watchlists)
+			}
+			for _, list := range state.Watchlists {
+				checked := "never checked"
+				if !list.LastChecked.IsZero() {
+					checked = "last checked " + list.LastChecked.Format(time.DateTime)
+				}
+				category := ""
+				if list.Category != "" {
+					category = " in " + list.Category
+				}
+				_, err := fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s%s\t%d papers seen, %s\n",
+					list.Name, list.Query, category, len(list.Seen), checked)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}),
+	}
+	addFormatFlag(cmd, &format, "text", "json")
+	return cmd
+}
+
+// Create the watch remove command, which removes watchlists.
+//
+// Returns the command.
+func newWatchRemoveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <name>...",
+		Short: "Remove watchlists",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			state, err := loadWatchlists()
+			if err != nil {
+				return err
+			}
+			for _, name := range args {
+				if err := state.Remove(name); err != nil {
+					return err
+				}
+			}
+			return state.Save()
+		}),
+	}
+}
+
+// Create the watch run command, which checks watchlists for new papers, once or periodically.
+//
+// Returns the command.
+func newWatchRunCommand() *cobra.Command {
+	var every time.Duration
+	var directory, smtpServer, smtpFrom, webhook string
+	var emails []string
+	cmd := &cobra.Command{
+		Use:   "run [<name>...]",
+		Short: "Check watchlists for new papers, and send digests of them",
+		Long: `Check the named watchlists (or every watchlist) for new papers, and send a digest of the new papers of
+each watchlist to a directory of Markdown files (--digest-dir), email (--email, through --smtp-server), or a webhook
+(--webhook), or to standard output if none is given. The SMTP user name and password, if the server needs them, come
+from the SMTP_USERNAME and SMTP_PASSWORD environment variables.
+
+With --every, the command keeps running, and checks the watchlists again every interval until interrupted.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(emails) > 0 && smtpServer == "" {
+				return fmt.Errorf("--email needs --smtp-server")
+			}
+			return nil
+		},
+		RunE: run(func(cmd *cobra.Command, args []string) error {
+			index, err := tools.GetIndex(cmd.Context())
+			if err != nil {
+				return err
+			}
+			watcher := &watch.Watcher{Index: index}
+			if directory != "" {
+				watcher.Notifiers = append(watcher.Notifiers, &watch.FileNotifier{Directory: directory})
+			}
+			if len(emails) > 0 {
+				watcher.Notifiers = append(watcher.Notifiers, &watch.SmtpNotifier{
+					Address:  smtpServer,
+					From:     smtpFrom,
+					To:       emails,
+					Username: os.Getenv("SMTP_USERNAME"),
+					Password: os.Getenv("SMTP_PASSWORD"),
+				})
+			}
+			if webhook != "" {
+				watcher.Notifiers = append(watcher.Notifiers,
+					&watch.WebhookNotifier{Url: webhook, HttpClient: tools.HttpClient})
+			}
+			if len(watcher.Notifiers) == 0 {
+				watcher.Notifiers = append(watcher.Notifiers, &watch.WriterNotifier{Writer: cmd.OutOrStdout()})
+			}
+			if every > 0 {
+				path, err := watch.DefaultStatePath()
+				if err != nil {
+					return err
+				}
+				return watcher.Watch(cmd.Context(), path, args, every)
+			}
+			state, err := loadWatchlists()
+			if err != nil {
+				return err
+			}
+			_, err = watcher.Run(cmd.Context(), state, args)
+			return err
+		}),
+	}
+	cmd.Flags().DurationVar(&every, "every", 0, "check again every `interval` (e.g., 6h) until interrupted")
+	cmd.Flags().StringVar(&directory, "digest-dir", "", "`directory` to which to write Markdown digests")
+	cmd.Flags().StringVar(&smtpServer, "smtp-server", "",
+		"`host:port` of the SMTP server through which to email digests")
+	cmd.Flags().StringVar(&smtpFrom, "smtp-from", "arxiv-researcher@localhost", "sender `address` of digest emails")
+	cmd.Flags().StringArrayVar(&emails, "email", nil, "`address` to which to email digests (repeatable)")
+	cmd.Flags().StringVar(&webhook, "webhook", "", "`URL` to which to post digests as JSON")
+	return cmd
+}
@@ -1,32 +1,150 @@
 package constants
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/joho/godotenv"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/openai"
 )
 
 // The singleton LLM instance used to generate responses to user/agent queries.
-var Llm *openai.LLM
+var Llm llms.Model
 
-var llmEmbedding = "text-embedding-3-small"
-var llmModel = "gpt-4o-mini"
+// The singleton client used to compute embeddings of documents and queries.
+var EmbedderClient embeddings.EmbedderClient
+
+// The name of the OpenAI model backing [Llm]. Users may override the default model by setting the `OPENAI_MODEL`
+// environment variable.
+var LlmModel = "gpt-4o-mini"
+
+// The name of the OpenAI model backing [EmbedderClient], which embeds the documents of new indexes. Existing indexes
+// record the model that embedded their documents, and keep using it until re-indexed.
+var EmbeddingModel = "text-embedding-3-small"
+
+// Prefixes of OpenAI model names that support native tool (function) calling. Agents should fall back to text-based
+// ReAct prompting for models that do not match any of these prefixes.
+var toolCallingModels = []string{
+	"gpt-4",
+	"gpt-3.5-turbo",
+	"gpt-5",
+	"o1",
+	"o3",
+	"o4",
+}
+
+// Prefixes of OpenAI model names that do not support native tool calling, even though they match a prefix of
+// [toolCallingModels].
+var nonToolCallingModels = []string{
+	"o1-mini",
+	"o1-preview",
+}
+
+// The error, if any, that kept [Init] from creating the [Llm] and [EmbedderClient] singletons.
+var initError error
 
 // Initialize constants for the tools package.
 // In particular, initialize the OpenAI LLM model.
-// Unlike Llama, LangChainGo obtains the OpenAI API key implicitly from the O/S environment.
-func init() {
-	var err error
-	err = godotenv.Load(".env")
+// Unlike Llama, LangChainGo obtains the OpenAI API key implicitly from the O/S environment, which may come from an
+// optional `.env` file in the current working directory.
+// If we cannot create the LLM, e.g., because the environment holds no API key, we record the error rather than fail,
+// so that commands that need no LLM (e.g., printing help) still work; see [Ready].
+// Programs call Init once at startup. Tests do not, and replace the singletons with fakes or scripted wrappers
+// instead of talking to OpenAI.
+//
+// Returns nil unless we fail to load the `.env` file, otherwise returns an error.
+func Init() error {
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed while loading .env file: %w", err)
+	}
+	if model := os.Getenv("OPENAI_MODEL"); model != "" {
+		LlmModel = model
+	}
+	llm, err := NewOpenAILlm()
 	if err != nil {
-		log.Fatalln("failed while loading .env file: ", err)
+		initError = fmt.Errorf("failed while initializing LLM: %w", err)
+		return nil
 	}
-	Llm, err = openai.New([]openai.Option{
-		openai.WithEmbeddingModel(llmEmbedding),
-		openai.WithModel(llmModel),
+	Llm = llm
+	EmbedderClient = llm
+	return nil
+}
+
+// Check whether the [Llm] and [EmbedderClient] singletons are ready for use.
+//
+// Returns nil if the singletons are ready, otherwise returns the error that kept us from creating them.
+func Ready() error {
+	return initError
+}
+
+// Create a new connection to the OpenAI LLM. The connection serves both as an LLM and as an embedder client.
+//
+// Returns the connection if we create it successfully, otherwise returns an error.
+func NewOpenAILlm() (*openai.LLM, error) {
+	return openai.New([]openai.Option{
+		openai.WithEmbeddingModel(EmbeddingModel),
+		openai.WithModel(LlmModel),
 	}...)
+}
+
+// Create a new connection to an OpenAI embedding model, for embedding with a model other than [EmbeddingModel].
+//
+// Returns the connection if we create it successfully, otherwise returns an error.
+func NewOpenAIEmbedderClient(model string) (*openai.LLM, error) {
+	return openai.New(openai.WithEmbeddingModel(model))
+}
+
+// Get the directory in which tools keep on-disk caches, e.g., of embeddings. Users may override the default directory
+// (`arxiv-researcher` in the user cache directory of the O/S) by setting the `ARXIV_RESEARCHER_CACHE_DIR` environment
+// variable.
+//
+// Returns the cache directory if we can determine it, otherwise returns an error.
+func CacheDirectory() (string, error) {
+	if directory := os.Getenv("ARXIV_RESEARCHER_CACHE_DIR"); directory != "" {
+		return directory, nil
+	}
+	directory, err := os.UserCacheDir()
 	if err != nil {
-		log.Fatalln("failed while initializing LLM: ", err)
+		return "", err
+	}
+	return filepath.Join(directory, "arxiv-researcher"), nil
+}
+
+// Get the directory in which tools keep data that users cannot easily recreate, e.g., the local document index. Users
+// may override the default directory (`.arxiv-researcher` in the home directory of the user) by setting the
+// `ARXIV_RESEARCHER_DATA_DIR` environment variable.
+//
+// Returns the data directory if we can determine it, otherwise returns an error.
+func DataDirectory() (string, error) {
+	if directory := os.Getenv("ARXIV_RESEARCHER_DATA_DIR"); directory != "" {
+		return directory, nil
+	}
+	directory, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, ".arxiv-researcher"), nil
+}
+
+// Check whether an OpenAI model supports native tool (function) calling.
+//
+// Returns true if the model supports tool calling, otherwise returns false.
+func SupportsToolCalling(model string) bool {
+	for _, prefix := range nonToolCallingModels {
+		if strings.HasPrefix(model, prefix) {
+			return false
+		}
+	}
+	for _, prefix := range toolCallingModels {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
 	}
+	return false
 }
@@ -0,0 +1,14 @@
+// Package evaluation measures the quality of the research assistant against gold-standard datasets, so that changes
+// to chunking, embedding models, retrieval strategies, or prompts can be compared and regressions caught.
+//
+// Retrieval evaluations run a [Retriever] on the queries of a dataset of [Case]s, each listing the arXiv IDs of the
+// papers relevant to its query, and report the recall at each cutoff, the mean reciprocal rank, the normalized
+// discounted cumulative gain, and the latency of the retriever (see [EvaluateRetrieval]).
+//
+// Behavior evaluations run the agent on [Scenario]s, each fixing the papers that the knowledge database and arXiv hold,
+// and check the tool calls and final answer of each run against what the scenario expects (see [Check]), optionally
+// asking an LLM to judge the run as well (see [Judge]).
+//
+// Evaluations depend only on the retrievers, transcripts, and LLMs given to them, so that tests can evaluate scripted
+// runs.
+package evaluation
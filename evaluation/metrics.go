@@ -0,0 +1,112 @@
+package evaluation
+
+import (
+	"math"
+	"slices"
+
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Get the set of the relevant IDs, without their versions.
+//
+// Returns the set.
+func relevantSet(relevant []string) map[string]bool {
+	set := make(map[string]bool, len(relevant))
+	for _, id := range relevant {
+		set[tools.BaseArxivId(id)] = true
+	}
+	return set
+}
+
+// Mark which of the first k retrieved IDs are relevant, counting each relevant paper only at its first rank.
+//
+// Returns a slice of up to k flags, one per rank.
+func relevantRanks(retrieved []string, relevant []string, k int) []bool {
+	set := relevantSet(relevant)
+	ranks := make([]bool, 0, min(k, len(retrieved)))
+	for _, id := range retrieved[:min(k, len(retrieved))] {
+		id = tools.BaseArxivId(id)
+		ranks = append(ranks, set[id])
+		delete(set, id)
+	}
+	return ranks
+}
+
+// Compute the recall at a cutoff: the fraction of the relevant papers among the first k retrieved papers.
+//
+// Returns the recall, or 0 if there are no relevant papers.
+func Recall(retrieved []string, relevant []string, k int) float64 {
+	count := len(relevantSet(relevant))
+	if count == 0 {
+		return 0
+	}
+	hits := 0
+	for _, hit := range relevantRanks(retrieved, relevant, k) {
+		if hit {
+			hits++
+		}
+	}
+	return float64(hits) / float64(count)
+}
+
+// Compute the reciprocal rank: one over the rank of the first relevant paper among the retrieved papers.
+//
+// Returns the reciprocal rank, or 0 if no retrieved paper is relevant.
+func ReciprocalRank(retrieved []string, relevant []string) float64 {
+	if rank := slices.Index(relevantRanks(retrieved, relevant, len(retrieved)), true); rank >= 0 {
+		return 1 / float64(rank+1)
+	}
+	return 0
+}
+
+// Compute the normalized discounted cumulative gain at a cutoff, with binary relevance: the discounted gain of the
+// first k retrieved papers, relative to that of an ideal ranking that retrieves the relevant papers first.
+//
+// Returns the normalized gain, or 0 if there are no relevant papers.
+func Ndcg(retrieved []string, relevant []string, k int) float64 {
+	ideal := 0.0
+	for rank := range min(k, len(relevantSet(relevant))) {
+		ideal += 1 / math.Log2(float64(rank+2))
+	}
+	if ideal == 0 {
+		return 0
+	}
+	gain := 0.0
+	for rank, hit := range relevantRanks(retrieved, relevant, k) {
+		if hit {
+			gain += 1 / math.Log2(float64(rank+2))
+		}
+	}
+	return gain / ideal
+}
+
+// A summary of the latencies of a set of calls, in milliseconds.
+type Latency struct {
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// Summarize latencies in milliseconds, taking percentiles by the nearest-rank method.
+//
+// Returns the summary, which is zero if there are no latencies.
+func SummarizeLatencies(latencies []float64) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sorted := slices.Sorted(slices.Values(latencies))
+	total := 0.0
+	for _, latency := range sorted {
+		total += latency
+	}
+	percentile := func(p float64) float64 {
+		return sorted[max(int(math.Ceil(p*float64(len(sorted))))-1, 0)]
+	}
+	return Latency{
+		MeanMs: total / float64(len(sorted)),
+		P50Ms:  percentile(0.5),
+		P95Ms:  percentile(0.95),
+		MaxMs:  sorted[len(sorted)-1],
+	}
+}
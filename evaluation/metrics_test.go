@@ -0,0 +1,48 @@
+package evaluation
+
+import (
+	"math"
+	"testing"
+)
+
+// Metrics match relevant papers in any version, and count each relevant paper once.
+func TestMetrics(t *testing.T) {
+	retrieved := []string{"2301.00001v1", "2210.03629v3", "2210.03629v2", "2303.11366v1"}
+	relevant := []string{"2210.03629", "2303.11366v4"}
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"recall@1", Recall(retrieved, relevant, 1), 0},
+		{"recall@2", Recall(retrieved, relevant, 2), 0.5},
+		{"recall@3", Recall(retrieved, relevant, 3), 0.5},
+		{"recall@10", Recall(retrieved, relevant, 10), 1},
+		{"reciprocal rank", ReciprocalRank(retrieved, relevant), 0.5},
+		{"nDCG@1", Ndcg(retrieved, relevant, 1), 0},
+		{"nDCG@10", Ndcg(retrieved, relevant, 10), (1/math.Log2(3) + 1/math.Log2(5)) / (1 + 1/math.Log2(3))},
+		{"nDCG of ideal ranking", Ndcg([]string{"2303.11366v4", "2210.03629v1"}, relevant, 5), 1},
+		{"recall of nothing", Recall(nil, relevant, 5), 0},
+		{"reciprocal rank of nothing", ReciprocalRank(nil, relevant), 0},
+		{"recall without relevant papers", Recall(retrieved, nil, 5), 0},
+	}
+	for _, test := range tests {
+		if math.Abs(test.got-test.want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, test.got)
+		}
+	}
+}
+
+// Percentiles take the nearest rank.
+func TestSummarizeLatencies(t *testing.T) {
+	latencies := make([]float64, 20)
+	for i := range latencies {
+		latencies[i] = float64(20 - i)
+	}
+	want := Latency{MeanMs: 10.5, P50Ms: 10, P95Ms: 19, MaxMs: 20}
+	if got := SummarizeLatencies(latencies); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if got := SummarizeLatencies(nil); got != (Latency{}) {
+		t.Errorf("expected no latency, got %+v", got)
+	}
+}
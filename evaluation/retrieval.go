@@ -0,0 +1,152 @@
+package evaluation
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A case of a retrieval dataset: a query, and the arXiv IDs of the papers relevant to it, in any version.
+type Case struct {
+	Query    string   `json:"query"`
+	Relevant []string `json:"relevant"`
+}
+
+// Retrieve the arXiv IDs of up to the given number of papers for a query, most relevant first.
+type Retriever func(ctx context.Context, query string, count int) ([]string, error)
+
+// The outcome of one query of a retrieval evaluation.
+type QueryResult struct {
+	Query     string   `json:"query"`
+	Relevant  []string `json:"relevant"`
+	Retrieved []string `json:"retrieved"`
+	Error     string   `json:"error,omitempty"`
+	LatencyMs float64  `json:"latency_ms"`
+	// The recall and nDCG at each cutoff.
+	Recall         map[int]float64 `json:"recall"`
+	Ndcg           map[int]float64 `json:"ndcg"`
+	ReciprocalRank float64         `json:"reciprocal_rank"`
+}
+
+// The result of evaluating a retriever on a dataset. Queries that fail count as retrieving no papers.
+type RetrievalReport struct {
+	Strategy string `json:"strategy"`
+	// The cutoffs at which we measure recall and nDCG, in increasing order.
+	Cutoffs  []int `json:"cutoffs"`
+	Queries  int   `json:"queries"`
+	Failures int   `json:"failures"`
+	// The mean recall and nDCG over the queries at each cutoff.
+	Recall  map[int]float64 `json:"recall"`
+	Ndcg    map[int]float64 `json:"ndcg"`
+	Mrr     float64         `json:"mrr"`
+	Latency Latency         `json:"latency"`
+	Results []QueryResult   `json:"results"`
+}
+
+// Load a retrieval dataset from a JSON Lines file, with a case per line, e.g.,
+// `{"query": "language model agents", "relevant": ["2210.03629"]}`. Blank lines are skipped.
+//
+// Returns the cases if we load them successfully, otherwise returns an error.
+func LoadDataset(path string) ([]Case, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed while opening dataset: %w", err)
+	}
+	defer file.Close()
+	var cases []Case
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var datasetCase Case
+		if err := json.Unmarshal([]byte(text), &datasetCase); err != nil {
+			return nil, fmt.Errorf("failed while parsing line %d of dataset '%s': %w", line, path, err)
+		}
+		if datasetCase.Query == "" || len(datasetCase.Relevant) == 0 {
+			return nil, fmt.Errorf("line %d of dataset '%s' needs a query and relevant papers", line, path)
+		}
+		cases = append(cases, datasetCase)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed while reading dataset: %w", err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("found no cases in dataset '%s'", path)
+	}
+	return cases, nil
+}
+
+// Evaluate a retriever on the cases of a dataset, retrieving as many papers for each query as the largest cutoff.
+// Queries that fail count as retrieving no papers, but a cancelled evaluation, e.g., one that exceeds its budget,
+// stops.
+//
+// Returns the report if the evaluation runs to completion, otherwise returns an error.
+func EvaluateRetrieval(
+	ctx context.Context,
+	strategy string,
+	retrieve Retriever,
+	cases []Case,
+	cutoffs []int,
+) (RetrievalReport, error) {
+	cutoffs = slices.Compact(slices.Sorted(slices.Values(cutoffs)))
+	if len(cutoffs) == 0 || cutoffs[0] < 1 {
+		return RetrievalReport{}, fmt.Errorf("cutoffs must be positive, got %v", cutoffs)
+	}
+	report := RetrievalReport{
+		Strategy: strategy,
+		Cutoffs:  cutoffs,
+		Queries:  len(cases),
+		Recall:   map[int]float64{},
+		Ndcg:     map[int]float64{},
+		Results:  make([]QueryResult, 0, len(cases)),
+	}
+	latencies := make([]float64, 0, len(cases))
+	for _, datasetCase := range cases {
+		result := QueryResult{
+			Query:     datasetCase.Query,
+			Relevant:  datasetCase.Relevant,
+			Retrieved: []string{},
+			Recall:    map[int]float64{},
+			Ndcg:      map[int]float64{},
+		}
+		started := time.Now()
+		retrieved, err := retrieve(ctx, datasetCase.Query, cutoffs[len(cutoffs)-1])
+		result.LatencyMs = float64(time.Since(started)) / float64(time.Millisecond)
+		if ctx.Err() != nil {
+			return RetrievalReport{}, context.Cause(ctx)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			report.Failures++
+		} else if retrieved != nil {
+			result.Retrieved = retrieved
+		}
+		for _, k := range cutoffs {
+			result.Recall[k] = Recall(result.Retrieved, datasetCase.Relevant, k)
+			result.Ndcg[k] = Ndcg(result.Retrieved, datasetCase.Relevant, k)
+			report.Recall[k] += result.Recall[k]
+			report.Ndcg[k] += result.Ndcg[k]
+		}
+		result.ReciprocalRank = ReciprocalRank(result.Retrieved, datasetCase.Relevant)
+		report.Mrr += result.ReciprocalRank
+		latencies = append(latencies, result.LatencyMs)
+		report.Results = append(report.Results, result)
+	}
+	if count := float64(len(cases)); count > 0 {
+		for _, k := range cutoffs {
+			report.Recall[k] /= count
+			report.Ndcg[k] /= count
+		}
+		report.Mrr /= count
+	}
+	report.Latency = SummarizeLatencies(latencies)
+	return report, nil
+}
@@ -0,0 +1,76 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Datasets hold a case per line, and every case needs a query and relevant papers.
+func TestLoadDataset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.jsonl")
+	text := `{"query": "agents", "relevant": ["2210.03629"]}
+
+{"query": "tools", "relevant": ["2302.04761v1", "2210.03629"]}
+`
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := LoadDataset(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) != 2 || cases[1].Query != "tools" || len(cases[1].Relevant) != 2 {
+		t.Errorf("unexpected cases %+v", cases)
+	}
+	if err := os.WriteFile(path, []byte(`{"query": "agents", "relevant": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDataset(path); err == nil {
+		t.Error("expected an error for a case without relevant papers")
+	}
+}
+
+// Reports average the metrics over the queries, counting failed queries as retrieving nothing.
+func TestEvaluateRetrieval(t *testing.T) {
+	cases := []Case{
+		{Query: "agents", Relevant: []string{"1"}},
+		{Query: "tools", Relevant: []string{"2"}},
+		{Query: "broken", Relevant: []string{"3"}},
+	}
+	var counts []int
+	retrieve := func(ctx context.Context, query string, count int) ([]string, error) {
+		counts = append(counts, count)
+		switch query {
+		case "agents":
+			return []string{"1", "2"}, nil
+		case "tools":
+			return []string{"1", "2"}, nil
+		}
+		return nil, errors.New("index unavailable")
+	}
+	report, err := EvaluateRetrieval(t.Context(), "vector", retrieve, cases, []int{5, 1, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Cutoffs) != 2 || counts[0] != 5 {
+		t.Errorf("unexpected cutoffs %v and counts %v", report.Cutoffs, counts)
+	}
+	if report.Queries != 3 || report.Failures != 1 || report.Results[2].Error != "index unavailable" {
+		t.Errorf("unexpected failures in %+v", report)
+	}
+	if report.Recall[1] != 1.0/3 || report.Recall[5] != 2.0/3 || report.Mrr != 0.5 {
+		t.Errorf("unexpected recall %v, MRR %v", report.Recall, report.Mrr)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := EvaluateRetrieval(ctx, "vector", retrieve, cases, []int{5}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled evaluation, got %v", err)
+	}
+	if _, err := EvaluateRetrieval(t.Context(), "vector", retrieve, cases, []int{0}); err == nil {
+		t.Error("expected an error for a zero cutoff")
+	}
+}
@@ -0,0 +1,195 @@
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// A scripted scenario for the research agent: a query, the papers that the knowledge database and arXiv hold while the
+// agent answers it, and what the agent is expected to do.
+type Scenario struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	// The papers in the knowledge database.
+	Index []tools.Paper `json:"index,omitempty"`
+	// The papers on arXiv, which searches of arXiv find by the words of their titles and summaries.
+	Arxiv  []tools.Paper `json:"arxiv,omitempty"`
+	Expect Expectation   `json:"expect"`
+}
+
+// Matches the calls of a tool, optionally only those whose input contains some text, e.g., an arXiv ID.
+type ToolCallPattern struct {
+	Tool  string `json:"tool"`
+	Input string `json:"input,omitempty"`
+}
+
+// What a scenario expects of the agent. Empty expectations always hold.
+type Expectation struct {
+	// Tool calls that the agent must make in this order, possibly with other calls between them.
+	Sequence []ToolCallPattern `json:"sequence,omitempty"`
+	// Whether the sequence must be the whole of the tool calls of the agent, rather than a subsequence of them.
+	Exact bool `json:"exact,omitempty"`
+	// Tool calls that the agent must not make.
+	Forbidden []ToolCallPattern `json:"forbidden,omitempty"`
+	// Texts that the final answer must contain, and texts that it must not, ignoring case.
+	AnswerContains []string `json:"answer_contains,omitempty"`
+	AnswerExcludes []string `json:"answer_excludes,omitempty"`
+	// Criteria on which an LLM judges the run, if asked to (see [Judge]).
+	Judge string `json:"judge,omitempty"`
+}
+
+// A tool call of the agent.
+type ToolCall struct {
+	Tool  string `json:"tool"`
+	Input string `json:"input"`
+}
+
+// The verdict of an LLM judging a run of a scenario.
+type Verdict struct {
+	Pass   bool   `json:"pass"`
+	Reason string `json:"reason"`
+}
+
+// Returned when the LLM judging a run does not respond with a verdict.
+var ErrInvalidVerdict = errors.New("LLM returned an invalid verdict")
+
+// The prompt with which we ask the LLM to judge a run of a scenario.
+const judgePrompt = `You are judging a run of a research assistant agent, which finds research papers on arXiv and in
+a knowledge database of papers, and downloads them. Judge whether the run meets the criteria below. Respond with a JSON
+object with a boolean "pass" field and a "reason" field explaining the verdict in one or two sentences, and nothing
+else.
+
+Criteria: %s
+
+Query: %s
+
+Tool calls, in order:
+%s
+Final answer:
+%s`
+
+// Load scenarios from a JSON file holding an array of scenarios. Every scenario needs a name and a query, and names
+// must be unique.
+//
+// Returns the scenarios if we load them successfully, otherwise returns an error.
+func LoadScenarios(path string) ([]Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading scenarios: %w", err)
+	}
+	var scenarios []Scenario
+	if err := json.Unmarshal(data, &scenarios); err != nil {
+		return nil, fmt.Errorf("failed while parsing scenarios '%s': %w", path, err)
+	}
+	if len(scenarios) == 0 {
+		return nil, fmt.Errorf("found no scenarios in '%s'", path)
+	}
+	names := map[string]bool{}
+	for i, scenario := range scenarios {
+		if scenario.Name == "" || scenario.Query == "" {
+			return nil, fmt.Errorf("scenario %d of '%s' needs a name and a query", i+1, path)
+		}
+		if names[scenario.Name] {
+			return nil, fmt.Errorf("scenarios of '%s' share the name '%s'", path, scenario.Name)
+		}
+		names[scenario.Name] = true
+	}
+	return scenarios, nil
+}
+
+// Collect the tool calls of a run, in order, from its transcript (see [tools.TranscriptEvent]).
+//
+// Returns the tool calls.
+func ToolCalls(events []tools.TranscriptEvent) []ToolCall {
+	calls := []ToolCall{}
+	for _, event := range events {
+		if event.Event == tools.ToolStartEvent {
+			calls = append(calls, ToolCall{Tool: event.Tool, Input: event.Input})
+		}
+	}
+	return calls
+}
+
+// Check whether a tool call matches the pattern.
+func (pattern ToolCallPattern) matches(call ToolCall) bool {
+	return call.Tool == pattern.Tool && strings.Contains(call.Input, pattern.Input)
+}
+
+// Describe the pattern, e.g., "PaperDownloader(2210.03629)".
+func (pattern ToolCallPattern) String() string {
+	if pattern.Input == "" {
+		return pattern.Tool
+	}
+	return fmt.Sprintf("%s(%s)", pattern.Tool, pattern.Input)
+}
+
+// Check the tool calls and final answer of a run against what a scenario expects. The LLM judge is not consulted.
+//
+// Returns a description of each unmet expectation, or nil if the run meets every expectation.
+func Check(expect Expectation, calls []ToolCall, answer string) []string {
+	var failures []string
+	next := 0
+	for _, call := range calls {
+		if next < len(expect.Sequence) && expect.Sequence[next].matches(call) {
+			next++
+		} else if expect.Exact {
+			failures = append(failures, fmt.Sprintf("unexpected call of %s with %s", call.Tool, call.Input))
+		}
+	}
+	if next < len(expect.Sequence) {
+		failures = append(failures, fmt.Sprintf("expected a call of %s after %d matching calls",
+			expect.Sequence[next], next))
+	}
+	for _, pattern := range expect.Forbidden {
+		if index := slices.IndexFunc(calls, pattern.matches); index >= 0 {
+			failures = append(failures, fmt.Sprintf("forbidden call of %s with %s", calls[index].Tool,
+				calls[index].Input))
+		}
+	}
+	lowerAnswer := strings.ToLower(answer)
+	for _, text := range expect.AnswerContains {
+		if !strings.Contains(lowerAnswer, strings.ToLower(text)) {
+			failures = append(failures, fmt.Sprintf("answer does not contain '%s'", text))
+		}
+	}
+	for _, text := range expect.AnswerExcludes {
+		if strings.Contains(lowerAnswer, strings.ToLower(text)) {
+			failures = append(failures, fmt.Sprintf("answer contains '%s'", text))
+		}
+	}
+	return failures
+}
+
+// Ask an LLM to judge a run of a scenario on the judging criteria of the scenario.
+//
+// Returns the verdict if the LLM gives one, [ErrInvalidVerdict] if the LLM responds with no verdict, otherwise returns
+// an error.
+func Judge(ctx context.Context, llm llms.Model, scenario Scenario, calls []ToolCall, answer string) (Verdict, error) {
+	var trace strings.Builder
+	for i, call := range calls {
+		fmt.Fprintf(&trace, "%d. %s %s\n", i+1, call.Tool, call.Input)
+	}
+	if len(calls) == 0 {
+		trace.WriteString("(none)\n")
+	}
+	prompt := fmt.Sprintf(judgePrompt, scenario.Expect.Judge, scenario.Query, trace.String(), answer)
+	response, err := llms.GenerateFromSinglePrompt(ctx, llm, prompt)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("failed while judging run: %w", err)
+	}
+	// LLMs sometimes wrap the verdict in prose or code fences, so we parse the outermost JSON object in the response.
+	start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+	var verdict Verdict
+	if start < 0 || end < start || json.Unmarshal([]byte(response[start:end+1]), &verdict) != nil {
+		return Verdict{}, fmt.Errorf("%w: %s", ErrInvalidVerdict, response)
+	}
+	return verdict, nil
+}
@@ -0,0 +1,96 @@
+package evaluation
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/fakes"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Scenarios need unique names and queries.
+func TestLoadScenarios(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenarios.json")
+	text := `[{"name": "index first", "query": "agents", "index": [{"id": "2210.03629v3", "title": "ReAct"}],
+		"expect": {"sequence": [{"tool": "IndexSearcher"}], "forbidden": [{"tool": "ArxivSearcher"}]}}]`
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scenarios, err := LoadScenarios(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenarios) != 1 || scenarios[0].Index[0].Title != "ReAct" || scenarios[0].Expect.Forbidden[0].Tool !=
+		"ArxivSearcher" {
+		t.Errorf("unexpected scenarios %+v", scenarios)
+	}
+	if err := os.WriteFile(path, []byte(`[{"name": "a", "query": "x"}, {"name": "a", "query": "y"}]`),
+		0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadScenarios(path); err == nil {
+		t.Error("expected an error for scenarios sharing a name")
+	}
+}
+
+// Tool calls come from the tool start events of a transcript, and expectations check their order and inputs.
+func TestCheck(t *testing.T) {
+	calls := ToolCalls([]tools.TranscriptEvent{
+		{Event: tools.RunStartEvent, Input: "agents"},
+		{Event: tools.ToolStartEvent, Tool: "IndexSearcher", Input: `{"query": "agents"}`},
+		{Event: tools.ToolEndEvent, Tool: "IndexSearcher", Input: `{"query": "agents"}`, Output: "[]"},
+		{Event: tools.ToolStartEvent, Tool: "ArxivSearcher", Input: `{"query": "agents"}`},
+		{Event: tools.ToolStartEvent, Tool: "PaperDownloader", Input: `{"url": "http://arxiv.org/pdf/2210.03629v3"}`},
+	})
+	if len(calls) != 3 || calls[2].Tool != "PaperDownloader" {
+		t.Fatalf("unexpected tool calls %+v", calls)
+	}
+	tests := []struct {
+		name   string
+		expect Expectation
+		want   []string
+	}{
+		{"subsequence", Expectation{Sequence: []ToolCallPattern{{Tool: "IndexSearcher"},
+			{Tool: "PaperDownloader", Input: "2210.03629"}}}, nil},
+		{"order", Expectation{Sequence: []ToolCallPattern{{Tool: "ArxivSearcher"}, {Tool: "IndexSearcher"}}},
+			[]string{"expected a call of IndexSearcher after 1 matching calls"}},
+		{"exact", Expectation{Sequence: []ToolCallPattern{{Tool: "IndexSearcher"}, {Tool: "PaperDownloader"}},
+			Exact: true}, []string{`unexpected call of ArxivSearcher with {"query": "agents"}`}},
+		{"forbidden", Expectation{Forbidden: []ToolCallPattern{{Tool: "PaperDownloader", Input: "2302.04761"},
+			{Tool: "ArxivSearcher"}}}, []string{`forbidden call of ArxivSearcher with {"query": "agents"}`}},
+		{"answer", Expectation{AnswerContains: []string{"react"}, AnswerExcludes: []string{"Toolformer"}},
+			[]string{"answer does not contain 'react'"}},
+	}
+	for _, test := range tests {
+		if got := Check(test.expect, calls, "No papers found."); !slices.Equal(got, test.want) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.want, got)
+		}
+	}
+}
+
+// The judge sees the criteria, the tool calls, and the answer, and responds with a verdict.
+func TestJudge(t *testing.T) {
+	scenario := Scenario{Query: "agents", Expect: Expectation{Judge: "The answer names ReAct."}}
+	calls := []ToolCall{{Tool: "IndexSearcher", Input: `{"query": "agents"}`}}
+	llm := fakes.NewLLM(fakes.Answer("```json\n{\"pass\": true, \"reason\": \"It names ReAct.\"}\n```"),
+		fakes.Answer("Looks good to me."))
+	verdict, err := Judge(t.Context(), llm, scenario, calls, "Found ReAct.")
+	if err != nil || !verdict.Pass || verdict.Reason != "It names ReAct." {
+		t.Errorf("unexpected verdict %+v, error %v", verdict, err)
+	}
+	prompt := llm.Calls()[0][0].Parts[0].(llms.TextContent).Text
+	for _, want := range []string{"Criteria: The answer names ReAct.", `1. IndexSearcher {"query": "agents"}`,
+		"Final answer:\nFound ReAct."} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("judge prompt %q does not contain %q", prompt, want)
+		}
+	}
+	if _, err := Judge(t.Context(), llm, scenario, calls, "Found ReAct."); !errors.Is(err, ErrInvalidVerdict) {
+		t.Errorf("expected ErrInvalidVerdict, got %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+// Package fakes provides deterministic, offline stand-ins for the LLM, embedder, and vector store singletons used by
+// agents and tools, so that tests can run agents end to end without OpenAI or Pinecone accounts.
+package fakes
@@ -0,0 +1,75 @@
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+// The default dimension of the vectors computed by an [Embedder].
+const DefaultDimension = 64
+
+// A deterministic fake embedder, which embeds texts as [stores.WordEmbedder] does, and counts the texts it embeds.
+//
+// Implements both the [embeddings.EmbedderClient] and the [embeddings.Embedder] interfaces.
+type Embedder struct {
+	Dimension int
+	mutex     sync.Mutex
+	texts     int
+}
+
+var (
+	_ embeddings.EmbedderClient = (*Embedder)(nil)
+	_ embeddings.Embedder       = (*Embedder)(nil)
+)
+
+// Create a new [Embedder] that computes vectors of [DefaultDimension] dimensions.
+//
+// Returns the new embedder.
+func NewEmbedder() *Embedder {
+	return &Embedder{Dimension: DefaultDimension}
+}
+
+// Compute the embeddings of a set of texts.
+//
+// Implements the [embeddings.EmbedderClient.CreateEmbedding] API call.
+func (embedder *Embedder) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	embedder.mutex.Lock()
+	embedder.texts += len(texts)
+	embedder.mutex.Unlock()
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = stores.EmbedWords(text, embedder.Dimension)
+	}
+	return vectors, nil
+}
+
+// Compute the embeddings of a set of documents.
+//
+// Implements the [embeddings.Embedder.EmbedDocuments] API call.
+func (embedder *Embedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedder.CreateEmbedding(ctx, texts)
+}
+
+// Compute the embedding of a query.
+//
+// Implements the [embeddings.Embedder.EmbedQuery] API call.
+func (embedder *Embedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := embedder.CreateEmbedding(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// Get the total number of texts the embedder has embedded so far.
+func (embedder *Embedder) Texts() int {
+	embedder.mutex.Lock()
+	defer embedder.mutex.Unlock()
+	return embedder.texts
+}
@@ -0,0 +1,92 @@
+package fakes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Returned when a test calls a [LLM] more often than it scripted responses for.
+var ErrScriptExhausted = errors.New("fake LLM ran out of scripted responses")
+
+// A scripted fake LLM. The LLM returns its scripted responses in order, one per call, regardless of the prompt, and
+// records the messages of every call so that tests can assert on the prompts an agent sends.
+//
+// Implements the [llms.Model] interface.
+type LLM struct {
+	mutex     sync.Mutex
+	responses []llms.ContentChoice
+	calls     [][]llms.MessageContent
+}
+
+var _ llms.Model = (*LLM)(nil)
+
+// Create a new [LLM] that returns the given responses in order. Use [Answer] and [ToolCalls] to build responses.
+//
+// Returns the new LLM.
+func NewLLM(responses ...llms.ContentChoice) *LLM {
+	return &LLM{responses: responses}
+}
+
+// Build a scripted response that contains only text, e.g., a final answer or a ReAct-formatted action.
+func Answer(text string) llms.ContentChoice {
+	return llms.ContentChoice{Content: text, StopReason: "stop"}
+}
+
+// Build a scripted response that asks to call one or more tools. Each call is a pair of a tool name and its JSON
+// arguments, e.g., ToolCalls("IndexSearcher", `{"query": "agents", "n": 5}`).
+func ToolCalls(nameArgumentPairs ...string) llms.ContentChoice {
+	choice := llms.ContentChoice{StopReason: "tool_calls"}
+	for i := 0; i+1 < len(nameArgumentPairs); i += 2 {
+		choice.ToolCalls = append(choice.ToolCalls, llms.ToolCall{
+			ID:   fmt.Sprintf("call_%d", i/2),
+			Type: "function",
+			FunctionCall: &llms.FunctionCall{
+				Name:      nameArgumentPairs[i],
+				Arguments: nameArgumentPairs[i+1],
+			},
+		})
+	}
+	if len(choice.ToolCalls) > 0 {
+		choice.FuncCall = choice.ToolCalls[0].FunctionCall
+	}
+	return choice
+}
+
+// Return the next scripted response.
+//
+// Implements the [llms.Model.GenerateContent] API call.
+func (llm *LLM) GenerateContent(
+	ctx context.Context,
+	messages []llms.MessageContent,
+	_ ...llms.CallOption,
+) (*llms.ContentResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	llm.mutex.Lock()
+	defer llm.mutex.Unlock()
+	llm.calls = append(llm.calls, messages)
+	if len(llm.calls) > len(llm.responses) {
+		return nil, fmt.Errorf("%w: got call %d", ErrScriptExhausted, len(llm.calls))
+	}
+	choice := llm.responses[len(llm.calls)-1]
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{&choice}}, nil
+}
+
+// Return the text of the next scripted response.
+//
+// Implements the [llms.Model.Call] API call.
+func (llm *LLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, llm, prompt, options...)
+}
+
+// Get the messages of every call to the LLM so far, in call order.
+func (llm *LLM) Calls() [][]llms.MessageContent {
+	llm.mutex.Lock()
+	defer llm.mutex.Unlock()
+	return append([][]llms.MessageContent(nil), llm.calls...)
+}
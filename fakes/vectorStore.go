@@ -0,0 +1,338 @@
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+// A fake in-memory vector store. The store embeds documents with an embedder (typically an [Embedder]), keeps them in
+// per-namespace lists, and answers similarity searches by brute-force cosine similarity. Like the real backends, the
+// store uses the [stores.IdKey] metadata entry of a document as its ID if present, replacing any existing document
+// with the same ID.
+//
+// Implements the [stores.Store] interface.
+type VectorStore struct {
+	embedder  embeddings.Embedder
+	nameSpace string
+	mutex     sync.Mutex
+	records   map[string][]record
+	manifests map[string]stores.Manifest
+	nextId    int
+}
+
+// A document held by a [VectorStore], along with its ID and embedding.
+type record struct {
+	id       string
+	values   []float32
+	document schema.Document
+}
+
+var _ stores.Store = (*VectorStore)(nil)
+
+// Create a new empty [VectorStore] that uses the given embedder and default namespace.
+//
+// Returns the new store.
+func NewVectorStore(embedder embeddings.Embedder, nameSpace string) *VectorStore {
+	return &VectorStore{
+		embedder:  embedder,
+		nameSpace: nameSpace,
+		records:   map[string][]record{},
+		manifests: map[string]stores.Manifest{},
+	}
+}
+
+// Embed a set of documents and add them to the store. The store supports the namespace and embedder options.
+//
+// Implements the [vectorstores.VectorStore.AddDocuments] API call.
+func (store *VectorStore) AddDocuments(
+	ctx context.Context,
+	documents []schema.Document,
+	options ...vectorstores.Option,
+) ([]string, error) {
+	opts := store.options(options...)
+	texts := make([]string, len(documents))
+	for i, document := range documents {
+		texts[i] = document.PageContent
+	}
+	vectors, err := opts.Embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	ids := make([]string, len(documents))
+	for i, document := range documents {
+		if id, ok := document.Metadata[stores.IdKey].(string); ok && id != "" {
+			ids[i] = id
+			store.records[opts.NameSpace] = slices.DeleteFunc(store.records[opts.NameSpace], func(record record) bool {
+				return record.id == id
+			})
+		} else {
+			store.nextId++
+			ids[i] = fmt.Sprintf("doc-%d", store.nextId)
+		}
+		store.records[opts.NameSpace] = append(store.records[opts.NameSpace], record{
+			id:     ids[i],
+			values: vectors[i],
+			document: schema.Document{
+				PageContent: document.PageContent,
+				Metadata:    maps.Clone(document.Metadata),
+			},
+		})
+	}
+	return ids, nil
+}
+
+// Find the documents most similar to a query. The store supports the namespace, embedder, score threshold, and filter
+// options; a filter is a map of metadata keys to the values that matching documents must hold.
+//
+// Implements the [vectorstores.VectorStore.SimilaritySearch] API call.
+func (store *VectorStore) SimilaritySearch(
+	ctx context.Context,
+	query string,
+	numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	opts := store.options(options...)
+	vector, err := opts.Embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return store.SimilaritySearchByVector(ctx, vector, numDocuments, options...)
+}
+
+// Find the documents most similar to an embedding. The store supports the namespace, score threshold, and filter
+// options.
+//
+// Implements the [stores.Store.SimilaritySearchByVector] API call.
+func (store *VectorStore) SimilaritySearchByVector(
+	ctx context.Context,
+	vector []float32,
+	numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	opts := store.options(options...)
+	filters, _ := opts.Filters.(map[string]any)
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	var documents []schema.Document
+	for _, record := range store.records[opts.NameSpace] {
+		if !matches(record.document.Metadata, filters) {
+			continue
+		}
+		score := cosine(vector, record.values)
+		if score < opts.ScoreThreshold {
+			continue
+		}
+		document := record.document
+		document.Metadata = maps.Clone(document.Metadata)
+		document.Score = score
+		documents = append(documents, document)
+	}
+	slices.SortStableFunc(documents, func(a, b schema.Document) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+	if len(documents) > numDocuments {
+		documents = documents[:numDocuments]
+	}
+	return documents, nil
+}
+
+// Count the documents in a namespace.
+//
+// Implements the [stores.Store.Count] API call.
+func (store *VectorStore) Count(ctx context.Context, nameSpace string) (int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return len(store.records[store.orDefault(nameSpace)]), nil
+}
+
+// List document IDs in a namespace, in lexical order. The pagination token is the last ID of the previous page.
+//
+// Implements the [stores.Store.List] API call.
+func (store *VectorStore) List(ctx context.Context, nameSpace string, limit int, token string) (stores.Page, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	var ids []string
+	for _, record := range store.records[store.orDefault(nameSpace)] {
+		if record.id > token {
+			ids = append(ids, record.id)
+		}
+	}
+	slices.Sort(ids)
+	if limit > 0 && len(ids) > limit {
+		return stores.Page{Ids: ids[:limit], Next: ids[limit-1]}, nil
+	}
+	return stores.Page{Ids: ids}, nil
+}
+
+// Fetch documents from a namespace by ID.
+//
+// Implements the [stores.Store.Fetch] API call.
+func (store *VectorStore) Fetch(ctx context.Context, nameSpace string, ids []string) ([]stores.Record, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	var records []stores.Record
+	for _, record := range store.records[store.orDefault(nameSpace)] {
+		if slices.Contains(ids, record.id) {
+			records = append(records, stores.Record{
+				Id:       record.id,
+				Values:   slices.Clone(record.values),
+				Content:  record.document.PageContent,
+				Metadata: maps.Clone(record.document.Metadata),
+			})
+		}
+	}
+	return records, nil
+}
+
+// Add documents, along with their embeddings, to a namespace, replacing any documents with the same IDs.
+//
+// Implements the [stores.Store.Upsert] API call.
+func (store *VectorStore) Upsert(ctx context.Context, nameSpace string, records []stores.Record) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	nameSpace = store.orDefault(nameSpace)
+	for _, upserted := range records {
+		store.records[nameSpace] = slices.DeleteFunc(store.records[nameSpace], func(record record) bool {
+			return record.id == upserted.Id
+		})
+		store.records[nameSpace] = append(store.records[nameSpace], record{
+			id:     upserted.Id,
+			values: slices.Clone(upserted.Values),
+			document: schema.Document{
+				PageContent: upserted.Content,
+				Metadata:    maps.Clone(upserted.Metadata),
+			},
+		})
+	}
+	return nil
+}
+
+// Delete documents from a namespace by ID.
+//
+// Implements the [stores.Store.Delete] API call.
+func (store *VectorStore) Delete(ctx context.Context, nameSpace string, ids []string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	nameSpace = store.orDefault(nameSpace)
+	store.records[nameSpace] = slices.DeleteFunc(store.records[nameSpace], func(record record) bool {
+		return slices.Contains(ids, record.id)
+	})
+	return nil
+}
+
+// Delete every document in a namespace.
+//
+// Implements the [stores.Store.DeleteAll] API call.
+func (store *VectorStore) DeleteAll(ctx context.Context, nameSpace string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.records, store.orDefault(nameSpace))
+	return nil
+}
+
+// Count the documents in every namespace.
+//
+// Implements the [stores.Store.NameSpaces] API call.
+func (store *VectorStore) NameSpaces(ctx context.Context) (map[string]int, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	counts := map[string]int{}
+	for nameSpace, records := range store.records {
+		if len(records) > 0 {
+			counts[nameSpace] = len(records)
+		}
+	}
+	return counts, nil
+}
+
+// Get the manifest of a namespace.
+//
+// Implements the [stores.Store.Manifest] API call.
+func (store *VectorStore) Manifest(ctx context.Context, nameSpace string) (*stores.Manifest, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if manifest, ok := store.manifests[store.orDefault(nameSpace)]; ok {
+		return &manifest, nil
+	}
+	return nil, nil
+}
+
+// Replace the manifest of a namespace.
+//
+// Implements the [stores.Store.SetManifest] API call.
+func (store *VectorStore) SetManifest(ctx context.Context, nameSpace string, manifest stores.Manifest) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.manifests[store.orDefault(nameSpace)] = manifest
+	return nil
+}
+
+// Release the store, which holds nothing to release.
+//
+// Implements the [stores.Store.Close] API call.
+func (store *VectorStore) Close() error {
+	return nil
+}
+
+// Get the given namespace, or the default namespace of the store if the given namespace is empty.
+func (store *VectorStore) orDefault(nameSpace string) string {
+	if nameSpace == "" {
+		return store.nameSpace
+	}
+	return nameSpace
+}
+
+// Apply a set of options over the defaults of the store.
+func (store *VectorStore) options(options ...vectorstores.Option) vectorstores.Options {
+	opts := vectorstores.Options{NameSpace: store.nameSpace, Embedder: store.embedder}
+	for _, option := range options {
+		option(&opts)
+	}
+	opts.NameSpace = store.orDefault(opts.NameSpace)
+	if opts.Embedder == nil {
+		opts.Embedder = store.embedder
+	}
+	return opts
+}
+
+// Check whether document metadata holds all of the values in a filter.
+func matches(metadata map[string]any, filters map[string]any) bool {
+	for key, value := range filters {
+		if fmt.Sprint(metadata[key]) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compute the cosine similarity of two vectors.
+func cosine(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i] * b[i])
+		normA += float64(a[i] * a[i])
+		normB += float64(b[i] * b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
@@ -0,0 +1,224 @@
+// Package logging configures structured logging with [log/slog] for the tools, agents, and commands: log levels, text
+// or JSON output, per-run correlation IDs that tie together every log record of a run, and redaction of sensitive
+// values, e.g., API keys, before they reach the logs.
+//
+// Code that logs uses the [slog] package functions with a context, e.g., [slog.InfoContext], so that the default
+// logger that [Setup] installs can attach the run ID of the context to each record.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// The output formats of log records.
+const (
+	TextFormat = "text"
+	JsonFormat = "json"
+)
+
+// The attribute key under which log records carry the run ID of their context.
+const RunIdKey = "run_id"
+
+// The value that replaces redacted values in log records.
+const Redacted = "[REDACTED]"
+
+// The attribute keys whose values we always redact. A key matches if it is one of these or ends with one of these as
+// whole words, ignoring case and the style of the key, e.g., "OPENAI_API_KEY" and "apiKey" match "api_key", and
+// "access_token" matches "token", but "total_tokens" and "secret_count" match nothing.
+var DefaultRedactedKeys = []string{"api_key", "apikey", "authorization", "password", "secret", "token"}
+
+// Patterns of sensitive values that we redact wherever they appear in logged text, e.g., in tool inputs or LLM
+// prompts: OpenAI and Pinecone API keys, and bearer tokens.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`),
+	regexp.MustCompile(`\bpcsk_[A-Za-z0-9_]{16,}`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]+=*`),
+}
+
+// Options of the loggers that [NewLogger] creates.
+type Options struct {
+	// The lowest level of the records to log.
+	Level slog.Level
+	// The output format of records: [TextFormat] (the default) or [JsonFormat].
+	Format string
+	// Attribute keys whose values to redact, in addition to [DefaultRedactedKeys].
+	RedactedKeys []string
+}
+
+// Create a logger that writes records to a writer in the given format, attaches the run ID of the context of each
+// record (see [WithRunId]), and redacts the values of sensitive attributes, along with sensitive values anywhere in the
+// messages and string attributes of records.
+//
+// Returns the logger.
+func NewLogger(w io.Writer, options Options) *slog.Logger {
+	keys := slices.Concat(DefaultRedactedKeys, options.RedactedKeys)
+	for i, key := range keys {
+		keys[i] = normalizeKey(key)
+	}
+	handlerOptions := &slog.HandlerOptions{
+		Level: options.Level,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			return redactAttr(keys, attr)
+		},
+	}
+	var handler slog.Handler
+	if options.Format == JsonFormat {
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	} else {
+		handler = slog.NewTextHandler(w, handlerOptions)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// Install a logger (see [NewLogger]) as the default logger, to which the [log] package also writes.
+func Setup(w io.Writer, options Options) {
+	slog.SetDefault(NewLogger(w, options))
+}
+
+// Install a default logger that discards every record.
+func Disable() {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+}
+
+// Redact sensitive values, e.g., API keys, in text.
+//
+// Returns the text with each sensitive value replaced by [Redacted].
+func Redact(text string) string {
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllString(text, Redacted)
+	}
+	return text
+}
+
+// Shorten text to at most the given number of bytes (without splitting characters) for logging, marking the cut with
+// an ellipsis.
+//
+// Returns the shortened text.
+func Truncate(text string, length int) string {
+	if len(text) <= length {
+		return text
+	}
+	for length > 0 && !isRuneStart(text[length]) {
+		length--
+	}
+	return text[:length] + "…"
+}
+
+// Check whether a byte starts a UTF-8 encoded character.
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// Redact an attribute of a log record if its key is sensitive, or if its value holds sensitive values. Errors and
+// values that implement [fmt.Stringer] are logged as text, so we redact their text too.
+//
+// Returns the attribute to log.
+func redactAttr(keys []string, attr slog.Attr) slog.Attr {
+	key := normalizeKey(attr.Key)
+	for _, sensitive := range keys {
+		if key == sensitive || strings.HasSuffix(key, "_"+sensitive) {
+			return slog.String(attr.Key, Redacted)
+		}
+	}
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(attr.Value.String()))
+	case slog.KindAny:
+		switch value := attr.Value.Any().(type) {
+		case error:
+			return slog.String(attr.Key, Redact(value.Error()))
+		case fmt.Stringer:
+			return slog.String(attr.Key, Redact(value.String()))
+		}
+	}
+	return attr
+}
+
+// Normalize an attribute key for matching against sensitive keys: lowercase words joined by underscores, splitting
+// camel case and any separators, e.g., "X-Api-Key" and "xApiKey" become "x_api_key".
+//
+// Returns the normalized key.
+func normalizeKey(key string) string {
+	var builder strings.Builder
+	previous := ' '
+	for _, r := range key {
+		switch {
+		case unicode.IsUpper(r):
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) {
+				builder.WriteByte('_')
+			}
+			builder.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			builder.WriteRune(r)
+		default:
+			if builder.Len() > 0 && previous != '_' {
+				builder.WriteByte('_')
+			}
+			r = '_'
+		}
+		previous = r
+	}
+	return strings.TrimSuffix(builder.String(), "_")
+}
+
+type runIdKey struct{}
+
+// Create a random run ID with which to correlate the log records of a run, e.g., of a command or an agent query.
+//
+// Returns the run ID.
+func NewRunId() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Attach a run ID to a context, so that the log records of calls with the context carry the run ID.
+//
+// Returns the context with the run ID.
+func WithRunId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIdKey{}, id)
+}
+
+// Get the run ID attached to a context.
+//
+// Returns the run ID if the context has one, otherwise returns an empty string.
+func RunId(ctx context.Context) string {
+	id, _ := ctx.Value(runIdKey{}).(string)
+	return id
+}
+
+// A handler that adds the run ID of the context of each record to the record before passing it on.
+//
+// Implements the [slog.Handler] interface.
+type contextHandler struct {
+	slog.Handler
+}
+
+// Add the run ID of the context, if any, to a record, and pass the record on.
+//
+// Implements the [slog.Handler.Handle] API call.
+func (handler contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RunId(ctx); id != "" {
+		record.AddAttrs(slog.String(RunIdKey, id))
+	}
+	return handler.Handler.Handle(ctx, record)
+}
+
+// Implements the [slog.Handler.WithAttrs] API call.
+func (handler contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{handler.Handler.WithAttrs(attrs)}
+}
+
+// Implements the [slog.Handler.WithGroup] API call.
+func (handler contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{handler.Handler.WithGroup(name)}
+}
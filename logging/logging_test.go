@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Records carry the run ID of their context, and sensitive values are redacted.
+func TestNewLogger(t *testing.T) {
+	var output bytes.Buffer
+	logger := NewLogger(&output, Options{Level: slog.LevelInfo, Format: JsonFormat, RedactedKeys: []string{"Email"}})
+	ctx := WithRunId(context.Background(), "abc123")
+	logger.InfoContext(ctx, "Calling tool",
+		"tool", "ArxivSearch",
+		"OPENAI_API_KEY", "sk-0123456789abcdef0123",
+		"user_email", "me@example.com",
+		"input", `{"query": "agents", "key": "sk-0123456789abcdef0123"}`,
+		"count", 3,
+		"error", errors.New("failed with key sk-0123456789abcdef0123"),
+		"url", &url.URL{Scheme: "https", Host: "example.com", RawQuery: "key=sk-0123456789abcdef0123"},
+	)
+	logger.DebugContext(ctx, "Not logged")
+	var record map[string]any
+	if err := json.Unmarshal(output.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %s: %v", output.String(), err)
+	}
+	want := map[string]any{
+		"msg":            "Calling tool",
+		"level":          "INFO",
+		RunIdKey:         "abc123",
+		"tool":           "ArxivSearch",
+		"OPENAI_API_KEY": Redacted,
+		"user_email":     Redacted,
+		"input":          `{"query": "agents", "key": "` + Redacted + `"}`,
+		"count":          3.0,
+		"error":          "failed with key " + Redacted,
+		"url":            "https://example.com?key=" + Redacted,
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("got %s %v, want %v", key, record[key], value)
+		}
+	}
+
+	output.Reset()
+	NewLogger(&output, Options{Level: slog.LevelDebug}).With("tool", "x").Debug("No run", "token", "secret")
+	if line := output.String(); strings.Contains(line, RunIdKey) || !strings.Contains(line, "token="+Redacted) ||
+		!strings.Contains(line, "tool=x") {
+		t.Errorf("unexpected text record %s", line)
+	}
+}
+
+func TestRedactAttr(t *testing.T) {
+	keys := []string{"api_key", "apikey", "authorization", "password", "secret", "token", "email"}
+	tests := []struct {
+		key      string
+		redacted bool
+	}{
+		{"token", true},
+		{"access_token", true},
+		{"X-Auth-Token", true},
+		{"OPENAI_API_KEY", true},
+		{"apiKey", true},
+		{"Authorization", true},
+		{"client_secret", true},
+		{"db.password", true},
+		{"user_email", true},
+		{"tokens", false},
+		{"max_tokens", false},
+		{"input_tokens", false},
+		{"total_tokens", false},
+		{"token_count", false},
+		{"secret_count", false},
+		{"tokenizer", false},
+		{"emails_sent", false},
+	}
+	for _, test := range tests {
+		got := redactAttr(keys, slog.Int(test.key, 42))
+		if redacted := got.Value.String() == Redacted; redacted != test.redacted {
+			t.Errorf("redactAttr(%q) = %v, want redacted %v", test.key, got.Value, test.redacted)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"no secrets here", "no secrets here"},
+		{"key sk-proj-abcdefghijklmnopqrstuv in text", "key " + Redacted + " in text"},
+		{"Authorization: Bearer abc.def-123", "Authorization: " + Redacted},
+		{"pinecone pcsk_abcdefghijklmnopqrstuv", "pinecone " + Redacted},
+		{"task-force sk-short", "task-force sk-short"},
+	}
+	for _, test := range tests {
+		if got := Redact(test.text); got != test.want {
+			t.Errorf("Redact(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("short", 10); got != "short" {
+		t.Errorf("got %q", got)
+	}
+	if got := Truncate("héllo", 2); got != "h…" {
+		t.Errorf("expected the cut to keep whole characters, got %q", got)
+	}
+}
@@ -0,0 +1,301 @@
+// Package prompts manages the prompt templates of the research agent. Prompts are Go template files, named by the
+// prompt and its version, e.g., `research.v2.tmpl`, so that several versions of a prompt can live side by side and be
+// compared (e.g., by `arxiv-researcher eval prompts`). Built-in prompts ship with the program, and users add their own
+// in the prompts directory of the data directory, where they override built-in prompts of the same name and version.
+//
+// A prompt file renders the instructions of the agent, and may define the `prefix` and `suffix` templates of ReAct
+// agents, overriding [DefaultSections]. Every template sees a [Context], which describes the date, the model, the
+// knowledge database, and the interests of the user.
+package prompts
+
+import (
+	"cmp"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"tmwong.org/arxiv-researcher-go/constants"
+)
+
+// The name of the prompts directory in the data directory.
+const DirectoryName = "prompts"
+
+// The file name extension of prompt files.
+const fileExtension = ".tmpl"
+
+// The name of the prompt that the agent uses unless told otherwise.
+const DefaultName = "research"
+
+// The source of built-in prompts.
+const BuiltInSource = "built-in"
+
+// The file name of the preferences file in the data directory.
+const PreferencesFileName = "preferences.md"
+
+// Returned when no prompt of a given name exists.
+var ErrUnknownPrompt = errors.New("no such prompt")
+
+// The built-in prompt files.
+//
+//go:embed templates/*.tmpl
+var builtIn embed.FS
+
+// Matches the base names of versioned prompt files, e.g., "research.v2".
+var versionedName = regexp.MustCompile(`^(.+)\.v([0-9]+)$`)
+
+// The default `prefix` and `suffix` templates of ReAct agents, which prompt files may override. The prefix sets the
+// agent up with its instructions and tools, and the suffix passes the agent the user query and its scratchpad (i.e.,
+// the record of its conversation with the LLM so far), once per iteration of the agent.
+const DefaultSections = `{{define "prefix"}}{{template "instructions" .}}
+You have access to the following tools:
+{{.Tools}}
+{{end}}{{define "suffix"}}Begin!
+Topic phrase: {{.Input}}
+{{.Scratchpad}}{{end}}`
+
+// A version of a prompt.
+type Prompt struct {
+	// The name of the prompt, e.g., "research".
+	Name string `json:"name"`
+	// The version of the prompt, or 0 if the file name has no version.
+	Version int `json:"version"`
+	// The path of the prompt file, or [BuiltInSource].
+	Source string `json:"source"`
+	// The Go template of the prompt.
+	Text string `json:"text,omitempty"`
+}
+
+// Get the ID of the prompt version, e.g., "research.v2", which selects the version exactly (see [Find]).
+//
+// Returns the ID.
+func (prompt Prompt) Id() string {
+	if prompt.Version == 0 {
+		return prompt.Name
+	}
+	return fmt.Sprintf("%s.v%d", prompt.Name, prompt.Version)
+}
+
+// What prompt templates know about a run of the agent.
+type Context struct {
+	// The date of the run, e.g., "2025-01-01".
+	Today string
+	// The model of the LLM of the run.
+	Model string
+	// The agent mode of the run, i.e., "functions" or "react".
+	Agent string
+	// The number of papers in the knowledge database, or 0 if unknown.
+	Papers int
+	// The preferences of the user (see [LoadPreferences]), if any.
+	Preferences string
+	// The topics of the watchlists of the user, if any.
+	Topics []string
+	// Placeholders that ReAct agents fill in with the descriptions of their tools, the user query, and their
+	// scratchpad. [Prompt.Render] sets them.
+	Tools, Input, Scratchpad string
+}
+
+// The prompts of an agent run.
+type Rendered struct {
+	// The instructions of the agent, which agents that use native tool calling pass as their system message.
+	Instructions string `json:"instructions"`
+	// The prefix and suffix of the prompts of ReAct agents.
+	Prefix string `json:"prefix"`
+	Suffix string `json:"suffix"`
+}
+
+// Get the prompts directory in the data directory.
+//
+// Returns the path of the directory if we locate the data directory successfully, otherwise returns an error.
+func DefaultDirectory() (string, error) {
+	directory, err := constants.DataDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, DirectoryName), nil
+}
+
+// List the built-in prompts, along with the prompts in a directory, which override built-in prompts of the same name
+// and version. A missing directory holds no prompts.
+//
+// Returns the prompts in order of name and version if we load them successfully, otherwise returns an error.
+func List(directory string) ([]Prompt, error) {
+	byId := map[string]Prompt{}
+	paths, err := fs.Glob(builtIn, "templates/*"+fileExtension)
+	if err != nil {
+		return nil, fmt.Errorf("failed while listing built-in prompts: %w", err)
+	}
+	for _, path := range paths {
+		text, err := builtIn.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed while reading built-in prompt: %w", err)
+		}
+		prompt := newPrompt(path, BuiltInSource, string(text))
+		byId[prompt.Id()] = prompt
+	}
+	if directory != "" {
+		paths, err := filepath.Glob(filepath.Join(directory, "*"+fileExtension))
+		if err != nil {
+			return nil, fmt.Errorf("failed while listing prompts: %w", err)
+		}
+		for _, path := range paths {
+			prompt, err := Load(path)
+			if err != nil {
+				return nil, err
+			}
+			byId[prompt.Id()] = prompt
+		}
+	}
+	prompts := make([]Prompt, 0, len(byId))
+	for _, prompt := range byId {
+		prompts = append(prompts, prompt)
+	}
+	slices.SortFunc(prompts, func(x, y Prompt) int {
+		return cmp.Or(cmp.Compare(x.Name, y.Name), cmp.Compare(x.Version, y.Version))
+	})
+	return prompts, nil
+}
+
+// Find a prompt among the prompts of [List]. The name may be the ID of a prompt version, e.g., "research.v1", the
+// name of a prompt, e.g., "research", which selects its latest version, or the path of a prompt file.
+//
+// Returns the prompt if we find it, [ErrUnknownPrompt] if no prompt matches the name, otherwise returns an error.
+func Find(directory string, name string) (Prompt, error) {
+	if strings.HasSuffix(name, fileExtension) || strings.ContainsRune(name, filepath.Separator) {
+		return Load(name)
+	}
+	prompts, err := List(directory)
+	if err != nil {
+		return Prompt{}, err
+	}
+	var latest *Prompt
+	for i, prompt := range prompts {
+		if prompt.Id() == name {
+			return prompt, nil
+		}
+		if prompt.Name == name {
+			latest = &prompts[i]
+		}
+	}
+	if latest == nil {
+		return Prompt{}, fmt.Errorf("%w: %s", ErrUnknownPrompt, name)
+	}
+	return *latest, nil
+}
+
+// Load a prompt from a prompt file, whose name gives the name and version of the prompt, e.g., `research.v2.tmpl`.
+//
+// Returns the prompt if we load it successfully, otherwise returns an error.
+func Load(path string) (Prompt, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return Prompt{}, fmt.Errorf("failed while reading prompt: %w", err)
+	}
+	prompt := newPrompt(path, path, string(text))
+	if _, err := prompt.parse(); err != nil {
+		return Prompt{}, err
+	}
+	return prompt, nil
+}
+
+// Create a prompt from the text of a prompt file.
+//
+// Returns the prompt.
+func newPrompt(path string, source string, text string) Prompt {
+	name := strings.TrimSuffix(filepath.Base(path), fileExtension)
+	prompt := Prompt{Name: name, Source: source, Text: text}
+	if match := versionedName.FindStringSubmatch(name); match != nil {
+		if version, err := strconv.Atoi(match[2]); err == nil {
+			prompt.Name, prompt.Version = match[1], version
+		}
+	}
+	return prompt
+}
+
+// Parse the templates of the prompt, along with the default sections that it does not override.
+//
+// Returns the template of the instructions if we parse the prompt successfully, otherwise returns an error.
+func (prompt Prompt) parse() (*template.Template, error) {
+	instructions := template.New("instructions").Funcs(template.FuncMap{"join": strings.Join})
+	if _, err := instructions.Parse(DefaultSections); err != nil {
+		return nil, fmt.Errorf("failed while parsing default prompt sections: %w", err)
+	}
+	if _, err := instructions.Parse(prompt.Text); err != nil {
+		return nil, fmt.Errorf("failed while parsing prompt %s: %w", prompt.Id(), err)
+	}
+	return instructions, nil
+}
+
+// Render the prompts of an agent run. The rendered prompts are themselves Go templates, which the agents execute with
+// their inputs on every iteration, so we escape the context, e.g., preferences that happen to hold template actions,
+// and render the placeholders of the context as the template actions that the agents fill in.
+//
+// Returns the rendered prompts if we render them successfully, otherwise returns an error.
+func (prompt Prompt) Render(context Context) (Rendered, error) {
+	instructions, err := prompt.parse()
+	if err != nil {
+		return Rendered{}, err
+	}
+	context.Today, context.Model, context.Agent = escape(context.Today), escape(context.Model), escape(context.Agent)
+	context.Preferences = escape(context.Preferences)
+	topics := make([]string, len(context.Topics))
+	for i, topic := range context.Topics {
+		topics[i] = escape(topic)
+	}
+	context.Topics = topics
+	context.Tools, context.Input, context.Scratchpad = "{{.tool_descriptions}}", "{{.input}}", "{{.agent_scratchpad}}"
+
+	var rendered Rendered
+	for name, text := range map[string]*string{
+		"instructions": &rendered.Instructions,
+		"prefix":       &rendered.Prefix,
+		"suffix":       &rendered.Suffix,
+	} {
+		var output strings.Builder
+		if err := instructions.ExecuteTemplate(&output, name, context); err != nil {
+			return Rendered{}, fmt.Errorf("failed while rendering %s of prompt %s: %w", name, prompt.Id(), err)
+		}
+		*text = output.String()
+	}
+	return rendered, nil
+}
+
+// Escape text for a Go template, so that the template renders the text as is.
+//
+// Returns the escaped text.
+func escape(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{"{{"}}`)
+}
+
+// Load the preferences of the user, e.g., the fields, venues, or kinds of papers that they care about, from the file
+// named by the `ARXIV_RESEARCHER_PREFERENCES` environment variable, or [PreferencesFileName] in the data directory if
+// the variable is not set. Users need not have preferences, so a missing preferences file in the data directory holds
+// no preferences.
+//
+// Returns the preferences if we load them successfully, otherwise returns an error.
+func LoadPreferences() (string, error) {
+	path := os.Getenv("ARXIV_RESEARCHER_PREFERENCES")
+	optional := path == ""
+	if optional {
+		directory, err := constants.DataDirectory()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(directory, PreferencesFileName)
+	}
+	text, err := os.ReadFile(path)
+	if optional && errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed while reading preferences: %w", err)
+	}
+	return strings.TrimSpace(string(text)), nil
+}
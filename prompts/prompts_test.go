@@ -0,0 +1,157 @@
+package prompts
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// The first version of the research prompt renders the prompts that the agent used before prompts were templates.
+func TestRenderOriginalPrompt(t *testing.T) {
+	prompt, err := Find("", "research.v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := prompt.Render(Context{Today: "2025-01-01", Papers: 42, Topics: []string{"agents"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rendered.Instructions, "Today is 2025-01-01.\nYou are a research assistant.") ||
+		!strings.HasSuffix(rendered.Instructions, "please say \"No papers\nfound\".\n") {
+		t.Errorf("unexpected instructions %q", rendered.Instructions)
+	}
+	if rendered.Prefix != rendered.Instructions+"\nYou have access to the following tools:\n{{.tool_descriptions}}\n" {
+		t.Errorf("unexpected prefix %q", rendered.Prefix)
+	}
+	if rendered.Suffix != "Begin!\nTopic phrase: {{.input}}\n{{.agent_scratchpad}}" {
+		t.Errorf("unexpected suffix %q", rendered.Suffix)
+	}
+}
+
+// Prompts see the context, which renders as is when the agents execute the rendered prompts.
+func TestRenderContext(t *testing.T) {
+	prompt, err := Find("", DefaultName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt.Id() != "research.v2" || prompt.Source != BuiltInSource {
+		t.Errorf("unexpected latest prompt %+v", prompt)
+	}
+	rendered, err := prompt.Render(Context{
+		Today:       "2025-01-01",
+		Papers:      42,
+		Topics:      []string{"agents", "retrieval"},
+		Preferences: "Skip papers on {{robotics}}.",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentPrompt, err := template.New("agent").Parse(rendered.Instructions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var instructions strings.Builder
+	if err := agentPrompt.Execute(&instructions, map[string]any{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"a knowledge database of 42 research papers",
+		"The user follows these topics: agents; retrieval.",
+		"precedence over the instructions above:\nSkip papers on {{robotics}}.\n",
+	} {
+		if !strings.Contains(instructions.String(), want) {
+			t.Errorf("instructions %q do not contain %q", instructions.String(), want)
+		}
+	}
+	rendered, err = prompt.Render(Context{Today: "2025-01-01"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(rendered.Instructions, "topics") ||
+		!strings.Contains(rendered.Instructions, "database of research") {
+		t.Errorf("unexpected instructions without context %q", rendered.Instructions)
+	}
+}
+
+// Prompts in the prompts directory add to the built-in prompts, and override their sections and the built-in prompts
+// of the same version.
+func TestFindPrompts(t *testing.T) {
+	directory := t.TempDir()
+	files := map[string]string{
+		"research.v1.tmpl": "Overridden.\n",
+		"research.v3.tmpl": "Research {{.Input}}.\n{{define \"suffix\"}}Go: {{.Input}}{{.Scratchpad}}{{end}}",
+		"terse.tmpl":       "Be terse.\n",
+		"notes.txt":        "not a prompt",
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(directory, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prompts, err := List(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, prompt := range prompts {
+		ids = append(ids, prompt.Id()+" "+prompt.Source)
+	}
+	want := []string{
+		"research.v1 " + filepath.Join(directory, "research.v1.tmpl"),
+		"research.v2 " + BuiltInSource,
+		"research.v3 " + filepath.Join(directory, "research.v3.tmpl"),
+		"terse " + filepath.Join(directory, "terse.tmpl"),
+	}
+	if strings.Join(ids, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected prompts %q", ids)
+	}
+
+	prompt, err := Find(directory, "research")
+	if err != nil || prompt.Version != 3 {
+		t.Fatalf("unexpected latest prompt %+v, error %v", prompt, err)
+	}
+	rendered, err := prompt.Render(Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rendered.Instructions != "Research {{.input}}.\n" || rendered.Suffix != "Go: {{.input}}{{.agent_scratchpad}}" ||
+		!strings.HasPrefix(rendered.Prefix, "Research {{.input}}.\n\nYou have access") {
+		t.Errorf("unexpected rendered prompt %+v", rendered)
+	}
+	if prompt, err := Find(directory, filepath.Join(directory, "terse.tmpl")); err != nil || prompt.Name != "terse" {
+		t.Errorf("unexpected prompt file %+v, error %v", prompt, err)
+	}
+	if _, err := Find(directory, "verbose"); !errors.Is(err, ErrUnknownPrompt) {
+		t.Errorf("expected an unknown prompt, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(directory, "broken.v1.tmpl"), []byte("{{.Today"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := List(directory); err == nil {
+		t.Error("expected an error listing a broken prompt")
+	}
+}
+
+// Preferences come from the file named by the environment, or from the data directory if they exist there.
+func TestLoadPreferences(t *testing.T) {
+	directory := t.TempDir()
+	t.Setenv("ARXIV_RESEARCHER_DATA_DIR", directory)
+	t.Setenv("ARXIV_RESEARCHER_PREFERENCES", "")
+	if preferences, err := LoadPreferences(); err != nil || preferences != "" {
+		t.Errorf("unexpected preferences %q, error %v", preferences, err)
+	}
+	err := os.WriteFile(filepath.Join(directory, PreferencesFileName), []byte("Prefer surveys.\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preferences, err := LoadPreferences(); err != nil || preferences != "Prefer surveys." {
+		t.Errorf("unexpected preferences %q, error %v", preferences, err)
+	}
+	t.Setenv("ARXIV_RESEARCHER_PREFERENCES", filepath.Join(directory, "missing.md"))
+	if _, err := LoadPreferences(); err == nil {
+		t.Error("expected an error for a missing preferences file")
+	}
+}
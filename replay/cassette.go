@@ -0,0 +1,155 @@
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Whether a [Cassette] records traffic from real services or replays traffic it recorded previously.
+type Mode int
+
+const (
+	// Answer all traffic from the cassette, and fail any interaction missing from it.
+	Replay Mode = iota
+	// Pass all traffic through to the real services, and save every interaction to the cassette.
+	Record
+)
+
+// Returned when a replaying [Cassette] holds no recording of an interaction.
+var ErrNotRecorded = errors.New("interaction not recorded in cassette")
+
+// A fixture file holding recorded interactions with external services. A cassette hands out wrappers for LLMs, HTTP
+// transports, and vector stores that all record into (or replay from) the same file.
+type Cassette struct {
+	path    string
+	mode    Mode
+	mutex   sync.Mutex
+	fixture fixture
+	// The number of interactions of each kind that we have replayed so far, keyed by interaction kind and key.
+	replayed map[string]int
+}
+
+// The on-disk format of a [Cassette].
+type fixture struct {
+	LLM      []llmInteraction    `json:"llm,omitempty"`
+	HTTP     []httpInteraction   `json:"http,omitempty"`
+	Searches []searchInteraction `json:"searches,omitempty"`
+	Upserts  []upsertInteraction `json:"upserts,omitempty"`
+}
+
+// Get the cassette mode selected by the `REPLAY_MODE` environment variable. The mode is [Record] if the variable is
+// "record", and [Replay] otherwise.
+func ModeFromEnv() Mode {
+	if os.Getenv("REPLAY_MODE") == "record" {
+		return Record
+	}
+	return Replay
+}
+
+// Open a cassette. In [Replay] mode, we load the recorded interactions from the fixture file at the given path; in
+// [Record] mode, we start with an empty cassette that [Cassette.Save] later writes to the path.
+//
+// Returns the cassette if we open it successfully, otherwise returns an error.
+func Open(path string, mode Mode) (*Cassette, error) {
+	cassette := &Cassette{
+		path:     path,
+		mode:     mode,
+		replayed: map[string]int{},
+	}
+	if mode == Record {
+		return cassette, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading cassette '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(content, &cassette.fixture); err != nil {
+		return nil, fmt.Errorf("failed while parsing cassette '%s': %w", path, err)
+	}
+	return cassette, nil
+}
+
+// Open a cassette for a test in the mode selected by [ModeFromEnv], and save the cassette when the test finishes.
+//
+// Returns the cassette; fails the test if we cannot open or save it.
+func Load(t testing.TB, path string) *Cassette {
+	t.Helper()
+	cassette, err := Open(path, ModeFromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := cassette.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+	return cassette
+}
+
+// Get the mode of the cassette.
+func (cassette *Cassette) Mode() Mode {
+	return cassette.mode
+}
+
+// Write the recorded interactions to the fixture file of the cassette. Saving a cassette in [Replay] mode does
+// nothing.
+//
+// Returns nil if we save the cassette successfully, otherwise returns an error.
+func (cassette *Cassette) Save() error {
+	if cassette.mode != Record {
+		return nil
+	}
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+	content, err := json.MarshalIndent(cassette.fixture, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed while marshalling cassette '%s': %w", cassette.path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cassette.path), 0755); err != nil {
+		return fmt.Errorf("failed while saving cassette '%s': %w", cassette.path, err)
+	}
+	if err := os.WriteFile(cassette.path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed while saving cassette '%s': %w", cassette.path, err)
+	}
+	return nil
+}
+
+// Find the next recorded interaction matching a key. Interactions that share a key replay in the order we recorded
+// them; once we exhaust them, we keep replaying the last one, since repeated identical requests (e.g., to download
+// the same paper twice) typically get identical responses.
+//
+// Returns the index of the interaction if there is one, otherwise returns [ErrNotRecorded].
+func (cassette *Cassette) next(kind string, key string, keys []string) (int, error) {
+	counter := kind + " " + key
+	var matches []int
+	for i, candidate := range keys {
+		if candidate == key {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("%w: %s %s (re-record with REPLAY_MODE=record)", ErrNotRecorded, kind, key)
+	}
+	n := cassette.replayed[counter]
+	cassette.replayed[counter]++
+	return matches[min(n, len(matches)-1)], nil
+}
+
+// Compute a stable key for a request by hashing its JSON representation.
+//
+// Returns the hex-encoded SHA-256 hash of the request.
+func hashKey(request any) (string, error) {
+	content, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed while marshalling request: %w", err)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
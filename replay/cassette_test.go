@@ -0,0 +1,88 @@
+package replay
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/fakes"
+)
+
+// Fetch a URL with a client and return the response body.
+func fetch(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	response, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// A cassette records text and binary HTTP responses and LLM calls, including their tool calls, and replays them after
+// the server goes away.
+func TestRecordThenReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/binary" {
+			w.Write([]byte{0xff, 0xfe, 0x00, 0x01})
+			return
+		}
+		w.Write([]byte("hello " + r.URL.Query().Get("name")))
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := Open(path, Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := fetch(t, recorder.Client(), server.URL+"/?name=arxiv")
+	binary := fetch(t, recorder.Client(), server.URL+"/binary")
+	answer, err := recorder.LLM(fakes.NewLLM(fakes.Answer("42"))).Call(t.Context(), "question")
+	if err != nil {
+		t.Fatal(err)
+	}
+	search := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "search")}
+	_, err = recorder.LLM(fakes.NewLLM(fakes.ToolCalls("IndexSearcher", `{"query": "agents"}`))).
+		GenerateContent(t.Context(), search)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	player, err := Open(path, Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fetch(t, player.Client(), server.URL+"/?name=arxiv"); got != text {
+		t.Errorf("replayed text %q, recorded %q", got, text)
+	}
+	if got := fetch(t, player.Client(), server.URL+"/binary"); got != binary {
+		t.Errorf("replayed binary %q, recorded %q", got, binary)
+	}
+	if got, err := player.LLM(nil).Call(t.Context(), "question"); err != nil || got != answer {
+		t.Errorf("replayed answer %q (%v), recorded %q", got, err, answer)
+	}
+	response, err := player.LLM(nil).GenerateContent(t.Context(), search)
+	if err != nil || len(response.Choices[0].ToolCalls) != 1 ||
+		response.Choices[0].ToolCalls[0].FunctionCall.Name != "IndexSearcher" ||
+		response.Choices[0].FuncCall.Arguments != `{"query": "agents"}` {
+		t.Errorf("unexpected replayed tool calls %+v, error %v", response, err)
+	}
+	if _, err := player.LLM(nil).Call(t.Context(), "another question"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded for unrecorded call, got %v", err)
+	}
+	if _, err := player.Client().Get(server.URL + "/missing"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded for unrecorded request, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+// Package replay provides a record/replay layer for the external traffic of agents and tools: LLM calls, HTTP
+// requests (e.g., arXiv queries and paper downloads), and vector store operations. In record mode, the layer passes
+// traffic through to the real services and saves each interaction to a fixture file (a "cassette"); in replay mode,
+// it answers from the cassette alone, so tests run deterministically and offline.
+//
+// Tests select the mode with the `REPLAY_MODE` environment variable, e.g.,
+//
+//	$ REPLAY_MODE=record go test ./cmd/arxiv-researcher
+//
+// re-records the cassettes used by the agent tests.
+package replay
@@ -0,0 +1,148 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// A recorded LLM call. We identify calls by a hash of their messages and tool names, rather than storing the whole
+// prompt, to keep cassettes readable.
+type llmInteraction struct {
+	Key     string           `json:"key"`
+	Choices []recordedChoice `json:"choices"`
+}
+
+// A recorded choice of an LLM response. We record the tool calls of choices ourselves, since an [llms.ToolCall] loses
+// its function call in a JSON round trip.
+type recordedChoice struct {
+	Content        string             `json:"content"`
+	StopReason     string             `json:"stop_reason,omitempty"`
+	GenerationInfo map[string]any     `json:"generation_info,omitempty"`
+	ToolCalls      []recordedToolCall `json:"tool_calls,omitempty"`
+}
+
+// A recorded tool call of an LLM response.
+type recordedToolCall struct {
+	Id       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function *llms.FunctionCall `json:"function,omitempty"`
+}
+
+// Convert the choices of an LLM response to their recorded form.
+//
+// Returns the recorded choices.
+func recordChoices(choices []*llms.ContentChoice) []recordedChoice {
+	recorded := make([]recordedChoice, len(choices))
+	for i, choice := range choices {
+		recorded[i] = recordedChoice{
+			Content:        choice.Content,
+			StopReason:     choice.StopReason,
+			GenerationInfo: choice.GenerationInfo,
+		}
+		for _, toolCall := range choice.ToolCalls {
+			recorded[i].ToolCalls = append(recorded[i].ToolCalls,
+				recordedToolCall{Id: toolCall.ID, Type: toolCall.Type, Function: toolCall.FunctionCall})
+		}
+	}
+	return recorded
+}
+
+// Convert recorded choices back to the choices of an LLM response. As LLM clients do, we also report the first tool
+// call of each choice as its function call.
+//
+// Returns the choices.
+func replayChoices(recorded []recordedChoice) []*llms.ContentChoice {
+	choices := make([]*llms.ContentChoice, len(recorded))
+	for i, choice := range recorded {
+		choices[i] = &llms.ContentChoice{
+			Content:        choice.Content,
+			StopReason:     choice.StopReason,
+			GenerationInfo: choice.GenerationInfo,
+		}
+		for _, toolCall := range choice.ToolCalls {
+			choices[i].ToolCalls = append(choices[i].ToolCalls,
+				llms.ToolCall{ID: toolCall.Id, Type: toolCall.Type, FunctionCall: toolCall.Function})
+		}
+		if len(choices[i].ToolCalls) > 0 {
+			choices[i].FuncCall = choices[i].ToolCalls[0].FunctionCall
+		}
+	}
+	return choices
+}
+
+// An LLM wrapper that records calls to a real LLM into a [Cassette], or replays them from it.
+//
+// Implements the [llms.Model] interface.
+type recordingLLM struct {
+	cassette *Cassette
+	inner    llms.Model
+}
+
+// Wrap an LLM so that its calls record into (or replay from) the cassette. In [Replay] mode, we never call the inner
+// LLM, which may therefore be nil.
+//
+// Returns the wrapped LLM.
+func (cassette *Cassette) LLM(inner llms.Model) llms.Model {
+	return &recordingLLM{cassette: cassette, inner: inner}
+}
+
+// Record or replay an LLM call.
+//
+// Implements the [llms.Model.GenerateContent] API call.
+func (llm *recordingLLM) GenerateContent(
+	ctx context.Context,
+	messages []llms.MessageContent,
+	options ...llms.CallOption,
+) (*llms.ContentResponse, error) {
+	var callOptions llms.CallOptions
+	for _, option := range options {
+		option(&callOptions)
+	}
+	toolNames := make([]string, 0, len(callOptions.Tools))
+	for _, tool := range callOptions.Tools {
+		if tool.Function != nil {
+			toolNames = append(toolNames, tool.Function.Name)
+		}
+	}
+	key, err := hashKey(map[string]any{"messages": messages, "tools": toolNames})
+	if err != nil {
+		return nil, err
+	}
+
+	cassette := llm.cassette
+	if cassette.mode == Record {
+		response, err := llm.inner.GenerateContent(ctx, messages, options...)
+		if err != nil {
+			return nil, err
+		}
+		cassette.mutex.Lock()
+		defer cassette.mutex.Unlock()
+		cassette.fixture.LLM = append(cassette.fixture.LLM,
+			llmInteraction{Key: key, Choices: recordChoices(response.Choices)})
+		return response, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+	keys := make([]string, len(cassette.fixture.LLM))
+	for i, interaction := range cassette.fixture.LLM {
+		keys[i] = interaction.Key
+	}
+	i, err := cassette.next("llm", key, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed while replaying LLM call: %w", err)
+	}
+	return &llms.ContentResponse{Choices: replayChoices(cassette.fixture.LLM[i].Choices)}, nil
+}
+
+// Record or replay a single-prompt LLM call.
+//
+// Implements the [llms.Model.Call] API call.
+func (llm *recordingLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, llm, prompt, options...)
+}
@@ -0,0 +1,115 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"unicode/utf8"
+)
+
+// A recorded HTTP exchange. We store text bodies (e.g., arXiv Atom feeds) verbatim so that cassettes stay readable
+// and editable, and binary bodies (e.g., PDFs) in base64.
+type httpInteraction struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     string      `json:"body"`
+	Encoding string      `json:"encoding,omitempty"`
+}
+
+// An HTTP transport that records exchanges with real servers into a [Cassette], or replays them from it.
+//
+// Implements the [http.RoundTripper] interface.
+type recordingTransport struct {
+	cassette *Cassette
+	inner    http.RoundTripper
+}
+
+// Wrap an HTTP transport so that its exchanges record into (or replay from) the cassette. If the inner transport is
+// nil, we use [http.DefaultTransport] when recording.
+//
+// Returns the wrapped transport.
+func (cassette *Cassette) Transport(inner http.RoundTripper) http.RoundTripper {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	return &recordingTransport{cassette: cassette, inner: inner}
+}
+
+// Get an HTTP client whose transport records into (or replays from) the cassette.
+func (cassette *Cassette) Client() *http.Client {
+	return &http.Client{Transport: cassette.Transport(nil)}
+}
+
+// Record or replay an HTTP exchange. We match exchanges by method and URL.
+//
+// Implements the [http.RoundTripper.RoundTrip] API call.
+func (transport *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	cassette := transport.cassette
+	if cassette.mode == Record {
+		response, err := transport.inner.RoundTrip(request)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed while recording response from '%s': %w", request.URL, err)
+		}
+		interaction := httpInteraction{
+			Method: request.Method,
+			URL:    request.URL.String(),
+			Status: response.StatusCode,
+			Header: response.Header,
+		}
+		if utf8.Valid(body) {
+			interaction.Body = string(body)
+		} else {
+			interaction.Body = base64.StdEncoding.EncodeToString(body)
+			interaction.Encoding = "base64"
+		}
+		cassette.mutex.Lock()
+		cassette.fixture.HTTP = append(cassette.fixture.HTTP, interaction)
+		cassette.mutex.Unlock()
+		response.Body = io.NopCloser(bytes.NewReader(body))
+		return response, nil
+	}
+
+	if err := request.Context().Err(); err != nil {
+		return nil, err
+	}
+	cassette.mutex.Lock()
+	keys := make([]string, len(cassette.fixture.HTTP))
+	for i, interaction := range cassette.fixture.HTTP {
+		keys[i] = interaction.Method + " " + interaction.URL
+	}
+	i, err := cassette.next("http", request.Method+" "+request.URL.String(), keys)
+	var interaction httpInteraction
+	if err == nil {
+		interaction = cassette.fixture.HTTP[i]
+	}
+	cassette.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	body := []byte(interaction.Body)
+	if interaction.Encoding == "base64" {
+		if body, err = base64.StdEncoding.DecodeString(interaction.Body); err != nil {
+			return nil, fmt.Errorf("failed while decoding recorded response from '%s': %w", request.URL, err)
+		}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// A recorded similarity search.
+type searchInteraction struct {
+	Query     string            `json:"query"`
+	N         int               `json:"n"`
+	Documents []schema.Document `json:"documents"`
+}
+
+// A recorded document upsert. We identify upserts by the page contents of the documents, which we store verbatim so
+// that fixtures stay readable and editable.
+type upsertInteraction struct {
+	Contents []string `json:"contents"`
+	IDs      []string `json:"ids"`
+}
+
+// A vector store wrapper that records operations on a real vector store into a [Cassette], or replays them from it.
+//
+// Implements the [vectorstores.VectorStore] interface.
+type recordingVectorStore struct {
+	cassette *Cassette
+	inner    vectorstores.VectorStore
+}
+
+// Wrap a vector store so that its operations record into (or replay from) the cassette. In [Replay] mode, we never
+// call the inner store, which may therefore be nil.
+//
+// Returns the wrapped vector store.
+func (cassette *Cassette) VectorStore(inner vectorstores.VectorStore) vectorstores.VectorStore {
+	return &recordingVectorStore{cassette: cassette, inner: inner}
+}
+
+// Record or replay a document upsert.
+//
+// Implements the [vectorstores.VectorStore.AddDocuments] API call.
+func (store *recordingVectorStore) AddDocuments(
+	ctx context.Context,
+	documents []schema.Document,
+	options ...vectorstores.Option,
+) ([]string, error) {
+	contents := make([]string, len(documents))
+	for i, document := range documents {
+		contents[i] = document.PageContent
+	}
+	cassette := store.cassette
+	if cassette.mode == Record {
+		ids, err := store.inner.AddDocuments(ctx, documents, options...)
+		if err != nil {
+			return nil, err
+		}
+		cassette.mutex.Lock()
+		defer cassette.mutex.Unlock()
+		cassette.fixture.Upserts = append(cassette.fixture.Upserts, upsertInteraction{Contents: contents, IDs: ids})
+		return ids, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+	keys := make([]string, len(cassette.fixture.Upserts))
+	for i, interaction := range cassette.fixture.Upserts {
+		keys[i] = fmt.Sprintf("%q", interaction.Contents)
+	}
+	i, err := cassette.next("upsert", fmt.Sprintf("%q", contents), keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed while replaying upsert: %w", err)
+	}
+	return cassette.fixture.Upserts[i].IDs, nil
+}
+
+// Record or replay a similarity search. We match searches by query and number of documents.
+//
+// Implements the [vectorstores.VectorStore.SimilaritySearch] API call.
+func (store *recordingVectorStore) SimilaritySearch(
+	ctx context.Context,
+	query string,
+	numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	cassette := store.cassette
+	if cassette.mode == Record {
+		documents, err := store.inner.SimilaritySearch(ctx, query, numDocuments, options...)
+		if err != nil {
+			return nil, err
+		}
+		cassette.mutex.Lock()
+		defer cassette.mutex.Unlock()
+		cassette.fixture.Searches = append(cassette.fixture.Searches, searchInteraction{
+			Query:     query,
+			N:         numDocuments,
+			Documents: documents,
+		})
+		return documents, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cassette.mutex.Lock()
+	defer cassette.mutex.Unlock()
+	keys := make([]string, len(cassette.fixture.Searches))
+	for i, interaction := range cassette.fixture.Searches {
+		keys[i] = fmt.Sprintf("%q %d", interaction.Query, interaction.N)
+	}
+	i, err := cassette.next("search", fmt.Sprintf("%q %d", query, numDocuments), keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed while replaying similarity search: %w", err)
+	}
+	return cassette.fixture.Searches[i].Documents, nil
+}
@@ -0,0 +1,139 @@
+package review
+
+import (
+	"math"
+	"slices"
+)
+
+// The most rounds of k-means clustering we run before we settle for the clusters we have.
+const maxClusterRounds = 50
+
+// The most clusters we choose when asked to choose the number of clusters ourselves.
+const maxAutoClusters = 8
+
+// Choose a number of clusters for a number of papers, by the rule of thumb of the square root of half the number of
+// papers, between 1 and [maxAutoClusters].
+//
+// Returns the number of clusters.
+func autoClusters(papers int) int {
+	return max(1, min(maxAutoClusters, int(math.Round(math.Sqrt(float64(papers)/2)))))
+}
+
+// Cluster vectors by cosine similarity with k-means. We seed the clusters deterministically, with the first vector and
+// then, one at a time, the vector least similar to every seed so far, so that the same vectors always fall into the
+// same clusters, and the seeds spread across the themes of the papers.
+//
+// Returns the cluster of each vector, numbered from 0 up to (but excluding) the smaller of the given number of clusters
+// and the number of vectors.
+func cluster(vectors [][]float32, clusters int) []int {
+	assignments := make([]int, len(vectors))
+	clusters = min(clusters, len(vectors))
+	if clusters <= 1 {
+		return assignments
+	}
+	points := make([][]float64, len(vectors))
+	for i, vector := range vectors {
+		points[i] = normalize(vector)
+	}
+	centroids := [][]float64{points[0]}
+	for len(centroids) < clusters {
+		farthest, lowest := 0, math.Inf(1)
+		for i, point := range points {
+			closest := math.Inf(-1)
+			for _, centroid := range centroids {
+				closest = max(closest, dot(point, centroid))
+			}
+			if closest < lowest {
+				farthest, lowest = i, closest
+			}
+		}
+		centroids = append(centroids, points[farthest])
+	}
+	for round := range maxClusterRounds {
+		changed := false
+		for i, point := range points {
+			best, highest := 0, math.Inf(-1)
+			for c, centroid := range centroids {
+				if similarity := dot(point, centroid); similarity > highest {
+					best, highest = c, similarity
+				}
+			}
+			if assignments[i] != best {
+				assignments[i] = best
+				changed = true
+			}
+		}
+		// Every vector starts in the first cluster, so the first round always updates the centroids.
+		if !changed && round > 0 {
+			break
+		}
+		for c := range centroids {
+			sum := make([]float64, len(points[0]))
+			members := 0
+			for i, point := range points {
+				if assignments[i] == c {
+					members++
+					for d := range sum {
+						sum[d] += point[d]
+					}
+				}
+			}
+			// An empty cluster keeps its centroid, and may win members back in the next round.
+			if members > 0 {
+				centroids[c] = normalize64(sum)
+			}
+		}
+	}
+	return compact(assignments)
+}
+
+// Renumber clusters from 0 in order of their first member, dropping numbers of clusters left without members.
+//
+// Returns the renumbered assignments.
+func compact(assignments []int) []int {
+	numbers := map[int]int{}
+	result := slices.Clone(assignments)
+	for i, assignment := range assignments {
+		number, ok := numbers[assignment]
+		if !ok {
+			number = len(numbers)
+			numbers[assignment] = number
+		}
+		result[i] = number
+	}
+	return result
+}
+
+// Scale a vector to unit length, unless it is all zeros.
+//
+// Returns the scaled vector.
+func normalize(vector []float32) []float64 {
+	result := make([]float64, len(vector))
+	for i, value := range vector {
+		result[i] = float64(value)
+	}
+	return normalize64(result)
+}
+
+// Scale a vector to unit length in place, unless it is all zeros.
+//
+// Returns the vector.
+func normalize64(vector []float64) []float64 {
+	if norm := math.Sqrt(dot(vector, vector)); norm > 0 {
+		for i := range vector {
+			vector[i] /= norm
+		}
+	}
+	return vector
+}
+
+// Compute the dot product of two vectors, which is the cosine similarity of the vectors if both are of unit length.
+//
+// Returns the dot product.
+func dot(x []float64, y []float64) float64 {
+	sum := 0.0
+	for i := range min(len(x), len(y)) {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
@@ -0,0 +1,7 @@
+// Package review builds literature reviews: it clusters papers on a topic by theme using the embeddings of the papers,
+// asks an LLM to name and summarize each theme, and renders the review as a Markdown or HTML report with a summary of
+// each theme, a timeline, and a bibliography.
+//
+// Reviews depend only on the LLM and the papers given to them, so that tests can build reviews with a scripted fake
+// LLM and hand-made embeddings.
+package review
@@ -0,0 +1,107 @@
+package review
+
+import (
+	htmlTemplate "html/template"
+	"io"
+	"strings"
+	"text/template"
+
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// The functions that the report templates use.
+var templateFunctions = map[string]any{
+	"add":     func(x int, y int) int { return x + y },
+	"authors": authors,
+	"year":    year,
+	"date":    func(review Review) string { return review.Generated.Format("2006-01-02") },
+}
+
+// The template of Markdown reports.
+var markdownReport = template.Must(template.New("markdown").Funcs(templateFunctions).Parse(
+	`# Literature review: {{.Topic}}
+
+_Generated on {{date .}} from {{len .Bibliography}} papers in {{len .Themes}} themes._
+
+## Themes
+{{range $i, $theme := .Themes}}
+### {{add $i 1}}. {{.Name}}
+
+{{.Summary}}
+
+{{range .Papers}}- [{{.Number}}] [{{.Paper.Title}}]({{.Paper.ArxivUrl}}) ({{year .Paper}})
+{{end}}{{end}}
+## Timeline
+{{range .Timeline}}
+- **{{.Year}}**: {{range $i, $entry := .Papers}}{{if $i}}; {{end}}{{.Paper.Title}} [{{.Number}}]{{end}}{{end}}
+
+## Bibliography
+{{range .Bibliography}}
+{{.Number}}. {{authors .Paper}} ({{year .Paper}}). *{{.Paper.Title}}*. arXiv:{{.Paper.Id}}. {{.Paper.ArxivUrl}}{{end}}
+`))
+
+// The template of HTML reports.
+var htmlReport = htmlTemplate.Must(htmlTemplate.New("html").Funcs(templateFunctions).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Literature review: {{.Topic}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; line-height: 1.5; }
+.meta { color: #666; }
+</style>
+</head>
+<body>
+<h1>Literature review: {{.Topic}}</h1>
+<p class="meta">Generated on {{date .}} from {{len .Bibliography}} papers in {{len .Themes}} themes.</p>
+<h2>Themes</h2>
+{{range $i, $theme := .Themes}}<h3>{{add $i 1}}. {{.Name}}</h3>
+<p>{{.Summary}}</p>
+<ul>
+{{range .Papers}}<li>[<a href="#ref-{{.Number}}">{{.Number}}</a>]
+{{- " "}}<a href="{{.Paper.ArxivUrl}}">{{.Paper.Title}}</a> ({{year .Paper}})</li>
+{{end}}</ul>
+{{end}}<h2>Timeline</h2>
+<ul>
+{{range .Timeline}}<li><strong>{{.Year}}</strong>:
+{{- " "}}{{range $i, $entry := .Papers}}{{if $i}}; {{end}}{{.Paper.Title}}
+{{- " "}}[<a href="#ref-{{.Number}}">{{.Number}}</a>]{{end}}</li>
+{{end}}</ul>
+<h2>Bibliography</h2>
+<ol>
+{{range .Bibliography}}<li id="ref-{{.Number}}">{{authors .Paper}} ({{year .Paper}}).
+{{- " "}}<em>{{.Paper.Title}}</em>. arXiv:{{.Paper.Id}}.
+{{- " "}}<a href="{{.Paper.ArxivUrl}}">{{.Paper.ArxivUrl}}</a></li>
+{{end}}</ol>
+</body>
+</html>
+`))
+
+// Write a review as a Markdown report, with a section on each theme, a timeline, and a bibliography.
+//
+// Returns nil if we write the report successfully, otherwise returns an error.
+func (review Review) WriteMarkdown(w io.Writer) error {
+	return markdownReport.Execute(w, review)
+}
+
+// Write a review as a standalone HTML report, with the same content as the Markdown report.
+//
+// Returns nil if we write the report successfully, otherwise returns an error.
+func (review Review) WriteHtml(w io.Writer) error {
+	return htmlReport.Execute(w, review)
+}
+
+// Format the authors of a paper for a bibliography entry: every author of a paper with up to three authors, otherwise
+// the first author followed by "et al.".
+//
+// Returns the authors, or "Anonymous" if the paper lists no authors.
+func authors(paper tools.Paper) string {
+	switch {
+	case len(paper.Authors) == 0:
+		return "Anonymous"
+	case len(paper.Authors) > 3:
+		return paper.Authors[0] + " et al."
+	default:
+		return strings.Join(paper.Authors, ", ")
+	}
+}
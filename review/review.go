@@ -0,0 +1,179 @@
+package review
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// The most words of the abstract of each paper that we give the LLM when we ask it to summarize a theme, which keeps
+// the prompt for a large theme well within the context window of the LLM.
+const abstractWords = 120
+
+// Returned when there are no papers to review.
+var ErrNoPapers = errors.New("no papers to review")
+
+// A literature review of a topic.
+type Review struct {
+	// The topic of the review.
+	Topic string
+	// When we built the review.
+	Generated time.Time
+	// The themes of the papers in the review, largest first.
+	Themes []Theme
+	// The papers in the review, grouped by the year in which they were published, earliest first.
+	Timeline []Year
+	// The papers in the review, numbered in order of publication.
+	Bibliography []Entry
+}
+
+// A theme shared by a cluster of papers in a review.
+type Theme struct {
+	// The name of the theme, as given by the LLM.
+	Name string
+	// The summary of the papers on the theme, as given by the LLM.
+	Summary string
+	// The papers on the theme, in order of publication.
+	Papers []Entry
+}
+
+// The papers in a review published in a year.
+type Year struct {
+	Year   string
+	Papers []Entry
+}
+
+// A paper in the bibliography of a review.
+type Entry struct {
+	// The number of the paper in the bibliography, starting at 1.
+	Number int
+	Paper  tools.Paper
+}
+
+// The prompt with which we ask the LLM to name and summarize the theme of a cluster of papers.
+const themePrompt = `You are helping a researcher survey the literature on "%s". The papers below share a theme.
+Name the theme in a few words, and summarize in one paragraph what the papers contribute to the theme, how they relate
+to each other, and how the theme developed over time. Refer to papers by their numbers in square brackets, e.g., [3].
+
+Respond in this format:
+Theme: <name of the theme>
+Summary: <one paragraph>
+
+Papers:
+%s`
+
+// Build a literature review of a topic from papers on the topic. We cluster the papers by the cosine similarity of
+// their embeddings into the given number of themes (or, if zero, a number of themes we choose by the number of papers),
+// and ask the LLM to name and summarize each theme from the titles and abstracts of its papers.
+//
+// Returns the review if we build it successfully, [ErrNoPapers] if there are no papers, otherwise returns an error.
+func Build(ctx context.Context, llm llms.Model, topic string, papers []tools.EmbeddedPaper, themes int) (
+	Review,
+	error,
+) {
+	if len(papers) == 0 {
+		return Review{}, ErrNoPapers
+	}
+	if themes <= 0 {
+		themes = autoClusters(len(papers))
+	}
+	review := Review{Topic: topic, Generated: time.Now()}
+
+	// Number the papers in order of publication.
+	order := make([]int, len(papers))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(x, y int) int {
+		return cmp.Or(cmp.Compare(papers[x].Paper.Published, papers[y].Paper.Published),
+			cmp.Compare(papers[x].Paper.Title, papers[y].Paper.Title))
+	})
+	entries := make([]Entry, len(papers))
+	for number, i := range order {
+		entries[i] = Entry{Number: number + 1, Paper: papers[i].Paper}
+		review.Bibliography = append(review.Bibliography, entries[i])
+	}
+
+	// Group the papers into themes, largest first, and into years, earliest first.
+	vectors := make([][]float32, len(papers))
+	for i, paper := range papers {
+		vectors[i] = paper.Embedding
+	}
+	assignments := cluster(vectors, themes)
+	review.Themes = make([]Theme, slices.Max(assignments)+1)
+	for _, entry := range review.Bibliography {
+		theme := &review.Themes[assignments[order[entry.Number-1]]]
+		theme.Papers = append(theme.Papers, entry)
+		year := year(entry.Paper)
+		if len(review.Timeline) == 0 || review.Timeline[len(review.Timeline)-1].Year != year {
+			review.Timeline = append(review.Timeline, Year{Year: year})
+		}
+		last := &review.Timeline[len(review.Timeline)-1]
+		last.Papers = append(last.Papers, entry)
+	}
+	slices.SortStableFunc(review.Themes, func(x, y Theme) int {
+		return cmp.Or(cmp.Compare(len(y.Papers), len(x.Papers)), cmp.Compare(x.Papers[0].Number, y.Papers[0].Number))
+	})
+
+	for i := range review.Themes {
+		theme := &review.Themes[i]
+		var listing strings.Builder
+		for _, entry := range theme.Papers {
+			words := strings.Fields(entry.Paper.Summary)
+			abstract := strings.Join(words[:min(len(words), abstractWords)], " ")
+			fmt.Fprintf(&listing, "[%d] %s (%s): %s\n", entry.Number, entry.Paper.Title, year(entry.Paper), abstract)
+		}
+		response, err := llms.GenerateFromSinglePrompt(ctx, llm, fmt.Sprintf(themePrompt, topic, listing.String()))
+		if err != nil {
+			return Review{}, fmt.Errorf("failed while summarizing theme: %w", err)
+		}
+		theme.Name, theme.Summary = parseTheme(response)
+		if theme.Name == "" {
+			theme.Name = fmt.Sprintf("Theme %d", i+1)
+		}
+	}
+	return review, nil
+}
+
+// Parse the response of the LLM to a theme prompt into the name and summary of the theme. If the response does not
+// follow the format we asked for, we take the whole response as the summary.
+//
+// Returns the name, which is empty if the response names no theme, and the summary.
+func parseTheme(response string) (string, string) {
+	var name string
+	var summary []string
+	inSummary := false
+	for line := range strings.Lines(response) {
+		line = strings.TrimSpace(line)
+		switch {
+		case !inSummary && strings.HasPrefix(line, "Theme:"):
+			name = strings.TrimSpace(strings.TrimPrefix(line, "Theme:"))
+		case !inSummary && strings.HasPrefix(line, "Summary:"):
+			inSummary = true
+			summary = append(summary, strings.TrimSpace(strings.TrimPrefix(line, "Summary:")))
+		case inSummary && line != "":
+			summary = append(summary, line)
+		}
+	}
+	if !inSummary {
+		return name, strings.TrimSpace(response)
+	}
+	return name, strings.TrimSpace(strings.Join(summary, " "))
+}
+
+// Get the year in which a paper was published.
+//
+// Returns the year, or "Undated" if the paper carries no publication date.
+func year(paper tools.Paper) string {
+	if len(paper.Published) < 4 {
+		return "Undated"
+	}
+	return paper.Published[:4]
+}
@@ -0,0 +1,102 @@
+package review
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/fakes"
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// Papers on two themes: prompting language models to act, and letting them learn from feedback.
+var papers = []tools.EmbeddedPaper{
+	{Paper: tools.Paper{Id: "2303.11366v4", Title: "Reflexion", Authors: []string{"Noah Shinn"},
+		Published: "2023-03-20", Summary: "Agents learn from verbal feedback.",
+		ArxivUrl: "http://arxiv.org/abs/2303.11366v4"}, Embedding: []float32{0.1, 0.9}},
+	{Paper: tools.Paper{Id: "2210.03629v3", Title: "ReAct", Authors: []string{"Shunyu Yao", "Jeffrey Zhao"},
+		Published: "2022-10-06", Summary: "Reasoning and acting.",
+		ArxivUrl: "http://arxiv.org/abs/2210.03629v3"}, Embedding: []float32{1, 0}},
+	{Paper: tools.Paper{Id: "2302.04761v1", Title: "Toolformer <LM>", Authors: []string{"Timo Schick"},
+		Published: "2023-02-09", Summary: "Language models teach themselves to use tools.",
+		ArxivUrl: "http://arxiv.org/abs/2302.04761v1"}, Embedding: []float32{0.9, 0.2}},
+	{Paper: tools.Paper{Id: "2305.10601v1", Title: "Self-Refine", Authors: []string{"A", "B", "C", "D"},
+		Published: "2023-03-30", Summary: "Iterative refinement with self-feedback.",
+		ArxivUrl: "http://arxiv.org/abs/2305.10601v1"}, Embedding: []float32{0, 1}},
+}
+
+// Vectors cluster by direction, into no more clusters than vectors.
+func TestCluster(t *testing.T) {
+	vectors := [][]float32{{1, 0, 0}, {0, 1, 0}, {0.9, 0.1, 0}, {0, 0, 1}, {0.1, 0.9, 0}, {0, 0.1, 0.9}}
+	if got, want := cluster(vectors, 3), []int{0, 1, 0, 2, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("got clusters %v, want %v", got, want)
+	}
+	if got := cluster(vectors[:2], 5); !slices.Equal(got, []int{0, 1}) {
+		t.Errorf("expected no more clusters than vectors, got %v", got)
+	}
+	if got := autoClusters(50); got != 5 {
+		t.Errorf("expected 5 clusters for 50 papers, got %d", got)
+	}
+}
+
+// Reviews number papers by publication, group them into named themes and years, and render as Markdown and HTML.
+func TestBuild(t *testing.T) {
+	llm := fakes.NewLLM(
+		fakes.Answer("Theme: Acting with tools\nSummary: ReAct [1] interleaves reasoning and acting,\nwhile "+
+			"Toolformer [2] learns to call tools."),
+		fakes.Answer("The papers learn from feedback."),
+	)
+	review, err := Build(t.Context(), llm, "language model agents", papers, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var numbers []string
+	for _, entry := range review.Bibliography {
+		numbers = append(numbers, entry.Paper.Title)
+	}
+	if !slices.Equal(numbers, []string{"ReAct", "Toolformer <LM>", "Reflexion", "Self-Refine"}) {
+		t.Errorf("expected the bibliography in order of publication, got %v", numbers)
+	}
+	if len(review.Themes) != 2 || review.Themes[0].Name != "Acting with tools" ||
+		!strings.HasPrefix(review.Themes[0].Summary, "ReAct [1] interleaves reasoning and acting, while Toolformer") ||
+		review.Themes[1].Name != "Theme 2" || review.Themes[1].Summary != "The papers learn from feedback." {
+		t.Errorf("unexpected themes %+v", review.Themes)
+	}
+	if len(review.Timeline) != 2 || review.Timeline[1].Year != "2023" || len(review.Timeline[1].Papers) != 3 {
+		t.Errorf("unexpected timeline %+v", review.Timeline)
+	}
+	prompt := llm.Calls()[1][0].Parts[0].(llms.TextContent).Text
+	if !strings.Contains(prompt, "[3] Reflexion (2023): Agents learn from verbal feedback.") {
+		t.Errorf("expected the prompt to list the papers of the theme, got %s", prompt)
+	}
+
+	var markdown bytes.Buffer
+	if err := review.WriteMarkdown(&markdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Literature review: language model agents\n",
+		"### 1. Acting with tools\n\nReAct [1]",
+		"- [2] [Toolformer <LM>](http://arxiv.org/abs/2302.04761v1) (2023)\n",
+		"- **2022**: ReAct [1]\n- **2023**: Toolformer <LM> [2]; Reflexion [3]; Self-Refine [4]\n",
+		"4. A et al. (2023). *Self-Refine*. arXiv:2305.10601v1. http://arxiv.org/abs/2305.10601v1\n",
+	} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("expected the Markdown report to contain %q, got\n%s", want, markdown.String())
+		}
+	}
+	var html bytes.Buffer
+	if err := review.WriteHtml(&html); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), "Toolformer &lt;LM&gt;") || !strings.Contains(html.String(), `id="ref-4"`) {
+		t.Errorf("unexpected HTML report\n%s", html.String())
+	}
+
+	if _, err := Build(t.Context(), llm, "nothing", nil, 0); !errors.Is(err, ErrNoPapers) {
+		t.Errorf("expected ErrNoPapers, got %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+// Package runs keeps a history of agent runs: the query, settings, and timing of each run, every event of the run,
+// e.g., the prompts and responses of its LLM calls, the evolution of its scratchpad, and its tool calls and their
+// results, along with its final answer (or error) and token usage.
+//
+// Each run persists as a JSON lines file in the runs directory of the data directory, named by the ID of the run. The
+// first line of the file marks the start of the run, the last line marks its end, and the lines in between are the
+// transcript of the run (see [tools.TranscriptEvent]), so that runs that crash still leave the record of what they did.
+package runs
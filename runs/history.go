@@ -0,0 +1,400 @@
+package runs
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/tools"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// The name of the runs directory in the data directory.
+const DirectoryName = "runs"
+
+// The file name extension of run files.
+const fileExtension = ".jsonl"
+
+// Returned when the history holds no run with a given ID.
+var ErrUnknownRun = errors.New("no such run")
+
+// Returned when a prefix of run IDs matches more than one run.
+var ErrAmbiguousRun = errors.New("ambiguous run ID")
+
+// The settings of a run.
+type Settings struct {
+	// The query that the run answers.
+	Query string
+	// The agent mode of the run, e.g., "functions".
+	Mode string
+	// The model of the LLM of the run.
+	Model string
+	// The ID of the prompt version of the run, e.g., "research.v2".
+	Prompt string
+	// The ID of the run that the run replays, if any.
+	ReplayOf string
+}
+
+// A run of the agent, as kept in the history.
+type Run struct {
+	Id       string    `json:"id"`
+	Query    string    `json:"query"`
+	Mode     string    `json:"mode,omitempty"`
+	Model    string    `json:"model,omitempty"`
+	Prompt   string    `json:"prompt,omitempty"`
+	ReplayOf string    `json:"replay_of,omitempty"`
+	Started  time.Time `json:"started"`
+	// Whether the run ended, as opposed to still running, or having crashed.
+	Finished   bool    `json:"finished"`
+	DurationMs float64 `json:"duration_ms,omitempty"`
+	// The final answer of the run, if it succeeded.
+	Answer string `json:"answer,omitempty"`
+	// The error of the run, if it failed.
+	Error string        `json:"error,omitempty"`
+	Usage *usage.Report `json:"usage,omitempty"`
+	// The numbers of LLM calls and tool calls of the run. [List] knows them only for runs that finished.
+	LlmCalls  int `json:"llm_calls"`
+	ToolCalls int `json:"tool_calls"`
+	// The transcript of the run, if loaded.
+	Events []tools.TranscriptEvent `json:"events,omitempty"`
+}
+
+// Get the runs directory in the data directory.
+//
+// Returns the path of the directory if we locate the data directory successfully, otherwise returns an error.
+func DefaultDirectory() (string, error) {
+	directory, err := constants.DataDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, DirectoryName), nil
+}
+
+// A run in progress, whose events we record to its file in the history. The recorder is the writer to pass to
+// [tools.LogHandler.SetTranscript].
+//
+// Implements the [io.Writer] interface.
+type Recorder struct {
+	mutex   sync.Mutex
+	file    *os.File
+	id      string
+	started time.Time
+	// The numbers of LLM calls and tool calls that the run made so far.
+	llmCalls  int
+	toolCalls int
+}
+
+var _ io.Writer = (*Recorder)(nil)
+
+// Start recording a run with the given ID and settings in the history in a directory.
+//
+// Returns the recorder of the run if we create its file successfully, otherwise returns an error.
+func Start(directory string, id string, settings Settings) (*Recorder, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed while creating runs directory: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(directory, id+fileExtension), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed while creating run file: %w", err)
+	}
+	recorder := &Recorder{file: file, id: id, started: time.Now().UTC()}
+	values := map[string]any{"mode": settings.Mode, "model": settings.Model, "prompt": settings.Prompt}
+	if settings.ReplayOf != "" {
+		values["replay_of"] = settings.ReplayOf
+	}
+	err = recorder.write(tools.TranscriptEvent{
+		Time:   recorder.started,
+		RunId:  id,
+		Event:  tools.RunStartEvent,
+		Input:  settings.Query,
+		Values: values,
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return recorder, nil
+}
+
+// Get the ID of the recorded run.
+func (recorder *Recorder) Id() string {
+	return recorder.id
+}
+
+// Write transcript lines to the file of the run, counting the calls that they start, so that the end of the run can
+// record the counts for [List].
+//
+// Implements the [io.Writer.Write] API call.
+func (recorder *Recorder) Write(data []byte) (int, error) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	var event struct {
+		Event string `json:"event"`
+	}
+	if json.Unmarshal(data, &event) == nil {
+		switch event.Event {
+		case tools.LlmStartEvent:
+			recorder.llmCalls++
+		case tools.ToolStartEvent:
+			recorder.toolCalls++
+		}
+	}
+	return recorder.file.Write(data)
+}
+
+// Write an event to the file of the run, with its sensitive values redacted.
+//
+// Returns nil if we write the event successfully, otherwise returns an error.
+func (recorder *Recorder) write(event tools.TranscriptEvent) error {
+	data, err := json.Marshal(event.Redact())
+	if err != nil {
+		return fmt.Errorf("failed while marshalling run event: %w", err)
+	}
+	if _, err := recorder.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed while writing run file: %w", err)
+	}
+	return nil
+}
+
+// Record the end of the run, with its final answer or error, and the tokens it spent, and close its file.
+//
+// Returns nil if we record the end successfully, otherwise returns an error.
+func (recorder *Recorder) Finish(answer string, runErr error, report usage.Report) error {
+	recorder.mutex.Lock()
+	values := map[string]any{"llm_calls": recorder.llmCalls, "tool_calls": recorder.toolCalls}
+	recorder.mutex.Unlock()
+	event := tools.TranscriptEvent{
+		Time:       time.Now().UTC(),
+		RunId:      recorder.id,
+		Event:      tools.RunEndEvent,
+		Output:     answer,
+		Values:     values,
+		Usage:      &report,
+		DurationMs: float64(time.Since(recorder.started)) / float64(time.Millisecond),
+	}
+	if runErr != nil {
+		event.Error = runErr.Error()
+	}
+	err := recorder.write(event)
+	if closeErr := recorder.file.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed while closing run file: %w", closeErr)
+	}
+	return err
+}
+
+// Load a run from the history in a directory. The ID may be a unique prefix of the ID of the run.
+//
+// Returns the run, including its events, if we load it successfully, [ErrUnknownRun] if no run matches the ID,
+// [ErrAmbiguousRun] if several runs do, otherwise returns an error.
+func Load(directory string, id string) (*Run, error) {
+	ids, err := runIds(directory)
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, candidate := range ids {
+		if candidate == id {
+			matches = []string{candidate}
+			break
+		}
+		if id != "" && strings.HasPrefix(candidate, id) {
+			matches = append(matches, candidate)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRun, id)
+	case 1:
+		return load(directory, matches[0])
+	default:
+		return nil, fmt.Errorf("%w: %s matches %s", ErrAmbiguousRun, id, strings.Join(matches, ", "))
+	}
+}
+
+// List the runs in the history in a directory, without their events, most recent first. We read only the start and
+// end events of each run, and skip (with a warning) runs whose files we cannot read. A missing directory holds no runs.
+//
+// Returns the runs if we read the directory successfully, otherwise returns an error.
+func List(directory string) ([]Run, error) {
+	ids, err := runIds(directory)
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(ids))
+	for _, id := range ids {
+		run, err := summarize(directory, id)
+		if err != nil {
+			slog.Warn("Skipping unreadable run", "run", id, "error", err)
+			continue
+		}
+		runs = append(runs, *run)
+	}
+	slices.SortFunc(runs, func(x, y Run) int {
+		return cmp.Or(y.Started.Compare(x.Started), cmp.Compare(x.Id, y.Id))
+	})
+	return runs, nil
+}
+
+// List the IDs of the runs in the history in a directory.
+//
+// Returns the IDs if we read the directory successfully, otherwise returns an error.
+func runIds(directory string) ([]string, error) {
+	entries, err := os.ReadDir(directory)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading runs directory: %w", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		if id, ok := strings.CutSuffix(entry.Name(), fileExtension); ok && entry.Type().IsRegular() {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// Load a run from its file in the history in a directory.
+//
+// Returns the run if we load it successfully, otherwise returns an error.
+func load(directory string, id string) (*Run, error) {
+	file, err := os.Open(filepath.Join(directory, id+fileExtension))
+	if err != nil {
+		return nil, fmt.Errorf("failed while opening run file: %w", err)
+	}
+	defer file.Close()
+	run := &Run{Id: id}
+	scanner := bufio.NewScanner(file)
+	// Events hold whole prompts and tool results, which may run long.
+	scanner.Buffer(nil, 64*1024*1024)
+	for scanner.Scan() {
+		var event tools.TranscriptEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("failed while parsing run file of %s: %w", id, err)
+		}
+		switch event.Event {
+		case tools.RunStartEvent:
+			run.start(event)
+			continue
+		case tools.RunEndEvent:
+			run.end(event)
+			continue
+		case tools.LlmStartEvent:
+			run.LlmCalls++
+		case tools.ToolStartEvent:
+			run.ToolCalls++
+		}
+		run.Events = append(run.Events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed while reading run file of %s: %w", id, err)
+	}
+	return run, nil
+}
+
+// Load a run from its file in the history in a directory without its events, reading only its start event, i.e., the
+// first line of the file, and its end event, i.e., the last line of the file, if the run finished.
+//
+// Returns the run if we read its start event successfully, otherwise returns an error.
+func summarize(directory string, id string) (*Run, error) {
+	file, err := os.Open(filepath.Join(directory, id+fileExtension))
+	if err != nil {
+		return nil, fmt.Errorf("failed while opening run file: %w", err)
+	}
+	defer file.Close()
+	first, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed while reading run file of %s: %w", id, err)
+	}
+	var event tools.TranscriptEvent
+	if err := json.Unmarshal(first, &event); err != nil || event.Event != tools.RunStartEvent {
+		return nil, fmt.Errorf("run file of %s does not start with the start of the run", id)
+	}
+	run := &Run{Id: id}
+	run.start(event)
+	last, err := lastLine(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading run file of %s: %w", id, err)
+	}
+	// A run that crashed may have left a partial line at the end, so we take a last line that does not parse as a run
+	// that never finished.
+	event = tools.TranscriptEvent{}
+	if json.Unmarshal(last, &event) == nil && event.Event == tools.RunEndEvent {
+		run.end(event)
+		run.LlmCalls = intValue(event.Values, "llm_calls")
+		run.ToolCalls = intValue(event.Values, "tool_calls")
+	}
+	return run, nil
+}
+
+// Read the last line of a file, reading the file backwards from its end in blocks.
+//
+// Returns the last line, without its newline, if we read it successfully, otherwise returns an error.
+func lastLine(file *os.File) ([]byte, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	const blockSize = 64 * 1024
+	end := info.Size()
+	var line []byte
+	for offset := end; offset > 0; {
+		size := min(int64(blockSize), offset)
+		offset -= size
+		block := make([]byte, size)
+		if _, err := file.ReadAt(block, offset); err != nil {
+			return nil, err
+		}
+		line = append(block, line...)
+		trimmed := bytes.TrimSuffix(line, []byte("\n"))
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+		if offset == 0 {
+			return trimmed, nil
+		}
+	}
+	return nil, nil
+}
+
+// Set the settings of a run from its start event.
+func (run *Run) start(event tools.TranscriptEvent) {
+	run.Query = event.Input
+	run.Started = event.Time
+	run.Mode, _ = event.Values["mode"].(string)
+	run.Model, _ = event.Values["model"].(string)
+	run.Prompt, _ = event.Values["prompt"].(string)
+	run.ReplayOf, _ = event.Values["replay_of"].(string)
+}
+
+// Set the outcome of a run from its end event.
+func (run *Run) end(event tools.TranscriptEvent) {
+	run.Finished = true
+	run.Answer = event.Output
+	run.Error = event.Error
+	run.Usage = event.Usage
+	run.DurationMs = event.DurationMs
+}
+
+// Get an integer from the values of an event, which hold numbers as float64 once they round-trip through JSON.
+//
+// Returns the integer if the values hold a number under the key, otherwise returns 0.
+func intValue(values map[string]any, key string) int {
+	if value, ok := values[key].(float64); ok {
+		return int(value)
+	}
+	return 0
+}
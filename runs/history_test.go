@@ -0,0 +1,120 @@
+package runs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/logging"
+	"tmwong.org/arxiv-researcher-go/tools"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// A recorded run loads with its settings, the events of its transcript, its outcome, and its counts of calls.
+func TestRecordAndLoad(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), DirectoryName)
+	recorder, err := Start(directory, "0123456789abcdef", Settings{
+		Query: "What is ReAct?", Mode: "functions", Model: "gpt-4o-mini", ReplayOf: "fedcba9876543210",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := tools.NewLogHandler()
+	handler.SetTranscript(recorder)
+	ctx := logging.WithRunId(t.Context(), recorder.Id())
+	handler.HandleLLMGenerateContentStart(ctx, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is ReAct?"),
+	})
+	handler.HandleLLMGenerateContentEnd(ctx, &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		ToolCalls: []llms.ToolCall{{ID: "call_0", Type: "function", FunctionCall: &llms.FunctionCall{
+			Name: "ArxivSearcher", Arguments: `{"query": "ReAct"}`,
+		}}},
+	}}})
+	handler.SetTranscript(nil)
+	report := usage.Report{TotalTokens: 42}
+	if err := recorder.Finish("A prompting method.", nil, report); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := Load(directory, "0123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Id != "0123456789abcdef" || run.Query != "What is ReAct?" || run.Mode != "functions" ||
+		run.Model != "gpt-4o-mini" || run.ReplayOf != "fedcba9876543210" || run.Started.IsZero() {
+		t.Errorf("unexpected settings %+v", run)
+	}
+	if !run.Finished || run.Answer != "A prompting method." || run.Error != "" || run.Usage == nil ||
+		run.Usage.TotalTokens != 42 {
+		t.Errorf("unexpected outcome %+v", run)
+	}
+	if run.LlmCalls != 1 || run.ToolCalls != 0 || len(run.Events) != 2 {
+		t.Fatalf("unexpected events %+v", run.Events)
+	}
+	// Listing the run reads the same settings, outcome, and counts from its start and end alone.
+	if history, err := List(directory); err != nil || len(history) != 1 || history[0].LlmCalls != 1 ||
+		history[0].Answer != run.Answer || history[0].Mode != run.Mode || history[0].Events != nil {
+		t.Errorf("unexpected history %+v, error %v", history, err)
+	}
+	// Tool calls survive the round trip through the file.
+	choices := run.Events[1].Choices
+	if len(choices) != 1 || len(choices[0].ToolCalls) != 1 || choices[0].ToolCalls[0].Name != "ArxivSearcher" ||
+		choices[0].ToolCalls[0].Arguments != `{"query": "ReAct"}` {
+		t.Errorf("unexpected choices %+v", choices)
+	}
+}
+
+// Runs list most recent first, including runs that never finished but not runs that cannot be read, and load by
+// unique prefix only.
+func TestListAndLookUp(t *testing.T) {
+	directory := t.TempDir()
+	if history, err := List(filepath.Join(directory, "missing")); err != nil || len(history) != 0 {
+		t.Errorf("unexpected history %v, error %v", history, err)
+	}
+	for i, id := range []string{"aaaa000000000000", "aaaa111111111111", "bbbb000000000000"} {
+		recorder, err := Start(directory, id, Settings{Query: fmt.Sprintf("query %d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id == "bbbb000000000000" {
+			continue
+		}
+		if err := recorder.Finish("", errors.New("failed"), usage.Report{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Start(directory, "aaaa000000000000", Settings{}); err == nil {
+		t.Error("expected an error starting a run that exists")
+	}
+	// The history ignores files other than run files, and skips run files that do not hold runs.
+	if err := os.WriteFile(filepath.Join(directory, "notes.txt"), []byte("not a run"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(directory, "cccc000000000000.jsonl"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := List(directory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[0].Id != "bbbb000000000000" || history[0].Finished ||
+		history[2].Error != "failed" || history[2].Events != nil {
+		t.Errorf("unexpected history %+v", history)
+	}
+	if _, err := Load(directory, "aaaa"); !errors.Is(err, ErrAmbiguousRun) {
+		t.Errorf("expected an ambiguous run, got %v", err)
+	}
+	if _, err := Load(directory, "dddd"); !errors.Is(err, ErrUnknownRun) {
+		t.Errorf("expected an unknown run, got %v", err)
+	}
+	if _, err := Load(directory, ""); !errors.Is(err, ErrUnknownRun) {
+		t.Errorf("expected an unknown run for an empty ID, got %v", err)
+	}
+	if run, err := Load(directory, "aaaa111111111111"); err != nil || run.Query != "query 1" {
+		t.Errorf("unexpected run %+v, error %v", run, err)
+	}
+}
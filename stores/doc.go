@@ -0,0 +1,5 @@
+// Package stores provides the vector store backends that hold the document index: a Pinecone backend for production
+// use, and a local on-disk backend for development without a Pinecone account. Unlike the generic LangChainGo vector
+// stores, the backends let callers choose the IDs of the documents they add, and manage the documents they hold by ID.
+// A deterministic [WordEmbedder] embeds the documents of indexes that need no embedding model, e.g., sandboxed ones.
+package stores
@@ -0,0 +1,405 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
+)
+
+// A vector store backend that keeps documents in a bbolt database file on the local file system, with one bucket per
+// namespace, and answers similarity searches by brute-force cosine similarity. The backend suits indexes of up to a few
+// tens of thousands of papers, which is plenty for development and personal use.
+//
+// Implements the [Store] interface.
+type Local struct {
+	db        *bolt.DB
+	embedder  embeddings.Embedder
+	nameSpace string
+}
+
+// A document held by a [Local] store, along with its embedding.
+type localRecord struct {
+	Values   []float32      `json:"values"`
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+var _ Store = (*Local)(nil)
+
+// Open (or create) a local store database file, which embeds documents with the given embedder (or, if nil, only with
+// embedders that requests pass as options) and uses the given default namespace. Only one process may open the file
+// at a time; if another process holds the file for longer than a second, we give up and return an error.
+//
+// Returns the store if we open it successfully, otherwise returns an error.
+func OpenLocal(path string, embedder embeddings.Embedder, nameSpace string) (*Local, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed while opening local index '%s': %w", path, err)
+	}
+	return &Local{db: db, embedder: embedder, nameSpace: nameSpace}, nil
+}
+
+// Embed a set of documents and add them to the store. The store supports the namespace and embedder options.
+//
+// Implements the [vectorstores.VectorStore.AddDocuments] API call.
+func (store *Local) AddDocuments(
+	ctx context.Context,
+	documents []schema.Document,
+	options ...vectorstores.Option,
+) ([]string, error) {
+	opts := applyOptions(store.nameSpace, store.embedder, options...)
+	texts := make([]string, len(documents))
+	for i, document := range documents {
+		texts[i] = document.PageContent
+	}
+	if opts.Embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	vectors, err := opts.Embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(documents) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d documents", len(vectors), len(documents))
+	}
+	ids := make([]string, len(documents))
+	records := make([]Record, len(documents))
+	for i, document := range documents {
+		ids[i] = documentId(document)
+		if ids[i] == "" {
+			ids[i] = uuid.New().String()
+		}
+		records[i] = Record{Id: ids[i], Values: vectors[i], Content: document.PageContent, Metadata: document.Metadata}
+	}
+	if err := store.Upsert(ctx, opts.NameSpace, records); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Find the documents most similar to a query. The store supports the namespace, embedder, score threshold, and filter
+// options.
+//
+// Implements the [vectorstores.VectorStore.SimilaritySearch] API call.
+func (store *Local) SimilaritySearch(
+	ctx context.Context,
+	query string,
+	numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	opts := applyOptions(store.nameSpace, store.embedder, options...)
+	if opts.Embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	vector, err := opts.Embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return store.SimilaritySearchByVector(ctx, vector, numDocuments, options...)
+}
+
+// Find the documents most similar to an embedding. The store supports the namespace, score threshold, and filter
+// options.
+//
+// Implements the [Store.SimilaritySearchByVector] API call.
+func (store *Local) SimilaritySearchByVector(
+	ctx context.Context,
+	vector []float32,
+	numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	opts := applyOptions(store.nameSpace, store.embedder, options...)
+	filter, err := filterOf(opts)
+	if err != nil {
+		return nil, err
+	}
+	var documents []schema.Document
+	err = store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName(opts.NameSpace))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, value []byte) error {
+			var record localRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return err
+			}
+			if len(record.Values) != len(vector) {
+				return fmt.Errorf("%w: query has dimension %d, but documents have dimension %d",
+					ErrDimensionMismatch, len(vector), len(record.Values))
+			}
+			if !Matches(record.Metadata, filter) {
+				return nil
+			}
+			score := Cosine(vector, record.Values)
+			if score < opts.ScoreThreshold {
+				return nil
+			}
+			documents = append(documents, schema.Document{
+				PageContent: record.Content,
+				Metadata:    record.Metadata,
+				Score:       score,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed while searching local index: %w", err)
+	}
+	return topDocuments(documents, numDocuments), nil
+}
+
+// Count the documents in a namespace.
+//
+// Implements the [Store.Count] API call.
+func (store *Local) Count(ctx context.Context, nameSpace string) (int, error) {
+	count := 0
+	err := store.db.View(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(bucketName(store.orDefault(nameSpace))); bucket != nil {
+			count = bucket.Stats().KeyN
+		}
+		return nil
+	})
+	return count, err
+}
+
+// List document IDs in a namespace, in lexical order. The pagination token is the last ID of the previous page, and a
+// limit of zero lists every document.
+//
+// Implements the [Store.List] API call.
+func (store *Local) List(ctx context.Context, nameSpace string, limit int, token string) (Page, error) {
+	var page Page
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName(store.orDefault(nameSpace)))
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		key, _ := cursor.First()
+		if token != "" {
+			key, _ = cursor.Seek([]byte(token))
+			if key != nil && string(key) == token {
+				key, _ = cursor.Next()
+			}
+		}
+		for ; key != nil; key, _ = cursor.Next() {
+			if limit > 0 && len(page.Ids) == limit {
+				page.Next = page.Ids[len(page.Ids)-1]
+				break
+			}
+			page.Ids = append(page.Ids, string(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return Page{}, fmt.Errorf("failed while listing local index: %w", err)
+	}
+	return page, nil
+}
+
+// Fetch documents from a namespace by ID.
+//
+// Implements the [Store.Fetch] API call.
+func (store *Local) Fetch(ctx context.Context, nameSpace string, ids []string) ([]Record, error) {
+	var records []Record
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName(store.orDefault(nameSpace)))
+		if bucket == nil {
+			return nil
+		}
+		for _, id := range ids {
+			value := bucket.Get([]byte(id))
+			if value == nil {
+				continue
+			}
+			var record localRecord
+			if err := json.Unmarshal(value, &record); err != nil {
+				return err
+			}
+			records = append(records, Record{
+				Id:       id,
+				Values:   record.Values,
+				Content:  record.Content,
+				Metadata: record.Metadata,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed while fetching from local index: %w", err)
+	}
+	return records, nil
+}
+
+// Add documents, along with their embeddings, to a namespace.
+//
+// Implements the [Store.Upsert] API call.
+func (store *Local) Upsert(ctx context.Context, nameSpace string, records []Record) error {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(bucketName(store.orDefault(nameSpace)))
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			value, err := json.Marshal(localRecord{
+				Values:   record.Values,
+				Content:  record.Content,
+				Metadata: record.Metadata,
+			})
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(record.Id), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed while adding documents to local index: %w", err)
+	}
+	return nil
+}
+
+// Delete documents from a namespace by ID.
+//
+// Implements the [Store.Delete] API call.
+func (store *Local) Delete(ctx context.Context, nameSpace string, ids []string) error {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(bucketName(store.orDefault(nameSpace)))
+		if bucket == nil {
+			return nil
+		}
+		for _, id := range ids {
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed while deleting documents from local index: %w", err)
+	}
+	return nil
+}
+
+// Delete every document in a namespace.
+//
+// Implements the [Store.DeleteAll] API call.
+func (store *Local) DeleteAll(ctx context.Context, nameSpace string) error {
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket(bucketName(store.orDefault(nameSpace)))
+		if errors.Is(err, bolterrors.ErrBucketNotFound) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed while deleting namespace from local index: %w", err)
+	}
+	return nil
+}
+
+// Count the documents in every namespace.
+//
+// Implements the [Store.NameSpaces] API call.
+func (store *Local) NameSpaces(ctx context.Context) (map[string]int, error) {
+	counts := map[string]int{}
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			nameSpace, found := strings.CutPrefix(string(name), bucketPrefix)
+			if !found {
+				return nil
+			}
+			if count := bucket.Stats().KeyN; count > 0 {
+				counts[nameSpace] = count
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed while listing namespaces of local index: %w", err)
+	}
+	return counts, nil
+}
+
+// Get the manifest of a namespace, which we keep in a bucket of manifests keyed by the bucket name of the namespace.
+//
+// Implements the [Store.Manifest] API call.
+func (store *Local) Manifest(ctx context.Context, nameSpace string) (*Manifest, error) {
+	var manifest *Manifest
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(manifestsBucket)
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get(bucketName(store.orDefault(nameSpace)))
+		if value == nil {
+			return nil
+		}
+		manifest = &Manifest{}
+		return json.Unmarshal(value, manifest)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading manifest from local index: %w", err)
+	}
+	return manifest, nil
+}
+
+// Replace the manifest of a namespace.
+//
+// Implements the [Store.SetManifest] API call.
+func (store *Local) SetManifest(ctx context.Context, nameSpace string, manifest Manifest) error {
+	value, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed while writing manifest to local index: %w", err)
+	}
+	err = store.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(manifestsBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(bucketName(store.orDefault(nameSpace)), value)
+	})
+	if err != nil {
+		return fmt.Errorf("failed while writing manifest to local index: %w", err)
+	}
+	return nil
+}
+
+// Close the store database file.
+//
+// Implements the [Store.Close] API call.
+func (store *Local) Close() error {
+	return store.db.Close()
+}
+
+// Get the given namespace, or the default namespace of the store if the given namespace is empty.
+func (store *Local) orDefault(nameSpace string) string {
+	if nameSpace == "" {
+		return store.nameSpace
+	}
+	return nameSpace
+}
+
+// Get the name of the bucket holding a namespace. bbolt does not allow empty bucket names, so we prefix namespace names
+// to support the (empty) default namespace.
+func bucketName(nameSpace string) []byte {
+	return []byte(bucketPrefix + nameSpace)
+}
+
+// The prefix of the names of buckets holding namespaces.
+const bucketPrefix = "namespace:"
+
+// The bucket holding the manifests of namespaces, keyed by the bucket names of the namespaces.
+var manifestsBucket = []byte("manifests")
@@ -0,0 +1,73 @@
+package stores_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"tmwong.org/arxiv-researcher-go/fakes"
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+func TestLocal(t *testing.T) {
+	store, err := stores.OpenLocal(filepath.Join(t.TempDir(), "index.db"), fakes.NewEmbedder(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	ctx := t.Context()
+	documents := []schema.Document{
+		{PageContent: "reasoning and acting", Metadata: map[string]any{stores.IdKey: "a", "Year": "2022"}},
+		{PageContent: "tool use", Metadata: map[string]any{stores.IdKey: "b", "Year": "2023"}},
+		{PageContent: "self reflection", Metadata: map[string]any{stores.IdKey: "c", "Year": "2023"}},
+	}
+	if _, err := store.AddDocuments(ctx, documents); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := store.SimilaritySearch(ctx, "tool use", 1, vectorstores.WithFilters(map[string]any{"Year": 2023}))
+	if err != nil || len(results) != 1 || results[0].PageContent != "tool use" {
+		t.Errorf("unexpected search results %v, error %v", results, err)
+	}
+
+	var ids []string
+	for page := (stores.Page{}); ; {
+		if page, err = store.List(ctx, "", 2, page.Next); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, page.Ids...)
+		if page.Next == "" {
+			break
+		}
+	}
+	if !slices.Equal(ids, []string{"a", "b", "c"}) {
+		t.Errorf("listed %v", ids)
+	}
+
+	records, err := store.Fetch(ctx, "", []string{"b", "missing"})
+	if err != nil || len(records) != 1 || records[0].Content != "tool use" || len(records[0].Values) == 0 {
+		t.Errorf("unexpected records %v, error %v", records, err)
+	}
+	results, err = store.SimilaritySearchByVector(ctx, records[0].Values, 1)
+	if err != nil || len(results) != 1 || results[0].PageContent != "tool use" {
+		t.Errorf("unexpected search by vector results %v, error %v", results, err)
+	}
+	if err := store.Upsert(ctx, "copy", records); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "", []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	counts, err := store.NameSpaces(ctx)
+	if err != nil || counts[""] != 2 || counts["copy"] != 1 {
+		t.Errorf("unexpected namespaces %v, error %v", counts, err)
+	}
+	if err := store.DeleteAll(ctx, "copy"); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := store.Count(ctx, "copy"); err != nil || count != 0 {
+		t.Errorf("counted %d documents after dropping namespace, error %v", count, err)
+	}
+}
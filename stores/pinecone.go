@@ -0,0 +1,437 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/pinecone-io/go-pinecone/pinecone"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// The metadata key under which the Pinecone backend saves the page content of each document. The key matches the
+// default of the LangChainGo Pinecone store, so that the backend reads indexes populated by earlier versions of the
+// indexer.
+const pineconeTextKey = "text"
+
+// The reserved namespace in which the Pinecone backend keeps the manifests of the other namespaces, one vector per
+// namespace. Pinecone has no place for namespace metadata, and keeping the manifests apart from the documents means
+// that deleting every document in a namespace keeps its manifest.
+const pineconeManifestNameSpace = "__manifests__"
+
+// The metadata key under which the Pinecone backend saves a manifest, as JSON, in the vector of the manifest.
+const pineconeManifestKey = "manifest"
+
+// The maximum number of vectors we upsert to Pinecone in a single request. Pinecone limits the size of upsert
+// requests to 2 MB, which holds about a hundred vectors of 1536 dimensions along with their metadata.
+const pineconeBatchSize = 100
+
+// A vector store backend that keeps documents in a Pinecone index.
+//
+// Implements the [Store] interface.
+type Pinecone struct {
+	client    *pinecone.Client
+	host      string
+	embedder  embeddings.Embedder
+	nameSpace string
+}
+
+var _ Store = (*Pinecone)(nil)
+
+// Create a new connection to a Pinecone index, which embeds documents with the given embedder (or, if nil, only with
+// embedders that requests pass as options) and uses the given default namespace.
+//
+// Returns the connection if we create it successfully, otherwise returns an error.
+func NewPinecone(apiKey string, host string, embedder embeddings.Embedder, nameSpace string) (*Pinecone, error) {
+	client, err := pinecone.NewClient(pinecone.NewClientParams{ApiKey: apiKey})
+	if err != nil {
+		return nil, fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	return &Pinecone{client: client, host: host, embedder: embedder, nameSpace: nameSpace}, nil
+}
+
+// Embed a set of documents and upsert them to the index. The store supports the namespace and embedder options.
+//
+// Implements the [vectorstores.VectorStore.AddDocuments] API call.
+func (store *Pinecone) AddDocuments(
+	ctx context.Context,
+	documents []schema.Document,
+	options ...vectorstores.Option,
+) ([]string, error) {
+	opts := applyOptions(store.nameSpace, store.embedder, options...)
+	texts := make([]string, len(documents))
+	for i, document := range documents {
+		texts[i] = document.PageContent
+	}
+	if opts.Embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	vectors, err := opts.Embedder.EmbedDocuments(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(documents) {
+		return nil, fmt.Errorf("embedder returned %d vectors for %d documents", len(vectors), len(documents))
+	}
+	ids := make([]string, len(documents))
+	records := make([]Record, len(documents))
+	for i, document := range documents {
+		ids[i] = documentId(document)
+		if ids[i] == "" {
+			ids[i] = uuid.New().String()
+		}
+		records[i] = Record{Id: ids[i], Values: vectors[i], Content: document.PageContent, Metadata: document.Metadata}
+	}
+	if err := store.Upsert(ctx, opts.NameSpace, records); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// Find the documents most similar to a query. The store supports the namespace, embedder, score threshold, and filter
+// options. Filters may use the [Pinecone metadata filter language], of which plain key-value maps are a subset.
+//
+// Implements the [vectorstores.VectorStore.SimilaritySearch] API call.
+//
+// [Pinecone metadata filter language]: https://docs.pinecone.io/guides/data/filter-with-metadata
+func (store *Pinecone) SimilaritySearch(
+	ctx context.Context,
+	query string,
+	numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	opts := applyOptions(store.nameSpace, store.embedder, options...)
+	if opts.Embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	vector, err := opts.Embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return store.SimilaritySearchByVector(ctx, vector, numDocuments, options...)
+}
+
+// Find the documents most similar to an embedding. The store supports the namespace, score threshold, and filter
+// options.
+//
+// Implements the [Store.SimilaritySearchByVector] API call.
+func (store *Pinecone) SimilaritySearchByVector(
+	ctx context.Context,
+	vector []float32,
+	numDocuments int,
+	options ...vectorstores.Option,
+) ([]schema.Document, error) {
+	opts := applyOptions(store.nameSpace, store.embedder, options...)
+	filter, err := filterOf(opts)
+	if err != nil {
+		return nil, err
+	}
+	filterStruct, err := pineconeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	connection, err := store.client.IndexWithNamespace(store.host, opts.NameSpace)
+	if err != nil {
+		return nil, fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	defer connection.Close()
+	result, err := connection.QueryByVectorValues(&ctx, &pinecone.QueryByVectorValuesRequest{
+		Vector:          vector,
+		TopK:            uint32(numDocuments),
+		Filter:          filterStruct,
+		IncludeMetadata: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed while querying Pinecone: %w", err)
+	}
+	documents := []schema.Document{}
+	for _, match := range result.Matches {
+		if match.Score < opts.ScoreThreshold {
+			continue
+		}
+		metadata := map[string]any{}
+		if match.Vector.Metadata != nil {
+			metadata = match.Vector.Metadata.AsMap()
+		}
+		content, _ := metadata[pineconeTextKey].(string)
+		delete(metadata, pineconeTextKey)
+		documents = append(documents, schema.Document{PageContent: content, Metadata: metadata, Score: match.Score})
+	}
+	return documents, nil
+}
+
+// Count the documents in a namespace.
+//
+// Implements the [Store.Count] API call.
+func (store *Pinecone) Count(ctx context.Context, nameSpace string) (int, error) {
+	connection, err := store.client.Index(store.host)
+	if err != nil {
+		return 0, fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	defer connection.Close()
+	stats, err := connection.DescribeIndexStats(&ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed while describing Pinecone index: %w", err)
+	}
+	if summary, ok := stats.Namespaces[store.orDefault(nameSpace)]; ok {
+		return int(summary.VectorCount), nil
+	}
+	return 0, nil
+}
+
+// List document IDs in a namespace, in the order of Pinecone, which is lexical.
+//
+// Implements the [Store.List] API call.
+func (store *Pinecone) List(ctx context.Context, nameSpace string, limit int, token string) (Page, error) {
+	connection, err := store.client.IndexWithNamespace(store.host, store.orDefault(nameSpace))
+	if err != nil {
+		return Page{}, fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	defer connection.Close()
+	request := &pinecone.ListVectorsRequest{}
+	if limit > 0 {
+		pineconeLimit := uint32(limit)
+		request.Limit = &pineconeLimit
+	}
+	if token != "" {
+		request.PaginationToken = &token
+	}
+	result, err := connection.ListVectors(&ctx, request)
+	if err != nil {
+		return Page{}, fmt.Errorf("failed while listing Pinecone index: %w", err)
+	}
+	page := Page{Ids: make([]string, 0, len(result.VectorIds))}
+	for _, id := range result.VectorIds {
+		if id != nil {
+			page.Ids = append(page.Ids, *id)
+		}
+	}
+	if result.NextPaginationToken != nil {
+		page.Next = *result.NextPaginationToken
+	}
+	return page, nil
+}
+
+// Fetch documents from a namespace by ID.
+//
+// Implements the [Store.Fetch] API call.
+func (store *Pinecone) Fetch(ctx context.Context, nameSpace string, ids []string) ([]Record, error) {
+	connection, err := store.client.IndexWithNamespace(store.host, store.orDefault(nameSpace))
+	if err != nil {
+		return nil, fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	defer connection.Close()
+	var records []Record
+	for start := 0; start < len(ids); start += pineconeBatchSize {
+		batch := ids[start:min(start+pineconeBatchSize, len(ids))]
+		result, err := connection.FetchVectors(&ctx, batch)
+		if err != nil {
+			return nil, fmt.Errorf("failed while fetching from Pinecone: %w", err)
+		}
+		for id, vector := range result.Vectors {
+			metadata := map[string]any{}
+			if vector.Metadata != nil {
+				metadata = vector.Metadata.AsMap()
+			}
+			content, _ := metadata[pineconeTextKey].(string)
+			delete(metadata, pineconeTextKey)
+			records = append(records, Record{Id: id, Values: vector.Values, Content: content, Metadata: metadata})
+		}
+	}
+	return records, nil
+}
+
+// Upsert documents, along with their embeddings, to a namespace, in batches.
+//
+// Implements the [Store.Upsert] API call.
+func (store *Pinecone) Upsert(ctx context.Context, nameSpace string, records []Record) error {
+	pineconeVectors := make([]*pinecone.Vector, len(records))
+	for i, record := range records {
+		metadata := make(map[string]any, len(record.Metadata)+1)
+		for key, value := range record.Metadata {
+			metadata[key] = value
+		}
+		metadata[pineconeTextKey] = record.Content
+		metadataStruct, err := structpb.NewStruct(metadata)
+		if err != nil {
+			return fmt.Errorf("failed while converting metadata of document '%s': %w", record.Id, err)
+		}
+		pineconeVectors[i] = &pinecone.Vector{Id: record.Id, Values: record.Values, Metadata: metadataStruct}
+	}
+
+	connection, err := store.client.IndexWithNamespace(store.host, store.orDefault(nameSpace))
+	if err != nil {
+		return fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	defer connection.Close()
+	for start := 0; start < len(pineconeVectors); start += pineconeBatchSize {
+		batch := pineconeVectors[start:min(start+pineconeBatchSize, len(pineconeVectors))]
+		if _, err := connection.UpsertVectors(&ctx, batch); err != nil {
+			return fmt.Errorf("failed while upserting documents to Pinecone: %w", err)
+		}
+	}
+	return nil
+}
+
+// Delete documents from a namespace by ID, in batches, since Pinecone limits the IDs of each request.
+//
+// Implements the [Store.Delete] API call.
+func (store *Pinecone) Delete(ctx context.Context, nameSpace string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	connection, err := store.client.IndexWithNamespace(store.host, store.orDefault(nameSpace))
+	if err != nil {
+		return fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	defer connection.Close()
+	for start := 0; start < len(ids); start += pineconeBatchSize {
+		batch := ids[start:min(start+pineconeBatchSize, len(ids))]
+		if err := connection.DeleteVectorsById(&ctx, batch); err != nil {
+			return fmt.Errorf("failed while deleting documents from Pinecone: %w", err)
+		}
+	}
+	return nil
+}
+
+// Delete every document in a namespace.
+//
+// Implements the [Store.DeleteAll] API call.
+func (store *Pinecone) DeleteAll(ctx context.Context, nameSpace string) error {
+	connection, err := store.client.IndexWithNamespace(store.host, store.orDefault(nameSpace))
+	if err != nil {
+		return fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	defer connection.Close()
+	if err := connection.DeleteAllVectorsInNamespace(&ctx); err != nil {
+		return fmt.Errorf("failed while deleting namespace from Pinecone: %w", err)
+	}
+	return nil
+}
+
+// Count the documents in every namespace.
+//
+// Implements the [Store.NameSpaces] API call.
+func (store *Pinecone) NameSpaces(ctx context.Context) (map[string]int, error) {
+	connection, err := store.client.Index(store.host)
+	if err != nil {
+		return nil, fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	defer connection.Close()
+	stats, err := connection.DescribeIndexStats(&ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed while describing Pinecone index: %w", err)
+	}
+	counts := make(map[string]int, len(stats.Namespaces))
+	for name, summary := range stats.Namespaces {
+		if name != pineconeManifestNameSpace && summary != nil && summary.VectorCount > 0 {
+			counts[name] = int(summary.VectorCount)
+		}
+	}
+	return counts, nil
+}
+
+// Get the manifest of a namespace from the vector that holds it in the manifest namespace.
+//
+// Implements the [Store.Manifest] API call.
+func (store *Pinecone) Manifest(ctx context.Context, nameSpace string) (*Manifest, error) {
+	connection, err := store.client.IndexWithNamespace(store.host, pineconeManifestNameSpace)
+	if err != nil {
+		return nil, fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	defer connection.Close()
+	id := pineconeManifestId(store.orDefault(nameSpace))
+	result, err := connection.FetchVectors(&ctx, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading manifest from Pinecone: %w", err)
+	}
+	vector, ok := result.Vectors[id]
+	if !ok || vector.Metadata == nil {
+		return nil, nil
+	}
+	content, _ := vector.Metadata.AsMap()[pineconeManifestKey].(string)
+	var manifest Manifest
+	if err := json.Unmarshal([]byte(content), &manifest); err != nil {
+		return nil, fmt.Errorf("failed while reading manifest from Pinecone: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Replace the manifest of a namespace by upserting the vector that holds it in the manifest namespace. Pinecone only
+// accepts vectors of the dimension of the index, with at least one non-zero value, so the vector is a unit vector of
+// that dimension.
+//
+// Implements the [Store.SetManifest] API call.
+func (store *Pinecone) SetManifest(ctx context.Context, nameSpace string, manifest Manifest) error {
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed while writing manifest to Pinecone: %w", err)
+	}
+	connection, err := store.client.IndexWithNamespace(store.host, pineconeManifestNameSpace)
+	if err != nil {
+		return fmt.Errorf("failed while connecting to Pinecone: %w", err)
+	}
+	defer connection.Close()
+	stats, err := connection.DescribeIndexStats(&ctx)
+	if err != nil {
+		return fmt.Errorf("failed while describing Pinecone index: %w", err)
+	}
+	values := make([]float32, max(stats.Dimension, 1))
+	values[0] = 1
+	metadata, err := structpb.NewStruct(map[string]any{pineconeManifestKey: string(content)})
+	if err != nil {
+		return fmt.Errorf("failed while writing manifest to Pinecone: %w", err)
+	}
+	vector := &pinecone.Vector{Id: pineconeManifestId(store.orDefault(nameSpace)), Values: values, Metadata: metadata}
+	if _, err := connection.UpsertVectors(&ctx, []*pinecone.Vector{vector}); err != nil {
+		return fmt.Errorf("failed while writing manifest to Pinecone: %w", err)
+	}
+	return nil
+}
+
+// Release the store. We open a fresh connection for each operation, so there is nothing to release.
+//
+// Implements the [Store.Close] API call.
+func (store *Pinecone) Close() error {
+	return nil
+}
+
+// Get the given namespace, or the default namespace of the store if the given namespace is empty.
+func (store *Pinecone) orDefault(nameSpace string) string {
+	if nameSpace == "" {
+		return store.nameSpace
+	}
+	return nameSpace
+}
+
+// Convert a metadata filter to the protocol buffer structure the Pinecone client expects.
+//
+// Returns the structure, or nil if there is no filter, if we convert the filter successfully, otherwise returns an
+// error.
+func pineconeFilter(filter map[string]any) (*structpb.Struct, error) {
+	if filter == nil {
+		return nil, nil
+	}
+	// Round-trip through JSON, so that the filter may hold any JSON-compatible values, e.g., slices of strings for
+	// `$in` clauses, which structpb does not convert directly.
+	content, err := json.Marshal(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed while converting filter: %w", err)
+	}
+	var filterStruct structpb.Struct
+	if err := filterStruct.UnmarshalJSON(content); err != nil {
+		return nil, fmt.Errorf("failed while converting filter: %w", err)
+	}
+	return &filterStruct, nil
+}
+
+// Get the ID of the vector holding the manifest of a namespace. Pinecone does not allow empty IDs, so we prefix
+// namespace names to support the (empty) default namespace.
+func pineconeManifestId(nameSpace string) string {
+	return "namespace:" + nameSpace
+}
@@ -0,0 +1,218 @@
+package stores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/schema"
+	"github.com/tmc/langchaingo/vectorstores"
+)
+
+// The metadata key whose value, if present, a backend uses as the ID of a document it adds, instead of generating a
+// random ID. Adding a document with the same ID as an existing document replaces the existing document.
+const IdKey = "ID"
+
+// A document held by a backend, along with its ID and embedding.
+type Record struct {
+	Id       string         `json:"id"`
+	Values   []float32      `json:"values"`
+	Content  string         `json:"content"`
+	Metadata map[string]any `json:"metadata"`
+}
+
+// A page of document IDs listed by a backend.
+type Page struct {
+	// The IDs of the documents on the page, in the order of the backend.
+	Ids []string
+	// The token to pass to [Store.List] to get the next page, or an empty string if this is the last page.
+	Next string
+}
+
+// A description of the embeddings in a namespace of a backend, which the backend keeps apart from the documents in the
+// namespace, so that dropping the documents keeps the manifest.
+type Manifest struct {
+	// The embedding model that computed the embeddings of the documents.
+	EmbeddingModel string `json:"embedding_model"`
+	// The dimension of the embeddings of the documents.
+	Dimension int `json:"dimension"`
+	// The namespace that holds the documents, if it is not the namespace that the manifest describes, e.g., because
+	// we re-indexed the documents into a new namespace with a new embedding model.
+	NameSpace string `json:"namespace,omitempty"`
+	// The time at which we last updated the manifest.
+	Updated time.Time `json:"updated"`
+}
+
+// Returned when adding or searching documents in a backend that has no embedder, and the request names no embedder
+// with the [vectorstores.WithEmbedder] option.
+var ErrNoEmbedder = errors.New("no embedder for vector store")
+
+// Returned when a query or document embedding has a different dimension than the embeddings in a namespace.
+var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// A vector store backend that holds the document index. Every method that takes a namespace uses the default
+// namespace of the store if the namespace is empty.
+type Store interface {
+	vectorstores.VectorStore
+
+	// Find the documents most similar to an embedding, e.g., the stored embedding of another document, instead of to a
+	// query that we embed first. The store supports the same options as for similarity searches, except the embedder,
+	// which it does not need.
+	//
+	// Returns up to the given number of documents, most similar first, if the search succeeds, otherwise returns an
+	// error.
+	SimilaritySearchByVector(
+		ctx context.Context,
+		vector []float32,
+		numDocuments int,
+		options ...vectorstores.Option,
+	) ([]schema.Document, error)
+
+	// Count the documents in a namespace.
+	//
+	// Returns the number of documents if we count them successfully, otherwise returns an error.
+	Count(ctx context.Context, nameSpace string) (int, error)
+
+	// List the IDs of up to the given (positive) number of documents in a namespace, starting at the page that a
+	// pagination token from an earlier call points to, or at the first page if the token is empty.
+	//
+	// Returns the page of IDs if we list them successfully, otherwise returns an error.
+	List(ctx context.Context, nameSpace string, limit int, token string) (Page, error)
+
+	// Fetch the documents with the given IDs from a namespace. We skip IDs of documents that do not exist.
+	//
+	// Returns the documents, in no particular order, if we fetch them successfully, otherwise returns an error.
+	Fetch(ctx context.Context, nameSpace string, ids []string) ([]Record, error)
+
+	// Add documents, along with their embeddings, to a namespace, replacing any documents with the same IDs.
+	//
+	// Returns nil if we add the documents successfully, otherwise returns an error.
+	Upsert(ctx context.Context, nameSpace string, records []Record) error
+
+	// Delete the documents with the given IDs from a namespace, or from the default namespace of the store if the
+	// namespace is empty. Deleting a document that does not exist is not an error.
+	//
+	// Returns nil if we delete the documents successfully, otherwise returns an error.
+	Delete(ctx context.Context, nameSpace string, ids []string) error
+
+	// Delete every document in a namespace.
+	//
+	// Returns nil if we delete the documents successfully, otherwise returns an error.
+	DeleteAll(ctx context.Context, nameSpace string) error
+
+	// Count the documents in every namespace of the store.
+	//
+	// Returns the number of documents in each non-empty namespace if we count them successfully, otherwise returns an
+	// error.
+	NameSpaces(ctx context.Context) (map[string]int, error)
+
+	// Get the manifest of a namespace.
+	//
+	// Returns the manifest, or nil if the namespace has no manifest, if we read it successfully, otherwise returns an
+	// error.
+	Manifest(ctx context.Context, nameSpace string) (*Manifest, error)
+
+	// Replace the manifest of a namespace. Replacing a manifest is atomic, so that readers see either the old or the
+	// new manifest.
+	//
+	// Returns nil if we replace the manifest successfully, otherwise returns an error.
+	SetManifest(ctx context.Context, nameSpace string, manifest Manifest) error
+
+	// Release any resources held by the store.
+	Close() error
+}
+
+// Get the ID of a document: the value of its [IdKey] metadata entry if present, otherwise an empty string.
+//
+// Returns the ID.
+func documentId(document schema.Document) string {
+	if id, ok := document.Metadata[IdKey].(string); ok {
+		return id
+	}
+	return ""
+}
+
+// Apply a set of options over the given default namespace and embedder.
+//
+// Returns the options.
+func applyOptions(nameSpace string, embedder embeddings.Embedder, options ...vectorstores.Option) vectorstores.Options {
+	opts := vectorstores.Options{NameSpace: nameSpace, Embedder: embedder}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.NameSpace == "" {
+		opts.NameSpace = nameSpace
+	}
+	if opts.Embedder == nil {
+		opts.Embedder = embedder
+	}
+	return opts
+}
+
+// Get the metadata filter of a set of options. Backends accept filters that map metadata keys to the values that
+// matching documents must hold.
+//
+// Returns the filter if the options hold a valid filter, otherwise returns an error.
+func filterOf(opts vectorstores.Options) (map[string]any, error) {
+	if opts.Filters == nil {
+		return nil, nil
+	}
+	filter, ok := opts.Filters.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unsupported filter of type %T", opts.Filters)
+	}
+	return filter, nil
+}
+
+// Check whether document metadata holds all of the values in a filter. We compare values by their string
+// representations, so that a filter may hold strings for metadata that a backend returns as numbers.
+//
+// Returns true if the metadata matches the filter, otherwise returns false.
+func Matches(metadata map[string]any, filter map[string]any) bool {
+	for key, value := range filter {
+		if fmt.Sprint(metadata[key]) != fmt.Sprint(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compute the cosine similarity of two vectors.
+//
+// Returns the similarity, or zero if either vector is zero.
+func Cosine(a, b []float32) float32 {
+	var dot, normA, normB float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+// Sort documents by descending score, and keep at most the given number of documents.
+//
+// Returns the sorted documents.
+func topDocuments(documents []schema.Document, numDocuments int) []schema.Document {
+	slices.SortStableFunc(documents, func(a, b schema.Document) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+	if len(documents) > numDocuments {
+		documents = documents[:numDocuments]
+	}
+	return documents
+}
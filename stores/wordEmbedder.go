@@ -0,0 +1,83 @@
+package stores
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/tmc/langchaingo/embeddings"
+)
+
+// A deterministic embedder that needs no embedding model, for indexes whose documents only need to be found by the
+// words they share with queries, e.g., the sandboxed indexes of evaluation scenarios. The embedder hashes each
+// lower-cased word of a text into one of [WordEmbedder.Dimension] buckets and normalizes the resulting bag-of-words
+// vector, so that texts sharing words have similar embeddings and identical texts always have identical embeddings.
+//
+// Implements both the [embeddings.EmbedderClient] and the [embeddings.Embedder] interfaces.
+type WordEmbedder struct {
+	Dimension int
+}
+
+var (
+	_ embeddings.EmbedderClient = WordEmbedder{}
+	_ embeddings.Embedder       = WordEmbedder{}
+)
+
+// Compute the embeddings of a set of texts.
+//
+// Implements the [embeddings.EmbedderClient.CreateEmbedding] API call.
+func (embedder WordEmbedder) CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = EmbedWords(text, embedder.Dimension)
+	}
+	return vectors, nil
+}
+
+// Compute the embeddings of a set of documents.
+//
+// Implements the [embeddings.Embedder.EmbedDocuments] API call.
+func (embedder WordEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	return embedder.CreateEmbedding(ctx, texts)
+}
+
+// Compute the embedding of a query.
+//
+// Implements the [embeddings.Embedder.EmbedQuery] API call.
+func (embedder WordEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := embedder.CreateEmbedding(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// Compute the normalized bag-of-words embedding of a text with the given number of dimensions (see [WordEmbedder]).
+//
+// Returns the embedding.
+func EmbedWords(text string, dimension int) []float32 {
+	vector := make([]float32, dimension)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		hash := fnv.New32a()
+		hash.Write([]byte(word))
+		vector[hash.Sum32()%uint32(dimension)]++
+	}
+	var norm float64
+	for _, value := range vector {
+		norm += float64(value * value)
+	}
+	if norm > 0 {
+		for i := range vector {
+			vector[i] /= float32(math.Sqrt(norm))
+		}
+	}
+	return vector
+}
@@ -0,0 +1,159 @@
+package telemetry
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// An introspection handler that traces LLM calls, chains, and retrievals as operations (see [Start]), counts the
+// tokens of LLM calls, and records agent actions and finishes as events on the span of the agent iteration. The tools
+// of the tools package trace their calls themselves, so we ignore tool events.
+//
+// LangChainGo reports the start and end of each call with the same context, so we pair up starts and ends by context
+// and kind of call. Since handlers cannot pass a context on to the calls they observe, the span of each call is a child
+// of the span of its context, e.g., of the agent iteration, rather than of the span of an enclosing chain.
+//
+// Implements the [callbacks.Handler] interface.
+type Handler struct {
+	callbacks.SimpleHandler
+	// The model of the LLM whose calls we trace, with which we label token counts.
+	model      string
+	mutex      sync.Mutex
+	operations map[operationKey][]*Operation
+}
+
+var _ callbacks.Handler = (*Handler)(nil)
+
+// Identifies the calls in progress of one kind with one context.
+type operationKey struct {
+	ctx  context.Context
+	kind string
+}
+
+// Create a handler that traces the calls of an LLM with the given model name, and of the chains, agents, and
+// retrievers that use it.
+//
+// Returns the handler.
+func NewHandler(model string) *Handler {
+	return &Handler{model: model, operations: map[operationKey][]*Operation{}}
+}
+
+// Start an operation of a kind of call with a context.
+func (handler *Handler) push(ctx context.Context, kind string, attributes ...attribute.KeyValue) {
+	// Contexts are comparable unless a caller implements its own, in which case we cannot pair starts and ends up.
+	if !reflect.TypeOf(ctx).Comparable() {
+		return
+	}
+	_, operation := Start(ctx, kind, attributes...)
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	key := operationKey{ctx, kind}
+	handler.operations[key] = append(handler.operations[key], operation)
+}
+
+// Take the most recently started operation of a kind of call with a context.
+//
+// Returns the operation if there is one in progress, otherwise returns nil.
+func (handler *Handler) pop(ctx context.Context, kind string) *Operation {
+	if !reflect.TypeOf(ctx).Comparable() {
+		return nil
+	}
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	key := operationKey{ctx, kind}
+	operations := handler.operations[key]
+	if len(operations) == 0 {
+		return nil
+	}
+	operation := operations[len(operations)-1]
+	if len(operations) == 1 {
+		delete(handler.operations, key)
+	} else {
+		handler.operations[key] = operations[:len(operations)-1]
+	}
+	return operation
+}
+
+// Implements the [callbacks.Handler.HandleLLMGenerateContentStart] API call.
+func (handler *Handler) HandleLLMGenerateContentStart(ctx context.Context, messages []llms.MessageContent) {
+	handler.push(ctx, "llm.generate", attribute.String("llm.model", handler.model))
+}
+
+// End the span of an LLM call, and count the tokens that the call used, if the LLM reports them.
+//
+// Implements the [callbacks.Handler.HandleLLMGenerateContentEnd] API call.
+func (handler *Handler) HandleLLMGenerateContentEnd(ctx context.Context, response *llms.ContentResponse) {
+	operation := handler.pop(ctx, "llm.generate")
+	if operation == nil {
+		return
+	}
+	var input, output int
+	if response != nil && len(response.Choices) > 0 {
+		input, output = usage.Tokens(response.Choices[0].GenerationInfo)
+	}
+	operation.SetAttributes(attribute.Int("llm.tokens.input", input), attribute.Int("llm.tokens.output", output))
+	RecordTokens(operation.ctx, handler.model, input, output)
+	operation.End(nil)
+}
+
+// Implements the [callbacks.Handler.HandleLLMError] API call.
+func (handler *Handler) HandleLLMError(ctx context.Context, err error) {
+	if operation := handler.pop(ctx, "llm.generate"); operation != nil {
+		operation.End(err)
+	}
+}
+
+// Implements the [callbacks.Handler.HandleChainStart] API call.
+func (handler *Handler) HandleChainStart(ctx context.Context, inputs map[string]any) {
+	handler.push(ctx, "chain")
+}
+
+// Implements the [callbacks.Handler.HandleChainEnd] API call.
+func (handler *Handler) HandleChainEnd(ctx context.Context, outputs map[string]any) {
+	if operation := handler.pop(ctx, "chain"); operation != nil {
+		operation.End(nil)
+	}
+}
+
+// Implements the [callbacks.Handler.HandleChainError] API call.
+func (handler *Handler) HandleChainError(ctx context.Context, err error) {
+	if operation := handler.pop(ctx, "chain"); operation != nil {
+		operation.End(err)
+	}
+}
+
+// Implements the [callbacks.Handler.HandleRetrieverStart] API call.
+func (handler *Handler) HandleRetrieverStart(ctx context.Context, query string) {
+	handler.push(ctx, "retriever")
+}
+
+// Implements the [callbacks.Handler.HandleRetrieverEnd] API call.
+func (handler *Handler) HandleRetrieverEnd(ctx context.Context, query string, documents []schema.Document) {
+	if operation := handler.pop(ctx, "retriever"); operation != nil {
+		operation.SetAttributes(attribute.Int("retriever.documents", len(documents)))
+		operation.End(nil)
+	}
+}
+
+// Record the tool call that an agent decided on as an event on the span of the context, i.e., of the agent iteration.
+//
+// Implements the [callbacks.Handler.HandleAgentAction] API call.
+func (handler *Handler) HandleAgentAction(ctx context.Context, action schema.AgentAction) {
+	trace.SpanFromContext(ctx).AddEvent("agent.action",
+		trace.WithAttributes(attribute.String("tool.name", action.Tool)))
+}
+
+// Record the final answer of an agent as an event on the span of the context, i.e., of the agent iteration.
+//
+// Implements the [callbacks.Handler.HandleAgentFinish] API call.
+func (handler *Handler) HandleAgentFinish(ctx context.Context, finish schema.AgentFinish) {
+	trace.SpanFromContext(ctx).AddEvent("agent.finish")
+}
@@ -0,0 +1,178 @@
+// Package telemetry traces and measures agent runs with OpenTelemetry: spans for agent iterations, LLM calls, tool
+// calls, embedding requests, vector queries, and HTTP requests, along with latency histograms, error counts, and token
+// counts, exported to standard output or over OTLP.
+//
+// Code that does measurable work wraps it in an [Operation] (see [Start]), and LangChainGo components report their
+// events to a [Handler]. Until [Setup] installs an exporter, the global OpenTelemetry providers discard everything,
+// so that instrumentation costs next to nothing when telemetry is off.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The instrumentation scope of our tracers and meters.
+const ScopeName = "tmwong.org/arxiv-researcher-go"
+
+// The service name under which we report telemetry, unless the `OTEL_SERVICE_NAME` environment variable overrides it.
+const ServiceName = "arxiv-researcher"
+
+// The exporters of telemetry.
+const (
+	// Export nothing.
+	NoExporter = "none"
+	// Export spans and metrics as JSON to a writer, standard output by default.
+	StdoutExporter = "stdout"
+	// Export spans and metrics over OTLP/HTTP to the collector named by the standard `OTEL_EXPORTER_OTLP_ENDPOINT`
+	// environment variable (by default, http://localhost:4318).
+	OtlpExporter = "otlp"
+)
+
+// The names of the metrics we record.
+const (
+	// A histogram of the durations of operations in seconds, by operation name and attributes.
+	DurationMetric = "arxiv_researcher.operation.duration"
+	// A count of failed operations, by operation name and attributes.
+	ErrorMetric = "arxiv_researcher.operation.errors"
+	// A count of tokens used by LLM calls, by model and token type ("input" or "output").
+	TokenMetric = "arxiv_researcher.llm.tokens"
+)
+
+// Options of the telemetry that [Setup] installs.
+type Options struct {
+	// The exporter of spans and metrics: [NoExporter] (the default), [StdoutExporter], or [OtlpExporter].
+	Exporter string
+	// The writer to which the stdout exporter writes. If nil, the exporter writes to standard output.
+	Writer io.Writer
+}
+
+// Install global OpenTelemetry tracer and meter providers that export spans and metrics with the given exporter. With
+// [NoExporter], we leave the global providers as they are.
+//
+// Returns a function that flushes any buffered telemetry and shuts the providers down if we install them successfully,
+// otherwise returns an error.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	var spanExporter sdktrace.SpanExporter
+	var metricExporter sdkmetric.Exporter
+	var err error
+	switch options.Exporter {
+	case "", NoExporter:
+		return func(context.Context) error { return nil }, nil
+	case StdoutExporter:
+		writer := options.Writer
+		if writer == nil {
+			writer = os.Stdout
+		}
+		if spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(writer)); err == nil {
+			metricExporter, err = stdoutmetric.New(stdoutmetric.WithWriter(writer))
+		}
+	case OtlpExporter:
+		if spanExporter, err = otlptracehttp.New(ctx); err == nil {
+			metricExporter, err = otlpmetrichttp.New(ctx)
+		}
+	default:
+		return nil, fmt.Errorf("unknown telemetry exporter '%s'", options.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed while creating telemetry exporter: %w", err)
+	}
+	serviceResource, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed while creating telemetry resource: %w", err)
+	}
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(serviceResource),
+	)
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter)),
+		sdkmetric.WithResource(serviceResource),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetMeterProvider(meterProvider)
+	return func(ctx context.Context) error {
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+// A unit of measurable work in progress, e.g., a tool call, traced as a span, and measured by the duration and error
+// metrics once it ends.
+type Operation struct {
+	ctx        context.Context
+	name       string
+	span       trace.Span
+	start      time.Time
+	attributes []attribute.KeyValue
+}
+
+// Start an operation, e.g., "tool.call", whose span is a child of the span of the context, if any. The attributes,
+// e.g., the name of a tool, label both the span and the metrics of the operation, so they should take few distinct
+// values.
+//
+// Returns a context that carries the span of the operation, to pass to the work the operation does, along with the
+// operation, which the caller must end.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, *Operation) {
+	ctx, span := otel.Tracer(ScopeName).Start(ctx, name, trace.WithAttributes(attributes...))
+	return ctx, &Operation{ctx: ctx, name: name, span: span, start: time.Now(), attributes: attributes}
+}
+
+// Add attributes to the span of an operation, but not to its metrics, e.g., to record the number of results of a
+// search.
+func (operation *Operation) SetAttributes(attributes ...attribute.KeyValue) {
+	operation.span.SetAttributes(attributes...)
+}
+
+// End an operation: record its duration, and, if it failed, record the error on its span and count it.
+func (operation *Operation) End(err error) {
+	attributes := metric.WithAttributes(append([]attribute.KeyValue{attribute.String("operation", operation.name)},
+		operation.attributes...)...)
+	meter := otel.Meter(ScopeName)
+	if err != nil {
+		operation.span.RecordError(err)
+		operation.span.SetStatus(codes.Error, err.Error())
+		if errorCounter, err := meter.Int64Counter(ErrorMetric,
+			metric.WithDescription("Number of failed operations")); err == nil {
+			errorCounter.Add(operation.ctx, 1, attributes)
+		}
+	}
+	if histogram, err := meter.Float64Histogram(DurationMetric, metric.WithUnit("s"),
+		metric.WithDescription("Duration of operations")); err == nil {
+		histogram.Record(operation.ctx, time.Since(operation.start).Seconds(), attributes)
+	}
+	operation.span.End()
+}
+
+// Count the tokens that an LLM call used.
+func RecordTokens(ctx context.Context, model string, input int, output int) {
+	counter, err := otel.Meter(ScopeName).Int64Counter(TokenMetric, metric.WithUnit("{token}"),
+		metric.WithDescription("Number of tokens used by LLM calls"))
+	if err != nil {
+		return
+	}
+	counter.Add(ctx, int64(input), metric.WithAttributes(attribute.String("llm.model", model),
+		attribute.String("llm.token.type", "input")))
+	counter.Add(ctx, int64(output), metric.WithAttributes(attribute.String("llm.model", model),
+		attribute.String("llm.token.type", "output")))
+}
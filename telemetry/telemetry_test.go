@@ -0,0 +1,144 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Install global providers that record spans and metrics in memory for the duration of a test.
+func record(t *testing.T) (*tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	t.Helper()
+	savedTracerProvider, savedMeterProvider := otel.GetTracerProvider(), otel.GetMeterProvider()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(savedTracerProvider)
+		otel.SetMeterProvider(savedMeterProvider)
+	})
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	return spans, reader
+}
+
+// Collect the data points of a sum metric, by the value of an attribute of each point.
+func sums(t *testing.T, reader *sdkmetric.ManualReader, name string, key attribute.Key) map[string]int64 {
+	t.Helper()
+	var metrics metricdata.ResourceMetrics
+	if err := reader.Collect(t.Context(), &metrics); err != nil {
+		t.Fatal(err)
+	}
+	points := map[string]int64{}
+	for _, scope := range metrics.ScopeMetrics {
+		for _, metric := range scope.Metrics {
+			if sum, ok := metric.Data.(metricdata.Sum[int64]); ok && metric.Name == name {
+				for _, point := range sum.DataPoints {
+					value, _ := point.Attributes.Value(key)
+					points[value.AsString()] += point.Value
+				}
+			}
+		}
+	}
+	return points
+}
+
+// Operations nest, and failed operations carry their error and count towards the error metric.
+func TestOperation(t *testing.T) {
+	spans, reader := record(t)
+	ctx, parent := Start(t.Context(), "agent.iteration")
+	_, child := Start(ctx, "tool.call", attribute.String("tool.name", "ArxivSearch"))
+	child.SetAttributes(attribute.Int("results", 3))
+	child.End(errors.New("arXiv is down"))
+	parent.End(nil)
+
+	ended := spans.Ended()
+	if len(ended) != 2 || ended[0].Name() != "tool.call" || ended[1].Name() != "agent.iteration" {
+		t.Fatalf("unexpected spans %v", ended)
+	}
+	if ended[0].Parent().SpanID() != ended[1].SpanContext().SpanID() {
+		t.Error("expected the tool call to be a child of the iteration")
+	}
+	if status := ended[0].Status(); status.Code != codes.Error || status.Description != "arXiv is down" {
+		t.Errorf("unexpected status %+v", status)
+	}
+	failures := sums(t, reader, ErrorMetric, "operation")
+	if failures["tool.call"] != 1 || failures["agent.iteration"] != 0 {
+		t.Errorf("unexpected error counts %v", failures)
+	}
+}
+
+// The handler traces LLM calls and chains, counts tokens, and records agent actions on the span of the context.
+func TestHandler(t *testing.T) {
+	spans, reader := record(t)
+	handler := NewHandler("gpt-4o-mini")
+	ctx, iteration := Start(t.Context(), "agent.iteration")
+	handler.HandleChainStart(ctx, map[string]any{"input": "agents"})
+	handler.HandleLLMGenerateContentStart(ctx, nil)
+	handler.HandleLLMGenerateContentEnd(ctx, &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		GenerationInfo: map[string]any{"PromptTokens": 120, "CompletionTokens": 30},
+	}}})
+	handler.HandleLLMGenerateContentStart(ctx, nil)
+	handler.HandleLLMError(ctx, errors.New("rate limited"))
+	handler.HandleChainEnd(ctx, map[string]any{"output": "done"})
+	handler.HandleAgentAction(ctx, schema.AgentAction{Tool: "ArxivSearch"})
+	// Ends without starts, e.g., from LLMs that started before the handler was installed, are ignored.
+	handler.HandleChainEnd(context.Background(), nil)
+	iteration.End(nil)
+
+	var names []string
+	for _, span := range spans.Ended() {
+		names = append(names, span.Name())
+	}
+	if got := strings.Join(names, " "); got != "llm.generate llm.generate chain agent.iteration" {
+		t.Errorf("unexpected spans %s", got)
+	}
+	events := spans.Ended()[3].Events()
+	if len(events) != 1 || events[0].Name != "agent.action" {
+		t.Errorf("unexpected events %v", events)
+	}
+	tokens := sums(t, reader, TokenMetric, "llm.token.type")
+	if tokens["input"] != 120 || tokens["output"] != 30 {
+		t.Errorf("unexpected token counts %v", tokens)
+	}
+	if len(handler.operations) != 0 {
+		t.Errorf("expected no operations in progress, got %v", handler.operations)
+	}
+}
+
+// The stdout exporter writes spans and metrics to its writer once telemetry shuts down.
+func TestSetup(t *testing.T) {
+	savedTracerProvider, savedMeterProvider := otel.GetTracerProvider(), otel.GetMeterProvider()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(savedTracerProvider)
+		otel.SetMeterProvider(savedMeterProvider)
+	})
+	var output bytes.Buffer
+	shutdown, err := Setup(t.Context(), Options{Exporter: StdoutExporter, Writer: &output})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, operation := Start(t.Context(), "vector.query")
+	operation.End(nil)
+	if err := shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(output.String(), `"Name":"vector.query"`) ||
+		!strings.Contains(output.String(), DurationMetric) || !strings.Contains(output.String(), ServiceName) {
+		t.Errorf("unexpected output %s", output.String())
+	}
+	if _, err := Setup(t.Context(), Options{Exporter: "zipkin"}); err == nil {
+		t.Error("expected an error for an unknown exporter")
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 )
 
@@ -14,6 +14,7 @@ var ArxivSearcher = Tool[arxivSearcherArgs]{
 	description:            arxivSearcherDescription,
 	Callback:               searchArxiv,
 	introspectionCallbacks: Logger,
+	timeout:                searchTimeout,
 }
 
 const (
@@ -33,16 +34,19 @@ Failure: Returns an error message.
 // The arguments for the [ArxivSearcher] tool. The structure and the [ArxivSearcher] tool description must remain in
 // sync with each other to ensure that agents call the tool with the correct JSON argument keys.
 type arxivSearcherArgs struct {
-	Query string `json:"query"`
-	N     int    `json:"n"`
+	Query string `json:"query" description:"The user keyword query"`
+	N     int    `json:"n" description:"The number of results to return"`
 }
 
 // Search arXiv for relevant papers to a user keyword query.
 //
 // Returns a JSON array of dictionary objects containing the title, summary, authors, and PDF download link for each
 // paper if the search is successful, otherwise returns an error message.
-func searchArxiv(_ context.Context, args arxivSearcherArgs) (string, error) {
-	rawPapers := FetchPapers(args.Query, args.N)
+func searchArxiv(ctx context.Context, args arxivSearcherArgs) (string, error) {
+	rawPapers, err := FetchPapers(ctx, args.Query, args.N)
+	if err != nil {
+		return fmt.Sprintf("failed while searching arXiv: %s", err), nil
+	}
 	cookedPapers := make([]map[string]string, len(rawPapers))
 	for i, paper := range rawPapers {
 		cookedPapers[i] = map[string]string{
@@ -57,6 +61,6 @@ func searchArxiv(_ context.Context, args arxivSearcherArgs) (string, error) {
 		return "", fmt.Errorf("failed while marshalling documents: %w", err)
 	}
 	result := string(content)
-	log.Printf("Tool returned with '%d' results.\n", len(rawPapers))
+	slog.InfoContext(ctx, "Tool returned", "results", len(rawPapers))
 	return result, nil
 }
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"tmwong.org/arxiv-researcher-go/citations"
+)
+
+// Singleton [Tool] instance to look up the papers that a paper cites.
+var PaperReferences = Tool[citationExplorerArgs]{
+	name:                   paperReferencesName,
+	description:            paperReferencesDescription,
+	Callback:               findReferences,
+	introspectionCallbacks: Logger,
+	timeout:                searchTimeout,
+}
+
+// Singleton [Tool] instance to look up the papers that cite a paper.
+var CitingPapers = Tool[citationExplorerArgs]{
+	name:                   citingPapersName,
+	description:            citingPapersDescription,
+	Callback:               findCitingPapers,
+	introspectionCallbacks: Logger,
+	timeout:                searchTimeout,
+}
+
+const (
+	paperReferencesName        = "PaperReferences"
+	paperReferencesDescription = `
+Get the references of a paper, i.e., the papers that the paper cites, from a citation database.
+
+JSON input format: { "id": "<arXiv ID or DOI of the paper>", "n": <number of results> }
+
+Success: Returns a JSON array of dictionary objects containing the title, authors, year, venue, arXiv ID, DOI, and
+citation count of each referenced paper
+
+Failure: Returns an error message.
+`
+	citingPapersName        = "CitingPapers"
+	citingPapersDescription = `
+Get the papers citing a paper from a citation database, to find follow-up work on the paper.
+
+JSON input format: { "id": "<arXiv ID or DOI of the paper>", "n": <number of results> }
+
+Success: Returns a JSON array of dictionary objects containing the title, authors, year, venue, arXiv ID, DOI, and
+citation count of each citing paper
+
+Failure: Returns an error message.
+`
+)
+
+// The arguments for the [PaperReferences] and [CitingPapers] tools. The structure and the tool descriptions must remain
+// in sync with each other to ensure that agents call the tools with the correct JSON argument keys.
+type citationExplorerArgs struct {
+	Id string `json:"id" description:"The arXiv ID or DOI of the paper"`
+	N  int    `json:"n" description:"The number of results to return"`
+}
+
+// Look up the papers that a paper cites.
+//
+// Returns a JSON array of dictionary objects describing each referenced paper if the lookup is successful, otherwise
+// returns an error message.
+func findReferences(ctx context.Context, args citationExplorerArgs) (string, error) {
+	return exploreCitations(ctx, args, citations.Client.References)
+}
+
+// Look up the papers that cite a paper.
+//
+// Returns a JSON array of dictionary objects describing each citing paper if the lookup is successful, otherwise
+// returns an error message.
+func findCitingPapers(ctx context.Context, args citationExplorerArgs) (string, error) {
+	return exploreCitations(ctx, args, citations.Client.Citations)
+}
+
+// Look up the papers related to a paper with a lookup method of the citation client.
+//
+// Returns a JSON array of dictionary objects describing each related paper if the lookup is successful, otherwise
+// returns an error message.
+func exploreCitations(
+	ctx context.Context,
+	args citationExplorerArgs,
+	lookup func(citations.Client, context.Context, citations.PaperId, int) ([]citations.Work, error),
+) (string, error) {
+	client, err := getCitationClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	works, err := lookup(client, ctx, citations.ParseId(args.Id), args.N)
+	if err != nil {
+		return fmt.Sprintf("failed while looking up citations: %s", err), nil
+	}
+	cookedWorks := make([]map[string]any, len(works))
+	for i, work := range works {
+		cookedWorks[i] = map[string]any{
+			"Title":          work.Title,
+			"Authors":        strings.Join(work.Authors, ", "),
+			"Year":           work.Year,
+			"Venue":          work.Venue,
+			"arXiv ID":       work.ArxivId,
+			"DOI":            work.Doi,
+			"Citation Count": work.CitationCount,
+		}
+	}
+	content, err := json.MarshalIndent(cookedWorks, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed while marshalling papers: %w", err)
+	}
+	slog.InfoContext(ctx, "Tool returned", "results", len(works))
+	return string(content), nil
+}
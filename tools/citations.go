@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"tmwong.org/arxiv-researcher-go/citations"
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+// The most references of each paper that we store in the index when enriching papers.
+const maxStoredReferences = 200
+
+// The scholarly database that the citation tools and paper enrichment use, either [citations.SemanticScholarSource]
+// or [citations.OpenAlexSource]. If empty, we use the database named by the `ARXIV_RESEARCHER_CITATION_SOURCE`
+// environment variable, or Semantic Scholar if the variable is not set. Commands may change the source, e.g., from
+// command-line flags.
+var CitationSource string
+
+// The client of the scholarly database that the citation tools use. If nil, the tools create a client with
+// [NewCitationClient] on first use. Tests may replace the client, e.g., with one pointing to a fake server.
+var CitationClient citations.Client
+
+// Guards the lazy creation of [CitationClient], since the agent runs the citation tools in parallel.
+var citationClientLock sync.Mutex
+
+// Create a client of a scholarly database, either [citations.SemanticScholarSource] or [citations.OpenAlexSource] (or,
+// if empty, the database named by [CitationSource]). The client sends requests with [HttpClient] to the base URL
+// named by the `SEMANTIC_SCHOLAR_API_URL` or `OPENALEX_API_URL` environment variable, or the public API if the
+// variable is not set, and authenticates with the `SEMANTIC_SCHOLAR_API_KEY` or `OPENALEX_EMAIL` environment
+// variable, if set.
+//
+// Returns the client if the source is a known database, otherwise returns an error.
+func NewCitationClient(source string) (citations.Client, error) {
+	return newCitationClient(source, HttpClient)
+}
+
+// Create a client of a scholarly database as [NewCitationClient] does, which sends requests with the given HTTP
+// client.
+//
+// Returns the client if the source is a known database, otherwise returns an error.
+func newCitationClient(source string, httpClient *http.Client) (citations.Client, error) {
+	source = cmp.Or(source, CitationSource, os.Getenv("ARXIV_RESEARCHER_CITATION_SOURCE"),
+		citations.SemanticScholarSource)
+	switch source {
+	case citations.SemanticScholarSource:
+		client := citations.NewSemanticScholar(os.Getenv("SEMANTIC_SCHOLAR_API_URL"),
+			os.Getenv("SEMANTIC_SCHOLAR_API_KEY"))
+		client.HttpClient = httpClient
+		return client, nil
+	case citations.OpenAlexSource:
+		client := citations.NewOpenAlex(os.Getenv("OPENALEX_API_URL"), os.Getenv("OPENALEX_EMAIL"))
+		client.HttpClient = httpClient
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown citation source '%s'", source)
+	}
+}
+
+// Get the client that the citation tools called with a context use: a new client that sends requests with the HTTP
+// client of the environment of the context (see [Environment]), if any, otherwise [CitationClient], creating it on
+// first use.
+//
+// Returns the client if we create it successfully, otherwise returns an error.
+func getCitationClient(ctx context.Context) (citations.Client, error) {
+	if httpClient, shared := httpClientFromContext(ctx); !shared {
+		return newCitationClient("", httpClient)
+	}
+	citationClientLock.Lock()
+	defer citationClientLock.Unlock()
+	if CitationClient == nil {
+		client, err := NewCitationClient("")
+		if err != nil {
+			return nil, err
+		}
+		CitationClient = client
+	}
+	return CitationClient, nil
+}
+
+// The outcome of enriching papers in the index with [Index.EnrichPapers].
+type EnrichResult struct {
+	// The number of papers we enriched.
+	Enriched int `json:"enriched"`
+	// The number of papers we skipped, since we enriched them before.
+	Skipped int `json:"skipped"`
+	// The arXiv IDs of the papers that the scholarly database does not hold.
+	NotFound []string `json:"not_found,omitempty"`
+}
+
+// Look up papers in the index in a scholarly database, by arXiv ID and DOI, and store their citation counts,
+// references, venues, and fields of study as extra metadata of their documents in the index. We keep the embeddings of
+// the documents as they are, since the extra metadata is not part of the embedded content. If no arXiv IDs are given,
+// we enrich every paper in the index. Unless forced, we skip papers that we enriched before, so that enriching again
+// after, e.g., hitting the rate limit of the database, picks up where we left off.
+//
+// Returns the outcome if we enrich the papers successfully, otherwise returns the outcome so far along with an error.
+func (index *Index) EnrichPapers(
+	ctx context.Context,
+	client citations.Client,
+	ids []string,
+	force bool,
+) (EnrichResult, error) {
+	var result EnrichResult
+	store, err := index.backend()
+	if err != nil {
+		return result, err
+	}
+	enrich := func(records []stores.Record) error {
+		var enriched []stores.Record
+		for _, record := range records {
+			if _, done := record.Metadata[enrichedKey]; done && !force {
+				result.Skipped++
+				continue
+			}
+			paper := paperFromRecord(record)
+			id := citations.PaperId{ArxivId: record.Id, Doi: paper.Doi}
+			work, err := client.Work(ctx, id)
+			if errors.Is(err, citations.ErrNotFound) {
+				result.NotFound = append(result.NotFound, record.Id)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed while looking up paper '%s': %w", record.Id, err)
+			}
+			references, err := client.References(ctx, id, maxStoredReferences)
+			if err != nil && !errors.Is(err, citations.ErrNotFound) {
+				return fmt.Errorf("failed while looking up references of paper '%s': %w", record.Id, err)
+			}
+			record.Metadata = enrichedMetadata(record.Metadata, work, references)
+			enriched = append(enriched, record)
+		}
+		if len(enriched) == 0 {
+			return nil
+		}
+		if err := store.Upsert(ctx, index.nameSpace(), enriched); err != nil {
+			return err
+		}
+		result.Enriched += len(enriched)
+		slog.InfoContext(ctx, "Enriched papers", "papers", result.Enriched)
+		return nil
+	}
+	if len(ids) == 0 {
+		return result, scan(ctx, store, index.nameSpace(), enrich)
+	}
+	baseIds := make([]string, len(ids))
+	for i, id := range ids {
+		baseIds[i] = BaseArxivId(id)
+	}
+	records, err := store.Fetch(ctx, index.nameSpace(), baseIds)
+	if err != nil {
+		return result, err
+	}
+	if len(records) < len(baseIds) {
+		return result, fmt.Errorf("%w: %d of %d papers", ErrPaperNotFound, len(baseIds)-len(records), len(baseIds))
+	}
+	return result, enrich(records)
+}
+
+// The metadata keys under which we store the extra metadata of enriched papers.
+const (
+	citationCountKey = "Citation Count"
+	referencesKey    = "References"
+	venueKey         = "Venue"
+	fieldsOfStudyKey = "Fields of Study"
+	enrichedKey      = "Enriched"
+)
+
+// Add the citation count, references, venue, and fields of study of a paper to a copy of the metadata of its
+// document. We store the references as a list of identifiers, e.g., "arXiv:2201.11903, DOI:10.18653/v1/N19-1423",
+// skipping references without either identifier.
+//
+// Returns the enriched metadata.
+func enrichedMetadata(metadata map[string]any, work citations.Work, references []citations.Work) map[string]any {
+	enriched := make(map[string]any, len(metadata)+5)
+	for key, value := range metadata {
+		enriched[key] = value
+	}
+	var ids []string
+	for _, reference := range references {
+		if id := reference.Id().String(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	enriched[citationCountKey] = work.CitationCount
+	enriched[referencesKey] = strings.Join(ids, ", ")
+	enriched[venueKey] = work.Venue
+	enriched[fieldsOfStudyKey] = strings.Join(work.FieldsOfStudy, ", ")
+	enriched[enrichedKey] = time.Now().UTC().Format(time.DateOnly)
+	return enriched
+}
@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"tmwong.org/arxiv-researcher-go/citations"
+)
+
+// Start a fake Semantic Scholar server that knows ReAct and Toolformer, and count the requests it serves.
+func newFakeSemanticScholar(t *testing.T, requests *atomic.Int32) citations.Client {
+	t.Helper()
+	responses := map[string]string{
+		"/paper/ARXIV:2210.03629": `{"title": "ReAct", "venue": "ICLR", "citationCount": 1500,
+			"fieldsOfStudy": ["Computer Science"]}`,
+		"/paper/ARXIV:2210.03629/references": `{"data": [
+			{"citedPaper": {"title": "Chain of Thought", "externalIds": {"ArXiv": "2201.11903"}}},
+			{"citedPaper": {"title": "BERT", "externalIds": {"DOI": "10.18653/v1/N19-1423"}}}]}`,
+		"/paper/ARXIV:2210.03629/citations": `{"data": [
+			{"citingPaper": {"title": "Reflexion", "year": 2023, "externalIds": {"ArXiv": "2303.11366"},
+				"citationCount": 900}}]}`,
+		"/paper/ARXIV:2302.04761":            `{"title": "Toolformer", "venue": "NeurIPS", "citationCount": 800}`,
+		"/paper/ARXIV:2302.04761/references": `{"data": []}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return citations.NewSemanticScholar(server.URL, "")
+}
+
+// Enriching papers stores their citation metadata, skips papers enriched before, and reports unknown papers.
+func TestEnrichPapers(t *testing.T) {
+	var requests atomic.Int32
+	client := newFakeSemanticScholar(t, &requests)
+	index := newManagedIndex(t)
+
+	result, err := index.EnrichPapers(t.Context(), client, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Enriched != 2 || result.Skipped != 0 || !slices.Equal(result.NotFound, []string{"2303.11366"}) {
+		t.Errorf("unexpected result %+v", result)
+	}
+	paper, err := index.FetchPaper(t.Context(), "2210.03629")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paper.CitationCount != 1500 || paper.Venue != "ICLR" ||
+		!slices.Equal(paper.FieldsOfStudy, []string{"Computer Science"}) ||
+		!slices.Equal(paper.References, []string{"arXiv:2201.11903", "DOI:10.18653/v1/N19-1423"}) {
+		t.Errorf("unexpected enriched paper %+v", paper)
+	}
+	if paper.Title != "ReAct" || paper.Id != "2210.03629v3" {
+		t.Errorf("enrichment lost arXiv metadata: %+v", paper)
+	}
+
+	requests.Store(0)
+	result, err = index.EnrichPapers(t.Context(), client, []string{"2210.03629v3", "2302.04761"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Enriched != 0 || result.Skipped != 2 || requests.Load() != 0 {
+		t.Errorf("unexpected result %+v after %d requests", result, requests.Load())
+	}
+	result, err = index.EnrichPapers(t.Context(), client, []string{"2302.04761"}, true)
+	if err != nil || result.Enriched != 1 {
+		t.Errorf("unexpected forced result %+v, error %v", result, err)
+	}
+}
+
+// The citation tools describe the related papers, and report unknown papers as recoverable errors.
+func TestCitationTools(t *testing.T) {
+	var requests atomic.Int32
+	CitationClient = newFakeSemanticScholar(t, &requests)
+	t.Cleanup(func() { CitationClient = nil })
+
+	result, err := CitingPapers.Call(t.Context(), `{"id": "arXiv:2210.03629v3", "n": 5}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, `"Title": "Reflexion"`) || !strings.Contains(result, `"Citation Count": 900`) {
+		t.Errorf("unexpected result %s", result)
+	}
+	result, err = PaperReferences.Call(t.Context(), `{"id": "1706.03762", "n": 5}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, "not found") {
+		t.Errorf("expected a not found message, got %s", result)
+	}
+}
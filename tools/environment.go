@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+)
+
+// The services that the tools of an agent run use, for runs that must not touch the shared ones, e.g., the runs of
+// evaluation scenarios against a knowledge database and arXiv of their own. Runs attach an environment to their
+// context with [WithEnvironment]; tools called with a context without an environment, or with an environment that
+// leaves a service out, use the shared service instead.
+type Environment struct {
+	// The index that the tools search, instead of the singleton index of [GetIndex].
+	Index *Index
+	// The HTTP client with which the tools query arXiv, download papers, and look up citations, instead of
+	// [HttpClient]. We never answer the arXiv queries of an environment from [ArxivCache].
+	HttpClient *http.Client
+	// The directory to which the tools download papers, instead of [PapersDirectory].
+	PapersDirectory string
+}
+
+type environmentKey struct{}
+
+// Attach an environment to a context, so that the tools called with the context use the services of the environment.
+//
+// Returns the context with the environment.
+func WithEnvironment(ctx context.Context, environment *Environment) context.Context {
+	return context.WithValue(ctx, environmentKey{}, environment)
+}
+
+// Get the environment attached to a context.
+//
+// Returns the environment, or an empty environment if the context has none.
+func environmentOf(ctx context.Context) *Environment {
+	if environment, ok := ctx.Value(environmentKey{}).(*Environment); ok && environment != nil {
+		return environment
+	}
+	return &Environment{}
+}
+
+// Get the index that tools called with a context use: the index of the environment of the context, if any, otherwise
+// the singleton index (see [GetIndex]).
+//
+// Returns the index if we connect to it successfully, otherwise returns an error.
+func IndexFromContext(ctx context.Context) (*Index, error) {
+	if environment := environmentOf(ctx); environment.Index != nil {
+		return environment.Index, nil
+	}
+	return GetIndex(ctx)
+}
+
+// Get the HTTP client that tools called with a context use: the client of the environment of the context, if any,
+// otherwise the shared [HttpClient], which alone may answer arXiv queries from [ArxivCache].
+//
+// Returns the client, along with whether it is the shared client.
+func httpClientFromContext(ctx context.Context) (*http.Client, bool) {
+	if environment := environmentOf(ctx); environment.HttpClient != nil {
+		return environment.HttpClient, false
+	}
+	return HttpClient, true
+}
+
+// Get the directory to which tools called with a context download papers.
+//
+// Returns the directory.
+func papersDirectoryFromContext(ctx context.Context) string {
+	return cmp.Or(environmentOf(ctx).PapersDirectory, PapersDirectory)
+}
@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+// A paper along with the embedding of its document, as the index would store it.
+type EmbeddedPaper struct {
+	Paper     Paper
+	Embedding []float32
+}
+
+// Returned when the index has no embedder of its own with which to embed papers it does not hold.
+var ErrNoEmbedder = errors.New("index has no embedder")
+
+// Gather papers on a topic, e.g., for a literature review: up to the given number of papers from the index, most
+// relevant first, followed by up to the given number of papers from arXiv that the index does not hold. We reuse the
+// stored embeddings of the papers from the index, and embed the papers from arXiv as we would if we added them to the
+// index, without adding them, so that every paper shares the same vector space.
+//
+// Returns the papers along with their embeddings if we gather them successfully, otherwise returns an error.
+func (index *Index) GatherPapers(ctx context.Context, topic string, indexCount int, arxivCount int) (
+	[]EmbeddedPaper,
+	error,
+) {
+	store, err := index.backend()
+	if err != nil {
+		return nil, err
+	}
+	var gathered []EmbeddedPaper
+	seen := map[string]bool{}
+	if indexCount > 0 {
+		papers, err := index.SearchPapers(ctx, topic, indexCount)
+		if err != nil {
+			return nil, err
+		}
+		ids := make([]string, len(papers))
+		for i, paper := range papers {
+			ids[i] = BaseArxivId(paper.Id)
+		}
+		records, err := store.Fetch(ctx, index.nameSpace(), ids)
+		if err != nil {
+			return nil, err
+		}
+		vectors := make(map[string][]float32, len(records))
+		for _, record := range records {
+			vectors[record.Id] = record.Values
+		}
+		for i, paper := range papers {
+			if vector, ok := vectors[ids[i]]; ok && !seen[ids[i]] {
+				seen[ids[i]] = true
+				gathered = append(gathered, EmbeddedPaper{Paper: paper, Embedding: vector})
+			}
+		}
+	}
+	if arxivCount > 0 {
+		papers, err := FetchPapers(ctx, topic, arxivCount)
+		if err != nil {
+			return nil, err
+		}
+		var fresh []Paper
+		var contents []string
+		for _, paper := range papers {
+			if id := BaseArxivId(paper.Id); !seen[id] && paper.Title != "" {
+				seen[id] = true
+				fresh = append(fresh, paper)
+				contents = append(contents, paperContent(paper))
+			}
+		}
+		if len(fresh) > 0 {
+			if index.embedder == nil {
+				return nil, ErrNoEmbedder
+			}
+			vectors, err := index.embedder.EmbedDocuments(ctx, contents)
+			if err != nil {
+				return nil, fmt.Errorf("failed while embedding papers: %w", err)
+			}
+			for i, paper := range fresh {
+				gathered = append(gathered, EmbeddedPaper{Paper: paper, Embedding: vectors[i]})
+			}
+		}
+	}
+	return gathered, nil
+}
+
+// A paper along with its relevance to a query.
+type RankedPaper struct {
+	Paper Paper `json:"paper"`
+	// The cosine similarity of the embeddings of the paper and the query.
+	Score float64 `json:"score"`
+}
+
+// Rank papers in the index by the similarity of their stored embeddings to the embedding of a query, e.g., to order
+// new papers on a topic. We skip papers that the index does not hold.
+//
+// Returns the papers, most relevant first, if we rank them successfully, otherwise returns an error.
+func (index *Index) RankPapers(ctx context.Context, query string, papers []Paper) ([]RankedPaper, error) {
+	store, err := index.backend()
+	if err != nil {
+		return nil, err
+	}
+	if index.embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	if len(papers) == 0 {
+		return []RankedPaper{}, nil
+	}
+	queryVector, err := index.embedder.EmbedQuery(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed while embedding query: %w", err)
+	}
+	ids := make([]string, len(papers))
+	for i, paper := range papers {
+		ids[i] = BaseArxivId(paper.Id)
+	}
+	records, err := store.Fetch(ctx, index.nameSpace(), ids)
+	if err != nil {
+		return nil, err
+	}
+	vectors := make(map[string][]float32, len(records))
+	for _, record := range records {
+		vectors[record.Id] = record.Values
+	}
+	ranked := []RankedPaper{}
+	for i, paper := range papers {
+		if vector, ok := vectors[ids[i]]; ok {
+			ranked = append(ranked, RankedPaper{Paper: paper, Score: float64(stores.Cosine(queryVector, vector))})
+		}
+	}
+	slices.SortStableFunc(ranked, func(x, y RankedPaper) int {
+		return cmp.Compare(y.Score, x.Score)
+	})
+	return ranked, nil
+}
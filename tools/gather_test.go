@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"tmwong.org/arxiv-researcher-go/fakes"
+)
+
+// Papers from the index keep their stored embeddings, and papers from arXiv that the index does not hold are embedded.
+func TestGatherPapers(t *testing.T) {
+	savedClient := HttpClient
+	t.Cleanup(func() { HttpClient = savedClient })
+	HttpClient = &http.Client{Transport: &fixtureTransport{status: http.StatusOK, fixture: "unicode_latex.atom"}}
+
+	managedIndex := newManagedIndex(t)
+	if _, err := managedIndex.GatherPapers(t.Context(), "agents", 2, 5); !errors.Is(err, ErrNoEmbedder) {
+		t.Errorf("expected ErrNoEmbedder, got %v", err)
+	}
+	managedIndex.embedder = fakes.NewEmbedder()
+	papers, err := managedIndex.GatherPapers(t.Context(), "agents", 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(papers) != 3 || papers[2].Paper.Id != "2301.01234v2" {
+		t.Fatalf("expected 2 papers from the index and 1 from arXiv, got %+v", papers)
+	}
+	store, err := managedIndex.backend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := store.Fetch(t.Context(), "", []string{BaseArxivId(papers[0].Paper.Id)})
+	if err != nil || len(records) != 1 || !slices.Equal(records[0].Values, papers[0].Embedding) {
+		t.Errorf("expected the stored embedding of %s, error %v", papers[0].Paper.Id, err)
+	}
+	if len(papers[2].Embedding) != fakes.DefaultDimension {
+		t.Errorf("expected an embedding of the paper from arXiv, got %v", papers[2].Embedding)
+	}
+}
+
+// Papers rank by the similarity of their stored embeddings to the query.
+func TestRankPapers(t *testing.T) {
+	managedIndex := newManagedIndex(t)
+	managedIndex.embedder = fakes.NewEmbedder()
+	papers := slices.Clone(managedPapers)
+	papers = append(papers, Paper{Id: "2401.00001v1", Title: "Not indexed"})
+	ranked, err := managedIndex.RankPapers(t.Context(), "Reflexion", papers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranked) != 3 || ranked[0].Paper.Title != "Reflexion" || ranked[0].Score <= ranked[1].Score {
+		t.Errorf("unexpected ranking %+v", ranked)
+	}
+}
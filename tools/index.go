@@ -1,65 +1,540 @@
 package tools
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/tmc/langchaingo/embeddings"
 	"github.com/tmc/langchaingo/schema"
-	"github.com/tmc/langchaingo/vectorstores/pinecone"
+	"github.com/tmc/langchaingo/vectorstores"
+	"tmwong.org/arxiv-researcher-go/cache"
 	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/stores"
+	"tmwong.org/arxiv-researcher-go/telemetry"
 )
 
-// Represents a connection to a Pinecone index that holds documents for a RAG-based chatbot agent.
+// Represents a connection to the document index that holds documents for a RAG-based chatbot agent.
 type Index struct {
-	context context.Context
-	store   pinecone.Store
+	store          vectorstores.VectorStore
+	embeddingCache *cache.EmbeddingCache
+	// The embedder for the embedding model of the index, or nil to use the embedder of the store.
+	embedder embeddings.Embedder
+	// The manifest of the index, or nil if the index has none (yet).
+	manifest *stores.Manifest
 }
 
 // Singleton [Index] connection instance used by a chatbot agent.
 var index *Index = nil
 
-// Get the singleton [Index] connection. On the first call, we open a new connection to Pinecone and attach a document
-// embedder that computes vector representations of (text) documents for use when indexing documents for storage and
-// retrieval. On subsequent calls, we return the existing connection.
+// Guards the singleton [Index] connection, since the agent runs tools in parallel and two tools may connect to the
+// index at once on first use.
+var indexLock sync.Mutex
+
+// The names of the vector store backends that may hold the document index.
+const (
+	// Keep the index in Pinecone.
+	PineconeBackend = "pinecone"
+	// Keep the index in a database file in the data directory, for development without a Pinecone account.
+	LocalBackend = "local"
+)
+
+// The file name of the embedding cache database in the cache directory.
+const EmbeddingCacheFileName = "embeddings.db"
+
+// The file name of the local backend database in the data directory.
+const LocalIndexFileName = "index.db"
+
+// Options for connecting to the document index.
+type IndexOptions struct {
+	// The vector store backend that holds the index, either [PineconeBackend] or [LocalBackend]. If empty, we use the
+	// backend named by the `ARXIV_RESEARCHER_BACKEND` environment variable, or Pinecone if the variable is not set.
+	Backend string
+	// The namespace of the index that holds the documents. If empty, we use the namespace named by the
+	// `PINECONE_NAME_SPACE` environment variable, or the default namespace if the variable is not set.
+	NameSpace string
+	// The embedding model that the index must use. If empty, we use the model named by the `OPENAI_EMBEDDING_MODEL`
+	// environment variable, or if the variable is not set, the model recorded in the manifest of the index, or
+	// [constants.EmbeddingModel] for an index without a manifest.
+	EmbeddingModel string
+	// Whether to connect with the model recorded in the manifest of the index even if the embedding model above asks
+	// for another, e.g., to re-index the documents with the model asked for.
+	AnyEmbeddingModel bool
+}
+
+// Fill in the defaults of any empty options from the environment.
+//
+// Returns the options with defaults filled in.
+func (options IndexOptions) Resolve() IndexOptions {
+	return IndexOptions{
+		Backend:           cmp.Or(options.Backend, os.Getenv("ARXIV_RESEARCHER_BACKEND"), PineconeBackend),
+		NameSpace:         cmp.Or(options.NameSpace, os.Getenv("PINECONE_NAME_SPACE")),
+		EmbeddingModel:    cmp.Or(options.EmbeddingModel, os.Getenv("OPENAI_EMBEDDING_MODEL")),
+		AnyEmbeddingModel: options.AnyEmbeddingModel,
+	}
+}
+
+// The options [GetIndex] uses when it first connects to the index. Commands may change the options, e.g., from
+// command-line flags, before they first call [GetIndex].
+var IndexConfig IndexOptions
+
+// Get the singleton [Index] connection. On the first call, we open a new connection to the configured backend and
+// attach a document embedder that computes vector representations of (text) documents for use when indexing documents
+// for storage and retrieval. The embedder uses the embedding model recorded in the manifest of the index, so that
+// queries and documents share a vector space, and we refuse to connect if the configuration asks for a different model
+// (unless it accepts any model). If the manifest points to another namespace that holds the documents (e.g., after
+// re-indexing), we use that namespace. The embedder answers from the on-disk embedding cache where it can, so that we
+// never pay twice to embed the same text with the same model. If we cannot open the cache, e.g., because another
+// process holds it, we carry on without it. On subsequent calls, we return the existing connection. Cancelling the
+// context of the first call cancels reading the manifest.
 //
 // Returns the singleton connection if it exists, otherwise returns an error.
-func GetIndex() (*Index, error) {
+func GetIndex(ctx context.Context) (*Index, error) {
+	indexLock.Lock()
+	defer indexLock.Unlock()
+	if index != nil {
+		return index, nil
+	}
+	if err := constants.Ready(); err != nil {
+		return nil, err
+	}
+	connection := &Index{}
+	if embeddingCache, err := OpenEmbeddingCache(); err != nil {
+		slog.Warn("Continuing without embedding cache", "error", err)
+	} else {
+		connection.embeddingCache = embeddingCache
+	}
+	config := IndexConfig.Resolve()
+	// We learn the embedding model from the manifest of the index, so we open the backend without an embedder, and
+	// pass the embedder with every request instead.
+	store, err := openStore(config)
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	connection.store = store
+	manifest, err := store.Manifest(ctx, "")
+	if err != nil {
+		connection.Close()
+		return nil, err
+	}
+	model := cmp.Or(config.EmbeddingModel, constants.EmbeddingModel)
+	if manifest != nil {
+		mismatch := config.EmbeddingModel != "" && config.EmbeddingModel != manifest.EmbeddingModel
+		if mismatch && !config.AnyEmbeddingModel {
+			connection.Close()
+			return nil, fmt.Errorf("%w: the index holds embeddings from '%s', but the configuration asks for '%s'; "+
+				"re-index the documents to change models", ErrEmbeddingModelMismatch, manifest.EmbeddingModel,
+				config.EmbeddingModel)
+		}
+		model = manifest.EmbeddingModel
+		connection.manifest = manifest
+	}
+	if connection.embedder, err = connection.newEmbedder(model); err != nil {
+		connection.Close()
+		return nil, err
+	}
+	index = connection
+	return index, nil
+}
+
+// Create an embedder for an embedding model, which answers from the embedding cache of the index, if any, and traces
+// the requests that the cache cannot answer and accounts for their tokens.
+//
+// Returns the embedder if we create it successfully, otherwise returns an error.
+func (index *Index) newEmbedder(model string) (embeddings.Embedder, error) {
+	client, err := EmbedderClientFor(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed while creating embedder client for '%s': %w", model, err)
+	}
+	base, err := embeddings.NewEmbedder(client)
+	if err != nil {
+		return nil, fmt.Errorf("failed while creating embedder: %w", err)
+	}
+	var embedder embeddings.Embedder = instrumentedEmbedder{Embedder: base, model: model}
+	if index.embeddingCache != nil {
+		return index.embeddingCache.Embedder(embedder, model), nil
+	}
+	return embedder, nil
+}
+
+// Get the client that computes embeddings with an embedding model: the shared [constants.EmbedderClient] for
+// [constants.EmbeddingModel], otherwise a new OpenAI client. Tests replace the function to embed with fakes.
+var EmbedderClientFor = func(model string) (embeddings.EmbedderClient, error) {
+	if model == constants.EmbeddingModel {
+		return constants.EmbedderClient, nil
+	}
+	return constants.NewOpenAIEmbedderClient(model)
+}
+
+// Open the backend named by a set of (resolved) options, without an embedder.
+//
+// Returns the backend if we open it successfully, otherwise returns an error.
+func openStore(config IndexOptions) (stores.Store, error) {
+	var store stores.Store
+	var err error
+	switch config.Backend {
+	case PineconeBackend:
+		store, err = stores.NewPinecone(
+			os.Getenv("PINECONE_API_KEY"),
+			os.Getenv("PINECONE_HOST_NAME"),
+			nil,
+			config.NameSpace,
+		)
+	case LocalBackend:
+		store, err = openLocalStore(config.NameSpace)
+	default:
+		err = fmt.Errorf("unknown index backend '%s'", config.Backend)
+	}
+	if err != nil {
+		// Avoid returning a typed nil pointer as a non-nil interface.
+		return nil, err
+	}
+	return store, nil
+}
+
+// Open the local backend database in the data directory, creating the directory if necessary.
+//
+// Returns the store if we open it successfully, otherwise returns an error.
+func openLocalStore(nameSpace string) (*stores.Local, error) {
+	directory, err := constants.DataDirectory()
+	if err != nil {
+		return nil, fmt.Errorf("failed while locating data directory: %w", err)
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed while creating data directory: %w", err)
+	}
+	return stores.OpenLocal(filepath.Join(directory, LocalIndexFileName), nil, nameSpace)
+}
+
+// Open the embedding cache database in the cache directory, creating the directory if necessary. [GetIndex] opens the
+// cache itself; commands open the cache directly only to inspect or prune it without connecting to the index.
+//
+// Returns the cache if we open it successfully, otherwise returns an error.
+func OpenEmbeddingCache() (*cache.EmbeddingCache, error) {
+	directory, err := constants.CacheDirectory()
+	if err != nil {
+		return nil, fmt.Errorf("failed while locating cache directory: %w", err)
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed while creating cache directory: %w", err)
+	}
+	return cache.OpenEmbeddingCache(filepath.Join(directory, EmbeddingCacheFileName))
+}
+
+// Create an [Index] connection backed by the given vector store, which embeds documents with the embedder of the
+// store, e.g., an index of its own for a sandboxed run (see [Environment]).
+//
+// Returns the connection.
+func NewIndex(store vectorstores.VectorStore) *Index {
+	return &Index{
+		store: store,
+	}
+}
+
+// Replace the singleton [Index] connection with one backed by the given vector store, e.g., a fake in-memory store
+// for testing.
+func SetIndex(store vectorstores.VectorStore) {
+	indexLock.Lock()
+	defer indexLock.Unlock()
+	index = NewIndex(store)
+}
+
+// Close the singleton [Index] connection, if any, along with its embedding cache. The next call to [GetIndex] opens a
+// new connection.
+//
+// Returns nil if we close the connection successfully, otherwise returns an error.
+func CloseIndex() error {
+	indexLock.Lock()
+	defer indexLock.Unlock()
 	if index == nil {
-		var err error
-		index = &Index{}
-		embedder, err := embeddings.NewEmbedder(constants.Llm)
+		return nil
+	}
+	err := index.Close()
+	index = nil
+	return err
+}
+
+// Close the connection to the index, along with its embedding cache.
+//
+// Returns nil if we close the connection successfully, otherwise returns an error.
+func (index *Index) Close() error {
+	var errs []error
+	if store, ok := index.store.(stores.Store); ok {
+		errs = append(errs, store.Close())
+	}
+	if index.embeddingCache != nil {
+		errs = append(errs, index.embeddingCache.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Get the embedding cache of the index.
+//
+// Returns the cache, or nil if the index runs without a cache.
+func (index *Index) EmbeddingCache() *cache.EmbeddingCache {
+	return index.embeddingCache
+}
+
+// Get the backend of the index, for operations beyond adding and searching documents.
+//
+// Returns the backend if it supports management operations, otherwise returns an error.
+func (index *Index) backend() (stores.Store, error) {
+	if store, ok := index.store.(stores.Store); ok {
+		return store, nil
+	}
+	return nil, ErrUnmanagedIndex
+}
+
+// Get the namespace of the backend that holds the documents of the index.
+//
+// Returns the namespace, or an empty string for the default namespace of the backend.
+func (index *Index) nameSpace() string {
+	if index.manifest != nil {
+		return index.manifest.NameSpace
+	}
+	return ""
+}
+
+// Get the options with which we add documents to, and search, the backend: the namespace that holds the documents, and
+// the embedder for the embedding model of the index.
+//
+// Returns the options.
+func (index *Index) options() []vectorstores.Option {
+	var options []vectorstores.Option
+	if nameSpace := index.nameSpace(); nameSpace != "" {
+		options = append(options, vectorstores.WithNameSpace(nameSpace))
+	}
+	if index.embedder != nil {
+		options = append(options, vectorstores.WithEmbedder(index.embedder))
+	}
+	return options
+}
+
+// Get the embedding model of the index.
+//
+// Returns the model recorded in the manifest of the index, or the model with which we embed documents for an index
+// without a manifest.
+func (index *Index) EmbeddingModel() string {
+	if index.manifest != nil {
+		return index.manifest.EmbeddingModel
+	}
+	return cmp.Or(IndexConfig.Resolve().EmbeddingModel, constants.EmbeddingModel)
+}
+
+// Get the manifest of the index.
+//
+// Returns the manifest, or nil if the index has none yet.
+func (index *Index) Manifest() *stores.Manifest {
+	return index.manifest
+}
+
+// Record the embedding model and dimension of the index in its manifest, if the index has no manifest yet, e.g.,
+// because we just added its first documents, or it predates manifests. We learn the dimension from one of the documents
+// we just added.
+//
+// Returns nil if we record the manifest successfully (or need not record it), otherwise returns an error.
+func (index *Index) recordManifest(ctx context.Context, ids []string) error {
+	store, ok := index.store.(stores.Store)
+	if index.manifest != nil || !ok || len(ids) == 0 {
+		return nil
+	}
+	records, err := store.Fetch(ctx, "", ids[:1])
+	if err != nil || len(records) == 0 {
+		return err
+	}
+	manifest := stores.Manifest{
+		EmbeddingModel: index.EmbeddingModel(),
+		Dimension:      len(records[0].Values),
+		Updated:        time.Now().UTC(),
+	}
+	if err := store.SetManifest(ctx, "", manifest); err != nil {
+		return err
+	}
+	index.manifest = &manifest
+	return nil
+}
+
+// Returned when the embedding model of the index differs from the model that computed some embeddings, e.g., those of
+// a snapshot, or the model that the configuration asks for.
+var ErrEmbeddingModelMismatch = errors.New("embedding model mismatch")
+
+// Returned when the backend of the index does not support an operation beyond adding and searching documents, e.g.,
+// because a test wraps the backend in a recording vector store.
+var ErrUnmanagedIndex = errors.New("index backend does not support management operations")
+
+// Count the papers in the index.
+//
+// Returns the number of papers if we count them successfully, otherwise returns an error.
+func (index *Index) Count(ctx context.Context) (int, error) {
+	store, err := index.backend()
+	if err != nil {
+		return 0, err
+	}
+	return store.Count(ctx, index.nameSpace())
+}
+
+// Delete papers from the index by arXiv ID. We ignore any version suffixes of the IDs, since the index holds at most
+// one version of each paper, and skip IDs of papers that the index does not hold. In a dry run, we only count the
+// papers that we would delete.
+//
+// Returns the number of papers deleted if we delete them successfully, otherwise returns an error.
+func (index *Index) DeletePapers(ctx context.Context, ids []string, dryRun bool) (int, error) {
+	store, err := index.backend()
+	if err != nil {
+		return 0, err
+	}
+	baseIds := make([]string, len(ids))
+	for i, id := range ids {
+		baseIds[i] = BaseArxivId(id)
+	}
+	records, err := store.Fetch(ctx, index.nameSpace(), baseIds)
+	if err != nil {
+		return 0, err
+	}
+	present := make([]string, len(records))
+	for i, record := range records {
+		present[i] = record.Id
+	}
+	if dryRun {
+		return len(present), nil
+	}
+	if err := store.Delete(ctx, index.nameSpace(), present); err != nil {
+		return 0, err
+	}
+	return len(present), nil
+}
+
+// Search the index for papers relevant to a query.
+//
+// Returns up to the given number of papers, most relevant first, if the search succeeds, otherwise returns an error.
+func (index *Index) SearchPapers(ctx context.Context, query string, count int) ([]Paper, error) {
+	ctx, operation := telemetry.Start(ctx, "vector.query")
+	documents, err := index.store.SimilaritySearch(ctx, query, count, index.options()...)
+	operation.End(err)
+	if err != nil {
+		return nil, fmt.Errorf("failed while searching index: %w", err)
+	}
+	papers := make([]Paper, len(documents))
+	for i, document := range documents {
+		papers[i] = paperFromMetadata(document.Metadata)
+		papers[i].Summary = summaryFromContent(document.PageContent)
+	}
+	return papers, nil
+}
+
+// The factor by which we grow the number of neighbors of a seed paper that we fetch while too few of them pass a paper
+// filter, since backends cannot apply paper filters themselves.
+const similarCandidateFactor = 5
+
+// The most neighbors of a seed paper that we fetch, since Pinecone caps the number of matches of a query.
+const maxSimilarCandidates = 10000
+
+// Find the papers in the index most similar to a seed paper, given by arXiv ID, excluding the seed paper itself. If the
+// index holds the seed paper, we use its stored embedding as the query vector, so that we need not embed anything;
+// otherwise we fetch the seed paper from arXiv and embed it as we would if we added it to the index. If the filter is
+// not empty, we return only neighbors that pass the filter, fetching a few times as many neighbors at a time until
+// enough of them pass the filter or the index runs out of neighbors.
+//
+// Returns up to the given number of papers, most similar first, if the search succeeds, otherwise returns an error.
+func (index *Index) SimilarPapers(ctx context.Context, id string, count int, filter PaperFilter) ([]Paper, error) {
+	seed := BaseArxivId(id)
+	search, err := index.neighbors(ctx, seed)
+	if err != nil {
+		return nil, err
+	}
+	for candidates := count + 1; ; candidates = min(candidates*similarCandidateFactor, maxSimilarCandidates) {
+		documents, err := search(candidates)
 		if err != nil {
-			return nil, fmt.Errorf("failed while creating embedder: %w", err)
+			return nil, err
 		}
-		index.store, err = pinecone.New(
-			pinecone.WithAPIKey(os.Getenv(("PINECONE_API_KEY"))),
-			pinecone.WithHost(os.Getenv("PINECONE_HOST_NAME")),
-			pinecone.WithEmbedder(embedder),
-			pinecone.WithNameSpace(os.Getenv("PINECONE_NAME_SPACE")),
-		)
+		papers := []Paper{}
+		for _, document := range documents {
+			paper := paperFromMetadata(document.Metadata)
+			paper.Summary = summaryFromContent(document.PageContent)
+			if BaseArxivId(paper.Id) == seed || !filter.Matches(paper) {
+				continue
+			}
+			papers = append(papers, paper)
+			if len(papers) == count {
+				break
+			}
+		}
+		if len(papers) == count || len(documents) < candidates || candidates == maxSimilarCandidates {
+			return papers, nil
+		}
+	}
+}
+
+// Prepare to find the documents in the index most similar to a seed paper, given by arXiv ID without a version
+// suffix, which we look up in the index or, failing that, on arXiv, only once however many searches follow.
+//
+// Returns a function that searches for up to a given number of documents, most similar first, which may include the
+// document of the seed paper, if we find the seed paper, otherwise returns an error.
+func (index *Index) neighbors(ctx context.Context, seed string) (func(count int) ([]schema.Document, error), error) {
+	if store, err := index.backend(); err == nil {
+		records, err := store.Fetch(ctx, index.nameSpace(), []string{seed})
 		if err != nil {
-			return nil, fmt.Errorf("failed while connecting to Pinecone: %w", err)
+			return nil, err
+		}
+		if len(records) > 0 {
+			return func(count int) ([]schema.Document, error) {
+				ctx, operation := telemetry.Start(ctx, "vector.query")
+				documents, err := store.SimilaritySearchByVector(ctx, records[0].Values, count, index.options()...)
+				operation.End(err)
+				if err != nil {
+					return nil, fmt.Errorf("failed while searching index: %w", err)
+				}
+				return documents, nil
+			}, nil
 		}
-		index.context = context.Background()
 	}
-	return index, nil
+	papers, err := FetchPapersById(ctx, []string{seed})
+	if err != nil {
+		return nil, err
+	}
+	if len(papers) == 0 || papers[0].Title == "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPaper, seed)
+	}
+	return func(count int) ([]schema.Document, error) {
+		ctx, operation := telemetry.Start(ctx, "vector.query")
+		documents, err := index.store.SimilaritySearch(ctx, paperContent(papers[0]), count, index.options()...)
+		operation.End(err)
+		if err != nil {
+			return nil, fmt.Errorf("failed while searching index: %w", err)
+		}
+		return documents, nil
+	}, nil
 }
 
+// Returned when neither the index nor arXiv holds a paper with a requested arXiv ID.
+var ErrUnknownPaper = errors.New("paper not found in index or on arXiv")
+
 // Add a set of papers to the document index. We treat the concatenated title and summary of each paper as the
-// document to index, and the metadata of each paper as the metadata of that document.
+// document to index, and the metadata of each paper as the metadata of that document. We identify each document by the
+// arXiv ID of its paper without the version suffix, so that adding a newer version of a paper replaces the older one.
+// Adding a paper the index holds keeps the metadata of its document that we write elsewhere, e.g., its structured
+// summary and citation metadata. Cancelling the context cancels any in-flight embedding and vector store requests.
 //
 // Returns nil if we add the papers successfully, otherwise returns an error.
-func (index *Index) AddPapers(papers []Paper) error {
+func (index *Index) AddPapers(ctx context.Context, papers []Paper) error {
+	held, err := index.heldMetadata(ctx, papers)
+	if err != nil {
+		return err
+	}
 	documents := make([]schema.Document, len(papers))
 	for i, paper := range papers {
-		content := make([]string, 2)
-		content[0] = fmt.Sprintf("Title: {%s}", paper.Title)
-		content[1] = fmt.Sprintf("Summary: {%s}", paper.Summary)
 		documents[i] = schema.Document{
 			Metadata: map[string]any{
+				stores.IdKey:        BaseArxivId(paper.Id),
 				"Title":             paper.Title,
 				"Authors":           strings.Join(paper.Authors, ", "),
 				"Published":         paper.Published,
@@ -70,9 +545,121 @@ func (index *Index) AddPapers(papers []Paper) error {
 				"PDF URL":           paper.PdfUrl,
 				"arxiv URL":         paper.ArxivUrl,
 			},
-			PageContent: strings.Join(content, "\n"),
+			PageContent: paperContent(paper),
+		}
+		for key, value := range held[BaseArxivId(paper.Id)] {
+			if _, ok := documents[i].Metadata[key]; !ok {
+				documents[i].Metadata[key] = value
+			}
 		}
 	}
-	_, err := index.store.AddDocuments(index.context, documents)
-	return err
+	ids, err := index.store.AddDocuments(ctx, documents, index.options()...)
+	if err != nil {
+		return err
+	}
+	return index.recordManifest(ctx, ids)
+}
+
+// Get the metadata of the documents that the index holds for a set of papers. Indexes whose backend we do not manage
+// cannot fetch documents, so they hold none.
+//
+// Returns the metadata of each document by the arXiv ID of its paper without the version suffix, or an error if we
+// fail to fetch the documents.
+func (index *Index) heldMetadata(ctx context.Context, papers []Paper) (map[string]map[string]any, error) {
+	held := map[string]map[string]any{}
+	store, err := index.backend()
+	if err != nil || len(papers) == 0 {
+		return held, nil
+	}
+	ids := make([]string, len(papers))
+	for i, paper := range papers {
+		ids[i] = BaseArxivId(paper.Id)
+	}
+	records, err := store.Fetch(ctx, index.nameSpace(), ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed while fetching indexed papers: %w", err)
+	}
+	for _, record := range records {
+		held[record.Id] = record.Metadata
+	}
+	return held, nil
+}
+
+// Get the content of the document of a paper in the index, i.e., the text that we embed: the concatenated title and
+// summary of the paper.
+//
+// Returns the content.
+func paperContent(paper Paper) string {
+	content := make([]string, 2)
+	content[0] = fmt.Sprintf("Title: {%s}", paper.Title)
+	content[1] = fmt.Sprintf("Summary: {%s}", paper.Summary)
+	return strings.Join(content, "\n")
+}
+
+// Reconstruct a paper from the metadata of its document in the index.
+//
+// Returns the paper.
+func paperFromMetadata(metadata map[string]any) Paper {
+	field := func(key string) string {
+		if value, ok := metadata[key].(string); ok {
+			return value
+		}
+		return ""
+	}
+	list := func(key string) []string {
+		if value := field(key); value != "" {
+			return strings.Split(value, ", ")
+		}
+		return nil
+	}
+	// Backends return numbers as float64 once they round-trip through JSON or protocol buffers.
+	number := func(key string) int {
+		switch value := metadata[key].(type) {
+		case float64:
+			return int(value)
+		case int:
+			return value
+		}
+		return 0
+	}
+	var summary *PaperSummary
+	if value := field(structuredSummaryKey); value != "" {
+		summary = &PaperSummary{}
+		if json.Unmarshal([]byte(value), summary) != nil {
+			summary = nil
+		}
+	}
+	// The ID entry holds the arXiv ID without its version suffix, which the arXiv URL adds if it names the same paper.
+	id := field(stores.IdKey)
+	if urlId := arxivId(field("arxiv URL")); id == "" || BaseArxivId(urlId) == id {
+		id = urlId
+	}
+	return Paper{
+		Id:                id,
+		Title:             field("Title"),
+		Authors:           list("Authors"),
+		Published:         field("Published"),
+		JournalReference:  field("Journal Reference"),
+		Doi:               field("DOI"),
+		PrimaryCategory:   field("Primary Category"),
+		Categories:        list("Categories"),
+		PdfUrl:            field("PDF URL"),
+		ArxivUrl:          field("arxiv URL"),
+		CitationCount:     number(citationCountKey),
+		Venue:             field(venueKey),
+		FieldsOfStudy:     list(fieldsOfStudyKey),
+		References:        list(referencesKey),
+		StructuredSummary: summary,
+	}
+}
+
+// Extract the summary of a paper from the content of its document in the index, as written by [Index.AddPapers].
+//
+// Returns the summary, or an empty string if the content holds no summary.
+func summaryFromContent(content string) string {
+	_, summary, found := strings.Cut(content, "\nSummary: {")
+	if !found {
+		return ""
+	}
+	return strings.TrimSuffix(summary, "}")
 }
@@ -0,0 +1,320 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+// The number of documents we list and fetch at a time when scanning a namespace of the index.
+const scanPageSize = 100
+
+// Returned when the index holds no paper with a requested arXiv ID.
+var ErrPaperNotFound = errors.New("paper not found in index")
+
+// A filter that selects papers in the index by category and publication date. The zero filter selects every paper.
+type PaperFilter struct {
+	// If not empty, select only papers listed under this arXiv category (e.g., "cs.CL"), as either their primary
+	// category or a cross-listing.
+	Category string
+	// If not empty, select only papers published on or after this date, in the form "2006-01-02".
+	Since string
+	// If not empty, select only papers published on or before this date, in the form "2006-01-02".
+	Until string
+}
+
+// Check whether the filter selects no papers beyond those the zero filter selects.
+//
+// Returns true if the filter is empty, otherwise returns false.
+func (filter PaperFilter) IsEmpty() bool {
+	return filter == PaperFilter{}
+}
+
+// Check whether a paper passes the filter.
+//
+// Returns true if the filter selects the paper, otherwise returns false.
+func (filter PaperFilter) Matches(paper Paper) bool {
+	if filter.Category != "" && paper.PrimaryCategory != filter.Category &&
+		!slices.Contains(paper.Categories, filter.Category) {
+		return false
+	}
+	published := paper.Published[:min(len(paper.Published), len("2006-01-02"))]
+	if filter.Since != "" && published < filter.Since {
+		return false
+	}
+	if filter.Until != "" && published > filter.Until {
+		return false
+	}
+	return true
+}
+
+// Statistics about the papers in a namespace of the index.
+type IndexStats struct {
+	// The number of papers.
+	Papers int `json:"papers"`
+	// The number of papers listed under each arXiv category, counting cross-listings.
+	ByCategory map[string]int `json:"by_category"`
+	// The number of papers published in each year.
+	ByYear map[string]int `json:"by_year"`
+}
+
+// List a page of the papers in the index, in the order of the backend, starting at the page that a pagination token
+// from an earlier call points to, or at the first page if the token is empty.
+//
+// Returns up to the given number of papers, along with the token for the next page (or an empty string if this is
+// the last page), if we list the papers successfully, otherwise returns an error.
+func (index *Index) ListPapers(ctx context.Context, limit int, token string) ([]Paper, string, error) {
+	store, err := index.backend()
+	if err != nil {
+		return nil, "", err
+	}
+	page, err := store.List(ctx, index.nameSpace(), limit, token)
+	if err != nil {
+		return nil, "", err
+	}
+	records, err := store.Fetch(ctx, index.nameSpace(), page.Ids)
+	if err != nil {
+		return nil, "", err
+	}
+	// Backends fetch in no particular order, so restore the order of the page.
+	slices.SortFunc(records, func(a, b stores.Record) int {
+		return slices.Index(page.Ids, a.Id) - slices.Index(page.Ids, b.Id)
+	})
+	papers := make([]Paper, len(records))
+	for i, record := range records {
+		papers[i] = paperFromRecord(record)
+	}
+	return papers, page.Next, nil
+}
+
+// Fetch a paper from the index by arXiv ID, ignoring any version suffix.
+//
+// Returns the paper if the index holds it, [ErrPaperNotFound] if the index does not hold it, otherwise returns an
+// error.
+func (index *Index) FetchPaper(ctx context.Context, id string) (Paper, error) {
+	store, err := index.backend()
+	if err != nil {
+		return Paper{}, err
+	}
+	records, err := store.Fetch(ctx, index.nameSpace(), []string{BaseArxivId(id)})
+	if err != nil {
+		return Paper{}, err
+	}
+	if len(records) == 0 {
+		return Paper{}, fmt.Errorf("%w: %s", ErrPaperNotFound, id)
+	}
+	return paperFromRecord(records[0]), nil
+}
+
+// Delete every paper that passes a filter from the index. The filter must not be empty; use [Index.DropNameSpace] to
+// delete every paper. In a dry run, we only count the papers that we would delete.
+//
+// Returns the number of papers deleted if we delete them successfully, otherwise returns an error.
+func (index *Index) DeletePapersWhere(ctx context.Context, filter PaperFilter, dryRun bool) (int, error) {
+	if filter.IsEmpty() {
+		return 0, errors.New("refusing to delete papers with an empty filter")
+	}
+	store, err := index.backend()
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	err = scan(ctx, store, index.nameSpace(), func(records []stores.Record) error {
+		for _, record := range records {
+			if filter.Matches(paperFromRecord(record)) {
+				ids = append(ids, record.Id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		return len(ids), nil
+	}
+	if err := store.Delete(ctx, index.nameSpace(), ids); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// Count the papers in every namespace of the index.
+//
+// Returns the number of papers in each non-empty namespace if we count them successfully, otherwise returns an error.
+func (index *Index) NameSpaces(ctx context.Context) (map[string]int, error) {
+	store, err := index.backend()
+	if err != nil {
+		return nil, err
+	}
+	return store.NameSpaces(ctx)
+}
+
+// Copy every paper, along with its embedding, from one namespace of the index to another, replacing any copies of the
+// same papers in the target namespace. Copying does not embed the papers again.
+//
+// Returns the number of papers copied if we copy them successfully, otherwise returns an error.
+func (index *Index) CopyNameSpace(ctx context.Context, from string, to string) (int, error) {
+	if from == to {
+		return 0, fmt.Errorf("cannot copy namespace '%s' to itself", from)
+	}
+	store, err := index.backend()
+	if err != nil {
+		return 0, err
+	}
+	copied := 0
+	err = scan(ctx, store, from, func(records []stores.Record) error {
+		if err := store.Upsert(ctx, to, records); err != nil {
+			return err
+		}
+		copied += len(records)
+		return nil
+	})
+	return copied, err
+}
+
+// Delete every paper in a namespace of the index.
+//
+// Returns nil if we delete the papers successfully, otherwise returns an error.
+func (index *Index) DropNameSpace(ctx context.Context, nameSpace string) error {
+	store, err := index.backend()
+	if err != nil {
+		return err
+	}
+	return store.DeleteAll(ctx, nameSpace)
+}
+
+// Compute statistics about the papers in the index, by scanning every paper.
+//
+// Returns the statistics if we compute them successfully, otherwise returns an error.
+func (index *Index) Stats(ctx context.Context) (IndexStats, error) {
+	stats := IndexStats{ByCategory: map[string]int{}, ByYear: map[string]int{}}
+	store, err := index.backend()
+	if err != nil {
+		return stats, err
+	}
+	err = scan(ctx, store, index.nameSpace(), func(records []stores.Record) error {
+		for _, record := range records {
+			paper := paperFromRecord(record)
+			stats.Papers++
+			categories := paper.Categories
+			if !slices.Contains(categories, paper.PrimaryCategory) && paper.PrimaryCategory != "" {
+				categories = append(categories, paper.PrimaryCategory)
+			}
+			for _, category := range categories {
+				stats.ByCategory[category]++
+			}
+			if len(paper.Published) >= 4 {
+				stats.ByYear[paper.Published[:4]]++
+			}
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// Call a function with successive pages of the documents in a namespace of a backend, until we run out of documents or
+// the function returns an error.
+//
+// Returns nil if we scan every document successfully, otherwise returns an error.
+func scan(ctx context.Context, store stores.Store, nameSpace string, function func([]stores.Record) error) error {
+	token := ""
+	for {
+		page, err := store.List(ctx, nameSpace, scanPageSize, token)
+		if err != nil {
+			return err
+		}
+		if len(page.Ids) > 0 {
+			records, err := store.Fetch(ctx, nameSpace, page.Ids)
+			if err != nil {
+				return err
+			}
+			if err := function(records); err != nil {
+				return err
+			}
+		}
+		if page.Next == "" {
+			return nil
+		}
+		token = page.Next
+	}
+}
+
+// Reconstruct a paper from its document in the index.
+//
+// Returns the paper.
+func paperFromRecord(record stores.Record) Paper {
+	paper := paperFromMetadata(record.Metadata)
+	paper.Summary = summaryFromContent(record.Content)
+	if paper.Id == "" {
+		paper.Id = record.Id
+	}
+	return paper
+}
+
+// Re-index every paper in the index with a new embedding model. We embed the content of each paper again with the new
+// model into a new namespace of the backend, and only once every paper is in the new namespace do we point the manifest
+// of the index to the new namespace, which switches readers over to the new embeddings in a single atomic write. If we
+// fail part of the way through, the index keeps using the old namespace, and re-indexing into the same namespace again
+// picks up where we left off, since the embedding cache still holds the embeddings we computed. The old namespace keeps
+// its papers until dropped.
+//
+// Returns the number of papers re-indexed if we re-index them successfully, otherwise returns an error.
+func (index *Index) Reindex(ctx context.Context, model string, nameSpace string) (int, error) {
+	store, err := index.backend()
+	if err != nil {
+		return 0, err
+	}
+	from := index.nameSpace()
+	if nameSpace == from {
+		return 0, fmt.Errorf("cannot re-index namespace '%s' into itself", from)
+	}
+	embedder, err := index.newEmbedder(model)
+	if err != nil {
+		return 0, err
+	}
+	reindexed := 0
+	dimension := 0
+	err = scan(ctx, store, from, func(records []stores.Record) error {
+		texts := make([]string, len(records))
+		for i, record := range records {
+			texts[i] = record.Content
+		}
+		vectors, err := embedder.EmbedDocuments(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed while embedding papers: %w", err)
+		}
+		if len(vectors) != len(records) {
+			return fmt.Errorf("embedder returned %d vectors for %d papers", len(vectors), len(records))
+		}
+		for i := range records {
+			records[i].Values = vectors[i]
+			dimension = len(vectors[i])
+		}
+		if err := store.Upsert(ctx, nameSpace, records); err != nil {
+			return err
+		}
+		reindexed += len(records)
+		return nil
+	})
+	if err != nil {
+		return reindexed, err
+	}
+
+	manifest := stores.Manifest{EmbeddingModel: model, Dimension: dimension, Updated: time.Now().UTC()}
+	// Describe the new namespace itself too, so that connecting to it directly also uses the new model.
+	if err := store.SetManifest(ctx, nameSpace, manifest); err != nil {
+		return reindexed, err
+	}
+	manifest.NameSpace = nameSpace
+	if err := store.SetManifest(ctx, "", manifest); err != nil {
+		return reindexed, err
+	}
+	index.manifest = &manifest
+	index.embedder = embedder
+	return reindexed, nil
+}
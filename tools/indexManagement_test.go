@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"tmwong.org/arxiv-researcher-go/fakes"
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+// Papers for the index management tests, spanning two years and overlapping categories.
+var managedPapers = []Paper{
+	{Id: "2210.03629v3", Title: "ReAct", Published: "2022-10-06T01:00:00Z", PrimaryCategory: "cs.CL",
+		Categories: []string{"cs.CL", "cs.AI"}, ArxivUrl: "http://arxiv.org/abs/2210.03629v3"},
+	{Id: "2302.04761v1", Title: "Toolformer", Published: "2023-02-09T18:00:00Z", PrimaryCategory: "cs.CL",
+		Categories: []string{"cs.CL"}, ArxivUrl: "http://arxiv.org/abs/2302.04761v1"},
+	{Id: "2303.11366v4", Title: "Reflexion", Published: "2023-03-20T17:00:00Z", PrimaryCategory: "cs.AI",
+		Categories: []string{"cs.AI", "cs.LG"}, ArxivUrl: "http://arxiv.org/abs/2303.11366v4"},
+}
+
+// Create an index of the managed papers, backed by a fake vector store.
+func newManagedIndex(t *testing.T) *Index {
+	t.Helper()
+	index := &Index{store: fakes.NewVectorStore(fakes.NewEmbedder(), "")}
+	if err := index.AddPapers(t.Context(), managedPapers); err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func TestListAndFetchPapers(t *testing.T) {
+	index := newManagedIndex(t)
+	var titles []string
+	token := ""
+	for {
+		papers, next, err := index.ListPapers(t.Context(), 2, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, paper := range papers {
+			titles = append(titles, paper.Title)
+		}
+		if next == "" {
+			break
+		}
+		token = next
+	}
+	if len(titles) != 3 || titles[0] != "ReAct" || titles[2] != "Reflexion" {
+		t.Errorf("unexpected listing %v", titles)
+	}
+
+	paper, err := index.FetchPaper(t.Context(), "2302.04761v2")
+	if err != nil || paper.Id != "2302.04761v1" {
+		t.Errorf("unexpected paper %+v, error %v", paper, err)
+	}
+	if _, err := index.FetchPaper(t.Context(), "1706.03762"); !errors.Is(err, ErrPaperNotFound) {
+		t.Errorf("expected ErrPaperNotFound, got %v", err)
+	}
+}
+
+// Adding a summarized and enriched paper again, e.g., a newer version of it, updates its arXiv metadata but keeps its
+// structured summary and citation metadata.
+func TestAddPapersKeepsMetadata(t *testing.T) {
+	var requests atomic.Int32
+	index := newManagedIndex(t)
+	if _, err := index.EnrichPapers(t.Context(), newFakeSemanticScholar(t, &requests), nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.storeSummary(t.Context(), "2210.03629", PaperSummary{Problem: "Reasoning"}); err != nil {
+		t.Fatal(err)
+	}
+	paper := managedPapers[0]
+	paper.Id, paper.Title, paper.ArxivUrl = "2210.03629v4", "ReAct v4", "http://arxiv.org/abs/2210.03629v4"
+	if err := index.AddPapers(t.Context(), []Paper{paper}); err != nil {
+		t.Fatal(err)
+	}
+	paper, err := index.FetchPaper(t.Context(), "2210.03629")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paper.Id != "2210.03629v4" || paper.Title != "ReAct v4" {
+		t.Errorf("expected the arXiv metadata of the newer version, got %+v", paper)
+	}
+	if paper.StructuredSummary == nil || paper.StructuredSummary.Problem != "Reasoning" ||
+		paper.CitationCount != 1500 || paper.Venue != "ICLR" || len(paper.References) != 2 {
+		t.Errorf("expected the summary and citation metadata to survive, got %+v", paper)
+	}
+}
+
+// Papers take their ID from the ID entry of their metadata, with the version of their arXiv URL if it names the same
+// paper, and from their arXiv URL alone for documents without an ID entry.
+func TestPaperFromMetadata(t *testing.T) {
+	tests := []struct {
+		metadata map[string]any
+		want     string
+	}{
+		{map[string]any{stores.IdKey: "2210.03629", "arxiv URL": "http://arxiv.org/abs/2210.03629v3"}, "2210.03629v3"},
+		{map[string]any{stores.IdKey: "2210.03629"}, "2210.03629"},
+		{map[string]any{stores.IdKey: "2210.03629", "arxiv URL": "http://arxiv.org/abs/2302.04761v1"}, "2210.03629"},
+		{map[string]any{"arxiv URL": "http://arxiv.org/abs/2302.04761v1"}, "2302.04761v1"},
+	}
+	for _, test := range tests {
+		if got := paperFromMetadata(test.metadata).Id; got != test.want {
+			t.Errorf("paperFromMetadata(%v) has ID %q, want %q", test.metadata, got, test.want)
+		}
+	}
+}
+
+func TestIndexStats(t *testing.T) {
+	stats, err := newManagedIndex(t).Stats(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Papers != 3 || stats.ByCategory["cs.CL"] != 2 || stats.ByCategory["cs.AI"] != 2 ||
+		stats.ByCategory["cs.LG"] != 1 || stats.ByYear["2022"] != 1 || stats.ByYear["2023"] != 2 {
+		t.Errorf("unexpected statistics %+v", stats)
+	}
+}
+
+// Deleting by ID counts only the papers that the index holds, and a dry run deletes nothing.
+func TestDeletePapers(t *testing.T) {
+	index := newManagedIndex(t)
+	ids := []string{"2210.03629v1", "2302.04761", "1706.03762"}
+	if deleted, err := index.DeletePapers(t.Context(), ids, true); err != nil || deleted != 2 {
+		t.Fatalf("dry run would delete %d papers, error %v, want 2", deleted, err)
+	}
+	if count, _ := index.Count(t.Context()); count != 3 {
+		t.Errorf("dry run left %d papers, want 3", count)
+	}
+	deleted, err := index.DeletePapers(t.Context(), ids, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := index.Count(t.Context()); deleted != 2 || count != 1 {
+		t.Errorf("deleted %d papers, leaving %d, want 2 and 1", deleted, count)
+	}
+}
+
+func TestDeletePapersWhere(t *testing.T) {
+	index := newManagedIndex(t)
+	if _, err := index.DeletePapersWhere(t.Context(), PaperFilter{}, false); err == nil {
+		t.Error("expected an empty filter to be refused")
+	}
+	filter := PaperFilter{Category: "cs.AI", Since: "2023-01-01"}
+	if deleted, err := index.DeletePapersWhere(t.Context(), filter, true); err != nil || deleted != 1 {
+		t.Fatalf("dry run would delete %d papers, error %v, want 1", deleted, err)
+	}
+	deleted, err := index.DeletePapersWhere(t.Context(), filter, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := index.Count(t.Context()); deleted != 1 || count != 2 {
+		t.Errorf("deleted %d papers, leaving %d, want 1 and 2", deleted, count)
+	}
+}
+
+func TestCopyAndDropNameSpace(t *testing.T) {
+	index := newManagedIndex(t)
+	copied, err := index.CopyNameSpace(t.Context(), "", "backup")
+	if err != nil || copied != 3 {
+		t.Fatalf("copied %d papers, error %v", copied, err)
+	}
+	if err := index.DropNameSpace(t.Context(), ""); err != nil {
+		t.Fatal(err)
+	}
+	counts, err := index.NameSpaces(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 1 || counts["backup"] != 3 {
+		t.Errorf("unexpected namespaces %v", counts)
+	}
+}
+
+func TestReindex(t *testing.T) {
+	saved := EmbedderClientFor
+	t.Cleanup(func() { EmbedderClientFor = saved })
+	EmbedderClientFor = func(model string) (embeddings.EmbedderClient, error) {
+		return &fakes.Embedder{Dimension: 32}, nil
+	}
+
+	index := newManagedIndex(t)
+	if manifest := index.Manifest(); manifest == nil || manifest.Dimension != fakes.DefaultDimension {
+		t.Fatalf("unexpected manifest %+v after adding papers", manifest)
+	}
+	reindexed, err := index.Reindex(t.Context(), "model-b", "v2")
+	if err != nil || reindexed != 3 {
+		t.Fatalf("re-indexed %d papers, error %v", reindexed, err)
+	}
+	manifest := index.Manifest()
+	if manifest.EmbeddingModel != "model-b" || manifest.Dimension != 32 || manifest.NameSpace != "v2" {
+		t.Errorf("unexpected manifest %+v after re-indexing", manifest)
+	}
+	papers, err := index.SearchPapers(t.Context(), "Toolformer", 1)
+	if err != nil || len(papers) != 1 || papers[0].Title != "Toolformer" {
+		t.Errorf("unexpected search results %+v, error %v", papers, err)
+	}
+	store := index.store.(stores.Store)
+	records, _ := store.Fetch(t.Context(), "v2", []string{"2302.04761"})
+	if len(records) != 1 || len(records[0].Values) != 32 {
+		t.Errorf("unexpected re-indexed records %+v", records)
+	}
+	if stored, _ := store.Manifest(t.Context(), ""); stored == nil || stored.NameSpace != "v2" {
+		t.Errorf("unexpected stored manifest %+v", stored)
+	}
+	if _, err := index.Reindex(t.Context(), "model-c", "v2"); err == nil {
+		t.Error("expected an error re-indexing a namespace into itself")
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 )
 
 // Singleton [Tool] instance to search the document index for relevant papers to a user keyword query.
@@ -13,6 +14,7 @@ var IndexSearcher = Tool[indexSearcherArgs]{
 	description:            indexSearcherDescription,
 	Callback:               searchIndex,
 	introspectionCallbacks: Logger,
+	timeout:                searchTimeout,
 }
 
 const (
@@ -32,30 +34,31 @@ Failure: Returns an error message.
 // The arguments for the [IndexSearcher] tool. The structure and the [IndexSearcher] tool description must remain in
 // sync with each other to ensure that agents call the tool with the correct JSON argument keys.
 type indexSearcherArgs struct {
-	Query string `json:"query"`
-	N     int    `json:"n"`
+	Query string `json:"query" description:"The user keyword query"`
+	N     int    `json:"n" description:"The number of results to return"`
 }
 
-// Search a document index for relevant papers to a user keyword query.
+// Search a document index for relevant papers to a user keyword query, as the vector retrieval strategy of
+// [Index.RetrievePapers] does.
 //
 // Returns a JSON array of dictionary objects containing the title, summary, authors, and PDF download link for each
 // paper if the search is successful, otherwise returns an error message.
-func searchIndex(_ context.Context, args indexSearcherArgs) (string, error) {
-	index, err := GetIndex()
+func searchIndex(ctx context.Context, args indexSearcherArgs) (string, error) {
+	index, err := IndexFromContext(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed while getting index: %s", err)
 	}
-	rawDocuments, err := index.store.SimilaritySearch(index.context, args.Query, args.N)
+	papers, err := index.SearchPapers(ctx, args.Query, args.N)
 	if err != nil {
-		return fmt.Sprintf("failed while searching index: %s", err), nil
+		return err.Error(), nil
 	}
-	cookedDocuments := make([]map[string]string, len(rawDocuments))
-	for i, document := range rawDocuments {
+	cookedDocuments := make([]map[string]string, len(papers))
+	for i, paper := range papers {
 		cookedDocuments[i] = map[string]string{
-			"Title":   fmt.Sprintf("%s", document.Metadata["Title"]),
-			"Authors": fmt.Sprintf("%s", document.Metadata["Authors"]),
-			"PDF URL": fmt.Sprintf("%s", document.Metadata["PDF URL"]),
-			"Summary": document.PageContent,
+			"Title":   paper.Title,
+			"Authors": strings.Join(paper.Authors, ", "),
+			"PDF URL": paper.PdfUrl,
+			"Summary": paperContent(paper),
 		}
 	}
 	content, err := json.MarshalIndent(cookedDocuments, "", "  ")
@@ -63,6 +66,6 @@ func searchIndex(_ context.Context, args indexSearcherArgs) (string, error) {
 		return "", fmt.Errorf("failed while marshalling documents: %w", err)
 	}
 	result := string(content)
-	log.Printf("Tool returned with '%d' results.\n", len(rawDocuments))
+	slog.InfoContext(ctx, "Tool returned", "results", len(papers))
 	return result, nil
 }
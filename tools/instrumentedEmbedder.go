@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/embeddings"
+	"go.opentelemetry.io/otel/attribute"
+	"tmwong.org/arxiv-researcher-go/telemetry"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// An embedder that traces each embedding request as an "embedding.request" operation (see [telemetry.Start]), and
+// accounts for the tokens of the request to the usage meter of its context (see [usage.FromContext]). Embedding
+// clients do not report the tokens of their requests, so we estimate them (see [usage.EstimateTokens]).
+//
+// Implements the [embeddings.Embedder] interface.
+type instrumentedEmbedder struct {
+	embeddings.Embedder
+	model string
+}
+
+// Implements the [embeddings.Embedder.EmbedDocuments] API call.
+func (embedder instrumentedEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	ctx, operation := telemetry.Start(ctx, "embedding.request", attribute.String("embedding.model", embedder.model))
+	operation.SetAttributes(attribute.Int("embedding.texts", len(texts)))
+	vectors, err := embedder.Embedder.EmbedDocuments(ctx, texts)
+	operation.End(err)
+	if err == nil {
+		tokens := 0
+		for _, text := range texts {
+			tokens += usage.EstimateTokens(text)
+		}
+		usage.FromContext(ctx).AddEmbedding(embedder.model, tokens)
+	}
+	return vectors, err
+}
+
+// Implements the [embeddings.Embedder.EmbedQuery] API call.
+func (embedder instrumentedEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	ctx, operation := telemetry.Start(ctx, "embedding.request", attribute.String("embedding.model", embedder.model))
+	operation.SetAttributes(attribute.Int("embedding.texts", 1))
+	vector, err := embedder.Embedder.EmbedQuery(ctx, text)
+	operation.End(err)
+	if err == nil {
+		usage.FromContext(ctx).AddEmbedding(embedder.model, usage.EstimateTokens(text))
+	}
+	return vector, err
+}
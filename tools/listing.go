@@ -0,0 +1,219 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+// The base URL of the arXiv daily listing feeds, to which we append a category, e.g., "cs.CL".
+const ArxivListingUrl = "https://rss.arxiv.org/rss/"
+
+// The announcement types of papers in arXiv daily listings.
+const (
+	// A paper newly submitted to the category of the listing.
+	AnnounceNew = "new"
+	// A paper newly submitted to another category, and cross-listed to the category of the listing.
+	AnnounceCross = "cross"
+	// A new version of a paper submitted to the category of the listing before.
+	AnnounceReplace = "replace"
+	// A new version of a paper cross-listed to the category of the listing before.
+	AnnounceReplaceCross = "replace-cross"
+)
+
+// A paper announced in an arXiv daily listing.
+type ListingEntry struct {
+	Paper Paper
+	// How arXiv announced the paper: [AnnounceNew], [AnnounceCross], [AnnounceReplace], or [AnnounceReplaceCross].
+	AnnounceType string
+}
+
+// Whether arXiv announced a new version of a paper it announced before, rather than a new paper.
+//
+// Returns true if the entry announces a replacement, otherwise returns false.
+func (entry ListingEntry) IsReplacement() bool {
+	return entry.AnnounceType == AnnounceReplace || entry.AnnounceType == AnnounceReplaceCross
+}
+
+// Fetch the arXiv daily listing of a category, e.g., "cs.CL", which announces the papers submitted to (or cross-listed
+// to) the category on the last announcement day, along with new versions of earlier papers. Listings are empty on days
+// without announcements, e.g., at weekends. If the arXiv response cache is open, we answer repeated requests from the
+// cache. Cancelling the context aborts the request.
+//
+// Returns the entries of the listing if we fetch it successfully, otherwise returns an error.
+func FetchListing(ctx context.Context, category string) ([]ListingEntry, error) {
+	response, err := getArxiv(ctx, ArxivListingUrl+category)
+	if err != nil {
+		return nil, fmt.Errorf("failed while fetching arXiv listing: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed while fetching arXiv listing of '%s': %s", category, response.Status)
+	}
+	return ParseListing(response.Body)
+}
+
+// Parse an arXiv daily listing feed. Listings come as RSS feeds, whose entries describe papers differently from the
+// entries of API query results: the description holds the arXiv ID and announcement type ahead of the abstract, the
+// authors come as a single comma-separated Dublin Core creator, and the categories list the primary category first.
+//
+// Returns the entries of the feed if the feed is a valid listing, otherwise returns an error.
+func ParseListing(feed io.Reader) ([]ListingEntry, error) {
+	listing, err := gofeed.NewParser().Parse(feed)
+	if err != nil {
+		return nil, fmt.Errorf("failed while parsing arXiv listing: %w", err)
+	}
+	entries := []ListingEntry{}
+	for _, item := range listing.Items {
+		id := item.GUID
+		if i := strings.LastIndexByte(id, ':'); i >= 0 {
+			id = id[i+1:]
+		}
+		if id == "" {
+			continue
+		}
+		arxivFields := item.Extensions["arxiv"]
+		abstract := item.Description
+		if _, after, found := strings.Cut(abstract, "Abstract:"); found {
+			abstract = after
+		}
+		var authors []string
+		for _, author := range item.Authors {
+			for name := range strings.SplitSeq(author.Name, ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					authors = append(authors, name)
+				}
+			}
+		}
+		published := item.Published
+		if item.PublishedParsed != nil {
+			published = item.PublishedParsed.UTC().Format(time.RFC3339)
+		}
+		primaryCategory := ""
+		if len(item.Categories) > 0 {
+			primaryCategory = item.Categories[0]
+		}
+		entries = append(entries, ListingEntry{
+			Paper: Paper{
+				Id:               id,
+				Title:            collapseSpace(item.Title),
+				Authors:          authors,
+				Summary:          collapseSpace(abstract),
+				Published:        published,
+				JournalReference: collapseSpace(getOptionalField("journal_reference", arxivFields)),
+				Doi:              getOptionalField("DOI", arxivFields),
+				PrimaryCategory:  primaryCategory,
+				Categories:       item.Categories,
+				PdfUrl:           "http://arxiv.org/pdf/" + id,
+				ArxivUrl:         "http://arxiv.org/abs/" + id,
+			},
+			AnnounceType: cmp.Or(getOptionalField("announce_type", arxivFields), AnnounceNew),
+		})
+	}
+	return entries, nil
+}
+
+// The outcome of ingesting arXiv daily listings with [Index.IngestListings].
+type IngestResult struct {
+	// The number of new papers, including cross-listed papers, that we added to the index.
+	Added int `json:"added"`
+	// The number of papers in the index that we replaced with a new version.
+	Updated int `json:"updated"`
+	// The number of entries we skipped, since they announced new versions of papers that the index does not hold, or
+	// papers already announced in the listing of another category.
+	Skipped int `json:"skipped"`
+}
+
+// Ingest the arXiv daily listings of categories into the index: add the new and cross-listed papers, and replace the
+// papers in the index whose new versions the listings announce. Papers cross-listed to several of the categories
+// appear in several listings, but we add each paper once. Unless asked to add every replacement, we skip new versions
+// of papers that the index does not hold, since they are not new, and the index did not want them before.
+//
+// Returns the outcome if we ingest the listings successfully, otherwise returns the outcome so far along with an error.
+func (index *Index) IngestListings(ctx context.Context, categories []string, allReplacements bool) (
+	IngestResult,
+	error,
+) {
+	var result IngestResult
+	seen := map[string]bool{}
+	var papers, replacements []Paper
+	for _, category := range categories {
+		entries, err := FetchListing(ctx, category)
+		if err != nil {
+			return result, err
+		}
+		for _, entry := range entries {
+			id := BaseArxivId(entry.Paper.Id)
+			if seen[id] {
+				result.Skipped++
+				continue
+			}
+			seen[id] = true
+			if entry.IsReplacement() && !allReplacements {
+				replacements = append(replacements, entry.Paper)
+			} else {
+				papers = append(papers, entry.Paper)
+			}
+		}
+	}
+	// We add every paper (new or replaced) alike, but count papers the index held before as updated.
+	held := map[string]stores.Record{}
+	if len(replacements) > 0 || len(papers) > 0 {
+		store, err := index.backend()
+		if err != nil {
+			return result, err
+		}
+		var ids []string
+		for _, paper := range slices.Concat(papers, replacements) {
+			ids = append(ids, BaseArxivId(paper.Id))
+		}
+		records, err := store.Fetch(ctx, index.nameSpace(), ids)
+		if err != nil {
+			return result, err
+		}
+		for _, record := range records {
+			held[record.Id] = record
+		}
+	}
+	for _, paper := range replacements {
+		if _, ok := held[BaseArxivId(paper.Id)]; ok {
+			papers = append(papers, paper)
+		} else {
+			result.Skipped++
+		}
+	}
+	if len(papers) == 0 {
+		return result, nil
+	}
+	// The date in a listing is the date of the announcement, not the date the paper was first published, so we keep
+	// the date the index holds for papers it held before.
+	for i, paper := range papers {
+		if record, ok := held[BaseArxivId(paper.Id)]; ok {
+			if published, ok := record.Metadata["Published"].(string); ok && published != "" {
+				papers[i].Published = published
+			}
+		}
+	}
+	if err := index.AddPapers(ctx, papers); err != nil {
+		return result, err
+	}
+	for _, paper := range papers {
+		if _, ok := held[BaseArxivId(paper.Id)]; ok {
+			result.Updated++
+		} else {
+			result.Added++
+		}
+	}
+	slog.InfoContext(ctx, "Ingested listings",
+		"added", result.Added, "updated", result.Updated, "skipped", result.Skipped)
+	return result, nil
+}
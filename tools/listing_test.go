@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"net/http"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestParseListing(t *testing.T) {
+	feed, err := os.Open("testdata/arxiv/listing.rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Close()
+	entries, err := ParseListing(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+	paper := entries[0].Paper
+	if paper.Id != "2410.04001v1" || paper.Title != "Agents That Plan Before They Act" ||
+		paper.Summary != "We propose an agent that plans before it acts." ||
+		!slices.Equal(paper.Authors, []string{"Ada Lovelace", "Alan Turing"}) ||
+		paper.Published != "2024-10-08T04:00:00Z" || paper.PrimaryCategory != "cs.CL" ||
+		paper.ArxivUrl != "http://arxiv.org/abs/2410.04001v1" || paper.PdfUrl != "http://arxiv.org/pdf/2410.04001v1" {
+		t.Errorf("unexpected paper %+v", paper)
+	}
+	var types []string
+	for _, entry := range entries {
+		types = append(types, entry.AnnounceType)
+	}
+	if want := []string{AnnounceNew, AnnounceCross, AnnounceReplace, AnnounceReplaceCross}; !slices.Equal(types, want) {
+		t.Errorf("got announcement types %v, want %v", types, want)
+	}
+	if entries[1].Paper.PrimaryCategory != "cs.RO" || entries[1].IsReplacement() || !entries[3].IsReplacement() {
+		t.Errorf("unexpected cross-list %+v", entries[1])
+	}
+	if replaced := entries[2].Paper; replaced.JournalReference != "ICLR 2023" ||
+		replaced.Doi != "10.48550/arXiv.2210.03629" {
+		t.Errorf("unexpected replacement %+v", replaced)
+	}
+}
+
+// New and cross-listed papers are added once across listings, and replacements only update papers in the index.
+func TestIngestListings(t *testing.T) {
+	savedClient := HttpClient
+	t.Cleanup(func() { HttpClient = savedClient })
+
+	transport := &fixtureTransport{status: http.StatusOK, fixture: "listing.rss"}
+	HttpClient = &http.Client{Transport: transport}
+	index := newManagedIndex(t)
+	if err := index.storeSummary(t.Context(), "2210.03629", PaperSummary{Problem: "Reasoning"}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := index.IngestListings(t.Context(), []string{"cs.CL", "cs.AI"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (IngestResult{Added: 2, Updated: 1, Skipped: 5}); result != want {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+	if transport.url != ArxivListingUrl+"cs.AI" {
+		t.Errorf("fetched %s last", transport.url)
+	}
+	paper, err := index.FetchPaper(t.Context(), "2210.03629")
+	if err != nil || paper.Id != "2210.03629v4" || paper.JournalReference != "ICLR 2023" {
+		t.Errorf("expected the new version, got %+v, error %v", paper, err)
+	}
+	if paper.Published != "2022-10-06T01:00:00Z" {
+		t.Errorf("expected the original publication date, got %s", paper.Published)
+	}
+	if paper.StructuredSummary == nil || paper.StructuredSummary.Problem != "Reasoning" {
+		t.Errorf("expected the stored summary, got %+v", paper.StructuredSummary)
+	}
+	if _, err := index.FetchPaper(t.Context(), "2101.00001"); err == nil {
+		t.Error("expected the replacement of a paper outside the index to be skipped")
+	}
+
+	result, err = index.IngestListings(t.Context(), []string{"cs.CL"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (IngestResult{Added: 1, Updated: 3}); result != want {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+
+	HttpClient = &http.Client{Transport: &fixtureTransport{status: http.StatusNotFound, fixture: "listing.rss"}}
+	if _, err := index.IngestListings(t.Context(), []string{"cs.XX"}, false); err == nil {
+		t.Error("expected an error for a missing listing")
+	}
+}
@@ -4,46 +4,285 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"tmwong.org/arxiv-researcher-go/logging"
 )
 
-// A simple introspection handler that logs the start and end of a chain, as well as any errors that occur while
-// running a tool. Implements the [callbacks.Handler] interface.
+// An introspection handler that logs every event of LLMs, chains, agents, retrievers, and tools as structured records
+// of the default [slog] logger, along with the time that each call took, and optionally writes the events to a JSON
+// lines transcript (see [LogHandler.SetTranscript]).
+//
+// LangChainGo reports the start and end of each call with the same context, so we time calls by pairing up their
+// starts and ends by context and kind of call.
+//
+// Implements the [callbacks.Handler] interface.
 type LogHandler struct {
 	callbacks.SimpleHandler
+	mutex  sync.Mutex
+	starts map[callKey][]time.Time
+	// The encoder of the transcript, if any.
+	transcript *json.Encoder
+}
+
+var _ callbacks.Handler = (*LogHandler)(nil)
+
+// Identifies the calls in progress of one kind with one context.
+type callKey struct {
+	ctx  context.Context
+	kind string
+}
+
+// Create a handler that logs events, without a transcript.
+//
+// Returns the handler.
+func NewLogHandler() *LogHandler {
+	return &LogHandler{starts: map[callKey][]time.Time{}}
 }
 
 // Singleton [LogHandler] instance used by a chatbot agent and all of its tools.
-var Logger = LogHandler{}
-
-func (l LogHandler) HandleChainStart(_ context.Context, inputs map[string]any) {
-	var scratchpad_length int
-	// The [agents.OneShotZeroAgent] agent uses the key `agent_scratchpad`` to store the scratchpad (i.e., the agent
-	// memory).
-	if scratchpad, ok := inputs["agent_scratchpad"].(string); ok {
-		scratchpad_length = len(scratchpad)
+var Logger = NewLogHandler()
+
+// Write every event from now on to a transcript, one JSON object (see [TranscriptEvent]) per line. A nil writer stops
+// writing the transcript.
+func (handler *LogHandler) SetTranscript(w io.Writer) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	if w == nil {
+		handler.transcript = nil
+	} else {
+		handler.transcript = json.NewEncoder(w)
+	}
+}
+
+// Note the start of a call of a kind with a context.
+func (handler *LogHandler) start(ctx context.Context, kind string) {
+	// Contexts are comparable unless a caller implements its own, in which case we cannot pair starts and ends up.
+	if !reflect.TypeOf(ctx).Comparable() {
+		return
+	}
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	key := callKey{ctx, kind}
+	handler.starts[key] = append(handler.starts[key], time.Now())
+}
+
+// Note the end of the most recently started call of a kind with a context.
+//
+// Returns the time that the call took if we saw it start, otherwise returns 0.
+func (handler *LogHandler) end(ctx context.Context, kind string) time.Duration {
+	if !reflect.TypeOf(ctx).Comparable() {
+		return 0
+	}
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	key := callKey{ctx, kind}
+	starts := handler.starts[key]
+	if len(starts) == 0 {
+		return 0
+	}
+	start := starts[len(starts)-1]
+	if len(starts) == 1 {
+		delete(handler.starts, key)
 	} else {
-		scratchpad_length = 0
+		handler.starts[key] = starts[:len(starts)-1]
+	}
+	return time.Since(start)
+}
+
+// Write an event to the transcript, if any, with its sensitive values redacted.
+func (handler *LogHandler) record(ctx context.Context, event TranscriptEvent, duration time.Duration) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+	if handler.transcript == nil {
+		return
+	}
+	event.Time = time.Now().UTC()
+	event.RunId = logging.RunId(ctx)
+	event.DurationMs = float64(duration) / float64(time.Millisecond)
+	if err := handler.transcript.Encode(event.Redact()); err != nil {
+		slog.WarnContext(ctx, "Failed while writing transcript", "event", event.Event, "error", err)
+	}
+}
+
+// Implements the [callbacks.Handler.HandleText] API call.
+func (handler *LogHandler) HandleText(ctx context.Context, text string) {
+	slog.DebugContext(ctx, "Text", "text", logging.Truncate(text, maxLoggedLength))
+	handler.record(ctx, TranscriptEvent{Event: TextEvent, Output: text}, 0)
+}
+
+// Implements the [callbacks.Handler.HandleLLMStart] API call.
+func (handler *LogHandler) HandleLLMStart(ctx context.Context, prompts []string) {
+	handler.start(ctx, "llm")
+	slog.DebugContext(ctx, "Calling LLM", "prompts", len(prompts))
+	handler.record(ctx, TranscriptEvent{Event: LlmStartEvent, Prompts: prompts}, 0)
+}
+
+// Implements the [callbacks.Handler.HandleLLMGenerateContentStart] API call.
+func (handler *LogHandler) HandleLLMGenerateContentStart(ctx context.Context, messages []llms.MessageContent) {
+	handler.start(ctx, "llm")
+	slog.DebugContext(ctx, "Calling LLM", "messages", len(messages))
+	handler.record(ctx, TranscriptEvent{Event: LlmStartEvent, Messages: newTranscriptMessages(messages)},
+		0)
+}
+
+// Implements the [callbacks.Handler.HandleLLMGenerateContentEnd] API call.
+func (handler *LogHandler) HandleLLMGenerateContentEnd(ctx context.Context, response *llms.ContentResponse) {
+	duration := handler.end(ctx, "llm")
+	var choices []TranscriptChoice
+	attributes := []any{"duration", duration}
+	if response != nil && len(response.Choices) > 0 {
+		choices = newTranscriptChoices(response.Choices)
+		choice := response.Choices[0]
+		attributes = append(attributes,
+			"tool_calls", len(choice.ToolCalls),
+			"content_length", len(choice.Content),
+			"stop_reason", choice.StopReason,
+		)
+		if tokens, ok := choice.GenerationInfo["TotalTokens"]; ok {
+			attributes = append(attributes, "total_tokens", tokens)
+		}
 	}
-	log.Printf(
-		"Entering chain with input '%s' and scratchpad of '%d' characters.\n",
-		strings.TrimSpace(inputs["input"].(string)),
-		scratchpad_length,
+	slog.InfoContext(ctx, "LLM responded", attributes...)
+	handler.record(ctx, TranscriptEvent{Event: LlmEndEvent, Choices: choices}, duration)
+}
+
+// Implements the [callbacks.Handler.HandleLLMError] API call.
+func (handler *LogHandler) HandleLLMError(ctx context.Context, err error) {
+	duration := handler.end(ctx, "llm")
+	slog.WarnContext(ctx, "Failed while calling LLM", "error", err, "duration", duration)
+	handler.record(ctx, TranscriptEvent{Event: LlmErrorEvent, Error: errorText(err)}, duration)
+}
+
+// Log the start of a chain, along with its input, if any, and the length of the scratchpad (i.e., the agent memory)
+// of agent chains.
+//
+// Implements the [callbacks.Handler.HandleChainStart] API call.
+func (handler *LogHandler) HandleChainStart(ctx context.Context, inputs map[string]any) {
+	handler.start(ctx, "chain")
+	var attributes []any
+	if input, ok := inputs["input"].(string); ok {
+		attributes = append(attributes, "input", logging.Truncate(strings.TrimSpace(input), maxLoggedLength))
+	}
+	// The [agents.OneShotZeroAgent] agent uses the key `agent_scratchpad` to store the scratchpad.
+	scratchpad, _ := inputs["agent_scratchpad"].(string)
+	attributes = append(attributes, "scratchpad_length", len(scratchpad))
+	slog.InfoContext(ctx, "Entering chain", attributes...)
+	handler.record(ctx, TranscriptEvent{Event: ChainStartEvent, Values: inputs}, 0)
+}
+
+// Implements the [callbacks.Handler.HandleChainEnd] API call.
+func (handler *LogHandler) HandleChainEnd(ctx context.Context, outputs map[string]any) {
+	duration := handler.end(ctx, "chain")
+	slog.InfoContext(ctx, "Exiting chain",
+		"outputs", logging.Truncate(formatAsJson(outputs), maxLoggedLength),
+		"duration", duration,
 	)
+	handler.record(ctx, TranscriptEvent{Event: ChainEndEvent, Values: outputs}, duration)
 }
 
-func (l LogHandler) HandleChainEnd(_ context.Context, outputs map[string]any) {
-	log.Println("Exiting chain with outputs:", l.formatAsJson(outputs))
+// Implements the [callbacks.Handler.HandleChainError] API call.
+func (handler *LogHandler) HandleChainError(ctx context.Context, err error) {
+	duration := handler.end(ctx, "chain")
+	slog.WarnContext(ctx, "Failed while running chain", "error", err, "duration", duration)
+	handler.record(ctx, TranscriptEvent{Event: ChainErrorEvent, Error: errorText(err)}, duration)
 }
 
-func (l LogHandler) HandleToolError(ctx context.Context, err error) {
-	log.Printf("Failed while running tool: %s\n", err.Error())
+// Log the start of a tool call. Tool inputs echo whatever the agent (or the user) wrote, so we log them in full only
+// at the debug level.
+//
+// Implements the [callbacks.Handler.HandleToolStart] API call.
+func (handler *LogHandler) HandleToolStart(ctx context.Context, input string) {
+	handler.start(ctx, "tool")
+	name := calledTool(ctx).name
+	slog.InfoContext(ctx, "Calling tool", "tool", name, "input_length", len(input))
+	slog.DebugContext(ctx, "Tool input", "tool", name, "input", logging.Truncate(input, maxLoggedLength))
+	handler.record(ctx, TranscriptEvent{Event: ToolStartEvent, Tool: name, Input: input}, 0)
+}
+
+// Implements the [callbacks.Handler.HandleToolEnd] API call.
+func (handler *LogHandler) HandleToolEnd(ctx context.Context, output string) {
+	duration := handler.end(ctx, "tool")
+	call := calledTool(ctx)
+	slog.InfoContext(ctx, "Tool finished", "tool", call.name, "output_length", len(output), "duration", duration)
+	handler.record(ctx, TranscriptEvent{Event: ToolEndEvent, Tool: call.name, Input: call.input, Output: output},
+		duration)
+}
+
+// Implements the [callbacks.Handler.HandleToolError] API call.
+func (handler *LogHandler) HandleToolError(ctx context.Context, err error) {
+	duration := handler.end(ctx, "tool")
+	call := calledTool(ctx)
+	slog.WarnContext(ctx, "Failed while running tool", "tool", call.name, "error", err, "duration", duration)
+	handler.record(ctx, TranscriptEvent{Event: ToolErrorEvent, Tool: call.name, Input: call.input,
+		Error: errorText(err)}, duration)
+}
+
+// Implements the [callbacks.Handler.HandleAgentAction] API call.
+func (handler *LogHandler) HandleAgentAction(ctx context.Context, action schema.AgentAction) {
+	slog.InfoContext(ctx, "Agent chose action", "tool", action.Tool, "input_length", len(action.ToolInput))
+	handler.record(ctx, TranscriptEvent{Event: AgentActionEvent, Tool: action.Tool, Action: &action}, 0)
+}
+
+// Implements the [callbacks.Handler.HandleAgentFinish] API call.
+func (handler *LogHandler) HandleAgentFinish(ctx context.Context, finish schema.AgentFinish) {
+	output, _ := finish.ReturnValues["output"].(string)
+	slog.InfoContext(ctx, "Agent finished", "output_length", len(output))
+	handler.record(ctx, TranscriptEvent{Event: AgentFinishEvent, Finish: &finish}, 0)
+}
+
+// Implements the [callbacks.Handler.HandleRetrieverStart] API call.
+func (handler *LogHandler) HandleRetrieverStart(ctx context.Context, query string) {
+	handler.start(ctx, "retriever")
+	slog.InfoContext(ctx, "Retrieving documents", "query", logging.Truncate(query, maxLoggedLength))
+	handler.record(ctx, TranscriptEvent{Event: RetrieverStartEvent, Input: query}, 0)
+}
+
+// Implements the [callbacks.Handler.HandleRetrieverEnd] API call.
+func (handler *LogHandler) HandleRetrieverEnd(ctx context.Context, query string, documents []schema.Document) {
+	duration := handler.end(ctx, "retriever")
+	slog.InfoContext(ctx, "Retrieved documents", "documents", len(documents), "duration", duration)
+	handler.record(ctx, TranscriptEvent{Event: RetrieverEndEvent, Input: query, Documents: documents}, duration)
+}
+
+// A tool call in progress, which [Tool.Call] attaches to the context of the call.
+type toolCall struct {
+	name  string
+	input string
+}
+
+type toolCallKey struct{}
+
+// Get the tool call that a context belongs to (see [Tool.Call]).
+//
+// Returns the tool call, whose fields are empty if the context belongs to no tool call.
+func calledTool(ctx context.Context) toolCall {
+	call, _ := ctx.Value(toolCallKey{}).(toolCall)
+	return call
+}
+
+// Get the message of an error, which may be nil.
+//
+// Returns the message, or an empty string if there is no error.
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
 }
 
-func (l LogHandler) formatAsJson(data any) string {
+// Format a value as JSON for a log record.
+//
+// Returns the JSON, or a description of the failure if the value cannot be marshalled.
+func formatAsJson(data any) string {
 	content, err := json.Marshal(data)
 	if err != nil {
 		return fmt.Sprintf("failed while marshalling data: %s", err)
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"tmwong.org/arxiv-researcher-go/logging"
+)
+
+// The handler logs every kind of event, tolerates chains without the inputs it looks for, times tool calls, and
+// writes every event to its transcript with its sensitive values redacted, while the logger keeps its token counts.
+func TestLogHandler(t *testing.T) {
+	savedLogger := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(savedLogger) })
+	var logs, transcript bytes.Buffer
+	logging.Setup(&logs, logging.Options{Level: slog.LevelDebug, Format: logging.JsonFormat})
+	handler := NewLogHandler()
+	handler.SetTranscript(&transcript)
+	ctx := logging.WithRunId(t.Context(), "0123456789abcdef")
+
+	secret := "sk-0123456789abcdef0123"
+	handler.HandleChainStart(ctx, map[string]any{"question": "What is ReAct?", "key": secret})
+	handler.HandleLLMGenerateContentStart(ctx, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeHuman, "What is ReAct? My key is "+secret),
+	})
+	handler.HandleLLMGenerateContentEnd(ctx, &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content: "A prompting method.", GenerationInfo: map[string]any{"TotalTokens": 42},
+	}}})
+	handler.HandleLLMError(ctx, errors.New("rate limited"))
+	handler.HandleChainEnd(ctx, map[string]any{"text": "A prompting method."})
+	handler.HandleAgentAction(ctx, schema.AgentAction{Tool: "Echo", ToolInput: `{"text": "hello"}`})
+	echo := Tool[echoArgs]{
+		name:                   "Echo",
+		Callback:               func(ctx context.Context, args echoArgs) (string, error) { return args.Text, nil },
+		introspectionCallbacks: handler,
+	}
+	if _, err := echo.Call(ctx, `{"text": "hello"}`); err != nil {
+		t.Fatal(err)
+	}
+	handler.HandleRetrieverStart(ctx, "agents")
+	handler.HandleRetrieverEnd(ctx, "agents", []schema.Document{{PageContent: "ReAct"}})
+	handler.HandleAgentFinish(ctx, schema.AgentFinish{ReturnValues: map[string]any{"output": "hello"}})
+	handler.SetTranscript(nil)
+	handler.HandleText(ctx, "after the transcript")
+
+	if strings.Contains(transcript.String(), secret) || !strings.Contains(transcript.String(), logging.Redacted) {
+		t.Errorf("expected the transcript to redact the key, got %s", transcript.String())
+	}
+	var events []TranscriptEvent
+	scanner := bufio.NewScanner(&transcript)
+	for scanner.Scan() {
+		var event TranscriptEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("unexpected transcript line %s: %s", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	var kinds []string
+	for _, event := range events {
+		kinds = append(kinds, event.Event)
+	}
+	want := "chain_start llm_start llm_end llm_error chain_end agent_action tool_start tool_end retriever_start " +
+		"retriever_end agent_finish"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Fatalf("unexpected events %s", got)
+	}
+	if end := events[2]; len(end.Choices) != 1 || end.Choices[0].Content != "A prompting method." {
+		t.Errorf("unexpected LLM response %+v", end.Choices)
+	}
+	if end := events[7]; end.Tool != "Echo" || end.Output != "hello" || end.DurationMs <= 0 ||
+		end.RunId != "0123456789abcdef" {
+		t.Errorf("unexpected tool end %+v", end)
+	}
+	for _, message := range []string{
+		"Entering chain", "LLM responded", "Failed while calling LLM", "Agent chose action", "Calling tool",
+		"Tool finished", "Retrieved documents", "Agent finished", "after the transcript",
+	} {
+		if !strings.Contains(logs.String(), message) {
+			t.Errorf("expected a log record with %q, got %s", message, logs.String())
+		}
+	}
+	if !strings.Contains(logs.String(), `"total_tokens":42`) {
+		t.Errorf("expected the LLM response record to log its total tokens, got %s", logs.String())
+	}
+	if len(handler.starts) != 0 {
+		t.Errorf("expected no calls in progress, got %v", handler.starts)
+	}
+}
@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -8,28 +10,41 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/mmcdole/gofeed"
 	ext "github.com/mmcdole/gofeed/extensions"
+	"go.opentelemetry.io/otel/attribute"
+	"tmwong.org/arxiv-researcher-go/cache"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/telemetry"
 )
 
 // Represents a paper held by arXiv. Each field corresponds to an equivalent field in the
 // [arXiv entry metadata specification]. Note that some fields are optional and may not be present in the metadata
-// returned by arXiv.
+// returned by arXiv. The remaining fields hold citation metadata from a scholarly database, which only papers that we
+// enriched in the index carry (see [Index.EnrichPapers]).
 //
 // [arXiv entry metadata specification]: https://info.arxiv.org/help/api/user-manual.html#_entry_metadata
 type Paper struct {
-	Id               string
-	Title            string
-	Authors          []string
-	Summary          string
-	Published        string
-	JournalReference string // Optional
-	Doi              string // Optional
-	PrimaryCategory  string
-	Categories       []string
-	PdfUrl           string
-	ArxivUrl         string
+	Id               string   `json:"id"`
+	Title            string   `json:"title"`
+	Authors          []string `json:"authors"`
+	Summary          string   `json:"summary,omitempty"`
+	Published        string   `json:"published"`
+	JournalReference string   `json:"journal_reference,omitempty"` // Optional
+	Doi              string   `json:"doi,omitempty"`               // Optional
+	PrimaryCategory  string   `json:"primary_category"`
+	Categories       []string `json:"categories"`
+	PdfUrl           string   `json:"pdf_url"`
+	ArxivUrl         string   `json:"arxiv_url"`
+	CitationCount    int      `json:"citation_count,omitempty"`  // Optional
+	Venue            string   `json:"venue,omitempty"`           // Optional
+	FieldsOfStudy    []string `json:"fields_of_study,omitempty"` // Optional
+	// The identifiers of the papers that the paper cites, e.g., "arXiv:2201.11903" or "DOI:10.18653/v1/N19-1423".
+	References []string `json:"references,omitempty"` // Optional
+	// The structured summary of the paper, if we summarized the paper (see [SummarizePaper]).
+	StructuredSummary *PaperSummary `json:"structured_summary,omitempty"` // Optional
 }
 
 // The directory in the local filesystem in which the download tool saves papers. This directory is relative to the
@@ -38,27 +53,84 @@ const (
 	PapersDirectory = "papers"
 )
 
+// The HTTP client the tools use to query arXiv and download papers. Tests may replace the client, e.g., with one whose
+// transport replays recorded responses.
+var HttpClient = http.DefaultClient
+
+// The file name of the arXiv response cache database in the cache directory.
+const ArxivCacheFileName = "arxiv.db"
+
+// The default time for which we answer repeated arXiv queries from the cache. arXiv publishes new papers once a day.
+const DefaultArxivCacheTTL = 24 * time.Hour
+
+// The cache of arXiv query responses, if any. Commands open the cache with [OpenArxivCache] unless the user bypasses
+// it; tests leave it nil, so that every query goes through [HttpClient]. We never cache paper downloads, since papers
+// are large and we save them to the local file system anyway.
+var ArxivCache *cache.ResponseCache
+
+// Open the arXiv response cache database in the cache directory, creating the directory if necessary, and use it to
+// answer arXiv queries from now on.
+//
+// Returns the cache if we open it successfully, otherwise returns an error.
+func OpenArxivCache(ttl time.Duration) (*cache.ResponseCache, error) {
+	directory, err := constants.CacheDirectory()
+	if err != nil {
+		return nil, fmt.Errorf("failed while locating cache directory: %w", err)
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed while creating cache directory: %w", err)
+	}
+	responseCache, err := cache.OpenResponseCache(filepath.Join(directory, ArxivCacheFileName), ttl)
+	if err != nil {
+		return nil, err
+	}
+	ArxivCache = responseCache
+	return responseCache, nil
+}
+
 // Get the value of an optional field from the an arXiv metadata.
 //
 // Returns the value of the field if it exists, otherwise returns an empty string.
 func getOptionalField(key string, fields map[string][]ext.Extension) string {
-	if field, ok := fields[key]; ok {
+	if field, ok := fields[key]; ok && len(field) > 0 {
 		return field[0].Value
 	} else {
 		return ""
 	}
 }
 
-// Download a paper from a URL to the local file system. The caller should ensure that the file name is a valid file
-// name for the local file system.
+// Get the value of an attribute of an optional field from the an arXiv metadata, e.g., the `term` attribute of the
+// `primary_category` field.
+//
+// Returns the value of the attribute if the field exists, otherwise returns an empty string.
+func getOptionalAttribute(key string, attribute string, fields map[string][]ext.Extension) string {
+	if field, ok := fields[key]; ok && len(field) > 0 {
+		return field[0].Attrs[attribute]
+	} else {
+		return ""
+	}
+}
+
+// Download a paper from a URL to [PapersDirectory] (or the papers directory of the environment of the context, see
+// [Environment]) in the local file system. The caller should ensure that the file name is a valid file name for the
+// local file system. Cancelling the context aborts the download, in which case we remove the partially
+// downloaded file. We trace the download as an "http.download" operation (see [telemetry.Start]).
 //
 // Returns nil if the paper is downloaded successfully, otherwise returns an error.
-func DownloadPaper(fileName string, url string) error {
-	if err := os.MkdirAll(PapersDirectory, 0755); err != nil {
+func DownloadPaper(ctx context.Context, fileName string, url string) (err error) {
+	ctx, operation := telemetry.Start(ctx, "http.download")
+	defer func() { operation.End(err) }()
+	directory := papersDirectoryFromContext(ctx)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return fmt.Errorf("failed while downloading from '%s': %w", url, err)
+	}
+	filePath := filepath.Join(directory, fileName)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
 		return fmt.Errorf("failed while downloading from '%s': %w", url, err)
 	}
-	filePath := filepath.Join(PapersDirectory, fileName)
-	if response, err := http.Get(url); err != nil {
+	client, _ := httpClientFromContext(ctx)
+	if response, err := client.Do(request); err != nil {
 		return fmt.Errorf("failed while downloading from '%s': %w", url, err)
 	} else {
 		defer response.Body.Close()
@@ -67,6 +139,7 @@ func DownloadPaper(fileName string, url string) error {
 		} else {
 			defer file.Close()
 			if _, err := io.Copy(file, response.Body); err != nil {
+				os.Remove(filePath)
 				return fmt.Errorf("failed while downloading from '%s': %w", url, err)
 			}
 		}
@@ -74,47 +147,178 @@ func DownloadPaper(fileName string, url string) error {
 	return nil
 }
 
-// Query arXiv for papers relevant to a given topic keyword. We parse out the returned data with the help of the
-// [arXiv entry metadata specification].
-//
-// Returns a list of zero or more [Paper] objects corresponding to each relevant paper found.
+// Returned when arXiv answers a query with an error feed, e.g., because the query is malformed.
+var ErrArxivQuery = errors.New("arXiv rejected query")
+
+// Query arXiv for papers relevant to a given topic keyword. If the arXiv response cache is open, we answer repeated
+// queries from the cache. Cancelling the context aborts the query.
 //
-// [arXiv entry metadata specification]: https://info.arxiv.org/help/api/user-manual.html#_entry_metadata
-func FetchPapers(keyword string, count int) []Paper {
-	arxivParser := gofeed.NewParser()
+// Returns a list of zero or more [Paper] objects corresponding to each relevant paper found if the query succeeds,
+// otherwise returns an error.
+func FetchPapers(ctx context.Context, keyword string, count int) ([]Paper, error) {
 	keywordEscaped := url.QueryEscape(keyword)
 	queryUrl := fmt.Sprintf(
 		"http://export.arxiv.org/api/query?search_query=all:%s&start=0&max_results=%d",
 		keywordEscaped,
 		count,
 	)
-	queryResults, _ := arxivParser.ParseURL(queryUrl)
-	var papers []Paper
-	// The results come back as an RSS feed but with some additional arXiv-specific fields in the extensions. We
-	// extract the relevant fields and create a Paper object for each result.
-	for i := range queryResults.Items {
-		arxivFields := queryResults.Items[i].Extensions["arxiv"]
+	return queryArxiv(ctx, queryUrl)
+}
+
+// Fetch the papers on a topic most recently submitted to arXiv, newest first, optionally only those listed under an
+// arXiv category. If the arXiv response cache is open, we answer repeated queries from the cache. Cancelling the
+// context aborts the query.
+//
+// Returns a list of [Paper] objects if the query succeeds, otherwise returns an error.
+func FetchRecentPapers(ctx context.Context, keyword string, category string, count int) ([]Paper, error) {
+	query := "all:" + keyword
+	if category != "" {
+		query = fmt.Sprintf("(%s) AND cat:%s", query, category)
+	}
+	queryUrl := fmt.Sprintf(
+		"http://export.arxiv.org/api/query?search_query=%s&sortBy=submittedDate&sortOrder=descending&start=0"+
+			"&max_results=%d",
+		url.QueryEscape(query),
+		count,
+	)
+	return queryArxiv(ctx, queryUrl)
+}
+
+// Fetch papers from arXiv by arXiv ID. An ID without a version suffix fetches the latest version of the paper. If the
+// arXiv response cache is open, we answer repeated queries from the cache. Cancelling the context aborts the query.
+//
+// Returns a list of [Paper] objects, one for each ID that arXiv knows, if the query succeeds, otherwise returns an
+// error.
+func FetchPapersById(ctx context.Context, ids []string) ([]Paper, error) {
+	queryUrl := fmt.Sprintf(
+		"http://export.arxiv.org/api/query?id_list=%s&start=0&max_results=%d",
+		url.QueryEscape(strings.Join(ids, ",")),
+		len(ids),
+	)
+	return queryArxiv(ctx, queryUrl)
+}
+
+// Send a query to the arXiv API, through the arXiv response cache if it is open.
+//
+// Returns the papers in the result feed if the query succeeds, otherwise returns an error.
+func queryArxiv(ctx context.Context, queryUrl string) ([]Paper, error) {
+	response, err := getArxiv(ctx, queryUrl)
+	if err != nil {
+		return nil, fmt.Errorf("failed while querying arXiv: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		// arXiv describes malformed queries with an error feed, which we prefer to report over the bare HTTP status.
+		if _, err := ParsePapers(response.Body); errors.Is(err, ErrArxivQuery) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed while querying arXiv: %s", response.Status)
+	}
+	return ParsePapers(response.Body)
+}
+
+// Send a GET request to arXiv, through the arXiv response cache if it is open. We trace the request, up to the
+// response headers, as an "http.arxiv" operation (see [telemetry.Start]).
+//
+// Returns the response, whose body the caller must close, if arXiv responds, otherwise returns an error.
+func getArxiv(ctx context.Context, url string) (*http.Response, error) {
+	ctx, operation := telemetry.Start(ctx, "http.arxiv")
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		operation.End(err)
+		return nil, err
+	}
+	client, shared := httpClientFromContext(ctx)
+	if shared && ArxivCache != nil {
+		client = ArxivCache.Client(client)
+	}
+	response, err := client.Do(request)
+	if err == nil {
+		operation.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode))
+	}
+	operation.End(err)
+	return response, err
+}
+
+// Parse an arXiv API query result feed. The results come back as an Atom feed but with some additional
+// arXiv-specific fields in the extensions. We parse out the returned data with the help of the
+// [arXiv entry metadata specification].
+//
+// Returns a list of zero or more [Paper] objects corresponding to each entry in the feed if the feed is a valid result
+// feed, otherwise returns an error. If the feed is an arXiv error feed, the error wraps [ErrArxivQuery].
+//
+// [arXiv entry metadata specification]: https://info.arxiv.org/help/api/user-manual.html#_entry_metadata
+func ParsePapers(feed io.Reader) ([]Paper, error) {
+	arxivParser := gofeed.NewParser()
+	queryResults, err := arxivParser.Parse(feed)
+	if err != nil {
+		return nil, fmt.Errorf("failed while parsing arXiv feed: %w", err)
+	}
+	papers := []Paper{}
+	for _, item := range queryResults.Items {
+		// arXiv reports errors as a feed holding a single entry whose ID points into the API error namespace.
+		if strings.Contains(item.GUID, "/api/errors") {
+			return nil, fmt.Errorf("%w: %s", ErrArxivQuery, collapseSpace(item.Description))
+		}
+		arxivFields := item.Extensions["arxiv"]
 		var authors []string
-		for _, author := range queryResults.Items[i].Authors {
+		for _, author := range item.Authors {
 			authors = append(authors, author.Name)
 		}
+		id := arxivId(item.GUID)
+		arxivUrl := item.Link
+		if arxivUrl == "" {
+			arxivUrl = "http://arxiv.org/abs/" + id
+		}
+		primaryCategory := getOptionalAttribute("primary_category", "term", arxivFields)
+		if primaryCategory == "" && len(item.Categories) > 0 {
+			primaryCategory = item.Categories[0]
+		}
 		paper := Paper{
-			Id: strings.Replace(queryResults.Items[i].GUID, "http://arxiv.org/abs/", "", 1),
-			// Remove the injected newlines from the title
-			Title:            strings.ReplaceAll(queryResults.Items[i].Title, "\n", ""),
+			Id: id,
+			// Remove the newlines and indentation that arXiv injects into long titles and summaries.
+			Title:            collapseSpace(item.Title),
 			Authors:          authors,
-			Summary:          queryResults.Items[i].Description,
-			Published:        queryResults.Items[i].Published,
-			JournalReference: getOptionalField("journal_ref", arxivFields),
+			Summary:          collapseSpace(item.Description),
+			Published:        item.Published,
+			JournalReference: collapseSpace(getOptionalField("journal_ref", arxivFields)),
 			Doi:              getOptionalField("doi", arxivFields),
-			PrimaryCategory:  arxivFields["primary_category"][0].Value,
-			Categories:       queryResults.Items[i].Categories,
+			PrimaryCategory:  primaryCategory,
+			Categories:       item.Categories,
 			// Annoyingly arXiv doesn't appear to populate the Links field with the PDF link, but according to the
 			// arXiv API specification we can construct the link.
-			PdfUrl:   strings.Replace(queryResults.Items[i].Link, "abs", "pdf", 1),
-			ArxivUrl: queryResults.Items[i].Link,
+			PdfUrl:   strings.Replace(arxivUrl, "/abs/", "/pdf/", 1),
+			ArxivUrl: arxivUrl,
 		}
 		papers = append(papers, paper)
 	}
-	return papers
+	return papers, nil
+}
+
+// Get the arXiv identifier of a paper from the ID of its feed entry, e.g., "2210.03629v3" from
+// "http://arxiv.org/abs/2210.03629v3", or "hep-th/9901001v1" from "http://arxiv.org/abs/hep-th/9901001v1".
+//
+// Returns the identifier, including any version suffix.
+func arxivId(guid string) string {
+	if _, id, found := strings.Cut(guid, "/abs/"); found {
+		return id
+	}
+	return guid
+}
+
+// Get the arXiv identifier of a paper without its version suffix, e.g., "2210.03629" from "2210.03629v3".
+//
+// Returns the identifier without any version suffix.
+func BaseArxivId(id string) string {
+	if i := strings.LastIndexByte(id, 'v'); i > 0 && i < len(id)-1 {
+		if strings.Trim(id[i+1:], "0123456789") == "" {
+			return id[:i]
+		}
+	}
+	return id
+}
+
+// Collapse every run of whitespace in a string into a single space, and trim leading and trailing whitespace.
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
 }
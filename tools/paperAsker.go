@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/constants"
+)
+
+// The number of passages of a paper we give the LLM to answer a question about the paper.
+const askPaperPassages = 6
+
+// Singleton [Tool] instance to answer a question about a paper from the full text of the paper.
+var AskPaper = Tool[paperAskerArgs]{
+	name:                   paperAskerName,
+	description:            paperAskerDescription,
+	Callback:               askPaper,
+	introspectionCallbacks: Logger,
+	timeout:                downloadTimeout,
+}
+
+const (
+	paperAskerName        = "AskPaper"
+	paperAskerDescription = `
+Answer a question about a paper from the full text of the paper, e.g., about its method, experiments, or results,
+which the summary does not cover. The paper is either an arXiv ID, which the tool downloads if necessary, or the file
+name of a paper already downloaded.
+
+JSON input format: { "paper": "<arXiv ID or file name>", "question": "<question about the paper>" }
+
+Success: Returns a JSON dictionary object containing the answer, which cites the pages it draws on as (p. N), and the
+passages of the paper it draws on along with their page numbers
+
+Failure: Returns an error message.
+`
+)
+
+// The arguments for the [AskPaper] tool. The structure and the [AskPaper] tool description must remain in sync with
+// each other to ensure that agents call the tool with the correct JSON argument keys.
+type paperAskerArgs struct {
+	Paper    string `json:"paper" description:"The arXiv ID of the paper, or the file name of a downloaded paper"`
+	Question string `json:"question" description:"The question about the paper"`
+}
+
+// The answer to a question about a paper, along with the passages of the paper that the answer draws on.
+type PaperAnswer struct {
+	Answer   string    `json:"answer"`
+	Passages []Passage `json:"passages"`
+}
+
+// The prompt with which we ask the LLM to answer a question from passages of a paper.
+const askPaperPrompt = `Answer the question about a research paper using only the passages from the paper below. Cite
+the page of every passage you draw on in the form (p. N). If the passages do not answer the question, say so instead of
+guessing.
+
+Passages:
+%s
+Question: %s
+Answer:`
+
+// Answer a question about a paper from the full text of the paper. We look for the paper in [PapersDirectory], and
+// download it from arXiv if it is not there and the paper names an arXiv ID. We extract and cache the text of the paper
+// (see [PaperText]), chunk it into passages, pick the passages most relevant to the question, and ask the LLM to
+// answer from those passages alone, citing their pages.
+//
+// Returns the answer if we answer the question successfully, otherwise returns an error.
+func AskPaperQuestion(ctx context.Context, llm llms.Model, paper string, question string) (PaperAnswer, error) {
+	path, err := locatePaper(ctx, paper)
+	if err != nil {
+		return PaperAnswer{}, err
+	}
+	pages, err := PaperText(path)
+	if err != nil {
+		return PaperAnswer{}, err
+	}
+	passages := rankPassages(question, chunkPages(pages), askPaperPassages)
+	if len(passages) == 0 {
+		return PaperAnswer{Answer: "The paper does not mention anything related to the question.", Passages: passages},
+			nil
+	}
+	var excerpts strings.Builder
+	for _, passage := range passages {
+		fmt.Fprintf(&excerpts, "(p. %d) %s\n\n", passage.Page, passage.Text)
+	}
+	answer, err := llms.GenerateFromSinglePrompt(ctx, llm, fmt.Sprintf(askPaperPrompt, excerpts.String(), question))
+	if err != nil {
+		return PaperAnswer{}, fmt.Errorf("failed while answering question: %w", err)
+	}
+	return PaperAnswer{Answer: strings.TrimSpace(answer), Passages: passages}, nil
+}
+
+// Find the local file of a paper, given as the file name of a paper in [PapersDirectory], or as an arXiv ID, in which
+// case we download the paper to the file that the download command would use, unless it is already there.
+//
+// Returns the path of the file if we find (or download) the paper, otherwise returns an error.
+func locatePaper(ctx context.Context, paper string) (string, error) {
+	directory := papersDirectoryFromContext(ctx)
+	if strings.HasSuffix(paper, ".pdf") {
+		path := filepath.Join(directory, filepath.Base(paper))
+		if !fileExists(path) {
+			return "", fmt.Errorf("no paper named '%s' in '%s'", filepath.Base(paper), directory)
+		}
+		return path, nil
+	}
+	id := strings.TrimPrefix(strings.TrimPrefix(paper, "arXiv:"), "arxiv:")
+	fileName := strings.ReplaceAll(id, "/", "_") + ".pdf"
+	path := filepath.Join(directory, fileName)
+	if fileExists(path) {
+		return path, nil
+	}
+	if err := DownloadPaper(ctx, fileName, "https://arxiv.org/pdf/"+id); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Answer a question about a paper from the full text of the paper.
+//
+// Returns a JSON dictionary object holding the answer and the passages it draws on if we answer the question
+// successfully, otherwise returns an error message.
+func askPaper(ctx context.Context, args paperAskerArgs) (string, error) {
+	if err := constants.Ready(); err != nil {
+		return "", err
+	}
+	answer, err := AskPaperQuestion(ctx, constants.Llm, args.Paper, args.Question)
+	if err != nil {
+		return fmt.Sprintf("failed while asking paper: %s", err), nil
+	}
+	content, err := json.MarshalIndent(answer, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed while marshalling answer: %w", err)
+	}
+	slog.InfoContext(ctx, "Tool returned", "results", len(answer.Passages))
+	return string(content), nil
+}
@@ -11,6 +11,7 @@ var PaperDownloader = Tool[downloadPaperArgs]{
 	description:            paperDownloaderDescription,
 	Callback:               downloadPaper,
 	introspectionCallbacks: Logger,
+	timeout:                downloadTimeout,
 }
 
 const (
@@ -30,16 +31,16 @@ Failure: Returns an error message.
 // The arguments for the [PaperDownloader] tool. The structure and the [PaperDownloader] tool description must remain
 // in sync with each other to ensure that agents call the tool with the correct JSON argument keys.
 type downloadPaperArgs struct {
-	FileName string `json:"fileName"`
-	URL      string `json:"url"`
+	FileName string `json:"fileName" description:"The file name, ending with .pdf"`
+	URL      string `json:"url" description:"The paper URL"`
 }
 
 // Download a paper from a URL to the local file system. The caller should ensure that the file name is a valid file
 // name for the local file system and ends with ".pdf".
 //
 // Returns a success message if the paper is downloaded successfully, otherwise returns an error message.
-func downloadPaper(_ context.Context, args downloadPaperArgs) (string, error) {
-	err := DownloadPaper(args.FileName, args.URL)
+func downloadPaper(ctx context.Context, args downloadPaperArgs) (string, error) {
+	err := DownloadPaper(ctx, args.FileName, args.URL)
 	if err != nil {
 		return fmt.Sprintf("failed while downloading paper: %s", err), nil
 	}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"tmwong.org/arxiv-researcher-go/constants"
+)
+
+// Singleton [Tool] instance to summarize a paper as a structured summary.
+var PaperSummarizer = Tool[paperSummarizerArgs]{
+	name:                   paperSummarizerName,
+	description:            paperSummarizerDescription,
+	Callback:               summarizePaper,
+	introspectionCallbacks: Logger,
+	timeout:                downloadTimeout,
+}
+
+const (
+	paperSummarizerName        = "SummarizePaper"
+	paperSummarizerDescription = `
+Summarize a paper as the problem it addresses, its method, the datasets it uses, its results, its limitations, and its
+key contributions, e.g., to compare papers or to describe a paper in more depth than its abstract. By default, the tool
+summarizes the abstract of the paper; optionally, it summarizes the full text of the paper instead, which takes longer.
+
+JSON input format: { "id": "<arXiv ID>", "full_text": <optional true to summarize the full text> }
+
+Success: Returns a JSON dictionary object containing the arXiv ID, title, and structured summary of the paper
+
+Failure: Returns an error message.
+`
+)
+
+// The arguments for the [PaperSummarizer] tool. The structure and the [PaperSummarizer] tool description must remain
+// in sync with each other to ensure that agents call the tool with the correct JSON argument keys.
+type paperSummarizerArgs struct {
+	Id       string `json:"id" description:"The arXiv ID of the paper to summarize"`
+	FullText bool   `json:"full_text,omitempty" description:"Summarize the full text of the paper, not its abstract"`
+}
+
+// Summarize a paper as a structured summary, reusing the summary stored in the document index if any.
+//
+// Returns a JSON dictionary object holding the summary if we summarize the paper successfully, otherwise returns an
+// error message.
+func summarizePaper(ctx context.Context, args paperSummarizerArgs) (string, error) {
+	if err := constants.Ready(); err != nil {
+		return "", err
+	}
+	index, err := IndexFromContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed while getting index: %s", err)
+	}
+	prompt, err := LoadSummaryTemplate("")
+	if err != nil {
+		return "", err
+	}
+	paper, err := index.SummarizePaper(ctx, constants.Llm, prompt, args.Id, args.FullText, false)
+	if err != nil {
+		return fmt.Sprintf("failed while summarizing paper: %s", err), nil
+	}
+	content, err := json.MarshalIndent(map[string]any{
+		"arXiv ID": BaseArxivId(paper.Id),
+		"Title":    paper.Title,
+		"Summary":  paper.StructuredSummary,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed while marshalling summary: %w", err)
+	}
+	slog.InfoContext(ctx, "Tool returned", "results", 1)
+	return string(content), nil
+}
@@ -0,0 +1,271 @@
+package tools
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+	"tmwong.org/arxiv-researcher-go/constants"
+)
+
+// The directory in the cache directory that holds the text we extract from papers.
+const PaperTextCacheDirectoryName = "papers"
+
+// The number of words in each passage into which we chunk the text of a paper, and the number of words by which
+// consecutive passages on a page overlap, so that no sentence falls between two passages.
+const (
+	passageWords   = 150
+	passageOverlap = 30
+)
+
+// Returned when a paper holds no text we can extract, e.g., because it is a scan.
+var ErrNoPaperText = errors.New("paper holds no extractable text")
+
+// The text of one page of a paper.
+type PaperPage struct {
+	// The page number, starting at 1.
+	Page int    `json:"page"`
+	Text string `json:"text"`
+}
+
+// A passage of the text of a paper, which never spans pages.
+type Passage struct {
+	// The number of the page that holds the passage, starting at 1.
+	Page int    `json:"page"`
+	Text string `json:"text"`
+}
+
+// The text of a paper in the paper text cache, along with the size and modification time of the paper file, which
+// tell us whether the paper changed since we extracted its text.
+type cachedPaperText struct {
+	Path     string      `json:"path"`
+	Size     int64       `json:"size"`
+	Modified time.Time   `json:"modified"`
+	Pages    []PaperPage `json:"pages"`
+}
+
+// Get the text of each page of a PDF paper. Extracting text from a PDF is slow, so we cache the text in the cache
+// directory, and answer from the cache until the paper file changes. If we cannot use the cache, we carry on without
+// it.
+//
+// Returns the pages that hold text if we extract any, [ErrNoPaperText] if the paper holds no text, otherwise returns
+// an error.
+func PaperText(path string) ([]PaperPage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading paper '%s': %w", path, err)
+	}
+	cachePath, err := paperTextCachePath(path)
+	if err == nil {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			var cached cachedPaperText
+			if json.Unmarshal(data, &cached) == nil && cached.Size == info.Size() &&
+				cached.Modified.Equal(info.ModTime()) {
+				return cached.Pages, nil
+			}
+		}
+	}
+	pages, err := extractPdfText(path)
+	if err != nil {
+		return nil, err
+	}
+	if cachePath != "" {
+		cached := cachedPaperText{Path: path, Size: info.Size(), Modified: info.ModTime(), Pages: pages}
+		data, err := json.Marshal(cached)
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(cachePath), 0755)
+		}
+		if err == nil {
+			err = os.WriteFile(cachePath, data, 0644)
+		}
+		if err != nil {
+			slog.Warn("Continuing without caching paper text", "error", err)
+		}
+	}
+	return pages, nil
+}
+
+// Get the path of the file in the paper text cache that holds the text of a paper, named after the hash of the
+// absolute path of the paper.
+//
+// Returns the path if we locate the cache directory successfully, otherwise returns an error.
+func paperTextCachePath(path string) (string, error) {
+	directory, err := constants.CacheDirectory()
+	if err != nil {
+		return "", err
+	}
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(absolute))
+	return filepath.Join(directory, PaperTextCacheDirectoryName, hex.EncodeToString(hash[:16])+".json"), nil
+}
+
+// Extract the text of each page of a PDF file, collapsing runs of whitespace. The PDF library panics on some malformed
+// files, so we recover from panics and report them as errors.
+//
+// Returns the pages that hold text if we extract any, [ErrNoPaperText] if the file holds no text, otherwise returns an
+// error.
+func extractPdfText(path string) (pages []PaperPage, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pages, err = nil, fmt.Errorf("failed while extracting text from '%s': %v", path, r)
+		}
+	}()
+	file, reader, err := pdf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed while opening PDF '%s': %w", path, err)
+	}
+	defer file.Close()
+	for number := 1; number <= reader.NumPage(); number++ {
+		page := reader.Page(number)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed while extracting text from page %d of '%s': %w", number, path, err)
+		}
+		if text = collapseSpace(text); text != "" {
+			pages = append(pages, PaperPage{Page: number, Text: text})
+		}
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoPaperText, path)
+	}
+	return pages, nil
+}
+
+// Chunk the text of the pages of a paper into overlapping passages of a fixed number of words.
+//
+// Returns the passages, in page order.
+func chunkPages(pages []PaperPage) []Passage {
+	var passages []Passage
+	for _, page := range pages {
+		words := strings.Fields(page.Text)
+		for start := 0; start < len(words); start += passageWords - passageOverlap {
+			end := min(start+passageWords, len(words))
+			passages = append(passages, Passage{Page: page.Page, Text: strings.Join(words[start:end], " ")})
+			if end == len(words) {
+				break
+			}
+		}
+	}
+	return passages
+}
+
+// Rank passages by their relevance to a question with the Okapi BM25 ranking function. We rank passages lexically
+// rather than by embedding, so that asking about a paper needs no embedding requests, and works without an index.
+//
+// Returns up to the given number of passages that share words with the question, most relevant first.
+func rankPassages(question string, passages []Passage, count int) []Passage {
+	texts := make([]string, len(passages))
+	for i, passage := range passages {
+		texts[i] = passage.Text
+	}
+	scores := bm25Scores(question, texts)
+	type scored struct {
+		passage Passage
+		score   float64
+	}
+	var ranked []scored
+	for i, score := range scores {
+		if score > 0 {
+			ranked = append(ranked, scored{passage: passages[i], score: score})
+		}
+	}
+	slices.SortStableFunc(ranked, func(x, y scored) int {
+		return cmp.Compare(y.score, x.score)
+	})
+	result := make([]Passage, 0, min(count, len(ranked)))
+	for _, r := range ranked[:min(count, len(ranked))] {
+		result = append(result, r.passage)
+	}
+	return result
+}
+
+// Score texts by their relevance to a query with the Okapi BM25 ranking function, treating the texts as the whole
+// collection of documents.
+//
+// Returns the score of each text, which is 0 for texts that share no words with the query.
+func bm25Scores(query string, texts []string) []float64 {
+	const k1, b = 1.2, 0.75
+	terms := tokenize(query)
+	documents := make([][]string, len(texts))
+	frequencies := map[string]int{}
+	totalLength := 0
+	for i, text := range texts {
+		documents[i] = tokenize(text)
+		totalLength += len(documents[i])
+		seen := map[string]bool{}
+		for _, word := range documents[i] {
+			if !seen[word] {
+				seen[word] = true
+				frequencies[word]++
+			}
+		}
+	}
+	scores := make([]float64, len(texts))
+	if totalLength == 0 {
+		return scores
+	}
+	averageLength := float64(totalLength) / float64(len(texts))
+	for i, document := range documents {
+		counts := map[string]int{}
+		for _, word := range document {
+			counts[word]++
+		}
+		for _, term := range terms {
+			tf := float64(counts[term])
+			if tf == 0 {
+				continue
+			}
+			n := float64(frequencies[term])
+			idf := math.Log(1 + (float64(len(texts))-n+0.5)/(n+0.5))
+			scores[i] += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(len(document))/averageLength))
+		}
+	}
+	return scores
+}
+
+// Split a text into lower-cased words of letters and digits, dropping common English words that carry no meaning on
+// their own.
+//
+// Returns the words.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return slices.DeleteFunc(words, func(word string) bool {
+		return stopWords[word]
+	})
+}
+
+// Common English words that we ignore when ranking passages.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true, "do": true,
+	"does": true, "for": true, "from": true, "how": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "that": true, "the": true, "this": true, "to": true, "was": true, "what": true, "which": true,
+	"with": true, "why": true, "who": true, "we": true, "they": true, "their": true, "its": true, "paper": true,
+}
+
+// Check whether a path names an existing file.
+//
+// Returns true if the file exists, otherwise returns false.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/fakes"
+)
+
+// Write a minimal PDF file with one page per given text, in the Helvetica font.
+func writeTestPdf(t *testing.T, path string, pages []string) {
+	t.Helper()
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+	for i, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] "+
+				"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		)
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buffer.Len()
+		fmt.Fprintf(&buffer, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buffer.Len()
+	fmt.Fprintf(&buffer, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buffer, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buffer, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	if err := os.WriteFile(path, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Extracted text comes from the cache until the paper changes.
+func TestPaperText(t *testing.T) {
+	t.Setenv("ARXIV_RESEARCHER_CACHE_DIR", t.TempDir())
+	path := filepath.Join(t.TempDir(), "paper.pdf")
+	writeTestPdf(t, path, []string{"Reasoning and acting.", "We evaluate on HotpotQA."})
+	pages, err := PaperText(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 || pages[1].Page != 2 || pages[1].Text != "We evaluate on HotpotQA." {
+		t.Errorf("unexpected pages %+v", pages)
+	}
+	cachePath, err := paperTextCachePath(path)
+	if err != nil || !fileExists(cachePath) {
+		t.Fatalf("expected cached text at '%s', error %v", cachePath, err)
+	}
+
+	writeTestPdf(t, path, []string{"A different paper altogether."})
+	if pages, err = PaperText(path); err != nil || len(pages) != 1 {
+		t.Errorf("expected the changed paper to be extracted again, got %+v, error %v", pages, err)
+	}
+}
+
+// Passages overlap, stay on their pages, and rank by the words they share with the question.
+func TestRankPassages(t *testing.T) {
+	words := make([]string, passageWords+10)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+	passages := chunkPages([]PaperPage{
+		{Page: 1, Text: strings.Join(words, " ")},
+		{Page: 2, Text: "We evaluate ReAct on HotpotQA and FEVER."},
+		{Page: 3, Text: "ReAct prompts interleave reasoning traces and actions."},
+	})
+	if len(passages) != 4 || passages[1].Page != 1 || !strings.HasPrefix(passages[1].Text,
+		fmt.Sprintf("w%d ", passageWords-passageOverlap)) {
+		t.Fatalf("unexpected passages %+v", passages)
+	}
+	ranked := rankPassages("Which datasets does the paper evaluate on?", passages, 2)
+	if len(ranked) != 1 || ranked[0].Page != 2 {
+		t.Errorf("unexpected ranking %+v", ranked)
+	}
+	ranked = rankPassages("How does ReAct interleave reasoning?", passages, 2)
+	if len(ranked) != 2 || ranked[0].Page != 3 {
+		t.Errorf("unexpected ranking %+v", ranked)
+	}
+}
+
+// The LLM answers from the most relevant passages, labelled with their pages.
+func TestAskPaperQuestion(t *testing.T) {
+	t.Setenv("ARXIV_RESEARCHER_CACHE_DIR", t.TempDir())
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(PapersDirectory, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestPdf(t, filepath.Join(PapersDirectory, "2210.03629.pdf"),
+		[]string{"ReAct interleaves reasoning and acting.", "We evaluate on HotpotQA and FEVER."})
+	llm := fakes.NewLLM(fakes.Answer("ReAct is evaluated on HotpotQA and FEVER (p. 2)."))
+
+	answer, err := AskPaperQuestion(t.Context(), llm, "2210.03629", "Which datasets are used to evaluate ReAct?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Answer != "ReAct is evaluated on HotpotQA and FEVER (p. 2)." || len(answer.Passages) != 2 ||
+		answer.Passages[0].Page != 2 {
+		t.Errorf("unexpected answer %+v", answer)
+	}
+	prompt := llm.Calls()[0][0].Parts[0].(llms.TextContent).Text
+	if !strings.Contains(prompt, "(p. 2) We evaluate on HotpotQA and FEVER.") {
+		t.Errorf("expected the prompt to label passages with pages, got %s", prompt)
+	}
+
+	if _, err := AskPaperQuestion(t.Context(), llm, "missing.pdf", "Anything?"); err == nil {
+		t.Error("expected an error for a missing paper file")
+	}
+}
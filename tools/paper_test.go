@@ -0,0 +1,196 @@
+package tools
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Parse an arXiv feed fixture from the testdata directory.
+func parseFixture(t *testing.T, name string) ([]Paper, error) {
+	t.Helper()
+	feed, err := os.Open(filepath.Join("testdata", "arxiv", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer feed.Close()
+	return ParsePapers(feed)
+}
+
+func TestParsePapers(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []Paper
+	}{
+		{
+			// Keep the version suffix of the ID, and join the title that arXiv wraps across lines.
+			fixture: "multi_version.atom",
+			want: []Paper{{
+				Id:    "2210.03629v3",
+				Title: "ReAct: Synergizing Reasoning and Acting in Language Models",
+				Authors: []string{
+					"Shunyu Yao",
+					"Jeffrey Zhao",
+					"Dian Yu",
+				},
+				Summary: "While large language models (LLMs) have demonstrated impressive capabilities across " +
+					"tasks in language understanding and interactive decision making, their abilities for " +
+					"reasoning and acting have primarily been studied as separate topics.",
+				Published:       "2022-10-06T01:00:32Z",
+				PrimaryCategory: "cs.CL",
+				Categories:      []string{"cs.CL", "cs.AI", "cs.LG"},
+				PdfUrl:          "http://arxiv.org/pdf/2210.03629v3",
+				ArxivUrl:        "http://arxiv.org/abs/2210.03629v3",
+			}},
+		},
+		{
+			// Keep the archive prefix of old-style IDs, and pick up the optional DOI and journal reference.
+			fixture: "old_style_id.atom",
+			want: []Paper{{
+				Id:               "hep-th/9901001v1",
+				Title:            "Boundary States and Black Hole Entropy",
+				Authors:          []string{"A. Author"},
+				Summary:          "We study boundary states of closed strings.",
+				Published:        "1999-01-01T17:34:46Z",
+				JournalReference: "Phys.Lett. B451 (1999) 1-8",
+				Doi:              "10.1016/S0370-2693(99)00123-4",
+				PrimaryCategory:  "hep-th",
+				Categories:       []string{"hep-th"},
+				PdfUrl:           "http://arxiv.org/pdf/hep-th/9901001v1",
+				ArxivUrl:         "http://arxiv.org/abs/hep-th/9901001v1",
+			}},
+		},
+		{
+			// Fall back to the update time, the first category, and constructed links for missing fields.
+			fixture: "missing_fields.atom",
+			want: []Paper{{
+				Id:              "2401.00001v1",
+				Title:           "A Sparse Entry",
+				Authors:         []string{"Solo Author"},
+				Summary:         "Only the required fields.",
+				Published:       "2024-01-01T00:00:01Z",
+				PrimaryCategory: "stat.ML",
+				Categories:      []string{"stat.ML"},
+				PdfUrl:          "http://arxiv.org/pdf/2401.00001v1",
+				ArxivUrl:        "http://arxiv.org/abs/2401.00001v1",
+			}},
+		},
+		{
+			// Preserve unicode and LaTeX markup verbatim, apart from collapsing whitespace.
+			fixture: "unicode_latex.atom",
+			want: []Paper{{
+				Id: "2301.01234v2",
+				Title: "On the $\\alpha$-Stable Schrödinger Equation with $L^2$ Initial Data: " +
+					"Café Effects & Beyond",
+				Authors: []string{"Zoë Ångström", "José Núñez"},
+				Summary: "We prove well-posedness for $\\partial_t u = -(-\\Delta)^{\\alpha/2} u$ in " +
+					"$\\mathbb{R}^d$, à la Müller.",
+				Published:       "2023-01-03T12:00:00Z",
+				PrimaryCategory: "math.AP",
+				Categories:      []string{"math.AP", "math-ph"},
+				PdfUrl:          "http://arxiv.org/pdf/2301.01234v2",
+				ArxivUrl:        "http://arxiv.org/abs/2301.01234v2",
+			}},
+		},
+		{
+			fixture: "empty.atom",
+			want:    []Paper{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			papers, err := parseFixture(t, test.fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(papers, test.want) {
+				t.Errorf("got papers\n%+v\nwant\n%+v", papers, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePapersErrorFeed(t *testing.T) {
+	_, err := parseFixture(t, "error.atom")
+	if !errors.Is(err, ErrArxivQuery) {
+		t.Fatalf("expected ErrArxivQuery, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "incorrect id format for 1234.12345") {
+		t.Errorf("error does not carry the arXiv message: %s", err)
+	}
+}
+
+func TestParsePapersMalformedFeed(t *testing.T) {
+	if _, err := ParsePapers(strings.NewReader("<html>Service Unavailable</html>")); err == nil {
+		t.Error("expected an error for a feed that is not Atom")
+	}
+}
+
+// A transport that answers every request with a fixed status and fixture file, and remembers the requested URL.
+type fixtureTransport struct {
+	status  int
+	fixture string
+	url     string
+}
+
+func (transport *fixtureTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport.url = request.URL.String()
+	feed, err := os.Open(filepath.Join("testdata", "arxiv", transport.fixture))
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: transport.status, Status: http.StatusText(transport.status), Body: feed}, nil
+}
+
+func TestFetchPapers(t *testing.T) {
+	savedClient := HttpClient
+	t.Cleanup(func() { HttpClient = savedClient })
+
+	transport := &fixtureTransport{status: http.StatusOK, fixture: "multi_version.atom"}
+	HttpClient = &http.Client{Transport: transport}
+	papers, err := FetchPapers(t.Context(), "reasoning acting", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(papers) != 1 || papers[0].Id != "2210.03629v3" {
+		t.Errorf("unexpected papers %+v", papers)
+	}
+	want := "http://export.arxiv.org/api/query?search_query=all:reasoning+acting&start=0&max_results=1"
+	if transport.url != want {
+		t.Errorf("queried %s, want %s", transport.url, want)
+	}
+
+	// arXiv answers malformed queries with HTTP 400 and an error feed, whose message we prefer over the status.
+	HttpClient = &http.Client{Transport: &fixtureTransport{status: http.StatusBadRequest, fixture: "error.atom"}}
+	if _, err := FetchPapers(t.Context(), "id:1234.12345", 10); !errors.Is(err, ErrArxivQuery) {
+		t.Errorf("expected ErrArxivQuery, got %v", err)
+	}
+	// Other failures come with an HTML page rather than a feed, so we report the status.
+	HttpClient = &http.Client{Transport: &fixtureTransport{status: http.StatusServiceUnavailable,
+		fixture: "unavailable.html"}}
+	if _, err := FetchPapers(t.Context(), "agents", 10); err == nil ||
+		!strings.Contains(err.Error(), http.StatusText(http.StatusServiceUnavailable)) {
+		t.Errorf("expected the HTTP status, got %v", err)
+	}
+}
+
+// Recent papers come newest first, optionally from one category.
+func TestFetchRecentPapers(t *testing.T) {
+	savedClient := HttpClient
+	t.Cleanup(func() { HttpClient = savedClient })
+
+	transport := &fixtureTransport{status: http.StatusOK, fixture: "multi_version.atom"}
+	HttpClient = &http.Client{Transport: transport}
+	if _, err := FetchRecentPapers(t.Context(), "agents", "cs.CL", 5); err != nil {
+		t.Fatal(err)
+	}
+	want := "http://export.arxiv.org/api/query?search_query=%28all%3Aagents%29+AND+cat%3Acs.CL" +
+		"&sortBy=submittedDate&sortOrder=descending&start=0&max_results=5"
+	if transport.url != want {
+		t.Errorf("queried %s, want %s", transport.url, want)
+	}
+}
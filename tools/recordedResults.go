@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// The results of the tool calls of an earlier run, by tool and input, which tools replaying the run answer calls
+// from (see [Tool.WithRecordedResults]). Replaying a run re-executes its agent, e.g., with a changed prompt, against
+// the same tool results, without calling external services or downloading papers again.
+//
+// Recorded results are safe for concurrent use.
+type RecordedResults struct {
+	mutex   sync.Mutex
+	results map[toolCall][]recordedResult
+}
+
+// The outcome of a recorded tool call: either its output, or the error that it failed with.
+type recordedResult struct {
+	output string
+	err    string
+}
+
+// Collect the outcomes of the tool calls in a transcript (see [TranscriptEvent]), both of the calls that ended
+// successfully and of the calls that failed, so that replaying the run reproduces the failures too.
+//
+// Returns the recorded results.
+func NewRecordedResults(events []TranscriptEvent) *RecordedResults {
+	recorded := &RecordedResults{results: map[toolCall][]recordedResult{}}
+	for _, event := range events {
+		var result recordedResult
+		switch event.Event {
+		case ToolEndEvent:
+			result = recordedResult{output: event.Output}
+		case ToolErrorEvent:
+			result = recordedResult{err: event.Error}
+		default:
+			continue
+		}
+		key := toolCall{name: event.Tool, input: normalizeInput(event.Input)}
+		recorded.results[key] = append(recorded.results[key], result)
+	}
+	return recorded
+}
+
+// Take the next recorded result of a call of a tool with an input. Calls with the same tool and input take the
+// results of the recorded calls in order, and reuse the last result once they run out.
+//
+// Returns the recorded result, or a message that tells the agent the result is missing if the run never made the
+// call, along with the error of the recorded call if it failed.
+func (recorded *RecordedResults) Take(name string, input string) (string, error) {
+	recorded.mutex.Lock()
+	defer recorded.mutex.Unlock()
+	key := toolCall{name: name, input: normalizeInput(input)}
+	results := recorded.results[key]
+	if len(results) == 0 {
+		return fmt.Sprintf("Tool '%s' has no recorded result for this input in the replayed run", name), nil
+	}
+	if len(results) > 1 {
+		recorded.results[key] = results[1:]
+	}
+	if results[0].err != "" {
+		return "", errors.New(results[0].err)
+	}
+	return results[0].output, nil
+}
+
+// Normalize the JSON input of a tool call, so that calls that differ only in whitespace match.
+//
+// Returns the compacted JSON, or the input itself if it is not JSON.
+func normalizeInput(input string) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(input)); err != nil {
+		return input
+	}
+	return compact.String()
+}
@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// The strategies with which we retrieve papers from the index for a query (see [Index.RetrievePapers]).
+const (
+	// Search the index by the similarity of the embeddings of papers to the embedding of the query, as the
+	// [IndexSearcher] tool does.
+	VectorRetrieval = "vector"
+	// Fuse the vector search with a lexical (BM25) ranking of its candidates by reciprocal rank fusion, which favors
+	// papers that share rare words, e.g., names of methods, with the query.
+	HybridRetrieval = "hybrid"
+	// Have the LLM re-rank the candidates of the vector search by their relevance to the query.
+	RerankedRetrieval = "reranked"
+)
+
+// The retrieval strategies, in order of cost.
+var RetrievalStrategies = []string{VectorRetrieval, HybridRetrieval, RerankedRetrieval}
+
+// The factor by which hybrid and re-ranked retrieval over-fetch candidates from the vector search.
+const retrievalCandidateFactor = 4
+
+// The constant of reciprocal rank fusion, which damps the influence of the top ranks of each ranking.
+const rankFusionConstant = 60
+
+// The number of words of the summary of each candidate that we show the LLM when re-ranking.
+const rerankSummaryWords = 60
+
+// Returned when the LLM does not respond with a ranking of the candidates.
+var ErrInvalidRanking = errors.New("LLM returned an invalid ranking")
+
+// The prompt with which we ask the LLM to re-rank candidate papers for a query.
+const rerankPrompt = `You are helping a researcher find papers relevant to a search query. Rank the candidate papers
+below by their relevance to the query, most relevant first. Respond with a JSON array of the numbers of the candidates,
+e.g., [3, 1, 2], leaving out candidates that are not relevant at all, and nothing else.
+
+Query: %s
+
+Candidates:
+%s`
+
+// Retrieve papers from the index for a query with a retrieval strategy, e.g., [HybridRetrieval]. Re-ranked retrieval
+// asks the given LLM to re-rank the candidates, while other strategies need no LLM.
+//
+// Returns up to the given number of papers, most relevant first, if we retrieve them successfully, otherwise returns
+// an error.
+func (index *Index) RetrievePapers(
+	ctx context.Context,
+	llm llms.Model,
+	strategy string,
+	query string,
+	count int,
+) ([]Paper, error) {
+	switch strategy {
+	case VectorRetrieval:
+		return index.SearchPapers(ctx, query, count)
+	case HybridRetrieval:
+		candidates, err := index.SearchPapers(ctx, query, count*retrievalCandidateFactor)
+		if err != nil {
+			return nil, err
+		}
+		return fuseRankings(candidates, lexicalRanking(query, candidates))[:min(count, len(candidates))], nil
+	case RerankedRetrieval:
+		candidates, err := index.SearchPapers(ctx, query, count*retrievalCandidateFactor)
+		if err != nil {
+			return nil, err
+		}
+		papers, err := RerankPapers(ctx, llm, query, candidates)
+		if err != nil {
+			return nil, err
+		}
+		return papers[:min(count, len(papers))], nil
+	default:
+		return nil, fmt.Errorf("unknown retrieval strategy '%s'", strategy)
+	}
+}
+
+// Rank papers lexically by the BM25 scores of their titles and summaries for a query.
+//
+// Returns the papers, most relevant first, with papers of equal scores in their original order.
+func lexicalRanking(query string, papers []Paper) []Paper {
+	texts := make([]string, len(papers))
+	for i, paper := range papers {
+		texts[i] = paper.Title + "\n" + paper.Summary
+	}
+	scores := bm25Scores(query, texts)
+	order := make([]int, len(papers))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(x, y int) int {
+		return cmp.Compare(scores[y], scores[x])
+	})
+	ranked := make([]Paper, len(papers))
+	for i, j := range order {
+		ranked[i] = papers[j]
+	}
+	return ranked
+}
+
+// Fuse rankings of the same papers by reciprocal rank fusion: each paper scores the sum of 1 / (k + rank) over the
+// rankings.
+//
+// Returns the papers of the first ranking in fused order, with papers of equal scores in their order in the first
+// ranking.
+func fuseRankings(rankings ...[]Paper) []Paper {
+	scores := map[string]float64{}
+	for _, ranking := range rankings {
+		for rank, paper := range ranking {
+			scores[paper.Id] += 1 / float64(rankFusionConstant+rank+1)
+		}
+	}
+	fused := slices.Clone(rankings[0])
+	slices.SortStableFunc(fused, func(x, y Paper) int {
+		return cmp.Compare(scores[y.Id], scores[x.Id])
+	})
+	return fused
+}
+
+// Re-rank papers by their relevance to a query with the LLM. Papers that the LLM leaves out of its ranking follow the
+// papers it ranks, in their original order, so that re-ranking never loses papers.
+//
+// Returns the papers, most relevant first, if the LLM ranks them, [ErrInvalidRanking] if the LLM responds with no
+// ranking, otherwise returns an error.
+func RerankPapers(ctx context.Context, llm llms.Model, query string, papers []Paper) ([]Paper, error) {
+	if len(papers) < 2 {
+		return papers, nil
+	}
+	var candidates strings.Builder
+	for i, paper := range papers {
+		words := strings.Fields(paper.Summary)
+		summary := strings.Join(words[:min(len(words), rerankSummaryWords)], " ")
+		fmt.Fprintf(&candidates, "[%d] %s: %s\n", i+1, paper.Title, summary)
+	}
+	response, err := llms.GenerateFromSinglePrompt(ctx, llm, fmt.Sprintf(rerankPrompt, query, candidates.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed while re-ranking papers: %w", err)
+	}
+	// LLMs sometimes wrap the ranking in prose or code fences, so we parse the outermost JSON array in the response.
+	start, end := strings.Index(response, "["), strings.LastIndex(response, "]")
+	var numbers []int
+	if start < 0 || end < start || json.Unmarshal([]byte(response[start:end+1]), &numbers) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRanking, response)
+	}
+	ranked := make([]Paper, 0, len(papers))
+	taken := make([]bool, len(papers))
+	for _, number := range numbers {
+		if number >= 1 && number <= len(papers) && !taken[number-1] {
+			taken[number-1] = true
+			ranked = append(ranked, papers[number-1])
+		}
+	}
+	for i, paper := range papers {
+		if !taken[i] {
+			ranked = append(ranked, paper)
+		}
+	}
+	return ranked, nil
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/fakes"
+)
+
+// Return the titles of papers, separated by commas.
+func paperTitles(papers []Paper) string {
+	titles := make([]string, len(papers))
+	for i, paper := range papers {
+		titles[i] = paper.Title
+	}
+	return strings.Join(titles, ",")
+}
+
+// Lexical ranking favors papers sharing words with the query, and rank fusion favors papers ranking high in both
+// rankings.
+func TestFuseRankings(t *testing.T) {
+	papers := []Paper{
+		{Id: "1", Title: "Agents", Summary: "Language agents that plan."},
+		{Id: "2", Title: "Reflexion", Summary: "Agents that reflect on verbal feedback."},
+		{Id: "3", Title: "Toolformer", Summary: "Models that learn to use tools."},
+	}
+	lexical := lexicalRanking("verbal reflection of agents", papers)
+	if titles := paperTitles(lexical); titles != "Reflexion,Agents,Toolformer" {
+		t.Errorf("unexpected lexical ranking %s", titles)
+	}
+	if titles := paperTitles(fuseRankings(papers, lexical)); titles != "Agents,Reflexion,Toolformer" {
+		t.Errorf("unexpected fused ranking %s", titles)
+	}
+	if titles := paperTitles(fuseRankings(papers, lexical, lexical)); titles != "Reflexion,Agents,Toolformer" {
+		t.Errorf("unexpected fused ranking %s", titles)
+	}
+}
+
+// Every strategy retrieves up to the given number of papers, and re-ranking follows the LLM's ranking, keeping the
+// papers the LLM leaves out.
+func TestRetrievePapers(t *testing.T) {
+	index := newManagedIndex(t)
+	for _, strategy := range []string{VectorRetrieval, HybridRetrieval} {
+		papers, err := index.RetrievePapers(t.Context(), nil, strategy, "Reflexion", 2)
+		if err != nil || len(papers) != 2 || papers[0].Title != "Reflexion" {
+			t.Errorf("unexpected %s retrieval %+v, error %v", strategy, papers, err)
+		}
+	}
+
+	llm := fakes.NewLLM(fakes.Answer("Ranking: [3, 1, 7, 3]"))
+	vector, err := index.RetrievePapers(t.Context(), nil, VectorRetrieval, "Reflexion", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reranked, err := index.RetrievePapers(t.Context(), llm, RerankedRetrieval, "Reflexion", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Paper{vector[2], vector[0], vector[1]}
+	if paperTitles(reranked) != paperTitles(want) {
+		t.Errorf("expected re-ranking %s, got %s", paperTitles(want), paperTitles(reranked))
+	}
+	if prompt := llm.Calls()[0][0].Parts[0].(llms.TextContent).Text; !strings.Contains(prompt, "Query: Reflexion") {
+		t.Errorf("unexpected re-ranking prompt %q", prompt)
+	}
+
+	llm = fakes.NewLLM(fakes.Answer("The first paper is the most relevant."))
+	_, err = index.RetrievePapers(t.Context(), llm, RerankedRetrieval, "Reflexion", 3)
+	if !errors.Is(err, ErrInvalidRanking) {
+		t.Errorf("expected ErrInvalidRanking, got %v", err)
+	}
+	if _, err := index.RetrievePapers(t.Context(), nil, "keyword", "Reflexion", 3); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// Get a JSON schema describing the input argument structure of a tool. Agents that use native LLM function calling
+// pass this schema to the LLM instead of parsing the pseudocode schema from the tool description.
+//
+// Returns a JSON schema object for the input argument structure type T.
+func (tool Tool[T]) Parameters() map[string]any {
+	var args T
+	return schemaOf(reflect.TypeOf(args))
+}
+
+// Build a JSON schema for a Go type. We derive property names from `json` struct tags, property descriptions from
+// `description` struct tags, and treat every property not tagged with `omitempty` as required.
+//
+// Returns a JSON schema object for the type.
+func schemaOf(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		required := []string{}
+		for i := range t.NumField() {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			property := schemaOf(field.Type)
+			if description := field.Tag.Get("description"); description != "" {
+				property["description"] = description
+			}
+			properties[name] = property
+			if !strings.Contains(options, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]any{"type": "object", "properties": properties, "required": required}
+	default:
+		return map[string]any{}
+	}
+}
+
+// Check that a decoded JSON value, e.g., as decoded by [json.Unmarshal] into an any, conforms to a JSON schema built by
+// [schemaOf]. We check the types of values, the required properties of objects, and the items of arrays; we ignore
+// properties the schema does not describe.
+//
+// Returns nil if the value conforms to the schema, otherwise returns an error naming an offending value.
+func validateSchema(value any, schema map[string]any) error {
+	return validateValue(value, schema, "$")
+}
+
+// Check that a decoded JSON value at a path conforms to a JSON schema built by [schemaOf].
+//
+// Returns nil if the value conforms to the schema, otherwise returns an error naming an offending value.
+func validateValue(value any, schema map[string]any, path string) error {
+	kind, _ := schema["type"].(string)
+	mismatch := func() error {
+		return fmt.Errorf("expected %s at '%s', got %s", kind, path, jsonTypeOf(value))
+	}
+	switch kind {
+	case "string":
+		if _, ok := value.(string); !ok {
+			return mismatch()
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return mismatch()
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			return mismatch()
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return mismatch()
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return mismatch()
+		}
+		itemSchema, _ := schema["items"].(map[string]any)
+		for i, item := range items {
+			if err := validateValue(item, itemSchema, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("missing required property '%s' at '%s'", name, path)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		additional, _ := schema["additionalProperties"].(map[string]any)
+		for name, property := range object {
+			propertySchema, ok := properties[name].(map[string]any)
+			if !ok {
+				propertySchema = additional
+			}
+			if propertySchema == nil {
+				continue
+			}
+			if err := validateValue(property, propertySchema, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Get the JSON type name of a decoded JSON value.
+//
+// Returns the type name.
+func jsonTypeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Singleton [Tool] instance to find papers in the document index similar to a given paper.
+var SimilarPapers = Tool[similarPapersArgs]{
+	name:                   similarPapersName,
+	description:            similarPapersDescription,
+	Callback:               findSimilarPapers,
+	introspectionCallbacks: Logger,
+	timeout:                searchTimeout,
+}
+
+const (
+	similarPapersName        = "SimilarPapers"
+	similarPapersDescription = `
+Find papers in the document index similar to a given paper, e.g., to explore related work on a paper the user names.
+The given paper need not be in the index. Optionally, only return papers listed under an arXiv category, or published
+within a date range.
+
+JSON input format: { "id": "<arXiv ID>", "n": <number of results>, "category": "<optional arXiv category>",
+"since": "<optional YYYY-MM-DD>", "until": "<optional YYYY-MM-DD>" }
+
+Success: Returns a JSON array of dictionary objects containing the arXiv ID, title, summary, authors, publication date,
+and PDF download link for each paper
+
+Failure: Returns an error message.
+`
+)
+
+// The arguments for the [SimilarPapers] tool. The structure and the [SimilarPapers] tool description must remain in
+// sync with each other to ensure that agents call the tool with the correct JSON argument keys.
+type similarPapersArgs struct {
+	Id       string `json:"id" description:"The arXiv ID of the paper to find similar papers to"`
+	N        int    `json:"n" description:"The number of results to return"`
+	Category string `json:"category,omitempty" description:"Only return papers listed under this arXiv category"`
+	Since    string `json:"since,omitempty" description:"Only return papers published since this date (YYYY-MM-DD)"`
+	Until    string `json:"until,omitempty" description:"Only return papers published by this date (YYYY-MM-DD)"`
+}
+
+// Find papers in the document index similar to a given paper.
+//
+// Returns a JSON array of dictionary objects describing each similar paper if the search is successful, otherwise
+// returns an error message.
+func findSimilarPapers(ctx context.Context, args similarPapersArgs) (string, error) {
+	index, err := IndexFromContext(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed while getting index: %s", err)
+	}
+	filter := PaperFilter{Category: args.Category, Since: args.Since, Until: args.Until}
+	papers, err := index.SimilarPapers(ctx, args.Id, args.N, filter)
+	if err != nil {
+		return fmt.Sprintf("failed while finding similar papers: %s", err), nil
+	}
+	cookedPapers := make([]map[string]string, len(papers))
+	for i, paper := range papers {
+		cookedPapers[i] = map[string]string{
+			"arXiv ID":  BaseArxivId(paper.Id),
+			"Title":     paper.Title,
+			"Authors":   strings.Join(paper.Authors, ", "),
+			"Published": paper.Published,
+			"PDF URL":   paper.PdfUrl,
+			"Summary":   paper.Summary,
+		}
+	}
+	content, err := json.MarshalIndent(cookedPapers, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed while marshalling documents: %w", err)
+	}
+	slog.InfoContext(ctx, "Tool returned", "results", len(papers))
+	return string(content), nil
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"tmwong.org/arxiv-researcher-go/fakes"
+)
+
+// Similar papers exclude the seed paper and pass the filter.
+func TestSimilarPapers(t *testing.T) {
+	managedIndex := newManagedIndex(t)
+	papers, err := managedIndex.SimilarPapers(t.Context(), "2210.03629v1", 5, PaperFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(papers) != 2 {
+		t.Fatalf("expected 2 similar papers, got %+v", papers)
+	}
+	for _, paper := range papers {
+		if paper.Title == "ReAct" {
+			t.Errorf("expected the seed paper to be excluded, got %+v", papers)
+		}
+	}
+
+	papers, err = managedIndex.SimilarPapers(t.Context(), "2210.03629", 5, PaperFilter{Category: "cs.LG"})
+	if err != nil || len(papers) != 1 || papers[0].Title != "Reflexion" {
+		t.Errorf("unexpected filtered papers %+v, error %v", papers, err)
+	}
+}
+
+// A selective filter keeps fetching neighbors until enough of them pass it, however far from the seed paper they are.
+func TestSimilarPapersWithSelectiveFilter(t *testing.T) {
+	index := &Index{store: fakes.NewVectorStore(fakes.NewEmbedder(), "")}
+	papers := []Paper{{Id: "2210.03629v3", Title: "Reasoning and acting agents", PrimaryCategory: "cs.CL",
+		Categories: []string{"cs.CL"}, ArxivUrl: "http://arxiv.org/abs/2210.03629v3"}}
+	for i := range 12 {
+		id := fmt.Sprintf("2301.%05dv1", i)
+		papers = append(papers, Paper{Id: id, Title: fmt.Sprintf("Reasoning and acting agents, part %d", i),
+			PrimaryCategory: "cs.CL", Categories: []string{"cs.CL"}, ArxivUrl: "http://arxiv.org/abs/" + id})
+	}
+	papers = append(papers, Paper{Id: "2302.00001v1", Title: "Protein folding", PrimaryCategory: "q-bio.BM",
+		Categories: []string{"q-bio.BM"}, ArxivUrl: "http://arxiv.org/abs/2302.00001v1"})
+	if err := index.AddPapers(t.Context(), papers); err != nil {
+		t.Fatal(err)
+	}
+	similar, err := index.SimilarPapers(t.Context(), "2210.03629", 1, PaperFilter{Category: "q-bio.BM"})
+	if err != nil || len(similar) != 1 || similar[0].Title != "Protein folding" {
+		t.Errorf("unexpected filtered papers %+v, error %v", similar, err)
+	}
+}
+
+// A seed paper missing from the index comes from arXiv.
+func TestSimilarPapersFromArxiv(t *testing.T) {
+	savedClient := HttpClient
+	t.Cleanup(func() { HttpClient = savedClient })
+	transport := &fixtureTransport{status: http.StatusOK, fixture: "multi_version.atom"}
+	HttpClient = &http.Client{Transport: transport}
+
+	managedIndex := newManagedIndex(t)
+	if _, err := managedIndex.DeletePapers(t.Context(), []string{"2210.03629"}, false); err != nil {
+		t.Fatal(err)
+	}
+	papers, err := managedIndex.SimilarPapers(t.Context(), "2210.03629", 1, PaperFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(papers) != 1 {
+		t.Errorf("expected 1 similar paper, got %+v", papers)
+	}
+	if !strings.Contains(transport.url, "id_list=2210.03629") {
+		t.Errorf("expected a query by arXiv ID, got %s", transport.url)
+	}
+}
+
+// The tool passes the filter arguments on to the index.
+func TestSimilarPapersTool(t *testing.T) {
+	index = newManagedIndex(t)
+	t.Cleanup(func() { index = nil })
+	result, err := SimilarPapers.Call(t.Context(), `{"id": "2210.03629", "n": 3, "since": "2023-03-01"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, `"arXiv ID": "2303.11366"`) || strings.Contains(result, "Toolformer") {
+		t.Errorf("unexpected result %s", result)
+	}
+}
@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tmc/langchaingo/schema"
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+// The format name of index snapshots, which identifies a snapshot file by its first line.
+const SnapshotFormat = "arxiv-researcher-snapshot"
+
+// The version of the index snapshot format that we write. We read snapshots of this version or earlier.
+const SnapshotVersion = 1
+
+// The first line of an index snapshot, which describes the documents on the following lines.
+//
+// A snapshot is a JSON lines file: the header, followed by one [stores.Record] per document, holding the ID, embedding,
+// page content, and metadata of the document. The format is independent of the backend, so that a snapshot of a
+// Pinecone index imports into a local index and vice versa.
+type SnapshotHeader struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	// The embedding model that computed the embeddings in the snapshot.
+	EmbeddingModel string `json:"embedding_model"`
+	// The namespace from which we exported the documents.
+	NameSpace string `json:"namespace"`
+}
+
+// Export every document in the index, along with its embedding, as a snapshot.
+//
+// Returns the number of documents exported if we export them successfully, otherwise returns an error.
+func (index *Index) ExportSnapshot(ctx context.Context, w io.Writer) (int, error) {
+	store, err := index.backend()
+	if err != nil {
+		return 0, err
+	}
+	nameSpace := index.nameSpace()
+	encoder := json.NewEncoder(w)
+	err = encoder.Encode(SnapshotHeader{
+		Format:         SnapshotFormat,
+		Version:        SnapshotVersion,
+		Created:        time.Now().UTC(),
+		EmbeddingModel: index.EmbeddingModel(),
+		NameSpace:      nameSpace,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed while writing snapshot: %w", err)
+	}
+	exported := 0
+	err = scan(ctx, store, nameSpace, func(records []stores.Record) error {
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return fmt.Errorf("failed while writing snapshot: %w", err)
+			}
+		}
+		exported += len(records)
+		return nil
+	})
+	return exported, err
+}
+
+// Import the documents in a snapshot into the index, replacing any documents with the same IDs. If
+// re-embedding, we discard the embeddings in the snapshot and embed the page content of each document again with the
+// current embedding model; otherwise, we refuse snapshots whose embeddings some other model computed, since their
+// vectors are meaningless to queries embedded with the current model, along with documents whose embeddings have a
+// different dimension than those of the index.
+//
+// Returns the number of documents imported if we import them successfully, otherwise returns an error.
+func (index *Index) ImportSnapshot(ctx context.Context, r io.Reader, reembed bool) (int, error) {
+	store, err := index.backend()
+	if err != nil {
+		return 0, err
+	}
+	decoder := json.NewDecoder(r)
+	var header SnapshotHeader
+	if err := decoder.Decode(&header); err != nil || header.Format != SnapshotFormat {
+		return 0, errors.New("failed while reading snapshot: not an index snapshot")
+	}
+	if header.Version > SnapshotVersion {
+		return 0, fmt.Errorf("failed while reading snapshot: unsupported snapshot version %d", header.Version)
+	}
+	model := index.EmbeddingModel()
+	if !reembed && header.EmbeddingModel != model {
+		return 0, fmt.Errorf("%w: snapshot embeddings are from '%s', but the index uses '%s'; re-embed to import",
+			ErrEmbeddingModelMismatch, header.EmbeddingModel, model)
+	}
+	nameSpace := index.nameSpace()
+
+	imported := 0
+	batch := make([]stores.Record, 0, scanPageSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		var err error
+		if reembed {
+			_, err = store.AddDocuments(ctx, documentsOf(batch), index.options()...)
+		} else {
+			err = store.Upsert(ctx, nameSpace, batch)
+		}
+		if err == nil {
+			err = index.recordManifest(ctx, []string{batch[0].Id})
+		}
+		if err != nil {
+			return fmt.Errorf("failed while importing snapshot: %w", err)
+		}
+		imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		var record stores.Record
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return imported, fmt.Errorf("failed while reading snapshot document %d: %w", imported+len(batch)+1, err)
+		}
+		if record.Id == "" {
+			return imported, fmt.Errorf("failed while reading snapshot document %d: missing ID", imported+len(batch)+1)
+		}
+		if !reembed && index.manifest != nil && len(record.Values) != index.manifest.Dimension {
+			return imported, fmt.Errorf("%w: snapshot document '%s' has dimension %d, but the index has dimension %d",
+				stores.ErrDimensionMismatch, record.Id, len(record.Values), index.manifest.Dimension)
+		}
+		batch = append(batch, record)
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return imported, err
+			}
+		}
+	}
+	return imported, flush()
+}
+
+// Convert records to documents to embed again, keeping their IDs.
+//
+// Returns the documents.
+func documentsOf(records []stores.Record) []schema.Document {
+	documents := make([]schema.Document, len(records))
+	for i, record := range records {
+		metadata := make(map[string]any, len(record.Metadata)+1)
+		for key, value := range record.Metadata {
+			metadata[key] = value
+		}
+		metadata[stores.IdKey] = record.Id
+		documents[i] = schema.Document{PageContent: record.Content, Metadata: metadata}
+	}
+	return documents
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"tmwong.org/arxiv-researcher-go/fakes"
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	source := newManagedIndex(t)
+	var snapshot bytes.Buffer
+	exported, err := source.ExportSnapshot(t.Context(), &snapshot)
+	if err != nil || exported != 3 {
+		t.Fatalf("exported %d documents, error %v", exported, err)
+	}
+	if lines := strings.Count(snapshot.String(), "\n"); lines != 4 {
+		t.Errorf("snapshot holds %d lines, want a header and 3 documents", lines)
+	}
+
+	target := fakes.NewVectorStore(fakes.NewEmbedder(), "")
+	imported, err := (&Index{store: target}).ImportSnapshot(t.Context(), bytes.NewReader(snapshot.Bytes()), false)
+	if err != nil || imported != 3 {
+		t.Fatalf("imported %d documents, error %v", imported, err)
+	}
+	want, _ := source.store.(stores.Store).Fetch(t.Context(), "", []string{"2210.03629"})
+	got, _ := target.Fetch(t.Context(), "", []string{"2210.03629"})
+	if len(got) != 1 || !slices.Equal(got[0].Values, want[0].Values) || got[0].Content != want[0].Content ||
+		got[0].Metadata["Title"] != "ReAct" {
+		t.Errorf("imported %+v, want %+v", got, want)
+	}
+}
+
+func TestSnapshotEmbeddingModelMismatch(t *testing.T) {
+	snapshot := `{"format": "arxiv-researcher-snapshot", "version": 1, "embedding_model": "text-embedding-ada-002"}
+{"id": "2210.03629", "values": [1, 0], "content": "Title: {ReAct}", "metadata": {"Title": "ReAct"}}
+`
+	embedder := fakes.NewEmbedder()
+	index := &Index{store: fakes.NewVectorStore(embedder, "")}
+	_, err := index.ImportSnapshot(t.Context(), strings.NewReader(snapshot), false)
+	if !errors.Is(err, ErrEmbeddingModelMismatch) {
+		t.Fatalf("expected ErrEmbeddingModelMismatch, got %v", err)
+	}
+	imported, err := index.ImportSnapshot(t.Context(), strings.NewReader(snapshot), true)
+	if err != nil || imported != 1 {
+		t.Fatalf("re-embedded %d documents, error %v", imported, err)
+	}
+	if texts := embedder.Texts(); texts != 1 {
+		t.Errorf("embedded %d texts, want the document embedded again", texts)
+	}
+	if paper, err := index.FetchPaper(t.Context(), "2210.03629"); err != nil || paper.Title != "ReAct" {
+		t.Errorf("unexpected paper %+v, error %v", paper, err)
+	}
+}
+
+func TestSnapshotNotASnapshot(t *testing.T) {
+	index := &Index{store: fakes.NewVectorStore(fakes.NewEmbedder(), "")}
+	if _, err := index.ImportSnapshot(t.Context(), strings.NewReader(`[{"id": "x"}]`), false); err == nil {
+		t.Error("expected an error importing a file that is not a snapshot")
+	}
+}
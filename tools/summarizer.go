@@ -0,0 +1,252 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/stores"
+)
+
+// The structured summary of a paper.
+type PaperSummary struct {
+	Problem       string   `json:"problem" description:"The problem that the paper addresses, and why it matters"`
+	Method        string   `json:"method" description:"The method or approach that the paper proposes or studies"`
+	Datasets      []string `json:"datasets" description:"The datasets, benchmarks, or environments that the paper uses"`
+	Results       string   `json:"results" description:"The main results of the paper, with numbers where given"`
+	Limitations   string   `json:"limitations" description:"The limitations of the work, as stated by the paper"`
+	Contributions []string `json:"contributions" description:"The key contributions that the paper claims"`
+}
+
+// The most words of the full text of a paper that we give the LLM to summarize, which keeps the prompt well within the
+// context window of the LLM. The opening of a paper, up to its experiments, usually covers everything we ask for.
+const summaryTextWords = 6000
+
+// The number of times we ask the LLM for a summary before we give up on a summary that does not conform to the schema.
+const summaryAttempts = 2
+
+// The metadata key under which we store the structured summary of a paper in the index, as a JSON object.
+const structuredSummaryKey = "Structured Summary"
+
+// Returned when the LLM does not respond with a summary that conforms to the schema of [PaperSummary].
+var ErrInvalidSummary = errors.New("LLM returned an invalid summary")
+
+// The path of the Go template file from which we build the prompt that asks the LLM to summarize a paper. If empty,
+// we use the file named by the `ARXIV_RESEARCHER_SUMMARY_TEMPLATE` environment variable, or [DefaultSummaryTemplate]
+// if the variable is not set. Commands may change the path, e.g., from command-line flags.
+var SummaryTemplate string
+
+// The data with which we execute the summary prompt template.
+type SummaryPromptData struct {
+	// The paper to summarize.
+	Paper Paper
+	// The opening of the full text of the paper, or an empty string if we summarize the paper from its abstract.
+	Text string
+	// The JSON schema to which the summary must conform.
+	Schema string
+}
+
+// The default summary prompt template. Templates may use the `join` function to join lists, e.g., of authors.
+const DefaultSummaryTemplate = `You are helping a researcher survey the literature. Summarize the research paper below
+as a JSON object that conforms to this JSON schema:
+
+{{.Schema}}
+
+Use only what the paper states. If the paper does not state something, use an empty string or an empty list. Respond
+with the JSON object alone.
+
+Title: {{.Paper.Title}}
+Authors: {{join .Paper.Authors ", "}}
+{{if .Text}}Full text:
+{{.Text}}{{else}}Abstract:
+{{.Paper.Summary}}{{end}}
+`
+
+// Load a summary prompt template from a Go template file, or from [SummaryTemplate] if the path is empty.
+//
+// Returns the template if we load it successfully, otherwise returns an error.
+func LoadSummaryTemplate(path string) (*template.Template, error) {
+	path = cmp.Or(path, SummaryTemplate, os.Getenv("ARXIV_RESEARCHER_SUMMARY_TEMPLATE"))
+	text := DefaultSummaryTemplate
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed while reading summary template: %w", err)
+		}
+		text = string(content)
+	}
+	prompt, err := template.New("summary").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed while parsing summary template: %w", err)
+	}
+	return prompt, nil
+}
+
+// Summarize a paper as a [PaperSummary] with the LLM, from the abstract of the paper, or from the opening of its full
+// text, which we download if necessary (see [AskPaperQuestion]). We validate the response of the LLM against the
+// schema of [PaperSummary], and if it does not conform, ask again, telling the LLM what was wrong.
+//
+// Returns the summary if we summarize the paper successfully, [ErrInvalidSummary] if the LLM keeps responding with
+// invalid summaries, otherwise returns an error.
+func SummarizePaper(
+	ctx context.Context,
+	llm llms.Model,
+	prompt *template.Template,
+	paper Paper,
+	fullText bool,
+) (PaperSummary, error) {
+	schema := schemaOf(reflect.TypeFor[PaperSummary]())
+	schemaJson, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return PaperSummary{}, fmt.Errorf("failed while marshalling summary schema: %w", err)
+	}
+	data := SummaryPromptData{Paper: paper, Schema: string(schemaJson)}
+	if fullText {
+		if data.Text, err = openingText(ctx, paper.Id); err != nil {
+			return PaperSummary{}, err
+		}
+	}
+	var request strings.Builder
+	if err := prompt.Execute(&request, data); err != nil {
+		return PaperSummary{}, fmt.Errorf("failed while executing summary template: %w", err)
+	}
+	for range summaryAttempts {
+		response, err := llms.GenerateFromSinglePrompt(ctx, llm, request.String())
+		if err != nil {
+			return PaperSummary{}, fmt.Errorf("failed while summarizing paper: %w", err)
+		}
+		summary, err := parseSummary(response, schema)
+		if err == nil {
+			return summary, nil
+		}
+		fmt.Fprintf(&request, "\nYour previous response was invalid (%s):\n%s\n\nRespond with a JSON object that "+
+			"conforms to the schema alone.\n", err, response)
+	}
+	return PaperSummary{}, fmt.Errorf("%w: %s", ErrInvalidSummary, paper.Id)
+}
+
+// Get the opening words of the full text of a paper, up to [summaryTextWords] words.
+//
+// Returns the opening if we extract the text of the paper successfully, otherwise returns an error.
+func openingText(ctx context.Context, id string) (string, error) {
+	path, err := locatePaper(ctx, BaseArxivId(id))
+	if err != nil {
+		return "", err
+	}
+	pages, err := PaperText(path)
+	if err != nil {
+		return "", err
+	}
+	var words []string
+	for _, page := range pages {
+		words = append(words, strings.Fields(page.Text)...)
+		if len(words) >= summaryTextWords {
+			break
+		}
+	}
+	return strings.Join(words[:min(len(words), summaryTextWords)], " "), nil
+}
+
+// Parse the response of the LLM to a summary request. LLMs often wrap JSON in a Markdown code block, or add a sentence
+// around it, so we parse the outermost JSON object in the response.
+//
+// Returns the summary if the response holds a JSON object that conforms to the schema, otherwise returns an error.
+func parseSummary(response string, schema map[string]any) (PaperSummary, error) {
+	start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return PaperSummary{}, errors.New("no JSON object in response")
+	}
+	var value any
+	if err := json.Unmarshal([]byte(response[start:end+1]), &value); err != nil {
+		return PaperSummary{}, err
+	}
+	if err := validateSchema(value, schema); err != nil {
+		return PaperSummary{}, err
+	}
+	var summary PaperSummary
+	if err := json.Unmarshal([]byte(response[start:end+1]), &summary); err != nil {
+		return PaperSummary{}, err
+	}
+	return summary, nil
+}
+
+// Summarize a paper by arXiv ID (see [SummarizePaper]). If the index holds the paper, we reuse the summary stored with
+// the paper unless forced, and otherwise store the new summary with the paper, so that, e.g., the get command shows it
+// later; if the index does not hold the paper, we fetch the paper from arXiv and leave the index as it is.
+//
+// Returns the paper along with its summary if we summarize the paper successfully, otherwise returns an error.
+func (index *Index) SummarizePaper(
+	ctx context.Context,
+	llm llms.Model,
+	prompt *template.Template,
+	id string,
+	fullText bool,
+	force bool,
+) (Paper, error) {
+	paper, err := index.FetchPaper(ctx, id)
+	indexed := err == nil
+	if indexed && paper.StructuredSummary != nil && !force {
+		return paper, nil
+	}
+	if err != nil && !errors.Is(err, ErrPaperNotFound) && !errors.Is(err, ErrUnmanagedIndex) {
+		return Paper{}, err
+	}
+	if !indexed {
+		papers, err := FetchPapersById(ctx, []string{id})
+		if err != nil {
+			return Paper{}, err
+		}
+		if len(papers) == 0 {
+			return Paper{}, fmt.Errorf("no paper with arXiv ID '%s'", id)
+		}
+		paper = papers[0]
+	}
+	summary, err := SummarizePaper(ctx, llm, prompt, paper, fullText)
+	if err != nil {
+		return Paper{}, err
+	}
+	paper.StructuredSummary = &summary
+	if indexed {
+		if err := index.storeSummary(ctx, id, summary); err != nil {
+			return Paper{}, err
+		}
+	}
+	return paper, nil
+}
+
+// Store the structured summary of a paper as extra metadata of its document in the index. We keep the embedding of the
+// document as it is, since the summary is not part of the embedded content.
+//
+// Returns nil if we store the summary successfully, otherwise returns an error.
+func (index *Index) storeSummary(ctx context.Context, id string, summary PaperSummary) error {
+	store, err := index.backend()
+	if err != nil {
+		return err
+	}
+	records, err := store.Fetch(ctx, index.nameSpace(), []string{BaseArxivId(id)})
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%w: %s", ErrPaperNotFound, id)
+	}
+	content, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed while marshalling summary: %w", err)
+	}
+	record := records[0]
+	metadata := make(map[string]any, len(record.Metadata)+1)
+	for key, value := range record.Metadata {
+		metadata[key] = value
+	}
+	metadata[structuredSummaryKey] = string(content)
+	record.Metadata = metadata
+	return store.Upsert(ctx, index.nameSpace(), []stores.Record{record})
+}
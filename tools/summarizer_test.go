@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+	"tmwong.org/arxiv-researcher-go/constants"
+	"tmwong.org/arxiv-researcher-go/fakes"
+)
+
+// A valid structured summary of ReAct, as an LLM might return it.
+const reactSummary = "```json\n" + `{
+  "problem": "LLMs reason and act in isolation.",
+  "method": "Interleave reasoning traces and task-specific actions.",
+  "datasets": ["HotpotQA", "FEVER", "ALFWorld", "WebShop"],
+  "results": "Outperforms imitation and reinforcement learning on ALFWorld by 34%.",
+  "limitations": "",
+  "contributions": ["The ReAct prompting paradigm"]
+}` + "\n```"
+
+// Responses must hold a JSON object that conforms to the summary schema.
+func TestParseSummary(t *testing.T) {
+	schema := schemaOf(reflect.TypeFor[PaperSummary]())
+	summary, err := parseSummary(reactSummary, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Datasets) != 4 || summary.Contributions[0] != "The ReAct prompting paradigm" {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	fields := `"problem": "p", "method": "m", "results": "r", "limitations": ""`
+	for response, problem := range map[string]string{
+		"I cannot summarize this paper.":                                "no JSON object",
+		`{` + fields + `, "datasets": []}`:                              "'contributions'",
+		`{` + fields + `, "datasets": "HotpotQA", "contributions": []}`: "expected array at '$.datasets'",
+		`{` + fields + `, "datasets": [1], "contributions": []}`:        "expected string at '$.datasets[0]'",
+	} {
+		if _, err := parseSummary(response, schema); err == nil || !strings.Contains(err.Error(), problem) {
+			t.Errorf("expected an error mentioning %s for %s, got %v", problem, response, err)
+		}
+	}
+}
+
+// Summaries of indexed papers are stored with the papers, and reused unless forced. An invalid response is retried.
+func TestIndexSummarizePaper(t *testing.T) {
+	managedIndex := newManagedIndex(t)
+	prompt, err := LoadSummaryTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm := fakes.NewLLM(fakes.Answer(`{"problem": "Reasoning without acting."}`), fakes.Answer(reactSummary))
+	paper, err := managedIndex.SummarizePaper(t.Context(), llm, prompt, "2210.03629", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paper.StructuredSummary == nil ||
+		paper.StructuredSummary.Method != "Interleave reasoning traces and task-specific actions." {
+		t.Fatalf("unexpected paper %+v", paper)
+	}
+	calls := llm.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected the invalid summary to be retried, got %d calls", len(calls))
+	}
+	first := calls[0][0].Parts[0].(llms.TextContent).Text
+	if !strings.Contains(first, "Title: ReAct") || !strings.Contains(first, `"contributions"`) {
+		t.Errorf("expected the prompt to hold the paper and the schema, got %s", first)
+	}
+	if retry := calls[1][0].Parts[0].(llms.TextContent).Text; !strings.Contains(retry, "missing required property") {
+		t.Errorf("expected the retry to explain the problem, got %s", retry)
+	}
+
+	stored, err := managedIndex.FetchPaper(t.Context(), "2210.03629")
+	if err != nil || !reflect.DeepEqual(stored.StructuredSummary, paper.StructuredSummary) {
+		t.Errorf("expected the summary to be stored, got %+v, error %v", stored, err)
+	}
+	if _, err := managedIndex.SummarizePaper(t.Context(), llm, prompt, "2210.03629", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(llm.Calls()) != 2 {
+		t.Error("expected the stored summary to be reused")
+	}
+
+	llm = fakes.NewLLM(fakes.Answer("Sorry."), fakes.Answer("Sorry again."))
+	_, err = managedIndex.SummarizePaper(t.Context(), llm, prompt, "2210.03629", false, true)
+	if !errors.Is(err, ErrInvalidSummary) {
+		t.Errorf("expected ErrInvalidSummary, got %v", err)
+	}
+}
+
+// Custom templates replace the default prompt.
+func TestSummaryTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "summary.tmpl")
+	content := "Summarize {{.Paper.Title}} by {{join .Paper.Authors \" and \"}} per {{.Schema}}"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ARXIV_RESEARCHER_SUMMARY_TEMPLATE", path)
+	prompt, err := LoadSummaryTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm := fakes.NewLLM(fakes.Answer(reactSummary))
+	paper := Paper{Id: "2210.03629", Title: "ReAct", Authors: []string{"Shunyu Yao", "Jeffrey Zhao"}}
+	if _, err := SummarizePaper(t.Context(), llm, prompt, paper, false); err != nil {
+		t.Fatal(err)
+	}
+	if text := llm.Calls()[0][0].Parts[0].(llms.TextContent).Text; !strings.HasPrefix(text,
+		"Summarize ReAct by Shunyu Yao and Jeffrey Zhao per {") {
+		t.Errorf("unexpected prompt %s", text)
+	}
+
+	if err := os.WriteFile(path, []byte("{{.Paper"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSummaryTemplate(""); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+// The tool returns the structured summary of the paper.
+func TestPaperSummarizerTool(t *testing.T) {
+	index = newManagedIndex(t)
+	savedLlm := constants.Llm
+	t.Cleanup(func() { index, constants.Llm = nil, savedLlm })
+	constants.Llm = fakes.NewLLM(fakes.Answer(reactSummary))
+	result, err := PaperSummarizer.Call(t.Context(), `{"id": "2210.03629"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, `"arXiv ID": "2210.03629"`) || !strings.Contains(result, `"HotpotQA"`) {
+		t.Errorf("unexpected result %s", result)
+	}
+}
@@ -4,12 +4,23 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/tmc/langchaingo/callbacks"
 	lcgtools "github.com/tmc/langchaingo/tools"
+	"go.opentelemetry.io/otel/attribute"
+	"tmwong.org/arxiv-researcher-go/telemetry"
 )
 
+// Default limits on the time each call of the singleton tools may take.
+const (
+	searchTimeout   = 30 * time.Second
+	downloadTimeout = 2 * time.Minute
+)
+
+// The number of bytes of long values, e.g., tool inputs and chain outputs, beyond which we truncate them in logs.
+const maxLoggedLength = 500
+
 // A generic type to use for implementing tools for chatbot agents. This type implements the [lcgtools.Tool] interface
 // and provides a way to define a tool with a name, description, and callback function. We provide this type instead of
 // using the raw [lcgtools.Tool] interface to make it simpler to declare type-safe input argument structures for each
@@ -49,6 +60,24 @@ type Tool[T any] struct {
 	// An optional set of introspection callback handlers that implement the [callbacks.Handler] interface. The tool
 	// will call the HandleToolStart, HandleToolEnd, and HandleToolError methods in the set where appropriate.
 	introspectionCallbacks callbacks.Handler
+	// An optional limit on the time each call of the tool may take. When a call times out, the callback sees its
+	// context cancelled, and the agent receives an error message that it may recover from, e.g., by trying again.
+	timeout time.Duration
+	// Optional results of earlier calls, which answer calls of the tool instead of its callback when we replay a run.
+	recorded *RecordedResults
+}
+
+// Get a copy of a tool that limits the time each call of the tool may take. A zero timeout removes the limit.
+func (tool Tool[T]) WithTimeout(timeout time.Duration) Tool[T] {
+	tool.timeout = timeout
+	return tool
+}
+
+// Get a copy of a tool that answers its calls from the results of the calls of an earlier run, instead of running its
+// callback (see [RecordedResults]). A nil set of results restores the callback.
+func (tool Tool[T]) WithRecordedResults(recorded *RecordedResults) Tool[T] {
+	tool.recorded = recorded
+	return tool
 }
 
 // Get the name of a tool.
@@ -66,24 +95,53 @@ func (tool Tool[T]) Description() string {
 }
 
 // Unmarshal the raw input from a chatbot agent into the input argument structure for a tool, and call the tool
-// callback.
+// callback. If the tool has a timeout, the callback receives a context that expires after the timeout. If the tool
+// replays recorded results, we answer from them without calling the callback, failing the calls that failed in the
+// recorded run. We trace each call as a "tool.call" operation (see [telemetry.Start]).
 //
 // Implements the [lcgtools.Tool.Call] API call.
 func (tool Tool[T]) Call(ctx context.Context, input string) (string, error) {
-	log.Printf("Calling tool '%s' with input '%s'.\n", tool.Name(), input)
+	// We trace every call, and count the failures that we report to the agent as results as errors too.
+	ctx, operation := telemetry.Start(ctx, "tool.call", attribute.String("tool.name", tool.Name()))
+	var failure error
+	defer func() { operation.End(failure) }()
+	// Introspection handlers only see the input or output of the call, so the context tells them which tool it is.
+	ctx = context.WithValue(ctx, toolCallKey{}, toolCall{name: tool.Name(), input: input})
 	if tool.introspectionCallbacks != nil {
 		tool.introspectionCallbacks.HandleToolStart(ctx, input)
 	}
 	var args T
 	if err := json.Unmarshal([]byte(input), &args); err != nil {
+		failure = err
 		if tool.introspectionCallbacks != nil {
 			tool.introspectionCallbacks.HandleToolError(ctx, err)
 		}
 		return fmt.Sprintf("Tool '%s' failed while unmarshalling arguments: %s", tool.Name(), err), nil
 	}
-	log.Printf("Calling tool '%s' callback with args '%+v'.\n", tool.Name(), args)
-	result, err := tool.Callback(ctx, args)
+	var result string
+	var err error
+	if tool.recorded != nil {
+		result, err = tool.recorded.Take(tool.Name(), input)
+	} else {
+		callCtx := ctx
+		if tool.timeout > 0 {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithTimeout(ctx, tool.timeout)
+			defer cancel()
+		}
+		result, err = tool.Callback(callCtx, args)
+	}
+	// Unlike a timeout of the tool itself, cancellation of the agent run as a whole (e.g., by the user) is not
+	// something the agent can recover from, so we return it as an error.
+	if ctx.Err() != nil {
+		failure = ctx.Err()
+		if tool.introspectionCallbacks != nil {
+			tool.introspectionCallbacks.HandleToolError(ctx, ctx.Err())
+		}
+		return "", fmt.Errorf("tool '%s' cancelled: %w", tool.Name(), ctx.Err())
+	}
 	if err != nil {
+		failure = err
 		if tool.introspectionCallbacks != nil {
 			tool.introspectionCallbacks.HandleToolError(ctx, err)
 		}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// The arguments for the test tools.
+type echoArgs struct {
+	Text string `json:"text" description:"The text to echo"`
+	N    int    `json:"n,omitempty"`
+}
+
+// A tool whose callback blocks until its context is cancelled.
+var blocker = Tool[echoArgs]{
+	name: "Blocker",
+	Callback: func(ctx context.Context, args echoArgs) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	},
+}
+
+func TestToolParameters(t *testing.T) {
+	parameters := blocker.Parameters()
+	properties := parameters["properties"].(map[string]any)
+	text := properties["text"].(map[string]any)
+	if text["type"] != "string" || text["description"] != "The text to echo" {
+		t.Errorf("unexpected schema for text: %v", text)
+	}
+	if n := properties["n"].(map[string]any); n["type"] != "integer" {
+		t.Errorf("unexpected schema for n: %v", n)
+	}
+	if required := parameters["required"].([]string); len(required) != 1 || required[0] != "text" {
+		t.Errorf("expected only text to be required, got %v", required)
+	}
+}
+
+// A tool that times out reports the timeout to the agent as a recoverable error message.
+func TestToolTimeout(t *testing.T) {
+	result, err := blocker.WithTimeout(10*time.Millisecond).Call(t.Context(), `{"text": "hello"}`)
+	if err != nil {
+		t.Fatalf("expected a recoverable error message, got error %v", err)
+	}
+	if !strings.Contains(result, "deadline exceeded") {
+		t.Errorf("unexpected result %q", result)
+	}
+}
+
+// A tool whose caller cancels the run returns the cancellation as an error, since the agent cannot recover from it.
+func TestToolCancellation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err := blocker.WithTimeout(time.Minute).Call(ctx, `{"text": "hello"}`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the caller's deadline as an error, got %v", err)
+	}
+}
+
+// A tool replaying a run answers calls from the recorded results in order, matching inputs regardless of whitespace,
+// without running its callback, and fails the calls that failed in the run.
+func TestRecordedResults(t *testing.T) {
+	recorded := NewRecordedResults([]TranscriptEvent{
+		{Event: ToolStartEvent, Tool: "Blocker", Input: `{"text": "hello"}`},
+		{Event: ToolEndEvent, Tool: "Blocker", Input: `{"text": "hello"}`, Output: "first"},
+		{Event: ToolEndEvent, Tool: "Blocker", Input: `{"text": "hello"}`, Output: "second"},
+		{Event: ToolErrorEvent, Tool: "Blocker", Input: `{"text": "bye"}`, Error: "failed"},
+	})
+	replayer := blocker.WithRecordedResults(recorded)
+	var results []string
+	for _, input := range []string{`{"text": "hello"}`, `{"text":"hello"}`, `{ "text": "hello" }`} {
+		result, err := replayer.Call(t.Context(), input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, result)
+	}
+	if strings.Join(results, ",") != "first,second,second" {
+		t.Errorf("unexpected results %v", results)
+	}
+	if result, err := replayer.Call(t.Context(), `{"text": "bye"}`); err != nil ||
+		result != "Tool 'Blocker' failed while running tool: failed" {
+		t.Errorf("expected the recorded failure, got %q, error %v", result, err)
+	}
+	if result, err := replayer.Call(t.Context(), `{"text": "hi"}`); err != nil || !strings.Contains(result,
+		"no recorded result") {
+		t.Errorf("unexpected result %q, error %v", result, err)
+	}
+}
+
+// Searching the index with the tool traces the query of the vector store within the span of the tool call.
+func TestIndexSearcherTracing(t *testing.T) {
+	savedTracerProvider := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(savedTracerProvider) })
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	ctx := WithEnvironment(t.Context(), &Environment{Index: newManagedIndex(t)})
+
+	result, err := IndexSearcher.Call(ctx, `{"query": "reasoning and acting", "n": 2}`)
+	if err != nil || !strings.Contains(result, `"Title": "ReAct"`) {
+		t.Fatalf("unexpected result %s, error %v", result, err)
+	}
+	var names []string
+	var query, call sdktrace.ReadOnlySpan
+	for _, span := range spans.Ended() {
+		names = append(names, span.Name())
+		switch span.Name() {
+		case "vector.query":
+			query = span
+		case "tool.call":
+			call = span
+		}
+	}
+	if query == nil || call == nil || query.Parent().SpanID() != call.SpanContext().SpanID() {
+		t.Errorf("expected a vector.query span within the tool.call span, got %v", names)
+	}
+}
@@ -0,0 +1,261 @@
+package tools
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/schema"
+	"tmwong.org/arxiv-researcher-go/logging"
+	"tmwong.org/arxiv-researcher-go/usage"
+)
+
+// The kinds of events in a transcript.
+const (
+	TextEvent           = "text"
+	LlmStartEvent       = "llm_start"
+	LlmEndEvent         = "llm_end"
+	LlmErrorEvent       = "llm_error"
+	ChainStartEvent     = "chain_start"
+	ChainEndEvent       = "chain_end"
+	ChainErrorEvent     = "chain_error"
+	ToolStartEvent      = "tool_start"
+	ToolEndEvent        = "tool_end"
+	ToolErrorEvent      = "tool_error"
+	AgentActionEvent    = "agent_action"
+	AgentFinishEvent    = "agent_finish"
+	RetrieverStartEvent = "retriever_start"
+	RetrieverEndEvent   = "retriever_end"
+	// The start of a run, holding the query of the run.
+	RunStartEvent = "run_start"
+	// The end of a run, holding the answer or error of the run, along with its usage.
+	RunEndEvent = "run_end"
+)
+
+// A record of an event of a run, one per line of a transcript. Unlike log records, events hold the full prompts,
+// responses, inputs, and outputs of the run, so that the run can be inspected (and its LLM calls replayed) later.
+type TranscriptEvent struct {
+	Time time.Time `json:"time"`
+	// The ID of the run (see [logging.RunId]), if any.
+	RunId string `json:"run_id,omitempty"`
+	// The kind of event, e.g., [LlmEndEvent].
+	Event string `json:"event"`
+	// The name of the tool of a tool event.
+	Tool string `json:"tool,omitempty"`
+	// The input of a tool, the query of a retriever, or the query of a run.
+	Input string `json:"input,omitempty"`
+	// The output of a tool, a text, or the answer of a run.
+	Output string `json:"output,omitempty"`
+	// The prompts of a single-prompt LLM call.
+	Prompts []string `json:"prompts,omitempty"`
+	// The messages of an LLM call.
+	Messages []TranscriptMessage `json:"messages,omitempty"`
+	// The choices of an LLM response.
+	Choices []TranscriptChoice `json:"choices,omitempty"`
+	// The inputs or outputs of a chain, or the settings of a run.
+	Values map[string]any `json:"values,omitempty"`
+	// The tool call that an agent decided on.
+	Action *schema.AgentAction `json:"action,omitempty"`
+	// The final answer of an agent.
+	Finish *schema.AgentFinish `json:"finish,omitempty"`
+	// The documents that a retriever returned.
+	Documents []schema.Document `json:"documents,omitempty"`
+	// The error of a failed call or run.
+	Error string `json:"error,omitempty"`
+	// The tokens that a run spent, along with their cost.
+	Usage *usage.Report `json:"usage,omitempty"`
+	// The time that an ended call or run took, in milliseconds.
+	DurationMs float64 `json:"duration_ms,omitempty"`
+}
+
+// Redact sensitive values, e.g., API keys, in the text of an event (see [logging.Redact]), so that transcripts keep
+// secrets out just as the logs do. We copy the parts of the event that we redact, and leave the event as it is.
+//
+// Returns the redacted event.
+func (event TranscriptEvent) Redact() TranscriptEvent {
+	event.Input = logging.Redact(event.Input)
+	event.Output = logging.Redact(event.Output)
+	event.Error = logging.Redact(event.Error)
+	if event.Prompts != nil {
+		prompts := make([]string, len(event.Prompts))
+		for i, prompt := range event.Prompts {
+			prompts[i] = logging.Redact(prompt)
+		}
+		event.Prompts = prompts
+	}
+	if event.Messages != nil {
+		messages := make([]TranscriptMessage, len(event.Messages))
+		for i, message := range event.Messages {
+			message.Text = logging.Redact(message.Text)
+			message.ToolCalls = redactToolCalls(message.ToolCalls)
+			if message.ToolResponses != nil {
+				responses := make([]TranscriptToolResponse, len(message.ToolResponses))
+				for j, response := range message.ToolResponses {
+					response.Content = logging.Redact(response.Content)
+					responses[j] = response
+				}
+				message.ToolResponses = responses
+			}
+			messages[i] = message
+		}
+		event.Messages = messages
+	}
+	if event.Choices != nil {
+		choices := make([]TranscriptChoice, len(event.Choices))
+		for i, choice := range event.Choices {
+			choice.Content = logging.Redact(choice.Content)
+			choice.ToolCalls = redactToolCalls(choice.ToolCalls)
+			choices[i] = choice
+		}
+		event.Choices = choices
+	}
+	event.Values = redactValues(event.Values)
+	if event.Action != nil {
+		action := *event.Action
+		action.ToolInput = logging.Redact(action.ToolInput)
+		action.Log = logging.Redact(action.Log)
+		event.Action = &action
+	}
+	if event.Finish != nil {
+		finish := *event.Finish
+		finish.ReturnValues = redactValues(finish.ReturnValues)
+		finish.Log = logging.Redact(finish.Log)
+		event.Finish = &finish
+	}
+	if event.Documents != nil {
+		documents := make([]schema.Document, len(event.Documents))
+		for i, document := range event.Documents {
+			document.PageContent = logging.Redact(document.PageContent)
+			documents[i] = document
+		}
+		event.Documents = documents
+	}
+	return event
+}
+
+// Redact sensitive values in the arguments of tool calls of a transcript.
+//
+// Returns a copy of the tool calls with their arguments redacted.
+func redactToolCalls(calls []TranscriptToolCall) []TranscriptToolCall {
+	if calls == nil {
+		return nil
+	}
+	redacted := make([]TranscriptToolCall, len(calls))
+	for i, call := range calls {
+		call.Arguments = logging.Redact(call.Arguments)
+		redacted[i] = call
+	}
+	return redacted
+}
+
+// Redact sensitive values in the string values of the inputs or outputs of a chain, or of the return values of an
+// agent.
+//
+// Returns a copy of the values with their string values redacted.
+func redactValues(values map[string]any) map[string]any {
+	if values == nil {
+		return nil
+	}
+	redacted := make(map[string]any, len(values))
+	for key, value := range values {
+		if text, ok := value.(string); ok {
+			value = logging.Redact(text)
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// A message of an LLM call in a transcript. LangChainGo cannot unmarshal the tool calls of the messages and responses
+// that it marshals, so transcripts keep messages and responses in forms of their own.
+type TranscriptMessage struct {
+	// The role of the author of the message, e.g., "human".
+	Role string `json:"role"`
+	// The text of the message, if any.
+	Text string `json:"text,omitempty"`
+	// The tool calls that an AI message requests.
+	ToolCalls []TranscriptToolCall `json:"tool_calls,omitempty"`
+	// The results of tool calls that a tool message returns.
+	ToolResponses []TranscriptToolResponse `json:"tool_responses,omitempty"`
+}
+
+// A tool call that an LLM requests, in a transcript.
+type TranscriptToolCall struct {
+	Id        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// The result of a tool call, in a transcript.
+type TranscriptToolResponse struct {
+	Id      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// A choice of an LLM response in a transcript.
+type TranscriptChoice struct {
+	Content        string               `json:"content,omitempty"`
+	StopReason     string               `json:"stop_reason,omitempty"`
+	ToolCalls      []TranscriptToolCall `json:"tool_calls,omitempty"`
+	GenerationInfo map[string]any       `json:"generation_info,omitempty"`
+}
+
+// Convert the messages of an LLM call for a transcript. We keep the text, tool call, and tool response parts of
+// messages, and leave out other parts, e.g., images.
+//
+// Returns the transcript messages.
+func newTranscriptMessages(messages []llms.MessageContent) []TranscriptMessage {
+	transcriptMessages := make([]TranscriptMessage, 0, len(messages))
+	for _, message := range messages {
+		transcriptMessage := TranscriptMessage{Role: string(message.Role)}
+		var texts []string
+		for _, part := range message.Parts {
+			switch part := part.(type) {
+			case llms.TextContent:
+				texts = append(texts, part.Text)
+			case llms.ToolCall:
+				transcriptMessage.ToolCalls = append(transcriptMessage.ToolCalls, newTranscriptToolCall(part))
+			case llms.ToolCallResponse:
+				transcriptMessage.ToolResponses = append(transcriptMessage.ToolResponses,
+					TranscriptToolResponse{Id: part.ToolCallID, Name: part.Name, Content: part.Content})
+			}
+		}
+		transcriptMessage.Text = strings.Join(texts, "\n")
+		transcriptMessages = append(transcriptMessages, transcriptMessage)
+	}
+	return transcriptMessages
+}
+
+// Convert the choices of an LLM response for a transcript.
+//
+// Returns the transcript choices.
+func newTranscriptChoices(choices []*llms.ContentChoice) []TranscriptChoice {
+	transcriptChoices := make([]TranscriptChoice, 0, len(choices))
+	for _, choice := range choices {
+		if choice == nil {
+			continue
+		}
+		transcriptChoice := TranscriptChoice{
+			Content:        choice.Content,
+			StopReason:     choice.StopReason,
+			GenerationInfo: choice.GenerationInfo,
+		}
+		for _, call := range choice.ToolCalls {
+			transcriptChoice.ToolCalls = append(transcriptChoice.ToolCalls, newTranscriptToolCall(call))
+		}
+		transcriptChoices = append(transcriptChoices, transcriptChoice)
+	}
+	return transcriptChoices
+}
+
+// Convert a tool call for a transcript.
+//
+// Returns the transcript tool call.
+func newTranscriptToolCall(call llms.ToolCall) TranscriptToolCall {
+	transcriptCall := TranscriptToolCall{Id: call.ID}
+	if call.FunctionCall != nil {
+		transcriptCall.Name, transcriptCall.Arguments = call.FunctionCall.Name, call.FunctionCall.Arguments
+	}
+	return transcriptCall
+}
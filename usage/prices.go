@@ -0,0 +1,83 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+)
+
+// The price of a model in US dollars per million tokens.
+type Price struct {
+	// The price of prompt tokens of LLMs, and of the tokens of texts embedded by embedding models.
+	Input float64 `json:"input"`
+	// The price of completion tokens of LLMs.
+	Output float64 `json:"output"`
+}
+
+// Estimate the cost of the tokens that a run spent on a model.
+//
+// Returns the cost in US dollars.
+func (price Price) Cost(usage ModelUsage) float64 {
+	return (float64(usage.InputTokens+usage.EmbeddingTokens)*price.Input + float64(usage.OutputTokens)*price.Output) /
+		1_000_000
+}
+
+// The prices of models, by model name.
+type PriceTable map[string]Price
+
+// The list prices of the OpenAI models that we use, at the time of writing. Prices change, so users can override them
+// with [LoadPrices].
+var DefaultPrices = PriceTable{
+	"gpt-4o":                 {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":            {Input: 0.15, Output: 0.60},
+	"gpt-4.1":                {Input: 2.00, Output: 8.00},
+	"gpt-4.1-mini":           {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":           {Input: 0.10, Output: 0.40},
+	"gpt-3.5-turbo":          {Input: 0.50, Output: 1.50},
+	"o3-mini":                {Input: 1.10, Output: 4.40},
+	"o4-mini":                {Input: 1.10, Output: 4.40},
+	"text-embedding-3-small": {Input: 0.02},
+	"text-embedding-3-large": {Input: 0.13},
+	"text-embedding-ada-002": {Input: 0.10},
+}
+
+// Look up the price of a model. Models may be dated snapshots of a priced model, e.g., "gpt-4o-mini-2024-07-18", so
+// if the table has no price for the model itself, we fall back to the longest model name that prefixes it.
+//
+// Returns the price of the model if the table has one, otherwise returns false.
+func (table PriceTable) Lookup(model string) (Price, bool) {
+	if price, ok := table[model]; ok {
+		return price, true
+	}
+	var match string
+	for name := range table {
+		if strings.HasPrefix(model, name+"-") && len(name) > len(match) {
+			match = name
+		}
+	}
+	price, ok := table[match]
+	return price, ok
+}
+
+// Load a price table from a JSON file that maps model names to prices, e.g.,
+//
+//	{"gpt-4o-mini": {"input": 0.15, "output": 0.60}}
+//
+// The prices in the file override the default prices (see [DefaultPrices]), and add to them.
+//
+// Returns the price table if we load the file successfully, otherwise returns an error.
+func LoadPrices(path string) (PriceTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading price table: %w", err)
+	}
+	var prices PriceTable
+	if err := json.Unmarshal(data, &prices); err != nil {
+		return nil, fmt.Errorf("failed while parsing price table %s: %w", path, err)
+	}
+	table := maps.Clone(DefaultPrices)
+	maps.Copy(table, prices)
+	return table, nil
+}
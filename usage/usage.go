@@ -0,0 +1,280 @@
+// Package usage accounts for the tokens that a run, e.g., an agent query or an indexing job, spends on LLM calls and
+// embedding requests, by model, estimates their cost in US dollars from a price table, and enforces budgets by
+// cancelling runs that exceed them.
+//
+// A run attaches a [Meter] to its context (see [Meter.Attach]), and the code that calls models reports the tokens it
+// spends to the meter of its context (see [FromContext]), e.g., through a [Handler].
+package usage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Returned (as the cause of the cancellation of its context) when a run exceeds its budget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Limits on what a run may spend. Zero limits are no limits.
+type Budget struct {
+	// The most tokens, of every kind and model, that the run may spend.
+	MaxTokens int
+	// The most that the run may cost, in US dollars.
+	MaxCost float64
+}
+
+// The tokens that a run spent on one model, along with their estimated cost.
+type ModelUsage struct {
+	Model string `json:"model"`
+	// The tokens of the prompts of LLM calls.
+	InputTokens int `json:"input_tokens,omitempty"`
+	// The tokens of the completions of LLM calls.
+	OutputTokens int `json:"output_tokens,omitempty"`
+	// The tokens of texts embedded by embedding requests.
+	EmbeddingTokens int `json:"embedding_tokens,omitempty"`
+	// The estimated cost in US dollars, or zero if the price table has no price for the model.
+	Cost float64 `json:"cost_usd"`
+}
+
+// The total tokens, of every kind, that a run spent on the model.
+//
+// Returns the number of tokens.
+func (usage ModelUsage) Tokens() int {
+	return usage.InputTokens + usage.OutputTokens + usage.EmbeddingTokens
+}
+
+// A summary of what a run spent.
+type Report struct {
+	// The usage of each model, in order of model name.
+	Models []ModelUsage `json:"models"`
+	// The total tokens, of every kind and model.
+	TotalTokens int `json:"total_tokens"`
+	// The total estimated cost in US dollars.
+	TotalCost float64 `json:"total_cost_usd"`
+	// The models that the price table has no price for, whose tokens the total cost leaves out.
+	UnpricedModels []string `json:"unpriced_models,omitempty"`
+}
+
+// Write a report as a table with a row for each model, followed by the totals.
+//
+// Returns nil if we write the report successfully, otherwise returns an error.
+func (report Report) WriteText(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "Model\tInput tokens\tOutput tokens\tEmbedding tokens\tCost (USD)\t")
+	for _, model := range report.Models {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%s\t\n", model.Model, model.InputTokens, model.OutputTokens,
+			model.EmbeddingTokens, formatCost(model.Cost, slices.Contains(report.UnpricedModels, model.Model)))
+	}
+	fmt.Fprintf(table, "Total\t\t\t%d tokens\t%s\t\n", report.TotalTokens, formatCost(report.TotalCost, false))
+	return table.Flush()
+}
+
+// Format a cost in US dollars, or a dash if the cost is unknown.
+//
+// Returns the formatted cost.
+func formatCost(cost float64, unknown bool) string {
+	if unknown {
+		return "-"
+	}
+	return fmt.Sprintf("$%.6f", cost)
+}
+
+// Accounts for the tokens of a run, and cancels the run once it exceeds its budget. A nil meter accounts for nothing,
+// so that code can report tokens whether or not its caller meters them. Meters are safe for concurrent use.
+type Meter struct {
+	prices PriceTable
+	budget Budget
+	mutex  sync.Mutex
+	models map[string]*ModelUsage
+	cancel context.CancelCauseFunc
+	// The meter of the enclosing run, if any, to which we report every token as well.
+	parent *Meter
+}
+
+// Create a meter that prices tokens with the given price table, and enforces the given budget.
+//
+// Returns the meter.
+func NewMeter(prices PriceTable, budget Budget) *Meter {
+	return &Meter{prices: prices, budget: budget, models: map[string]*ModelUsage{}}
+}
+
+// Create a meter that accounts for a part of the run of this meter, e.g., one of several agent runs of a command. The
+// child reports every token to this meter as well, which keeps enforcing its budget for the whole run. A nil meter
+// has a child that reports to no parent.
+//
+// Returns the child meter.
+func (meter *Meter) NewChild() *Meter {
+	if meter == nil {
+		return NewMeter(DefaultPrices, Budget{})
+	}
+	child := NewMeter(meter.prices, Budget{})
+	child.parent = meter
+	return child
+}
+
+type meterKey struct{}
+
+// Attach a meter to a context, so that code running with the context reports its tokens to the meter. Once the run
+// exceeds the budget of the meter, we cancel the returned context with [ErrBudgetExceeded] as the cause, which callers
+// can recover with [context.Cause].
+//
+// Returns the context with the meter, along with a function that releases its resources.
+func (meter *Meter) Attach(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.WithValue(ctx, meterKey{}, meter))
+	meter.mutex.Lock()
+	meter.cancel = cancel
+	meter.mutex.Unlock()
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// Get the meter attached to a context.
+//
+// Returns the meter if the context has one, otherwise returns nil.
+func FromContext(ctx context.Context) *Meter {
+	meter, _ := ctx.Value(meterKey{}).(*Meter)
+	return meter
+}
+
+// Account for the tokens of an LLM call.
+func (meter *Meter) AddCompletion(model string, inputTokens int, outputTokens int) {
+	meter.add(model, func(usage *ModelUsage) {
+		usage.InputTokens += inputTokens
+		usage.OutputTokens += outputTokens
+	})
+}
+
+// Account for the tokens of an embedding request.
+func (meter *Meter) AddEmbedding(model string, tokens int) {
+	meter.add(model, func(usage *ModelUsage) {
+		usage.EmbeddingTokens += tokens
+	})
+}
+
+// Update the usage of a model, and cancel the run if it exceeds its budget.
+func (meter *Meter) add(model string, update func(usage *ModelUsage)) {
+	if meter == nil {
+		return
+	}
+	meter.mutex.Lock()
+	usage, ok := meter.models[model]
+	if !ok {
+		usage = &ModelUsage{Model: model}
+		meter.models[model] = usage
+	}
+	update(usage)
+	if err := meter.check(); err != nil && meter.cancel != nil {
+		meter.cancel(err)
+	}
+	meter.mutex.Unlock()
+	meter.parent.add(model, update)
+}
+
+// Check the usage so far against the budget. The caller must hold the lock of the meter.
+//
+// Returns nil if the run is within its budget, otherwise returns an error that wraps [ErrBudgetExceeded].
+func (meter *Meter) check() error {
+	report := meter.report()
+	if meter.budget.MaxTokens > 0 && report.TotalTokens > meter.budget.MaxTokens {
+		return fmt.Errorf("%w: spent %d tokens, more than the limit of %d", ErrBudgetExceeded, report.TotalTokens,
+			meter.budget.MaxTokens)
+	}
+	if meter.budget.MaxCost > 0 && report.TotalCost > meter.budget.MaxCost {
+		return fmt.Errorf("%w: spent $%.6f, more than the limit of $%.6f", ErrBudgetExceeded, report.TotalCost,
+			meter.budget.MaxCost)
+	}
+	return nil
+}
+
+// Summarize the usage so far.
+//
+// Returns the report, which holds no models if the meter is nil or has accounted for nothing.
+func (meter *Meter) Report() Report {
+	if meter == nil {
+		return Report{Models: []ModelUsage{}}
+	}
+	meter.mutex.Lock()
+	defer meter.mutex.Unlock()
+	return meter.report()
+}
+
+// Summarize the usage so far. The caller must hold the lock of the meter.
+//
+// Returns the report.
+func (meter *Meter) report() Report {
+	report := Report{Models: []ModelUsage{}}
+	for _, usage := range meter.models {
+		model := *usage
+		if price, ok := meter.prices.Lookup(model.Model); ok {
+			model.Cost = price.Cost(model)
+		} else {
+			report.UnpricedModels = append(report.UnpricedModels, model.Model)
+		}
+		report.Models = append(report.Models, model)
+		report.TotalTokens += model.Tokens()
+		report.TotalCost += model.Cost
+	}
+	slices.SortFunc(report.Models, func(x, y ModelUsage) int { return cmp.Compare(x.Model, y.Model) })
+	slices.Sort(report.UnpricedModels)
+	return report
+}
+
+// An introspection handler that reports the tokens of the LLM calls it observes to the meter of their context. LLMs
+// report their tokens in the generation info of their responses, as the OpenAI LLM does.
+//
+// Implements the [callbacks.Handler] interface.
+type Handler struct {
+	callbacks.SimpleHandler
+	// The name of the model of the LLM whose calls we observe.
+	Model string
+}
+
+var _ callbacks.Handler = Handler{}
+
+// Implements the [callbacks.Handler.HandleLLMGenerateContentEnd] API call.
+func (handler Handler) HandleLLMGenerateContentEnd(ctx context.Context, response *llms.ContentResponse) {
+	if response == nil || len(response.Choices) == 0 {
+		return
+	}
+	input, output := Tokens(response.Choices[0].GenerationInfo)
+	FromContext(ctx).AddCompletion(handler.Model, input, output)
+}
+
+// Get the number of input (prompt) and output (completion) tokens that an LLM reports in the generation info of its
+// response.
+//
+// Returns the numbers of input and output tokens, or 0 for either if the info does not report it.
+func Tokens(info map[string]any) (int, int) {
+	return intInfo(info, "PromptTokens"), intInfo(info, "CompletionTokens")
+}
+
+// Get an integer from the generation info of an LLM response.
+//
+// Returns the integer if the info holds a number under the key, otherwise returns 0.
+func intInfo(info map[string]any, key string) int {
+	switch value := info[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	default:
+		return 0
+	}
+}
+
+// Estimate the number of tokens of a text, for models that do not report them, e.g., embedding models. English text
+// averages about four characters per token.
+//
+// Returns the estimated number of tokens.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,123 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Meters account for tokens by model, price them, and leave unpriced models out of the total cost.
+func TestReport(t *testing.T) {
+	meter := NewMeter(DefaultPrices, Budget{})
+	ctx, release := meter.Attach(t.Context())
+	defer release()
+	handler := Handler{Model: "gpt-4o-mini-2024-07-18"}
+	for range 2 {
+		handler.HandleLLMGenerateContentEnd(ctx, &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+			GenerationInfo: map[string]any{"PromptTokens": 500_000, "CompletionTokens": 250_000},
+		}}})
+	}
+	FromContext(ctx).AddEmbedding("text-embedding-3-small", 1_000_000)
+	FromContext(ctx).AddEmbedding("nomic-embed-text", 1_000)
+	// Code that runs without a meter accounts for nothing.
+	FromContext(context.Background()).AddCompletion("gpt-4o", 1, 1)
+
+	report := meter.Report()
+	if len(report.Models) != 3 || report.Models[0].Model != "gpt-4o-mini-2024-07-18" ||
+		report.Models[0].InputTokens != 1_000_000 || report.Models[0].OutputTokens != 500_000 {
+		t.Fatalf("unexpected models %+v", report.Models)
+	}
+	if report.TotalTokens != 2_501_000 || math.Abs(report.TotalCost-(0.15+0.30+0.02)) > 1e-9 {
+		t.Errorf("unexpected totals %d tokens, $%f", report.TotalTokens, report.TotalCost)
+	}
+	if len(report.UnpricedModels) != 1 || report.UnpricedModels[0] != "nomic-embed-text" {
+		t.Errorf("unexpected unpriced models %v", report.UnpricedModels)
+	}
+	var text strings.Builder
+	if err := report.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "$0.470000") || !strings.Contains(text.String(), "2501000 tokens") {
+		t.Errorf("unexpected report %s", text.String())
+	}
+}
+
+// Runs that exceed a budget are cancelled with the reason.
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		budget Budget
+		want   bool
+	}{
+		{Budget{}, false},
+		{Budget{MaxTokens: 2_000}, false},
+		{Budget{MaxTokens: 1_000}, true},
+		{Budget{MaxCost: 0.01}, false},
+		{Budget{MaxCost: 0.0001}, true},
+	}
+	for _, test := range tests {
+		meter := NewMeter(DefaultPrices, test.budget)
+		ctx, release := meter.Attach(t.Context())
+		meter.AddCompletion("gpt-4o", 1_000, 500)
+		cause := context.Cause(ctx)
+		if exceeded := errors.Is(cause, ErrBudgetExceeded); exceeded != test.want {
+			t.Errorf("budget %+v: unexpected cause %v", test.budget, cause)
+		}
+		release()
+	}
+}
+
+// Price files override and add to the default prices.
+func TestLoadPrices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prices.json")
+	err := os.WriteFile(path, []byte(`{"gpt-4o-mini": {"input": 1, "output": 2}, "llama3": {"input": 0.5}}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prices, err := LoadPrices(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price, _ := prices.Lookup("gpt-4o-mini"); price != (Price{Input: 1, Output: 2}) {
+		t.Errorf("unexpected price %+v", price)
+	}
+	if _, ok := prices.Lookup("llama3"); !ok {
+		t.Error("expected a price for the added model")
+	}
+	if price, _ := prices.Lookup("gpt-4o-2024-08-06"); price != DefaultPrices["gpt-4o"] {
+		t.Errorf("unexpected price %+v for a snapshot", price)
+	}
+	if _, ok := prices.Lookup("gpt-4"); ok {
+		t.Error("expected no price for a prefix of a priced model")
+	}
+	if _, err := LoadPrices(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+// Child meters account for their part of a run alone, and report it to the parent, which enforces its budget.
+func TestChildMeter(t *testing.T) {
+	parent := NewMeter(DefaultPrices, Budget{MaxTokens: 1_500})
+	ctx, release := parent.Attach(t.Context())
+	defer release()
+	for range 2 {
+		child := parent.NewChild()
+		childCtx, releaseChild := child.Attach(ctx)
+		FromContext(childCtx).AddCompletion("gpt-4o-mini", 600, 200)
+		releaseChild()
+		if report := child.Report(); report.TotalTokens != 800 {
+			t.Errorf("unexpected child usage %+v", report)
+		}
+	}
+	if report := parent.Report(); report.TotalTokens != 1_600 {
+		t.Errorf("unexpected parent usage %+v", report)
+	}
+	if !errors.Is(context.Cause(ctx), ErrBudgetExceeded) {
+		t.Errorf("expected the parent run to exceed its budget, got %v", context.Cause(ctx))
+	}
+}
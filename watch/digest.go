@@ -0,0 +1,57 @@
+package watch
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// The new papers on the topic of a watchlist, found by one check of the watchlist.
+type Digest struct {
+	// The name of the watchlist.
+	Watchlist string `json:"watchlist"`
+	// The arXiv query of the topic of the watchlist.
+	Query string `json:"query"`
+	// When we checked the watchlist.
+	Generated time.Time `json:"generated"`
+	// The new papers that are relevant enough to report, most relevant first.
+	Papers []tools.RankedPaper `json:"papers"`
+	// The arXiv IDs, without version suffixes, of every new paper, including those not relevant enough to report.
+	seen []string
+}
+
+// The subject line of a digest, e.g., for email.
+//
+// Returns the subject line.
+func (digest Digest) Subject() string {
+	if len(digest.Papers) == 1 {
+		return "1 new paper on " + digest.Query
+	}
+	return fmt.Sprintf("%d new papers on %s", len(digest.Papers), digest.Query)
+}
+
+// The template of Markdown digests.
+var markdownDigest = template.Must(template.New("digest").Funcs(map[string]any{
+	"authors": func(paper tools.Paper) string { return strings.Join(paper.Authors, ", ") },
+	"date":    func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+}).Parse(`# {{.Subject}}
+
+_Watchlist "{{.Watchlist}}", checked on {{date .Generated}}._
+{{range .Papers}}
+## [{{.Paper.Title}}]({{.Paper.ArxivUrl}})
+
+{{authors .Paper}} · {{.Paper.PrimaryCategory}} · relevance {{printf "%.2f" .Score}}
+
+{{.Paper.Summary}}
+{{end}}`))
+
+// Write a digest as Markdown, with a section on each paper.
+//
+// Returns nil if we write the digest successfully, otherwise returns an error.
+func (digest Digest) WriteMarkdown(w io.Writer) error {
+	return markdownDigest.Execute(w, digest)
+}
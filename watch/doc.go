@@ -0,0 +1,7 @@
+// Package watch keeps watchlists of saved topics, and checks them for papers newly submitted to arXiv: it adds new
+// papers on each topic to the document index, skips papers it reported before, ranks the new papers by their
+// relevance to the topic, and sends a digest of them to notifiers, e.g., Markdown files, email, or webhooks.
+//
+// The watchlists, along with the papers each watchlist has seen, persist in a JSON state file in the data directory,
+// so that checks pick up where earlier checks, possibly of other processes, left off.
+package watch
@@ -0,0 +1,203 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sends digests of new papers somewhere, e.g., to a file, a mailbox, or a chat channel.
+type Notifier interface {
+	// Send a digest.
+	//
+	// Returns nil if we send the digest successfully, otherwise returns an error.
+	Notify(ctx context.Context, digest Digest) error
+}
+
+// Writes digests as Markdown to a writer, e.g., standard output.
+//
+// Implements the [Notifier] interface.
+type WriterNotifier struct {
+	Writer io.Writer
+}
+
+// Implements the [Notifier.Notify] API call.
+func (notifier *WriterNotifier) Notify(ctx context.Context, digest Digest) error {
+	return digest.WriteMarkdown(notifier.Writer)
+}
+
+// Writes each digest as a Markdown file to a directory, named after the watchlist and the time of the check, e.g.,
+// "agents-20250102-150405.md".
+//
+// Implements the [Notifier] interface.
+type FileNotifier struct {
+	Directory string
+}
+
+// Implements the [Notifier.Notify] API call.
+func (notifier *FileNotifier) Notify(ctx context.Context, digest Digest) error {
+	if err := os.MkdirAll(notifier.Directory, 0755); err != nil {
+		return fmt.Errorf("failed while creating digest directory: %w", err)
+	}
+	var content bytes.Buffer
+	if err := digest.WriteMarkdown(&content); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.md", strings.ReplaceAll(digest.Watchlist, string(filepath.Separator), "_"),
+		digest.Generated.Format("20060102-150405"))
+	if err := os.WriteFile(filepath.Join(notifier.Directory, name), content.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed while writing digest: %w", err)
+	}
+	return nil
+}
+
+// How long we wait for an SMTP server to accept a digest email, so that an unresponsive server cannot stall the
+// checks of a watcher.
+const smtpTimeout = time.Minute
+
+// Emails digests as plain-text Markdown through an SMTP server. The notifier authenticates with the server only if it
+// has a user name, which the server must accept over TLS (or on localhost).
+//
+// Implements the [Notifier] interface.
+type SmtpNotifier struct {
+	// The host and port of the server, e.g., "localhost:25".
+	Address  string
+	From     string
+	To       []string
+	Username string
+	Password string
+}
+
+// Implements the [Notifier.Notify] API call.
+func (notifier *SmtpNotifier) Notify(ctx context.Context, digest Digest) error {
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", notifier.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(notifier.To, ", "))
+	fmt.Fprintf(&message, "Subject: [arxiv-researcher] %s\r\n", digest.Subject())
+	fmt.Fprintf(&message, "Date: %s\r\n", digest.Generated.Format("Mon, 02 Jan 2006 15:04:05 -0700"))
+	message.WriteString("MIME-Version: 1.0\r\nContent-Type: text/markdown; charset=utf-8\r\n\r\n")
+	var body bytes.Buffer
+	if err := digest.WriteMarkdown(&body); err != nil {
+		return err
+	}
+	message.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+	if err := notifier.send(ctx, message.Bytes()); err != nil {
+		return fmt.Errorf("failed while sending digest email: %w", err)
+	}
+	return nil
+}
+
+// Send an email through the SMTP server, as [smtp.SendMail] does, but giving up once the context is done or the
+// server takes longer than [smtpTimeout], since [smtp.SendMail] waits on the server indefinitely.
+//
+// Returns nil if the server accepts the email, otherwise returns an error.
+func (notifier *SmtpNotifier) send(ctx context.Context, message []byte) error {
+	host, _, err := net.SplitHostPort(notifier.Address)
+	if err != nil {
+		return fmt.Errorf("failed while parsing SMTP server address: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+	var dialer net.Dialer
+	connection, err := dialer.DialContext(ctx, "tcp", notifier.Address)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	connection.SetDeadline(deadline)
+	// Cancelling the context interrupts any exchange in progress.
+	stop := context.AfterFunc(ctx, func() { connection.SetDeadline(time.Now()) })
+	defer stop()
+	client, err := smtp.NewClient(connection, host)
+	if err != nil {
+		connection.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if notifier.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("SMTP server does not support authentication")
+		}
+		if err := client.Auth(smtp.PlainAuth("", notifier.Username, notifier.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(notifier.From); err != nil {
+		return err
+	}
+	for _, to := range notifier.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// Posts digests as JSON to a webhook. Besides the digest, the JSON object holds the Markdown digest under the "text"
+// key, which chat services such as Slack and Mattermost show as the message.
+//
+// Implements the [Notifier] interface.
+type WebhookNotifier struct {
+	Url string
+	// The HTTP client with which we post digests, or nil to use [http.DefaultClient].
+	HttpClient *http.Client
+}
+
+// Implements the [Notifier.Notify] API call.
+func (notifier *WebhookNotifier) Notify(ctx context.Context, digest Digest) error {
+	var text bytes.Buffer
+	if err := digest.WriteMarkdown(&text); err != nil {
+		return err
+	}
+	payload, err := json.Marshal(struct {
+		Digest
+		Text string `json:"text"`
+	}{digest, text.String()})
+	if err != nil {
+		return fmt.Errorf("failed while marshalling digest: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, notifier.Url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed while creating webhook request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	client := notifier.HttpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed while posting digest: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("webhook rejected digest with status %s", response.Status)
+	}
+	return nil
+}
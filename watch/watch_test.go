@@ -0,0 +1,298 @@
+package watch
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// An index that ranks papers by a fixed relevance of each title.
+type fakeIndex struct {
+	added  []string
+	scores map[string]float64
+}
+
+func (index *fakeIndex) AddPapers(ctx context.Context, papers []tools.Paper) error {
+	for _, paper := range papers {
+		index.added = append(index.added, paper.Id)
+	}
+	return nil
+}
+
+func (index *fakeIndex) RankPapers(ctx context.Context, query string, papers []tools.Paper) (
+	[]tools.RankedPaper,
+	error,
+) {
+	var ranked []tools.RankedPaper
+	for _, paper := range papers {
+		ranked = append(ranked, tools.RankedPaper{Paper: paper, Score: index.scores[paper.Title]})
+	}
+	slices.SortFunc(ranked, func(x, y tools.RankedPaper) int { return cmp.Compare(y.Score, x.Score) })
+	return ranked, nil
+}
+
+// A notifier that fails.
+type failingNotifier struct{}
+
+func (notifier failingNotifier) Notify(ctx context.Context, digest Digest) error {
+	return errors.New("mailbox full")
+}
+
+// The most recent submissions on the topic, as arXiv returns them.
+var recent = []tools.Paper{
+	{Id: "2310.00001v1", Title: "Agents A", ArxivUrl: "http://arxiv.org/abs/2310.00001v1", Summary: "About agents."},
+	{Id: "2310.00002v2", Title: "Agents B", ArxivUrl: "http://arxiv.org/abs/2310.00002v2"},
+	{Id: "2310.00003v1", Title: "Off topic", ArxivUrl: "http://arxiv.org/abs/2310.00003v1"},
+}
+
+func newWatcher(index *fakeIndex, papers *[]tools.Paper) *Watcher {
+	return &Watcher{
+		Index: index,
+		Fetch: func(ctx context.Context, query string, category string, count int) ([]tools.Paper, error) {
+			return *papers, nil
+		},
+	}
+}
+
+// Checks report new relevant papers once, and the state persists across runs.
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch", StateFileName)
+	state, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = state.Add(Watchlist{Name: "agents", Query: "language model agents", Count: 10, MinScore: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Add(Watchlist{Name: "agents", Query: "again"}); !errors.Is(err, ErrDuplicateWatchlist) {
+		t.Errorf("expected ErrDuplicateWatchlist, got %v", err)
+	}
+	index := &fakeIndex{scores: map[string]float64{"Agents A": 0.7, "Agents B": 0.9, "Off topic": 0.1}}
+	papers := recent[:2]
+	watcher := newWatcher(index, &papers)
+	directory := t.TempDir()
+	watcher.Notifiers = []Notifier{&FileNotifier{Directory: directory}}
+
+	digests, err := watcher.Run(t.Context(), state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(digests) != 1 || len(digests[0].Papers) != 2 || digests[0].Papers[0].Paper.Title != "Agents B" {
+		t.Fatalf("unexpected digests %+v", digests)
+	}
+	files, err := filepath.Glob(filepath.Join(directory, "agents-*.md"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected a digest file, got %v, error %v", files, err)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(content), "# 2 new papers on language model agents\n") ||
+		!strings.Contains(string(content), "## [Agents A](http://arxiv.org/abs/2310.00001v1)\n") {
+		t.Errorf("unexpected digest\n%s", content)
+	}
+
+	// A new paper shows up alongside the papers seen before, but is not relevant enough to report.
+	papers = recent
+	state, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digests, err = watcher.Run(t.Context(), state, []string{"agents"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := []string{"2310.00001v1", "2310.00002v2", "2310.00003v1"}
+	if len(digests[0].Papers) != 0 || !slices.Equal(index.added, added) {
+		t.Errorf("expected only the new paper to be indexed and none reported, got %+v, added %v", digests, index.added)
+	}
+	list, err := state.Get("agents")
+	if err != nil || len(list.Seen) != 3 || list.LastChecked.IsZero() {
+		t.Errorf("unexpected watchlist %+v, error %v", list, err)
+	}
+	if _, err := watcher.Run(t.Context(), state, []string{"robots"}); !errors.Is(err, ErrUnknownWatchlist) {
+		t.Errorf("expected ErrUnknownWatchlist, got %v", err)
+	}
+}
+
+// Watchlists that another process adds or removes while a check runs stay added or removed.
+func TestRunKeepsConcurrentEdits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFileName)
+	state, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"agents", "robots"} {
+		if err := state.Add(Watchlist{Name: name, Query: name, Count: 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := state.Save(); err != nil {
+		t.Fatal(err)
+	}
+	papers := recent[:1]
+	watcher := newWatcher(&fakeIndex{}, &papers)
+	watcher.Fetch = func(ctx context.Context, query string, category string, count int) ([]tools.Paper, error) {
+		other, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := other.Add(Watchlist{Name: "planning", Query: "planning", Count: 10}); err != nil {
+			return nil, err
+		}
+		if err := other.Remove("robots"); err != nil {
+			return nil, err
+		}
+		return papers, other.Save()
+	}
+	if _, err := watcher.Run(t.Context(), state, []string{"agents"}); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, list := range saved.Watchlists {
+		names = append(names, list.Name)
+	}
+	if list, err := saved.Get("agents"); err != nil || len(list.Seen) != 1 || !slices.Equal(names,
+		[]string{"agents", "planning"}) {
+		t.Errorf("unexpected watchlists %v, agents %+v, error %v", names, list, err)
+	}
+}
+
+// Papers whose digest could not be sent are reported again by the next check.
+func TestRunFailedNotification(t *testing.T) {
+	state, err := Load(filepath.Join(t.TempDir(), StateFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Add(Watchlist{Name: "agents", Query: "agents", Count: 10}); err != nil {
+		t.Fatal(err)
+	}
+	papers := recent[:1]
+	watcher := newWatcher(&fakeIndex{}, &papers)
+	watcher.Notifiers = []Notifier{failingNotifier{}}
+	if _, err := watcher.Run(t.Context(), state, nil); err == nil || !strings.Contains(err.Error(), "mailbox full") {
+		t.Errorf("expected the notifier error, got %v", err)
+	}
+	watcher.Notifiers = nil
+	digests, err := watcher.Run(t.Context(), state, nil)
+	if err != nil || len(digests[0].Papers) != 1 {
+		t.Errorf("expected the paper to be reported again, got %+v, error %v", digests, err)
+	}
+}
+
+// Webhooks receive the digest along with its Markdown text.
+func TestWebhookNotifier(t *testing.T) {
+	var received map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+	digest := Digest{Watchlist: "agents", Query: "agents", Papers: []tools.RankedPaper{{Paper: recent[0], Score: 0.5}}}
+	if err := (&WebhookNotifier{Url: server.URL}).Notify(t.Context(), digest); err != nil {
+		t.Fatal(err)
+	}
+	if received["watchlist"] != "agents" || !strings.Contains(received["text"].(string), "About agents.") {
+		t.Errorf("unexpected payload %v", received)
+	}
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer rejecting.Close()
+	if err := (&WebhookNotifier{Url: rejecting.URL}).Notify(t.Context(), digest); err == nil {
+		t.Error("expected an error for a rejected digest")
+	}
+}
+
+// Digest emails go through the SMTP server.
+func TestSmtpNotifier(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	messages := make(chan string, 1)
+	go serveSmtp(listener, messages)
+
+	notifier := &SmtpNotifier{Address: listener.Addr().String(), From: "watch@localhost", To: []string{"me@localhost"}}
+	digest := Digest{Watchlist: "agents", Query: "agents", Papers: []tools.RankedPaper{{Paper: recent[0], Score: 0.5}}}
+	if err := notifier.Notify(t.Context(), digest); err != nil {
+		t.Fatal(err)
+	}
+	message := <-messages
+	if !strings.Contains(message, "Subject: [arxiv-researcher] 1 new paper on agents\r\n") ||
+		!strings.Contains(message, "## [Agents A](http://arxiv.org/abs/2310.00001v1)\r\n") {
+		t.Errorf("unexpected message\n%s", message)
+	}
+
+	// A server that never greets us does not hold up the notifier past its context.
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	notifier.Address = silent.Addr().String()
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	if err := notifier.Notify(ctx, digest); err == nil {
+		t.Error("expected an error from a silent SMTP server")
+	}
+}
+
+// Serve a single SMTP session that accepts one message, and send the message to a channel.
+func serveSmtp(listener net.Listener, messages chan<- string) {
+	connection, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer connection.Close()
+	reader := bufio.NewReader(connection)
+	io.WriteString(connection, "220 localhost ready\r\n")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+		case "DATA":
+			io.WriteString(connection, "354 go ahead\r\n")
+			var message strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				message.WriteString(line)
+			}
+			messages <- message.String()
+			io.WriteString(connection, "250 accepted\r\n")
+		case "QUIT":
+			io.WriteString(connection, "221 bye\r\n")
+			return
+		default:
+			io.WriteString(connection, "250 ok\r\n")
+		}
+	}
+}
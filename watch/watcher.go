@@ -0,0 +1,143 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"tmwong.org/arxiv-researcher-go/tools"
+)
+
+// The document index to which a [Watcher] adds new papers, and by which it ranks them.
+//
+// [tools.Index] implements the interface.
+type Index interface {
+	AddPapers(ctx context.Context, papers []tools.Paper) error
+	RankPapers(ctx context.Context, query string, papers []tools.Paper) ([]tools.RankedPaper, error)
+}
+
+// Checks watchlists for new papers, and sends digests of the new papers to notifiers.
+type Watcher struct {
+	Index Index
+	// Fetch the papers on a topic most recently submitted to arXiv. If nil, we use [tools.FetchRecentPapers]; tests
+	// may replace the function.
+	Fetch func(ctx context.Context, query string, category string, count int) ([]tools.Paper, error)
+	// The notifiers to which we send digests.
+	Notifiers []Notifier
+}
+
+// Check a watchlist for new papers: fetch the most recent submissions on the topic from arXiv, skip papers that the
+// watchlist saw before, add the new papers to the index, and rank them by their relevance to the topic, dropping those
+// less relevant than the watchlist asks for. We leave the watchlist as it is, so that callers can mark the papers as
+// seen only once they report them (see [Watcher.Run]).
+//
+// Returns the digest of the new papers, which may hold no papers, if we check the watchlist successfully, otherwise
+// returns an error.
+func (watcher *Watcher) Check(ctx context.Context, list Watchlist) (Digest, error) {
+	fetch := watcher.Fetch
+	if fetch == nil {
+		fetch = tools.FetchRecentPapers
+	}
+	digest := Digest{Watchlist: list.Name, Query: list.Query, Generated: time.Now(), Papers: []tools.RankedPaper{}}
+	papers, err := fetch(ctx, list.Query, list.Category, list.Count)
+	if err != nil {
+		return Digest{}, err
+	}
+	var fresh []tools.Paper
+	for _, paper := range papers {
+		id := tools.BaseArxivId(paper.Id)
+		if paper.Title == "" || slices.Contains(list.Seen, id) {
+			continue
+		}
+		fresh = append(fresh, paper)
+		digest.seen = append(digest.seen, id)
+	}
+	if len(fresh) == 0 {
+		return digest, nil
+	}
+	if err := watcher.Index.AddPapers(ctx, fresh); err != nil {
+		return Digest{}, fmt.Errorf("failed while indexing new papers: %w", err)
+	}
+	ranked, err := watcher.Index.RankPapers(ctx, list.Query, fresh)
+	if err != nil {
+		return Digest{}, fmt.Errorf("failed while ranking new papers: %w", err)
+	}
+	for _, paper := range ranked {
+		if paper.Score >= list.MinScore {
+			digest.Papers = append(digest.Papers, paper)
+		}
+	}
+	return digest, nil
+}
+
+// Check watchlists for new papers (see [Watcher.Check]), send the digest of each watchlist with new papers to every
+// notifier, and mark the new papers as seen, saving the state after each watchlist, so that a failure part of the way
+// through loses no progress. Saving merges the check into the state file, keeping the changes that other processes
+// made to the watchlists in the meantime. If no names are given, we check every watchlist. If sending a digest fails,
+// we leave its papers unseen, so that the next check reports them again.
+//
+// Returns the digests of the watchlists if we check them successfully, otherwise returns the digests so far along with
+// an error.
+func (watcher *Watcher) Run(ctx context.Context, state *State, names []string) ([]Digest, error) {
+	if len(names) == 0 {
+		for _, list := range state.Watchlists {
+			names = append(names, list.Name)
+		}
+	}
+	var digests []Digest
+	for _, name := range names {
+		list, err := state.Get(name)
+		if err != nil {
+			return digests, err
+		}
+		digest, err := watcher.Check(ctx, *list)
+		if err != nil {
+			return digests, fmt.Errorf("failed while checking watchlist '%s': %w", name, err)
+		}
+		if len(digest.Papers) > 0 {
+			var errs []error
+			for _, notifier := range watcher.Notifiers {
+				errs = append(errs, notifier.Notify(ctx, digest))
+			}
+			if err := errors.Join(errs...); err != nil {
+				return digests, fmt.Errorf("failed while sending digest of watchlist '%s': %w", name, err)
+			}
+		}
+		if err := state.saveCheck(*list, digest.seen, digest.Generated); err != nil {
+			return digests, err
+		}
+		slog.InfoContext(ctx, "Checked watchlist", "watchlist", name, "papers", len(digest.Papers))
+		digests = append(digests, digest)
+	}
+	return digests, nil
+}
+
+// Check watchlists (see [Watcher.Run]) now and then every interval, until the context is cancelled. We reload the
+// state before every check, so that watchlists added or removed in the meantime, e.g., from the command line, take
+// effect. A failed check does not stop later checks.
+//
+// Returns the error of the context once it is cancelled.
+func (watcher *Watcher) Watch(ctx context.Context, path string, names []string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		state, err := Load(path)
+		if err == nil {
+			_, err = watcher.Run(ctx, state, names)
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.WarnContext(ctx, "Continuing after failed check", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"tmwong.org/arxiv-researcher-go/constants"
+)
+
+// The file name of the watchlist state file in the data directory.
+const StateFileName = "watchlists.json"
+
+// The most arXiv IDs we remember as seen for each watchlist. Checks only look at the most recent submissions, so we
+// forget the oldest IDs without reporting their papers again.
+const maxSeen = 5000
+
+// Returned when a watchlist of a given name already exists.
+var ErrDuplicateWatchlist = errors.New("watchlist already exists")
+
+// Returned when no watchlist of a given name exists.
+var ErrUnknownWatchlist = errors.New("no such watchlist")
+
+// A saved topic, along with the papers on the topic that we reported before.
+type Watchlist struct {
+	// The name of the watchlist.
+	Name string `json:"name"`
+	// The arXiv query of the topic, e.g., "language model agents".
+	Query string `json:"query"`
+	// The arXiv category to which to restrict papers, or an empty string for papers of any category.
+	Category string `json:"category,omitempty"`
+	// The number of most recent submissions on the topic that each check looks at.
+	Count int `json:"count"`
+	// The lowest relevance (cosine similarity to the query) of papers to report, or zero to report every new paper.
+	MinScore float64 `json:"min_score,omitempty"`
+	// The arXiv IDs, without version suffixes, of the papers we saw before, oldest first.
+	Seen []string `json:"seen,omitempty"`
+	// When we last checked the watchlist, or the zero time if never.
+	LastChecked time.Time `json:"last_checked,omitzero"`
+}
+
+// Remember papers as seen, forgetting the oldest papers if we remember too many.
+func (list *Watchlist) markSeen(ids []string) {
+	for _, id := range ids {
+		if !slices.Contains(list.Seen, id) {
+			list.Seen = append(list.Seen, id)
+		}
+	}
+	if len(list.Seen) > maxSeen {
+		list.Seen = slices.Clone(list.Seen[len(list.Seen)-maxSeen:])
+	}
+}
+
+// The watchlists, as kept in the state file.
+type State struct {
+	// The path of the state file.
+	path string
+	// The names of the watchlists that the state file held when we last loaded or saved it.
+	saved      map[string]bool
+	Watchlists []Watchlist `json:"watchlists"`
+}
+
+// Get the path of the state file in the data directory.
+//
+// Returns the path if we locate the data directory successfully, otherwise returns an error.
+func DefaultStatePath() (string, error) {
+	directory, err := constants.DataDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, StateFileName), nil
+}
+
+// Load the watchlists from a state file. A missing file holds no watchlists.
+//
+// Returns the state if we load it successfully, otherwise returns an error.
+func Load(path string) (*State, error) {
+	state := &State{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed while reading watchlists: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed while parsing watchlists '%s': %w", path, err)
+	}
+	state.markSaved()
+	return state, nil
+}
+
+// Remember the watchlists as those that the state file holds.
+func (state *State) markSaved() {
+	state.saved = map[string]bool{}
+	for _, list := range state.Watchlists {
+		state.saved[list.Name] = true
+	}
+}
+
+// Save the watchlists to the state file. We write a temporary file and rename it over the state file, so that a crash
+// never leaves a partial state file behind.
+//
+// Returns nil if we save the state successfully, otherwise returns an error.
+func (state *State) Save() error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed while marshalling watchlists: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(state.path), 0755); err != nil {
+		return fmt.Errorf("failed while saving watchlists: %w", err)
+	}
+	temporary := state.path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0644); err != nil {
+		return fmt.Errorf("failed while saving watchlists: %w", err)
+	}
+	if err := os.Rename(temporary, state.path); err != nil {
+		return fmt.Errorf("failed while saving watchlists: %w", err)
+	}
+	state.markSaved()
+	return nil
+}
+
+// Save a check of a watchlist to the state file: reload the file, mark the papers of the check as seen in the
+// watchlist there, and save the file. Reloading keeps the changes that other processes saved since we loaded the state,
+// e.g., watchlists added or removed from the command line while a long check ran, which saving our copy of the state
+// would undo. We then adopt the reloaded watchlists. If another process removed the watchlist in the meantime, we leave
+// it removed, but we add a watchlist that the file never held.
+//
+// Returns nil if we save the check successfully, otherwise returns an error.
+func (state *State) saveCheck(list Watchlist, seen []string, checked time.Time) error {
+	latest, err := Load(state.path)
+	if err != nil {
+		return err
+	}
+	saved, err := latest.Get(list.Name)
+	if errors.Is(err, ErrUnknownWatchlist) && !state.saved[list.Name] {
+		latest.Watchlists = append(latest.Watchlists, list)
+		saved, err = latest.Get(list.Name)
+	}
+	if err == nil {
+		saved.markSeen(seen)
+		saved.LastChecked = checked
+	}
+	if err := latest.Save(); err != nil {
+		return err
+	}
+	state.Watchlists, state.saved = latest.Watchlists, latest.saved
+	return nil
+}
+
+// Add a watchlist.
+//
+// Returns nil if we add the watchlist, [ErrDuplicateWatchlist] if a watchlist of the same name exists, otherwise
+// returns an error.
+func (state *State) Add(list Watchlist) error {
+	if list.Name == "" || list.Query == "" {
+		return errors.New("watchlists need a name and a query")
+	}
+	if _, err := state.Get(list.Name); err == nil {
+		return fmt.Errorf("%w: %s", ErrDuplicateWatchlist, list.Name)
+	}
+	state.Watchlists = append(state.Watchlists, list)
+	return nil
+}
+
+// Remove a watchlist by name.
+//
+// Returns nil if we remove the watchlist, otherwise returns [ErrUnknownWatchlist].
+func (state *State) Remove(name string) error {
+	count := len(state.Watchlists)
+	state.Watchlists = slices.DeleteFunc(state.Watchlists, func(list Watchlist) bool { return list.Name == name })
+	if len(state.Watchlists) == count {
+		return fmt.Errorf("%w: %s", ErrUnknownWatchlist, name)
+	}
+	return nil
+}
+
+// Get a watchlist by name, for update in place.
+//
+// Returns the watchlist if it exists, otherwise returns [ErrUnknownWatchlist].
+func (state *State) Get(name string) (*Watchlist, error) {
+	for i := range state.Watchlists {
+		if state.Watchlists[i].Name == name {
+			return &state.Watchlists[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownWatchlist, name)
+}